@@ -0,0 +1,394 @@
+// Package accounts はアカウントの削除（猶予期間つきの論理削除と、期間経過後の完全削除）を扱う。
+//
+// 削除を申請したアカウントは users.deleted_at が設定され、プロフィールや投稿者名が表示されなくなる。
+// 猶予期間内であれば取り消すことができ、期間を過ぎると PurgeExpired がユーザーのデータをまとめて削除する。
+package accounts
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"portfolio-amarimono/aggregates"
+	"portfolio-amarimono/handlers/utils"
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/storage"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultGracePeriod は削除の申請から完全削除までの猶予期間
+const DefaultGracePeriod = 30 * 24 * time.Hour
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrNotPendingDeletion = errors.New("user is not pending deletion")
+	ErrGracePeriodOver    = errors.New("grace period has ended")
+)
+
+// Service はアカウントの削除を扱う
+type Service struct {
+	DB     *gorm.DB
+	Images storage.ImageStore
+	// ExportFiles は個人データのエクスポートのZIPの保存先（exports.Service.Files と同じ非公開のストレージ）
+	ExportFiles storage.ImageStore
+	// GracePeriod は削除の申請から完全削除までの期間
+	GracePeriod time.Duration
+	Now         func() time.Time
+}
+
+// NewService は Service を初期化するコンストラクタ
+func NewService(db *gorm.DB, images, exportFiles storage.ImageStore) *Service {
+	return &Service{
+		DB:          db,
+		Images:      images,
+		ExportFiles: exportFiles,
+		GracePeriod: DefaultGracePeriod,
+		Now:         time.Now,
+	}
+}
+
+// Deletion は削除の申請状況
+type Deletion struct {
+	UserID      string    `json:"user_id"`
+	RequestedAt time.Time `json:"requested_at"`
+	PurgeAfter  time.Time `json:"purge_after"`
+}
+
+// PurgeResult は1ユーザーの完全削除の結果（テーブルごとの件数）
+type PurgeResult struct {
+	UserID string `json:"user_id"`
+	// Deleted は削除した行数
+	Deleted map[string]int64 `json:"deleted"`
+	// Anonymized は他のユーザーのデータに残る参照を匿名化した行数（通報・監査ログ）
+	Anonymized map[string]int64 `json:"anonymized"`
+	// ImagesDeleted はストレージから削除した画像の数
+	ImagesDeleted int `json:"images_deleted"`
+}
+
+// PurgeRunResult は猶予期間を過ぎたアカウントの一括削除の結果
+type PurgeRunResult struct {
+	Purged []*PurgeResult    `json:"purged"`
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// RequestDeletion はアカウントの削除を申請する（既に申請済みの場合はその状況を返す）
+func (s *Service) RequestDeletion(userID string) (*Deletion, error) {
+	now := s.Now()
+	res := s.DB.Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NULL", userID).
+		Updates(map[string]interface{}{"deleted_at": now, "updated_at": now})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return &Deletion{UserID: userID, RequestedAt: now, PurgeAfter: now.Add(s.GracePeriod)}, nil
+	}
+
+	deletion, err := s.PendingDeletion(userID)
+	if err != nil {
+		return nil, err
+	}
+	if deletion == nil {
+		return nil, ErrUserNotFound
+	}
+	return deletion, nil
+}
+
+// PendingDeletion は削除の申請状況を返す（申請していない場合は nil）
+func (s *Service) PendingDeletion(userID string) (*Deletion, error) {
+	var user models.User
+	err := s.DB.Select("id", "deleted_at").Where("id = ?", userID).Take(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt == nil {
+		return nil, nil
+	}
+	return &Deletion{UserID: userID, RequestedAt: *user.DeletedAt, PurgeAfter: user.DeletedAt.Add(s.GracePeriod)}, nil
+}
+
+// CancelDeletion は猶予期間内の削除の申請を取り消し、アカウントを元に戻す
+func (s *Service) CancelDeletion(userID string) error {
+	deletion, err := s.PendingDeletion(userID)
+	if err != nil {
+		return err
+	}
+	if deletion == nil {
+		return ErrNotPendingDeletion
+	}
+	if !s.Now().Before(deletion.PurgeAfter) {
+		return ErrGracePeriodOver
+	}
+	return s.DB.Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", userID).
+		Updates(map[string]interface{}{"deleted_at": nil, "updated_at": s.Now()}).Error
+}
+
+// PurgeExpired は猶予期間を過ぎたアカウントを完全削除する。1件の失敗で他のアカウントの削除は止めない。
+func (s *Service) PurgeExpired(ctx context.Context) (*PurgeRunResult, error) {
+	var userIDs []string
+	if err := s.DB.Model(&models.User{}).
+		Where("deleted_at IS NOT NULL AND deleted_at <= ?", s.Now().Add(-s.GracePeriod)).
+		Order("deleted_at").
+		Pluck("id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+
+	result := &PurgeRunResult{Purged: make([]*PurgeResult, 0, len(userIDs))}
+	for _, id := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		purged, err := s.Purge(ctx, id)
+		if err != nil {
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[id] = err.Error()
+			log.Printf("⚠️ Failed to purge account %s: %v", id, err)
+			continue
+		}
+		result.Purged = append(result.Purged, purged)
+	}
+	return result, nil
+}
+
+// Purge はユーザーのデータを1つのトランザクションで完全に削除し、コミット後にストレージの画像を削除する。
+// 他のユーザーのデータに残る参照（通報の対応者・監査ログ）は削除せずに匿名化する。
+func (s *Service) Purge(ctx context.Context, userID string) (*PurgeResult, error) {
+	result := &PurgeResult{
+		UserID:     userID,
+		Deleted:    map[string]int64{},
+		Anonymized: map[string]int64{},
+	}
+	var imageKeys, exportKeys []string
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).Take(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		if user.ProfileImage != nil && *user.ProfileImage != "" {
+			imageKeys = append(imageKeys, *user.ProfileImage)
+		}
+
+		// いいね・「作った」記録は削除したうえでレシピの集計値を減らす
+		var likedRecipeIDs []string
+		if err := tx.Raw("DELETE FROM likes WHERE user_id = ? RETURNING recipe_id", userID).Scan(&likedRecipeIDs).Error; err != nil {
+			return err
+		}
+		for _, recipeID := range likedRecipeIDs {
+			if err := aggregates.LikeRemoved(tx, recipeID); err != nil {
+				return err
+			}
+		}
+		result.Deleted["likes"] = int64(len(likedRecipeIDs))
+
+		var cookedRecipeIDs []string
+		if err := tx.Raw("DELETE FROM cook_logs WHERE user_id = ? RETURNING recipe_id", userID).Scan(&cookedRecipeIDs).Error; err != nil {
+			return err
+		}
+		for _, recipeID := range cookedRecipeIDs {
+			if err := aggregates.CookLogRemoved(tx, recipeID); err != nil {
+				return err
+			}
+		}
+		result.Deleted["cook_logs"] = int64(len(cookedRecipeIDs))
+
+		// 他人のレビューに付けた「参考になった」
+		var votedReviewIDs []string
+		if err := tx.Raw("DELETE FROM review_helpful_votes WHERE user_id = ? RETURNING review_id", userID).Scan(&votedReviewIDs).Error; err != nil {
+			return err
+		}
+		for _, reviewID := range votedReviewIDs {
+			if err := tx.Exec("UPDATE reviews SET helpful_count = GREATEST(helpful_count - 1, 0) WHERE id = ?", reviewID).Error; err != nil {
+				return err
+			}
+		}
+		result.Deleted["review_helpful_votes"] = int64(len(votedReviewIDs))
+
+		// レビュー（写真・通報・投票は外部キーで一緒に削除される）
+		var photoURLs []string
+		if err := tx.Model(&models.ReviewPhoto{}).Where("user_id = ?", userID).Pluck("image_url", &photoURLs).Error; err != nil {
+			return err
+		}
+		imageKeys = append(imageKeys, photoURLs...)
+		var reviewedRecipeIDs []string
+		if err := tx.Raw("DELETE FROM reviews WHERE user_id = ? RETURNING recipe_id", userID).Scan(&reviewedRecipeIDs).Error; err != nil {
+			return err
+		}
+		seen := map[string]bool{}
+		for _, recipeID := range reviewedRecipeIDs {
+			if seen[recipeID] {
+				continue
+			}
+			seen[recipeID] = true
+			if err := aggregates.RefreshReviewStats(tx, recipeID); err != nil {
+				return err
+			}
+		}
+		result.Deleted["reviews"] = int64(len(reviewedRecipeIDs))
+		result.Deleted["review_photos"] = int64(len(photoURLs))
+
+		// 通報は削除し、モデレーターとしての対応記録と監査ログは匿名化して残す
+		if err := deleteWhere(tx, result, "review_reports", "reporter_id = ?", userID); err != nil {
+			return err
+		}
+		if err := anonymize(tx, result, "review_reports", "resolved_by = NULL", "resolved_by = ?", userID); err != nil {
+			return err
+		}
+		if err := anonymize(tx, result, "review_moderation_actions", "moderator_id = NULL", "moderator_id = ?", userID); err != nil {
+			return err
+		}
+		if err := anonymize(tx, result, "review_moderation_actions", "review_user_id = NULL, comment = NULL", "review_user_id = ?", userID); err != nil {
+			return err
+		}
+
+		// 個人データのエクスポート（ZIPはコミット後に削除する）
+		if err := tx.Model(&models.DataExport{}).Where("user_id = ? AND file_key <> ''", userID).Pluck("file_key", &exportKeys).Error; err != nil {
+			return err
+		}
+		if err := deleteWhere(tx, result, "data_exports", "user_id = ?", userID); err != nil {
+			return err
+		}
+
+		// フォロー・フォロワーの関係
+		if err := deleteWhere(tx, result, "follows", "follower_id = ? OR followee_id = ?", userID, userID); err != nil {
+			return err
+		}
+
+		// フィード（本人の新着はフォロワーのフィードからも外部キーで一緒に削除される）。
+		// 投稿したレシピに付いた他のユーザーのレビューはレシピと一緒に削除されるため、その新着も取り消す。
+		if err := deleteWhere(tx, result, "feed_items", "user_id = ?", userID); err != nil {
+			return err
+		}
+		if err := deleteWhere(tx, result, "activities",
+			"actor_id = ? OR (kind = ? AND subject_id IN (SELECT reviews.id FROM reviews JOIN recipes ON recipes.id = reviews.recipe_id WHERE recipes.user_id = ?))",
+			userID, models.ActivityKindReview, userID); err != nil {
+			return err
+		}
+
+		// 受け取った通知と通知設定は削除し、他のユーザーへの通知に残る操作者は匿名化する
+		for _, table := range []string{"notifications", "notification_preferences"} {
+			if err := deleteWhere(tx, result, table, "user_id = ?", userID); err != nil {
+				return err
+			}
+		}
+		if err := anonymize(tx, result, "notifications", "actor_id = NULL", "actor_id = ?", userID); err != nil {
+			return err
+		}
+
+		// コレクション（中のレシピの並びは外部キーで一緒に削除される）・設定・利用回数・ロール
+		for _, table := range []string{"collections", "user_ingredient_defaults", "user_dietary_restrictions", "ai_usage", "user_roles"} {
+			if err := deleteWhere(tx, result, table, "user_id = ?", userID); err != nil {
+				return err
+			}
+		}
+
+		// 投稿したレシピ（他のユーザーのいいね・レビュー・記録も外部キーで一緒に削除される）
+		var recipes []models.Recipe
+		if err := tx.Select("id", "image_url", "image_variants", "instructions").Where("user_id = ?", userID).Find(&recipes).Error; err != nil {
+			return err
+		}
+		if len(recipes) > 0 {
+			recipeIDs := make([]string, 0, len(recipes))
+			for _, recipe := range recipes {
+				recipeIDs = append(recipeIDs, recipe.ID.String())
+				if recipe.MainImage != "" {
+					imageKeys = append(imageKeys, recipe.MainImage)
+				}
+				imageKeys = append(imageKeys, recipe.ImageVariants.Keys()...)
+				for _, instruction := range recipe.Instructions {
+					if instruction.ImageURL != "" {
+						imageKeys = append(imageKeys, instruction.ImageURL)
+					}
+					imageKeys = append(imageKeys, instruction.ImageVariants.Keys()...)
+				}
+			}
+			if err := tx.Where("recipe_id IN ?", recipeIDs).Delete(&models.RecipeIngredient{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := deleteWhere(tx, result, "recipes", "user_id = ?", userID); err != nil {
+			return err
+		}
+
+		return deleteWhere(tx, result, "users", "id = ?", userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range exportKeys {
+		if s.ExportFiles == nil {
+			break
+		}
+		if err := s.ExportFiles.Delete(ctx, key); err != nil {
+			log.Printf("⚠️ Failed to delete export %s of purged account %s: %v", key, userID, err)
+		}
+	}
+
+	// 画像の削除に失敗しても、残った画像は孤立画像の回収で削除される
+	for _, key := range imageKeys {
+		if err := utils.DeleteImage(ctx, s.Images, key); err != nil {
+			log.Printf("⚠️ Failed to delete image %s of purged account %s: %v", key, userID, err)
+			continue
+		}
+		result.ImagesDeleted++
+	}
+
+	return result, nil
+}
+
+// StartPurger は猶予期間を過ぎたアカウントを interval ごとに完全削除する（interval が0以下なら何もしない）
+func (s *Service) StartPurger(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			result, err := s.PurgeExpired(ctx)
+			if err != nil {
+				log.Printf("⚠️ Failed to purge deleted accounts: %v", err)
+				continue
+			}
+			if len(result.Purged) > 0 || len(result.Failed) > 0 {
+				log.Printf("🗑️ Deleted accounts purged: %d purged, %d failed", len(result.Purged), len(result.Failed))
+			}
+		}
+	}()
+}
+
+func deleteWhere(tx *gorm.DB, result *PurgeResult, table, query string, args ...interface{}) error {
+	res := tx.Exec("DELETE FROM "+table+" WHERE "+query, args...)
+	if res.Error != nil {
+		return res.Error
+	}
+	result.Deleted[table] += res.RowsAffected
+	return nil
+}
+
+func anonymize(tx *gorm.DB, result *PurgeResult, table, set, query string, args ...interface{}) error {
+	res := tx.Exec("UPDATE "+table+" SET "+set+" WHERE "+query, args...)
+	if res.Error != nil {
+		return res.Error
+	}
+	result.Anonymized[table] += res.RowsAffected
+	return nil
+}
@@ -0,0 +1,241 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/storage"
+	"portfolio-amarimono/testharness/pgtest"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+
+const (
+	purgedUser = "00000000-0000-4000-8000-000000000001"
+	otherUser  = "00000000-0000-4000-8000-000000000002"
+
+	purgedRecipe = "00000000-0000-4000-8000-0000000000a1"
+	otherRecipe  = "00000000-0000-4000-8000-0000000000a2"
+
+	purgedReview = "00000000-0000-4000-8000-0000000000b1"
+	otherReview  = "00000000-0000-4000-8000-0000000000b2"
+)
+
+func TestDeletionLifecycle(t *testing.T) {
+	db := pgtest.Open(t)
+	createUser(t, db, purgedUser, nil)
+	s := NewService(db, nil, nil)
+	requestedAt := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	now := requestedAt
+	s.Now = func() time.Time { return now }
+
+	steps := []struct {
+		name        string
+		now         time.Time
+		run         func() error
+		wantErr     error
+		wantPending bool
+	}{
+		{"cancel without a request", requestedAt, func() error { return s.CancelDeletion(purgedUser) }, ErrNotPendingDeletion, false},
+		{"request", requestedAt, func() error { _, err := s.RequestDeletion(purgedUser); return err }, nil, true},
+		{"request again keeps the first date", requestedAt.Add(time.Hour), func() error { _, err := s.RequestDeletion(purgedUser); return err }, nil, true},
+		{"cancel within the grace period", requestedAt.Add(DefaultGracePeriod - time.Second), func() error { return s.CancelDeletion(purgedUser) }, nil, false},
+		{"request after cancelling", requestedAt, func() error { _, err := s.RequestDeletion(purgedUser); return err }, nil, true},
+		{"cancel after the grace period", requestedAt.Add(DefaultGracePeriod), func() error { return s.CancelDeletion(purgedUser) }, ErrGracePeriodOver, true},
+		{"unknown user", requestedAt, func() error { _, err := s.RequestDeletion(otherUser); return err }, ErrUserNotFound, true},
+	}
+	for _, step := range steps {
+		now = step.now
+		if err := step.run(); !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: err = %v, want %v", step.name, err, step.wantErr)
+		}
+		deletion, err := s.PendingDeletion(purgedUser)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if (deletion != nil) != step.wantPending {
+			t.Fatalf("%s: pending = %+v, want %v", step.name, deletion, step.wantPending)
+		}
+		if deletion != nil && (!deletion.RequestedAt.Equal(requestedAt) || !deletion.PurgeAfter.Equal(requestedAt.Add(DefaultGracePeriod))) {
+			t.Errorf("%s: deletion = %+v", step.name, deletion)
+		}
+	}
+}
+
+func TestPurge(t *testing.T) {
+	db := pgtest.Open(t)
+	images := storage.NewMemoryStore("https://images.example.com")
+	exportFiles := storage.NewMemoryStore("")
+	seedAccounts(t, db, images, exportFiles)
+	s := NewService(db, images, exportFiles)
+
+	result, err := s.Purge(context.Background(), purgedUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for table, want := range map[string]int64{
+		"likes":        1,
+		"reviews":      1,
+		"follows":      1,
+		"feed_items":   1,
+		"activities":   2, // 本人のレシピの新着と、そのレシピに付いた他のユーザーのレビューの新着
+		"data_exports": 1,
+		"recipes":      1,
+		"users":        1,
+	} {
+		if got := result.Deleted[table]; got != want {
+			t.Errorf("deleted %s = %d, want %d", table, got, want)
+		}
+	}
+	if result.ImagesDeleted != 2 {
+		t.Errorf("images deleted = %d, want 2", result.ImagesDeleted)
+	}
+
+	// 本人のデータと、本人のレシピに付いた他のユーザーのデータが残っていないこと
+	remaining := []struct {
+		query string
+		args  []interface{}
+		want  int64
+	}{
+		{"SELECT COUNT(*) FROM users WHERE id = ?", []interface{}{purgedUser}, 0},
+		{"SELECT COUNT(*) FROM recipes WHERE user_id = ?", []interface{}{purgedUser}, 0},
+		{"SELECT COUNT(*) FROM likes WHERE user_id = ? OR recipe_id = ?", []interface{}{purgedUser, purgedRecipe}, 0},
+		{"SELECT COUNT(*) FROM reviews WHERE id IN ?", []interface{}{[]string{purgedReview, otherReview}}, 0},
+		{"SELECT COUNT(*) FROM follows", nil, 0},
+		{"SELECT COUNT(*) FROM activities", nil, 0},
+		{"SELECT COUNT(*) FROM data_exports", nil, 0},
+		// 他のユーザーのアカウントとレシピは残り、いいね数は減る
+		{"SELECT COUNT(*) FROM users WHERE id = ?", []interface{}{otherUser}, 1},
+		{"SELECT like_count FROM recipes WHERE id = ?", []interface{}{otherRecipe}, 0},
+	}
+	for _, r := range remaining {
+		var got int64
+		if err := db.Raw(r.query, r.args...).Scan(&got).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got != r.want {
+			t.Errorf("%s = %d, want %d", r.query, got, r.want)
+		}
+	}
+
+	for store, keys := range map[storage.ImageStore][]string{
+		images:      {"profiles/purged.jpg", "recipes/purged.jpg"},
+		exportFiles: {"exports/purged.zip"},
+	} {
+		for _, key := range keys {
+			if ok, _ := store.Exists(context.Background(), key); ok {
+				t.Errorf("%s was not deleted", key)
+			}
+		}
+	}
+	if ok, _ := images.Exists(context.Background(), "recipes/other.jpg"); !ok {
+		t.Error("another user's image was deleted")
+	}
+
+	if _, err := s.Purge(context.Background(), purgedUser); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	db := pgtest.Open(t)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	users := []struct {
+		name      string
+		deletedAt *time.Time
+		wantGone  bool
+	}{
+		{"active", nil, false},
+		{"within the grace period", timePtr(now.Add(-DefaultGracePeriod + time.Second)), false},
+		{"grace period just ended", timePtr(now.Add(-DefaultGracePeriod)), true},
+		{"long ago", timePtr(now.Add(-2 * DefaultGracePeriod)), true},
+	}
+	ids := make([]string, len(users))
+	for i, u := range users {
+		ids[i] = uuid.New().String()
+		createUser(t, db, ids[i], u.deletedAt)
+	}
+
+	s := NewService(db, storage.NewMemoryStore(""), nil)
+	s.Now = func() time.Time { return now }
+	result, err := s.PurgeExpired(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 申請の古い順に削除する
+	var purged []string
+	for _, p := range result.Purged {
+		purged = append(purged, p.UserID)
+	}
+	if want := []string{ids[3], ids[2]}; !reflect.DeepEqual(purged, want) || len(result.Failed) != 0 {
+		t.Errorf("purged = %v, failed = %v, want %v", purged, result.Failed, want)
+	}
+	for i, u := range users {
+		var count int64
+		if err := db.Model(&models.User{}).Where("id = ?", ids[i]).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if gone := count == 0; gone != u.wantGone {
+			t.Errorf("%s: gone = %v, want %v", u.name, gone, u.wantGone)
+		}
+	}
+}
+
+// seedAccounts は削除するユーザーと他のユーザーが互いのレシピにいいね・レビューし、フォローしている状態を作成する
+func seedAccounts(t *testing.T, db *gorm.DB, images, exportFiles storage.ImageStore) {
+	t.Helper()
+	ctx := context.Background()
+	for store, keys := range map[storage.ImageStore][]string{
+		images:      {"profiles/purged.jpg", "recipes/purged.jpg", "recipes/other.jpg"},
+		exportFiles: {"exports/purged.zip"},
+	} {
+		for _, key := range keys {
+			if err := store.Put(ctx, key, strings.NewReader("data"), "application/octet-stream"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	createUser(t, db, purgedUser, nil)
+	createUser(t, db, otherUser, nil)
+	statements := []struct {
+		sql  string
+		args []interface{}
+	}{
+		{"UPDATE users SET profile_image = ? WHERE id = ?", []interface{}{images.URL("profiles/purged.jpg"), purgedUser}},
+		{"INSERT INTO recipes (id, name, user_id, image_url, like_count) VALUES (?, 'purged', ?, ?, 1), (?, 'other', ?, ?, 1)",
+			[]interface{}{purgedRecipe, purgedUser, images.URL("recipes/purged.jpg"), otherRecipe, otherUser, images.URL("recipes/other.jpg")}},
+		{"INSERT INTO likes (user_id, recipe_id) VALUES (?, ?), (?, ?)", []interface{}{purgedUser, otherRecipe, otherUser, purgedRecipe}},
+		{"INSERT INTO reviews (id, user_id, recipe_id, rating) VALUES (?, ?, ?, 4), (?, ?, ?, 5)",
+			[]interface{}{purgedReview, purgedUser, otherRecipe, otherReview, otherUser, purgedRecipe}},
+		{"INSERT INTO follows (follower_id, followee_id) VALUES (?, ?)", []interface{}{otherUser, purgedUser}},
+		{"INSERT INTO activities (actor_id, kind, subject_id) VALUES (?, 'recipe', ?), (?, 'review', ?)",
+			[]interface{}{purgedUser, purgedRecipe, otherUser, otherReview}},
+		{"INSERT INTO feed_items (user_id, activity_id, created_at) SELECT ?, id, created_at FROM activities WHERE actor_id = ?",
+			[]interface{}{purgedUser, otherUser}},
+		{"INSERT INTO data_exports (user_id, status, file_key) VALUES (?, 'completed', 'exports/purged.zip')", []interface{}{purgedUser}},
+	}
+	for _, st := range statements {
+		if err := db.Exec(st.sql, st.args...).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func createUser(t *testing.T, db *gorm.DB, id string, deletedAt *time.Time) {
+	t.Helper()
+	if err := db.Exec("INSERT INTO users (id, email, deleted_at) VALUES (?, ?, ?)", id, id+"@example.com", deletedAt).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
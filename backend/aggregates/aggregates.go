@@ -0,0 +1,169 @@
+package aggregates
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// recipes.like_count / review_count / rating_avg / cook_count はいいね・レビュー・「作った」記録と同じトランザクションで更新する。
+// 更新漏れや手動でのデータ修正によるずれは Reconcile で修復する。
+
+// LikeAdded はいいねの追加に合わせていいね数を1増やす
+func LikeAdded(tx *gorm.DB, recipeID string) error {
+	if err := tx.Exec("UPDATE recipes SET like_count = like_count + 1 WHERE id = ?", recipeID).Error; err != nil {
+		return fmt.Errorf("failed to increment like count: %v", err)
+	}
+	return nil
+}
+
+// LikeRemoved はいいねの削除に合わせていいね数を1減らす
+func LikeRemoved(tx *gorm.DB, recipeID string) error {
+	if err := tx.Exec("UPDATE recipes SET like_count = GREATEST(like_count - 1, 0) WHERE id = ?", recipeID).Error; err != nil {
+		return fmt.Errorf("failed to decrement like count: %v", err)
+	}
+	return nil
+}
+
+// CookLogged は「作った」記録の追加に合わせて作られた回数を1増やす
+func CookLogged(tx *gorm.DB, recipeID string) error {
+	if err := tx.Exec("UPDATE recipes SET cook_count = cook_count + 1 WHERE id = ?", recipeID).Error; err != nil {
+		return fmt.Errorf("failed to increment cook count: %v", err)
+	}
+	return nil
+}
+
+// CookLogRemoved は「作った」記録の削除に合わせて作られた回数を1減らす
+func CookLogRemoved(tx *gorm.DB, recipeID string) error {
+	if err := tx.Exec("UPDATE recipes SET cook_count = GREATEST(cook_count - 1, 0) WHERE id = ?", recipeID).Error; err != nil {
+		return fmt.Errorf("failed to decrement cook count: %v", err)
+	}
+	return nil
+}
+
+// RefreshReviewStats はレシピのレビュー数と平均評価を再計算する（非表示のレビューは数えない）。
+// 平均は差分では正確に更新できないため、レシピの行をロックしてから集計し直す。
+func RefreshReviewStats(tx *gorm.DB, recipeID string) error {
+	var locked []string
+	if err := tx.Raw("SELECT id FROM recipes WHERE id = ? FOR UPDATE", recipeID).Scan(&locked).Error; err != nil {
+		return fmt.Errorf("failed to lock recipe: %v", err)
+	}
+	err := tx.Exec(`
+		UPDATE recipes SET
+			review_count = stats.review_count,
+			rating_avg = stats.rating_avg
+		FROM (
+			SELECT COUNT(*) AS review_count, COALESCE(ROUND(AVG(rating)::numeric, 2), 0) AS rating_avg
+			FROM reviews WHERE recipe_id = ? AND status = 'visible'
+		) stats
+		WHERE recipes.id = ?`, recipeID, recipeID).Error
+	if err != nil {
+		return fmt.Errorf("failed to update review stats: %v", err)
+	}
+	return nil
+}
+
+// Drift は保存されている集計値と実際の値がずれていたレシピ
+type Drift struct {
+	RecipeID          string  `json:"recipe_id"`
+	LikeCount         int     `json:"like_count"`
+	ActualLikeCount   int     `json:"actual_like_count"`
+	ReviewCount       int     `json:"review_count"`
+	ActualReviewCount int     `json:"actual_review_count"`
+	RatingAvg         float64 `json:"rating_avg"`
+	ActualRatingAvg   float64 `json:"actual_rating_avg"`
+	CookCount         int     `json:"cook_count"`
+	ActualCookCount   int     `json:"actual_cook_count"`
+}
+
+// ReconcileResult は集計値の修復結果
+type ReconcileResult struct {
+	Checked int     `json:"checked"`
+	Drifted []Drift `json:"drifted"`
+	Fixed   int     `json:"fixed"`
+	DryRun  bool    `json:"dry_run"`
+}
+
+// actualStatsSQL はlikes・reviews・cook_logsから集計した実際の値
+const actualStatsSQL = `
+	SELECT r.id AS recipe_id,
+		r.like_count, COALESCE(l.cnt, 0) AS actual_like_count,
+		r.review_count, COALESCE(rv.cnt, 0) AS actual_review_count,
+		r.rating_avg, COALESCE(rv.avg, 0) AS actual_rating_avg,
+		r.cook_count, COALESCE(cl.cnt, 0) AS actual_cook_count
+	FROM recipes r
+	LEFT JOIN (SELECT recipe_id, COUNT(*) AS cnt FROM likes GROUP BY recipe_id) l ON l.recipe_id = r.id
+	LEFT JOIN (SELECT recipe_id, COUNT(*) AS cnt, ROUND(AVG(rating)::numeric, 2) AS avg FROM reviews WHERE status = 'visible' GROUP BY recipe_id) rv ON rv.recipe_id = r.id
+	LEFT JOIN (SELECT recipe_id, COUNT(*) AS cnt FROM cook_logs GROUP BY recipe_id) cl ON cl.recipe_id = r.id`
+
+// Reconcile はすべてのレシピの集計値を実際の値と比較し、ずれているものを修復する
+func Reconcile(db *gorm.DB, dryRun bool) (*ReconcileResult, error) {
+	result := &ReconcileResult{Drifted: make([]Drift, 0), DryRun: dryRun}
+
+	var checked int64
+	if err := db.Table("recipes").Count(&checked).Error; err != nil {
+		return nil, fmt.Errorf("failed to count recipes: %v", err)
+	}
+	result.Checked = int(checked)
+
+	if err := db.Raw(`
+		SELECT * FROM (` + actualStatsSQL + `) s
+		WHERE s.like_count <> s.actual_like_count
+			OR s.review_count <> s.actual_review_count
+			OR s.rating_avg <> s.actual_rating_avg
+			OR s.cook_count <> s.actual_cook_count
+		ORDER BY s.recipe_id`).Scan(&result.Drifted).Error; err != nil {
+		return nil, fmt.Errorf("failed to check recipe aggregates: %v", err)
+	}
+	if dryRun || len(result.Drifted) == 0 {
+		return result, nil
+	}
+
+	// 確認してから修復するまでの間の更新も反映されるよう、修復時にもう一度集計する
+	res := db.Exec(`
+		UPDATE recipes SET
+			like_count = s.actual_like_count,
+			review_count = s.actual_review_count,
+			rating_avg = s.actual_rating_avg,
+			cook_count = s.actual_cook_count
+		FROM (` + actualStatsSQL + `) s
+		WHERE recipes.id = s.recipe_id
+			AND (recipes.like_count <> s.actual_like_count
+				OR recipes.review_count <> s.actual_review_count
+				OR recipes.rating_avg <> s.actual_rating_avg
+				OR recipes.cook_count <> s.actual_cook_count)`)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to fix recipe aggregates: %v", res.Error)
+	}
+	result.Fixed = int(res.RowsAffected)
+	return result, nil
+}
+
+// StartReconciler は一定間隔で集計値のずれを修復する。ctxがキャンセルされると停止する。
+func StartReconciler(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			result, err := Reconcile(db, false)
+			if err != nil {
+				log.Printf("⚠️ Failed to reconcile recipe aggregates: %v", err)
+				continue
+			}
+			if result.Fixed > 0 {
+				log.Printf("🔧 Recipe aggregates reconciled: %d of %d recipes fixed", result.Fixed, result.Checked)
+			}
+		}
+	}()
+}
@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Message はチャット形式のメッセージ
+type Message struct {
+	Role    string
+	Content string
+}
+
+const (
+	RoleSystem = "system"
+	RoleUser   = "user"
+)
+
+// Request はテキスト生成のリクエスト。
+// PromptID・Version・Vars はスタブが決定的な出力を作るため、Messages は実際のモデルに送るために使う。
+type Request struct {
+	PromptID    string
+	Version     string
+	Vars        map[string]string
+	Messages    []Message
+	MaxTokens   int
+	Temperature float32
+}
+
+// Response はテキスト生成の結果
+type Response struct {
+	Content          string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// TextGenerator はAIによるテキスト生成を抽象化するインターフェース
+type TextGenerator interface {
+	Generate(ctx context.Context, req Request) (*Response, error)
+	// Name はプロバイダー名（ログやレスポンスに含める）
+	Name() string
+}
+
+// NewFromEnv は AI_PROVIDER 環境変数に応じてTextGeneratorを作成する。
+// 未設定の場合、開発環境ではスタブ、それ以外ではOpenAI互換APIを使用する。
+func NewFromEnv() (TextGenerator, error) {
+	provider := os.Getenv("AI_PROVIDER")
+	if provider == "" {
+		if os.Getenv("ENVIRONMENT") == "development" {
+			provider = "stub"
+		} else {
+			provider = "openai"
+		}
+	}
+
+	switch provider {
+	case "stub":
+		return NewStub(), nil
+	case "openai":
+		return NewOpenAI(OpenAIConfigFromEnv())
+	default:
+		return nil, fmt.Errorf("unknown AI_PROVIDER: %s", provider)
+	}
+}
+
+// GenerateJSON はプロンプトを描画して生成し、JSONの出力を out にデコードする
+func GenerateJSON(ctx context.Context, gen TextGenerator, promptID string, vars map[string]string, out interface{}) (*Response, error) {
+	req, err := Render(promptID, "", vars)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := gen.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(extractJSON(resp.Content)), out); err != nil {
+		return resp, fmt.Errorf("failed to parse %s response: %v", gen.Name(), err)
+	}
+	return resp, nil
+}
+
+// extractJSON はモデルがコードブロックや前置きを付けた場合でもJSON部分だけを取り出す
+func extractJSON(content string) string {
+	start := strings.IndexAny(content, "{[")
+	end := strings.LastIndexAny(content, "}]")
+	if start < 0 || end < start {
+		return content
+	}
+	return content[start : end+1]
+}
@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenAIConfig はOpenAI互換APIの接続設定
+type OpenAIConfig struct {
+	APIKey string
+	// BaseURL を変えるとローカルのOpenAI互換サーバー（Ollama・LM Studioなど）に接続できる
+	BaseURL string
+	Model   string
+}
+
+// OpenAIConfigFromEnv は環境変数から設定を作成する
+func OpenAIConfigFromEnv() OpenAIConfig {
+	model := os.Getenv("AI_MODEL")
+	if model == "" {
+		model = openai.GPT3Dot5Turbo
+	}
+	return OpenAIConfig{
+		APIKey:  os.Getenv("OPENAI_API_KEY"),
+		BaseURL: os.Getenv("AI_BASE_URL"),
+		Model:   model,
+	}
+}
+
+// OpenAIGenerator はOpenAI互換のChat Completions APIを使うTextGenerator
+type OpenAIGenerator struct {
+	client *openai.Client
+	model  string
+}
+
+// NewOpenAI はOpenAI互換APIのクライアントを作成する
+func NewOpenAI(cfg OpenAIConfig) (*OpenAIGenerator, error) {
+	if cfg.APIKey == "" && cfg.BaseURL == "" {
+		return nil, fmt.Errorf("OPENAI_API_KEY is not set")
+	}
+	clientConfig := openai.DefaultConfig(cfg.APIKey)
+	if cfg.BaseURL != "" {
+		clientConfig.BaseURL = cfg.BaseURL
+	}
+	return &OpenAIGenerator{
+		client: openai.NewClientWithConfig(clientConfig),
+		model:  cfg.Model,
+	}, nil
+}
+
+func (g *OpenAIGenerator) Name() string {
+	return "openai"
+}
+
+func (g *OpenAIGenerator) Generate(ctx context.Context, req Request) (*Response, error) {
+	messages := make([]openai.ChatCompletionMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		messages = append(messages, openai.ChatCompletionMessage{Role: m.Role, Content: m.Content})
+	}
+
+	resp, err := g.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:       g.model,
+		Messages:    messages,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat completion: %v", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("empty chat completion response")
+	}
+
+	return &Response{
+		Content:          resp.Choices[0].Message.Content,
+		Model:            resp.Model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	}, nil
+}
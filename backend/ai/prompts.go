@@ -0,0 +1,197 @@
+package ai
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// PromptTemplate はバージョン付きのプロンプト。
+// 内容を変える場合は既存のバージョンを書き換えず、新しいバージョンを追加する。
+type PromptTemplate struct {
+	ID          string
+	Version     string
+	System      string
+	User        string
+	MaxTokens   int
+	Temperature float32
+}
+
+const (
+	PromptRecipeDescription = "recipe_description"
+	PromptRecipeFAQ         = "recipe_faq"
+	PromptRecipeSteps       = "recipe_steps"
+	PromptRecipeTips        = "recipe_tips"
+)
+
+var promptTemplates = []PromptTemplate{
+	{
+		ID:      PromptRecipeDescription,
+		Version: "v1",
+		System: `あなたは料理の説明文を生成する専門家です。
+以下の条件で料理の説明文を生成してください：
+1. キャッチフレーズ（30文字以内）：料理の特徴を簡潔に表現
+2. 詳細な説明（150文字前後）：材料や調理法、味わいの特徴を具体的に説明
+
+出力形式：
+{
+  "catchphrase": "キャッチフレーズ",
+  "summary": "詳細な説明"
+}`,
+		User:        `「{{.recipe_name}}」の説明文を生成してください。`,
+		MaxTokens:   150,
+		Temperature: 0.7,
+	},
+	{
+		ID:      PromptRecipeFAQ,
+		Version: "v1",
+		System: `あなたは家庭料理のレシピ編集者です。
+材料と手順から、作る人が疑問に思いそうな質問と回答を3〜5件作成してください。
+保存方法・作り置き・失敗しやすいポイント・アレンジなど、手順に書かれていない内容を優先してください。
+回答は100文字以内で、材料と手順に書かれていない食材や調理器具を前提にしないでください。
+
+出力形式：
+{
+  "faq": [
+    {"question": "質問", "answer": "回答"}
+  ]
+}`,
+		User: `レシピ名：{{.recipe_name}}
+
+材料：
+{{.ingredients}}
+
+手順：
+{{.steps}}`,
+		MaxTokens:   800,
+		Temperature: 0.7,
+	},
+	{
+		ID:      PromptRecipeSteps,
+		Version: "v1",
+		System: `あなたは家庭料理のレシピ編集者です。
+手順の説明文を、料理に慣れていない人でも迷わないように書き直してください。
+火加減・時間・目安となる状態（色や固さ）を補い、1手順あたり80文字以内にしてください。
+手順の数と順番は変えず、材料にない食材を追加しないでください。
+
+出力形式：
+{
+  "steps": [
+    {"stepNumber": 1, "description": "書き直した説明文"}
+  ]
+}`,
+		User: `レシピ名：{{.recipe_name}}
+
+材料：
+{{.ingredients}}
+
+手順：
+{{.steps}}`,
+		MaxTokens:   1000,
+		Temperature: 0.4,
+	},
+	{
+		ID:      PromptRecipeTips,
+		Version: "v1",
+		System: `あなたは家庭料理のレシピ編集者です。
+材料のうち、家に無いことが多いものや手に入りにくいものについて、代わりに使える食材と使うときのコツを3〜5件提案してください。
+コツは60文字以内で、分量や加熱時間の調整が必要な場合はその内容を含めてください。
+
+出力形式：
+{
+  "tips": [
+    {"ingredient": "元の材料", "substitute": "代わりの食材", "note": "使うときのコツ"}
+  ]
+}`,
+		User: `レシピ名：{{.recipe_name}}
+
+材料：
+{{.ingredients}}
+
+手順：
+{{.steps}}`,
+		MaxTokens:   600,
+		Temperature: 0.7,
+	},
+}
+
+// LatestVersion はプロンプトの既定のバージョンを返す。
+// AI_PROMPT_VERSION_<ID>（例: AI_PROMPT_VERSION_RECIPE_DESCRIPTION=v1）で固定できる。
+func LatestVersion(id string) string {
+	if v := os.Getenv("AI_PROMPT_VERSION_" + strings.ToUpper(id)); v != "" {
+		return v
+	}
+	versions := make([]string, 0)
+	for _, p := range promptTemplates {
+		if p.ID == id {
+			versions = append(versions, p.Version)
+		}
+	}
+	if len(versions) == 0 {
+		return ""
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if len(versions[i]) != len(versions[j]) {
+			return len(versions[i]) < len(versions[j])
+		}
+		return versions[i] < versions[j]
+	})
+	return versions[len(versions)-1]
+}
+
+// GetPrompt は指定したバージョンのプロンプトを返す（versionが空の場合は既定のバージョン）
+func GetPrompt(id, version string) (PromptTemplate, error) {
+	if version == "" {
+		version = LatestVersion(id)
+	}
+	for _, p := range promptTemplates {
+		if p.ID == id && p.Version == version {
+			return p, nil
+		}
+	}
+	return PromptTemplate{}, fmt.Errorf("prompt %s@%s not found", id, version)
+}
+
+// Render はプロンプトに変数を埋め込んでリクエストを作る
+func Render(id, version string, vars map[string]string) (Request, error) {
+	p, err := GetPrompt(id, version)
+	if err != nil {
+		return Request{}, err
+	}
+
+	system, err := renderTemplate(p.ID+".system", p.System, vars)
+	if err != nil {
+		return Request{}, err
+	}
+	user, err := renderTemplate(p.ID+".user", p.User, vars)
+	if err != nil {
+		return Request{}, err
+	}
+
+	return Request{
+		PromptID: p.ID,
+		Version:  p.Version,
+		Vars:     vars,
+		Messages: []Message{
+			{Role: RoleSystem, Content: system},
+			{Role: RoleUser, Content: user},
+		},
+		MaxTokens:   p.MaxTokens,
+		Temperature: p.Temperature,
+	}, nil
+}
+
+func renderTemplate(name, text string, vars map[string]string) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse prompt %s: %v", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %v", name, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,142 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// StubGenerator は外部APIを呼ばずに、プロンプトIDと変数から決定的な出力を返すTextGenerator。
+// 開発環境・オフラインでのテストに使う。
+type StubGenerator struct {
+	renderers map[string]func(vars map[string]string) interface{}
+}
+
+// NewStub はスタブを作成する
+func NewStub() *StubGenerator {
+	return &StubGenerator{
+		renderers: map[string]func(vars map[string]string) interface{}{
+			PromptRecipeDescription: stubRecipeDescription,
+			PromptRecipeFAQ:         stubRecipeFAQ,
+			PromptRecipeSteps:       stubRecipeSteps,
+			PromptRecipeTips:        stubRecipeTips,
+		},
+	}
+}
+
+func (g *StubGenerator) Name() string {
+	return "stub"
+}
+
+func (g *StubGenerator) Generate(ctx context.Context, req Request) (*Response, error) {
+	render, ok := g.renderers[req.PromptID]
+	if !ok {
+		return nil, fmt.Errorf("stub has no template for prompt %s", req.PromptID)
+	}
+	content, err := json.Marshal(render(req.Vars))
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Content: string(content), Model: "stub"}, nil
+}
+
+// 代表的な料理の説明文
+var stubDescriptions = map[string]struct {
+	Catchphrase string
+	Summary     string
+}{
+	"カレー": {
+		Catchphrase: "スパイスの香りが食欲をそそる、定番の家庭料理",
+		Summary:     "玉ねぎをじっくり炒めることで甘みが増し、スパイスの風味と絶妙に調和します。具材の旨味が溶け込んだ濃厚なルーは、ご飯が進むこと間違いなし。",
+	},
+	"親子丼": {
+		Catchphrase: "卵と鶏肉の相性が抜群、和食の定番メニュー",
+		Summary:     "鶏肉の旨味と卵のまろやかさが絶妙に調和した、日本人なら誰もが大好きな丼物。甘辛いタレがご飯とよく合い、心も体も満たされる一品です。",
+	},
+	"味噌汁": {
+		Catchphrase: "日本の食卓に欠かせない、心を癒す伝統の味",
+		Summary:     "出汁の旨味と味噌の香りが広がる、日本人の心のふるさと。具材の組み合わせで無限のバリエーションが楽しめ、毎日飲んでも飽きない味わいです。",
+	},
+	"ハンバーグ": {
+		Catchphrase: "肉汁たっぷり、手作りならではの美味しさ",
+		Summary:     "挽肉の旨味を最大限に引き出す、手作りハンバーグ。肉汁が溢れ出すジューシーな食感と、デミグラスソースの深い味わいが絶妙に調和します。",
+	},
+	"オムライス": {
+		Catchphrase: "ふわふわ卵とケチャップライスの絶妙なハーモニー",
+		Summary:     "ふわふわの卵と、ケチャップで味付けしたチキンライスが織りなす、子供から大人まで大好きな洋食の定番。見た目も味も大満足の一品です。",
+	},
+	"ラーメン": {
+		Catchphrase: "スープの旨味が染み込む、心も体も温まる一杯",
+		Summary:     "長時間煮込んだスープの深い味わいと、麺のコシが絶妙に調和。具材の旨味が溶け込んだ一杯は、疲れた心と体を癒してくれます。",
+	},
+}
+
+func stubRecipeDescription(vars map[string]string) interface{} {
+	name := vars["recipe_name"]
+	if d, ok := stubDescriptions[name]; ok {
+		return map[string]string{"catchphrase": d.Catchphrase, "summary": d.Summary}
+	}
+	return map[string]string{
+		"catchphrase": "栄養満点！野菜の甘みが引き立つ、家族みんなが喜ぶ絶品" + name,
+		"summary":     name + "は、新鮮な食材を使用し、丁寧に調理することで、素材の旨味を最大限に引き出した一品です。野菜の甘みとスパイスの香りが絶妙に調和し、一度食べたらやみつきになる味わいです。家族みんなで楽しめる、心も体も満たされる料理です。",
+	}
+}
+
+func stubRecipeFAQ(vars map[string]string) interface{} {
+	name := vars["recipe_name"]
+	return map[string]interface{}{
+		"faq": []map[string]string{
+			{"question": name + "は作り置きできますか？", "answer": "粗熱を取ってから密閉容器に入れ、冷蔵庫で2日ほど保存できます。食べる前にしっかり温め直してください。"},
+			{"question": "味が薄いときはどうすればいいですか？", "answer": "仕上げに調味料を少しずつ足して味を見ながら調整してください。煮詰めると味が濃くなるので入れすぎに注意しましょう。"},
+			{"question": "量を倍にしても同じ手順で作れますか？", "answer": "材料を倍にして同じ手順で作れます。加熱時間は少し長めにし、火が通っているか確認してください。"},
+		},
+	}
+}
+
+func stubRecipeSteps(vars map[string]string) interface{} {
+	steps := make([]map[string]interface{}, 0)
+	for i, line := range stubLines(vars["steps"]) {
+		description := line
+		if !strings.HasSuffix(description, "。") {
+			description += "。"
+		}
+		steps = append(steps, map[string]interface{}{
+			"stepNumber":  i + 1,
+			"description": description + "様子を見ながら進めてください。",
+		})
+	}
+	return map[string]interface{}{"steps": steps}
+}
+
+func stubRecipeTips(vars map[string]string) interface{} {
+	tips := make([]map[string]string, 0)
+	for _, line := range stubLines(vars["ingredients"]) {
+		if len(tips) >= 3 {
+			break
+		}
+		ingredient := strings.Fields(line)[0]
+		tips = append(tips, map[string]string{
+			"ingredient": ingredient,
+			"substitute": "お好みの" + ingredient + "の代わりになる食材",
+			"note":       "同じくらいの分量で置き換え、火の通り具合を見て加熱時間を調整してください。",
+		})
+	}
+	return map[string]interface{}{"tips": tips}
+}
+
+// stubLines は「- 」や「1. 」で始まる箇条書きの変数を行ごとに分ける
+func stubLines(text string) []string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "- ")
+		if i := strings.Index(line, ". "); i > 0 && i <= 3 {
+			line = line[i+2:]
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"portfolio-amarimono/testharness"
+)
+
+// apicheck は使い捨てスキーマにrecipe-dataをシードし、全ルートのレスポンスをゴールデンファイルと比較する。
+// 接続先を指定しない場合は埋め込みのPostgresを起動する（go test ./testharness と同じ）。
+//
+//	go run ./cmd/apicheck
+//	go run ./cmd/apicheck -update  # ゴールデンファイルを更新
+//	go run ./cmd/apicheck -dsn "host=localhost port=54322 user=postgres password=postgres dbname=postgres sslmode=disable" -keep
+func main() {
+	dsn := flag.String("dsn", "", "Postgresの接続文字列（未指定時は HARNESS_DATABASE_URL、それも無ければ埋め込みのPostgres）")
+	dataRoot := flag.String("data-root", "..", "original-data と recipe-data を含むディレクトリ")
+	sample := flag.Int("sample", 5, "取り込むレシピ数（0で全件）")
+	goldenDir := flag.String("golden", "testharness/testdata/golden", "ゴールデンファイルのディレクトリ")
+	update := flag.Bool("update", false, "比較せずにゴールデンファイルを書き直す")
+	keep := flag.Bool("keep", false, "終了時に使い捨てスキーマを削除しない（-dsn などで外部のPostgresを使う場合のみ）")
+	flag.Parse()
+
+	h, err := testharness.New(testharness.Config{
+		DSN:          *dsn,
+		DataRoot:     *dataRoot,
+		RecipeSample: *sample,
+		KeepSchema:   *keep,
+	})
+	if err != nil {
+		log.Fatalf("Failed to start harness: %v", err)
+	}
+
+	results, err := testharness.Run(h, testharness.DefaultScenarios(h.Seed), *goldenDir, *update)
+	if closeErr := h.Close(); closeErr != nil {
+		log.Printf("Failed to clean up harness: %v", closeErr)
+	}
+	if err != nil {
+		log.Fatalf("Failed to run scenarios: %v", err)
+	}
+	if *keep {
+		log.Printf("Schema kept: %s", h.Schema())
+	}
+
+	failed := 0
+	for _, r := range results {
+		switch {
+		case r.Updated:
+			fmt.Printf("UPDATED %s\n", r.Name)
+		case r.Diff != "":
+			failed++
+			fmt.Printf("FAIL    %s\n%s\n", r.Name, r.Diff)
+		default:
+			fmt.Printf("ok      %s\n", r.Name)
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("%d/%d scenarios failed\n", failed, len(results))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"portfolio-amarimono/db"
+	"portfolio-amarimono/jobs"
+	"portfolio-amarimono/storage"
+)
+
+// imagegc はDBから参照されていない画像をストレージから探して報告・削除する。
+// 既定では報告のみ（dry-run）で、-delete を指定した場合に猶予期間を過ぎた孤立画像を削除する。
+//
+//	go run ./cmd/imagegc                      # 孤立画像を報告
+//	go run ./cmd/imagegc -delete -grace 168h  # 7日以上前の孤立画像を削除
+func main() {
+	deleteOrphans := flag.Bool("delete", false, "孤立画像を削除する（未指定時はdry-run）")
+	grace := flag.Duration("grace", 72*time.Hour, "この期間より新しいオブジェクトは削除しない")
+	prefixes := flag.String("prefixes", strings.Join(jobs.DefaultImageGCPrefixes, ","), "走査するプレフィックス（カンマ区切り）")
+	flag.Parse()
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize database: %v", err)
+	}
+
+	imageStore, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize image storage: %v", err)
+	}
+
+	result, err := jobs.CollectOrphanedImages(context.Background(), dbConn.DB, imageStore, jobs.ImageGCOptions{
+		Prefixes:    strings.Split(*prefixes, ","),
+		GracePeriod: *grace,
+		DryRun:      !*deleteOrphans,
+	})
+	if err != nil {
+		log.Fatalf("❌ Image GC failed: %v", err)
+	}
+
+	log.Printf("🔍 Scanned: %d, Referenced: %d, Orphans: %d, In grace period: %d, Deleted: %d, Failed: %d",
+		result.Scanned, result.Referenced, len(result.Orphans), len(result.InGracePeriod), len(result.Deleted), len(result.Failed))
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		log.Fatalf("❌ Failed to write report: %v", err)
+	}
+
+	if len(result.Failed) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"portfolio-amarimono/db"
+	"portfolio-amarimono/jobs"
+)
+
+// importprompts は recipe-data/recipes/<slug>/prompts.json の画像生成プロンプトをDBに取り込む。
+// すでにプロンプトがあるステップは上書きしない。
+//
+//	go run ./cmd/importprompts -recipes ../recipe-data/recipes
+func main() {
+	recipesDir := flag.String("recipes", "../recipe-data/recipes", "recipe-data/recipes ディレクトリ")
+	flag.Parse()
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize database: %v", err)
+	}
+
+	result, err := jobs.ImportImagePrompts(dbConn.DB, *recipesDir)
+	if err != nil {
+		log.Fatalf("❌ Import failed: %v", err)
+	}
+
+	log.Printf("✅ Recipes: %d, Saved prompts: %d, Skipped: %d", result.Recipes, result.Saved, len(result.Skipped))
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		log.Fatalf("❌ Failed to write report: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"portfolio-amarimono/db"
+	"portfolio-amarimono/jobs"
+	"portfolio-amarimono/storage"
+)
+
+// migrateimages はDBから参照されている画像をストレージ間でコピーし、DBの画像URLを書き換える。
+// 進捗はチェックポイントファイルに保存されるため、中断しても同じコマンドで再開できる。
+//
+//	go run ./cmd/migrateimages -from supabase -to r2                # コピーと検証のみ
+//	go run ./cmd/migrateimages -from supabase -to r2 -rewrite-urls  # コピー後にDBを書き換え
+func main() {
+	from := flag.String("from", string(storage.TypeSupabase), "移行元のストレージ（local / r2 / s3 / supabase）")
+	to := flag.String("to", string(storage.TypeR2), "移行先のストレージ（local / r2 / s3 / supabase）")
+	checkpointPath := flag.String("checkpoint", "image_migration_checkpoint.json", "進捗を保存するファイル")
+	rewriteURLs := flag.Bool("rewrite-urls", false, "全件のコピー成功後にDBの画像URLを書き換える")
+	absoluteURLs := flag.Bool("absolute-urls", false, "書き換え時にキーではなく移行先の公開URLを保存する")
+	dryRun := flag.Bool("dry-run", false, "対象件数の確認のみ")
+	flag.Parse()
+
+	if *from == *to {
+		log.Fatalf("❌ -from and -to must be different")
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize database: %v", err)
+	}
+
+	src, err := storage.New(storage.Type(*from))
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize source storage: %v", err)
+	}
+	dst, err := storage.New(storage.Type(*to))
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize destination storage: %v", err)
+	}
+
+	result, err := jobs.MigrateImages(context.Background(), dbConn.DB, src, dst, *from, *to, jobs.ImageMigrationOptions{
+		CheckpointPath: *checkpointPath,
+		RewriteURLs:    *rewriteURLs,
+		AbsoluteURLs:   *absoluteURLs,
+		DryRun:         *dryRun,
+	})
+
+	writeFailed := false
+	if result != nil {
+		log.Printf("📦 Total: %d, Copied: %d, Skipped: %d, Missing: %d, Failed: %d",
+			result.Total, result.Copied, result.Skipped, len(result.Missing), len(result.Failed))
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if encodeErr := encoder.Encode(result); encodeErr != nil {
+			log.Printf("❌ Failed to write migration result: %v", encodeErr)
+			writeFailed = true
+		}
+	}
+	if err != nil {
+		log.Fatalf("❌ Image migration failed: %v", err)
+	}
+	if len(result.Failed) > 0 || writeFailed {
+		os.Exit(1)
+	}
+}
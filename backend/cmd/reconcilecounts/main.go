@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"portfolio-amarimono/aggregates"
+	"portfolio-amarimono/db"
+)
+
+// reconcilecounts はレシピのいいね数・レビュー数・平均評価・作られた回数を likes / reviews / cook_logs から集計し直し、ずれを修復する。
+// 既定では修復まで行い、-dry-run を指定した場合はずれの報告のみ行う。
+//
+//	go run ./cmd/reconcilecounts -dry-run
+func main() {
+	dryRun := flag.Bool("dry-run", false, "ずれの報告のみで修復しない")
+	flag.Parse()
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize database: %v", err)
+	}
+
+	result, err := aggregates.Reconcile(dbConn.DB, *dryRun)
+	if err != nil {
+		log.Fatalf("❌ Reconcile failed: %v", err)
+	}
+
+	log.Printf("🔍 Checked: %d, Drifted: %d, Fixed: %d", result.Checked, len(result.Drifted), result.Fixed)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		log.Fatalf("❌ Failed to write report: %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"portfolio-amarimono/db"
+	"portfolio-amarimono/recommend"
+)
+
+// recsimilarity はおすすめ用のレシピ間の類似度を再計算する（cronなどから定期実行する用）。
+// APIサーバーも RECOMMENDATION_REFRESH_INTERVAL の間隔で同じ処理を実行している。
+//
+//	go run ./cmd/recsimilarity -neighbors 30
+func main() {
+	neighbors := flag.Int("neighbors", recommend.DefaultNeighbors, "レシピごとに保存する類似レシピの数")
+	flag.Parse()
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize database: %v", err)
+	}
+
+	result, err := recommend.RefreshSimilarities(dbConn.DB, *neighbors)
+	if err != nil {
+		log.Fatalf("❌ Failed to refresh recipe similarities: %v", err)
+	}
+
+	log.Printf("✅ Recipe similarities refreshed: %d recipes, %d pairs (%s)", result.Recipes, result.Pairs, result.Duration)
+}
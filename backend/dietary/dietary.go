@@ -0,0 +1,189 @@
+// Package dietary はアレルゲン（特定原材料）と食事制限による除外を扱う。
+//
+// 具材には「この制限がある人は避ける」という意味でアレルゲン・食事制限のコードをタグ付けし
+// （ingredient_dietary_tags）、ユーザーは除外したいコードをプロフィールに保存する
+// （user_dietary_restrictions）。レシピ検索・おすすめは、保存されたコードのタグが付いた
+// 具材を使うレシピを除き、どの具材が原因で除外したかを返す。
+package dietary
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"portfolio-amarimono/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 制限の種類
+const (
+	KindAllergen = "allergen"
+	KindDiet     = "diet"
+)
+
+// アレルゲン（食品表示で義務付けられている特定原材料）
+const (
+	AllergenShrimp    = "shrimp"
+	AllergenCrab      = "crab"
+	AllergenWalnut    = "walnut"
+	AllergenWheat     = "wheat"
+	AllergenBuckwheat = "buckwheat"
+	AllergenEgg       = "egg"
+	AllergenMilk      = "milk"
+	AllergenPeanut    = "peanut"
+)
+
+// 食事制限
+const (
+	DietVegetarian = "vegetarian"
+	DietNoPork     = "no_pork"
+	DietLowSalt    = "low_salt"
+)
+
+// ErrUnknownRestriction は定義されていないコードが指定された場合のエラー
+var ErrUnknownRestriction = errors.New("unknown dietary restriction")
+
+// Restriction はアレルゲン・食事制限の定義
+type Restriction struct {
+	Code  string `json:"code"`
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+}
+
+// Restrictions は選択できるアレルゲン・食事制限の一覧（表示順）
+var Restrictions = []Restriction{
+	{Code: AllergenShrimp, Kind: KindAllergen, Label: "えび"},
+	{Code: AllergenCrab, Kind: KindAllergen, Label: "かに"},
+	{Code: AllergenWalnut, Kind: KindAllergen, Label: "くるみ"},
+	{Code: AllergenWheat, Kind: KindAllergen, Label: "小麦"},
+	{Code: AllergenBuckwheat, Kind: KindAllergen, Label: "そば"},
+	{Code: AllergenEgg, Kind: KindAllergen, Label: "卵"},
+	{Code: AllergenMilk, Kind: KindAllergen, Label: "乳"},
+	{Code: AllergenPeanut, Kind: KindAllergen, Label: "落花生"},
+	{Code: DietVegetarian, Kind: KindDiet, Label: "ベジタリアン"},
+	{Code: DietNoPork, Kind: KindDiet, Label: "豚肉不使用"},
+	{Code: DietLowSalt, Kind: KindDiet, Label: "減塩"},
+}
+
+// Lookup はコードに対応する定義を返す
+func Lookup(code string) (Restriction, bool) {
+	for _, r := range Restrictions {
+		if r.Code == code {
+			return r, true
+		}
+	}
+	return Restriction{}, false
+}
+
+// normalize は重複を除いて表示順に並べる（未定義のコードがあれば ErrUnknownRestriction）
+func normalize(codes []string) ([]string, error) {
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		if _, ok := Lookup(code); !ok {
+			return nil, ErrUnknownRestriction
+		}
+		seen[code] = true
+	}
+	result := make([]string, 0, len(seen))
+	for _, r := range Restrictions {
+		if seen[r.Code] {
+			result = append(result, r.Code)
+		}
+	}
+	return result, nil
+}
+
+// UserRestrictions はユーザーが保存した除外コードを表示順で返す
+func UserRestrictions(db *gorm.DB, userID string) ([]string, error) {
+	var codes []string
+	if err := db.Model(&models.UserDietaryRestriction{}).Where("user_id = ?", userID).Pluck("code", &codes).Error; err != nil {
+		return nil, err
+	}
+	// 定義から外れたコードが残っていても無視する
+	known := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if _, ok := Lookup(code); ok {
+			known = append(known, code)
+		}
+	}
+	sortCodes(known)
+	return known, nil
+}
+
+// SetUserRestrictions はユーザーの除外コードを codes で置き換える
+func SetUserRestrictions(db *gorm.DB, userID string, codes []string, now time.Time) ([]string, error) {
+	codes, err := normalize(codes)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserDietaryRestriction{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		rows := make([]models.UserDietaryRestriction, 0, len(codes))
+		for _, code := range codes {
+			rows = append(rows, models.UserDietaryRestriction{UserID: models.FromUUID(id), Code: code, CreatedAt: now})
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// IngredientTags は具材に付いているタグを表示順で返す
+func IngredientTags(db *gorm.DB, ingredientID int) ([]string, error) {
+	var codes []string
+	if err := db.Model(&models.IngredientDietaryTag{}).Where("ingredient_id = ?", ingredientID).Pluck("code", &codes).Error; err != nil {
+		return nil, err
+	}
+	if codes == nil {
+		codes = []string{}
+	}
+	sortCodes(codes)
+	return codes, nil
+}
+
+// SetIngredientTags は具材のタグを codes で置き換える
+func SetIngredientTags(db *gorm.DB, ingredientID int, codes []string) ([]string, error) {
+	codes, err := normalize(codes)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("ingredient_id = ?", ingredientID).Delete(&models.IngredientDietaryTag{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		rows := make([]models.IngredientDietaryTag, 0, len(codes))
+		for _, code := range codes {
+			rows = append(rows, models.IngredientDietaryTag{IngredientID: ingredientID, Code: code})
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// sortCodes はコードを Restrictions の表示順に並べる
+func sortCodes(codes []string) {
+	order := make(map[string]int, len(Restrictions))
+	for i, r := range Restrictions {
+		order[r.Code] = i
+	}
+	sort.SliceStable(codes, func(i, j int) bool { return order[codes[i]] < order[codes[j]] })
+}
@@ -0,0 +1,219 @@
+package dietary
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/testharness/pgtest"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		codes   []string
+		want    []string
+		wantErr error
+	}{
+		{"empty", nil, []string{}, nil},
+		{"display order", []string{DietLowSalt, AllergenEgg, AllergenShrimp}, []string{AllergenShrimp, AllergenEgg, DietLowSalt}, nil},
+		{"duplicates", []string{AllergenMilk, AllergenMilk}, []string{AllergenMilk}, nil},
+		{"unknown", []string{AllergenEgg, "gluten"}, nil, ErrUnknownRestriction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalize(tt.codes)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalize = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortConflicts(t *testing.T) {
+	conflicts := []Conflict{
+		{IngredientID: 7, Restriction: DietLowSalt},
+		{IngredientID: 3, Restriction: DietVegetarian},
+		{IngredientID: 7, Restriction: AllergenWheat},
+		{IngredientID: 3, Restriction: AllergenEgg},
+	}
+	sortConflicts(conflicts)
+	want := []Conflict{
+		{IngredientID: 3, Restriction: AllergenEgg},
+		{IngredientID: 3, Restriction: DietVegetarian},
+		{IngredientID: 7, Restriction: AllergenWheat},
+		{IngredientID: 7, Restriction: DietLowSalt},
+	}
+	if !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("sortConflicts = %+v, want %+v", conflicts, want)
+	}
+}
+
+const (
+	recipeOmelette = "00000000-0000-4000-8000-0000000000b1"
+	recipeSalad    = "00000000-0000-4000-8000-0000000000b2"
+	recipeUdon     = "00000000-0000-4000-8000-0000000000b3"
+
+	ingredientEgg      = 1
+	ingredientTomato   = 2
+	ingredientUdon     = 3
+	ingredientSoySauce = 4
+)
+
+func TestConflicts(t *testing.T) {
+	db := pgtest.Open(t)
+	seedTaggedRecipes(t, db)
+	all := []string{recipeOmelette, recipeSalad, recipeUdon}
+
+	tests := []struct {
+		name  string
+		ids   []string
+		codes []string
+		want  map[string][]Conflict
+	}{
+		{"no codes", all, nil, map[string][]Conflict{}},
+		{"no recipes", nil, []string{AllergenEgg}, map[string][]Conflict{}},
+		{"egg", all, []string{AllergenEgg}, map[string][]Conflict{
+			recipeOmelette: {{IngredientID: ingredientEgg, IngredientName: "卵", Restriction: AllergenEgg, Label: "卵"}},
+		}},
+		{"one ingredient with several tags", all, []string{DietLowSalt, AllergenWheat}, map[string][]Conflict{
+			recipeOmelette: {{IngredientID: ingredientSoySauce, IngredientName: "醤油", Restriction: AllergenWheat, Label: "小麦"}, {IngredientID: ingredientSoySauce, IngredientName: "醤油", Restriction: DietLowSalt, Label: "減塩"}},
+			recipeUdon: {
+				{IngredientID: ingredientUdon, IngredientName: "うどん", Restriction: AllergenWheat, Label: "小麦"},
+				{IngredientID: ingredientSoySauce, IngredientName: "醤油", Restriction: AllergenWheat, Label: "小麦"},
+				{IngredientID: ingredientSoySauce, IngredientName: "醤油", Restriction: DietLowSalt, Label: "減塩"},
+			},
+		}},
+		{"only requested recipes", []string{recipeSalad}, []string{AllergenEgg, AllergenWheat}, map[string][]Conflict{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Conflicts(db, tt.ids, tt.codes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Conflicts = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExclude(t *testing.T) {
+	db := pgtest.Open(t)
+	seedTaggedRecipes(t, db)
+	recipes := []models.Recipe{
+		{ID: models.FromUUID(uuid.MustParse(recipeUdon)), Name: "うどん"},
+		{ID: models.FromUUID(uuid.MustParse(recipeSalad)), Name: "サラダ"},
+		{ID: models.FromUUID(uuid.MustParse(recipeOmelette)), Name: "オムレツ"},
+	}
+
+	tests := []struct {
+		name         string
+		codes        []string
+		wantKept     []string
+		wantExcluded []string
+	}{
+		{"no restrictions", nil, []string{recipeUdon, recipeSalad, recipeOmelette}, []string{}},
+		{"egg", []string{AllergenEgg}, []string{recipeUdon, recipeSalad}, []string{recipeOmelette}},
+		{"keeps the original order", []string{AllergenWheat}, []string{recipeSalad}, []string{recipeUdon, recipeOmelette}},
+		{"no conflicts", []string{AllergenShrimp}, []string{recipeUdon, recipeSalad, recipeOmelette}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, excluded, err := Exclude(db, recipes, tt.codes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keptIDs := []string{}
+			for _, r := range kept {
+				keptIDs = append(keptIDs, r.ID.String())
+			}
+			excludedIDs := []string{}
+			for _, e := range excluded {
+				excludedIDs = append(excludedIDs, e.RecipeID)
+				if len(e.Conflicts) == 0 || e.RecipeName == "" {
+					t.Errorf("exclusion without a reason: %+v", e)
+				}
+			}
+			if !reflect.DeepEqual(keptIDs, tt.wantKept) {
+				t.Errorf("kept = %v, want %v", keptIDs, tt.wantKept)
+			}
+			if !reflect.DeepEqual(excludedIDs, tt.wantExcluded) {
+				t.Errorf("excluded = %v, want %v", excludedIDs, tt.wantExcluded)
+			}
+		})
+	}
+}
+
+func TestUserRestrictions(t *testing.T) {
+	db := pgtest.Open(t)
+	userID := uuid.New().String()
+
+	saved, err := SetUserRestrictions(db, userID, []string{DietVegetarian, AllergenEgg, AllergenEgg}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{AllergenEgg, DietVegetarian}; !reflect.DeepEqual(saved, want) {
+		t.Errorf("saved = %v, want %v", saved, want)
+	}
+	got, err := UserRestrictions(db, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, saved) {
+		t.Errorf("UserRestrictions = %v, want %v", got, saved)
+	}
+
+	if _, err := SetUserRestrictions(db, userID, []string{"gluten"}, time.Now()); !errors.Is(err, ErrUnknownRestriction) {
+		t.Errorf("err = %v, want ErrUnknownRestriction", err)
+	}
+	if _, err := SetUserRestrictions(db, userID, nil, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := UserRestrictions(db, userID); err != nil || len(got) != 0 {
+		t.Errorf("after clearing = %v, %v", got, err)
+	}
+}
+
+// seedTaggedRecipes はタグ付きの具材を使うレシピを作成する。
+// オムレツ（卵・トマト・醤油）、サラダ（トマト）、うどん（うどん・醤油）
+func seedTaggedRecipes(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	statements := []string{
+		`INSERT INTO units (id, name) VALUES (1, 'g')`,
+		`INSERT INTO ingredient_genres (id, name) VALUES (1, '食材')`,
+		`INSERT INTO ingredients (id, name, genre_id, unit_id) VALUES (1, '卵', 1, 1), (2, 'トマト', 1, 1), (3, 'うどん', 1, 1), (4, '醤油', 1, 1)`,
+		`INSERT INTO ingredient_dietary_tags (ingredient_id, code) VALUES (1, 'egg'), (3, 'wheat'), (4, 'low_salt'), (4, 'wheat')`,
+	}
+	for _, sql := range statements {
+		if err := db.Exec(sql).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id, ingredients := range map[string][]int{
+		recipeOmelette: {ingredientEgg, ingredientTomato, ingredientSoySauce},
+		recipeSalad:    {ingredientTomato},
+		recipeUdon:     {ingredientUdon, ingredientSoySauce},
+	} {
+		if err := db.Exec("INSERT INTO recipes (id, name) VALUES (?, ?)", id, "recipe").Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, ingredientID := range ingredients {
+			if err := db.Exec("INSERT INTO recipe_ingredients (recipe_id, ingredient_id, quantity_required) VALUES (?, ?, 1)", id, ingredientID).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package dietary
+
+import (
+	"sort"
+
+	"portfolio-amarimono/models"
+
+	"gorm.io/gorm"
+)
+
+// Conflict はレシピが除外される原因になった具材と制限
+type Conflict struct {
+	IngredientID   int    `json:"ingredient_id"`
+	IngredientName string `json:"ingredient_name"`
+	Restriction    string `json:"restriction"`
+	Label          string `json:"label"`
+}
+
+// Exclusion は除外したレシピと、その原因
+type Exclusion struct {
+	RecipeID   string     `json:"recipe_id"`
+	RecipeName string     `json:"recipe_name"`
+	Conflicts  []Conflict `json:"conflicts"`
+}
+
+// Conflicts はレシピごとに、codes のタグが付いた具材を返す（該当する具材が無いレシピは含まない）
+func Conflicts(db *gorm.DB, recipeIDs []string, codes []string) (map[string][]Conflict, error) {
+	result := make(map[string][]Conflict)
+	if len(recipeIDs) == 0 || len(codes) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		RecipeID       string
+		IngredientID   int
+		IngredientName string
+		Code           string
+	}
+	if err := db.Table("recipe_ingredients").
+		Select("recipe_ingredients.recipe_id, ingredients.id AS ingredient_id, ingredients.name AS ingredient_name, ingredient_dietary_tags.code").
+		Joins("JOIN ingredient_dietary_tags ON ingredient_dietary_tags.ingredient_id = recipe_ingredients.ingredient_id").
+		Joins("JOIN ingredients ON ingredients.id = recipe_ingredients.ingredient_id").
+		Where("recipe_ingredients.recipe_id IN ? AND ingredient_dietary_tags.code IN ?", recipeIDs, codes).
+		Order("recipe_ingredients.recipe_id, ingredients.id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		restriction, _ := Lookup(row.Code)
+		result[row.RecipeID] = append(result[row.RecipeID], Conflict{
+			IngredientID:   row.IngredientID,
+			IngredientName: row.IngredientName,
+			Restriction:    row.Code,
+			Label:          restriction.Label,
+		})
+	}
+	for _, conflicts := range result {
+		sortConflicts(conflicts)
+	}
+	return result, nil
+}
+
+// Exclude は codes に抵触するレシピを除き、残ったレシピと除外したレシピを元の順序のまま返す
+func Exclude(db *gorm.DB, recipes []models.Recipe, codes []string) ([]models.Recipe, []Exclusion, error) {
+	excluded := make([]Exclusion, 0)
+	if len(codes) == 0 || len(recipes) == 0 {
+		return recipes, excluded, nil
+	}
+
+	ids := make([]string, len(recipes))
+	for i, recipe := range recipes {
+		ids[i] = recipe.ID.String()
+	}
+	conflicts, err := Conflicts(db, ids, codes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kept := make([]models.Recipe, 0, len(recipes))
+	for _, recipe := range recipes {
+		id := recipe.ID.String()
+		if c, ok := conflicts[id]; ok {
+			excluded = append(excluded, Exclusion{RecipeID: id, RecipeName: recipe.Name, Conflicts: c})
+			continue
+		}
+		kept = append(kept, recipe)
+	}
+	return kept, excluded, nil
+}
+
+// sortConflicts は具材のID順、同じ具材の中では制限の表示順に並べる
+func sortConflicts(conflicts []Conflict) {
+	order := make(map[string]int, len(Restrictions))
+	for i, r := range Restrictions {
+		order[r.Code] = i
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		if conflicts[i].IngredientID != conflicts[j].IngredientID {
+			return conflicts[i].IngredientID < conflicts[j].IngredientID
+		}
+		return order[conflicts[i].Restriction] < order[conflicts[j].Restriction]
+	})
+}
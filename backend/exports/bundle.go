@@ -0,0 +1,333 @@
+package exports
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/storage"
+
+	"gorm.io/gorm"
+)
+
+// csvTimeFormat は recipe-data の CSV と同じ日時の形式
+const csvTimeFormat = "2006-01-02 15:04:05.000000"
+
+// recipeCSVHeader / ingredientsCSVHeader は recipe-data/recipes/<slug>/ の CSV と同じ列
+var (
+	recipeCSVHeader = []string{
+		"id", "name", "image_url", "genre_id", "cooking_time", "cost_estimate", "summary", "nutrition",
+		"catchphrase", "instructions", "faq", "user_id", "is_public", "is_draft", "created_at", "updated_at",
+	}
+	ingredientsCSVHeader = []string{"recipe_id", "ingredient_id", "quantity_required", "unit_id"}
+)
+
+// Manifest はエクスポートに含めたデータの概要（manifest.json）
+type Manifest struct {
+	UserID     string         `json:"user_id"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Counts     map[string]int `json:"counts"`
+	Files      []string       `json:"files"`
+}
+
+// ImageLink はユーザーに関係する画像のキーと公開URL（images.json）
+type ImageLink struct {
+	Type string `json:"type"`
+	// OwnerID は画像が属するレシピ・レビューなどのID
+	OwnerID string `json:"owner_id,omitempty"`
+	Key     string `json:"key"`
+	URL     string `json:"url"`
+}
+
+// likeExport はいいねしたレシピ（likes.json）
+type likeExport struct {
+	RecipeID   string    `json:"recipe_id"`
+	RecipeName string    `json:"recipe_name"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Build はユーザーの個人データを ZIP として w に書き出す。
+// JSON のほか、投稿したレシピは recipe-data と同じ recipes/<id>/recipe.csv・ingredients.csv の形式で含める。
+func Build(ctx context.Context, db *gorm.DB, store storage.ImageStore, userID string, now time.Time, w io.Writer) (*Manifest, error) {
+	zw := zip.NewWriter(w)
+	manifest := &Manifest{UserID: userID, ExportedAt: now, Counts: map[string]int{}}
+	var images []ImageLink
+	addImage := func(kind, ownerID, key string) {
+		if key == "" {
+			return
+		}
+		key = storage.KeyFromStoreURL(store, key)
+		link := ImageLink{Type: kind, OwnerID: ownerID, Key: key}
+		if store != nil {
+			link.URL = store.URL(key)
+		}
+		images = append(images, link)
+	}
+	writeJSON := func(name string, v interface{}) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("failed to write %s: %v", name, err)
+		}
+		manifest.Files = append(manifest.Files, name)
+		return nil
+	}
+
+	// プロフィール
+	var user models.User
+	if err := db.Where("id = ?", userID).Take(&user).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %v", err)
+	}
+	var role string
+	db.Table("user_roles").Select("role").Where("user_id = ?", userID).Scan(&role)
+	user.Role = role
+	if user.Role == "" {
+		user.Role = "user"
+	}
+	if user.ProfileImage != nil {
+		addImage("profile", userID, *user.ProfileImage)
+	}
+	if err := writeJSON("profile.json", user); err != nil {
+		return nil, err
+	}
+
+	// いいね
+	likes := make([]likeExport, 0)
+	if err := db.Table("likes").
+		Select("likes.recipe_id, recipes.name AS recipe_name, likes.created_at").
+		Joins("LEFT JOIN recipes ON recipes.id = likes.recipe_id").
+		Where("likes.user_id = ?", userID).
+		Order("likes.created_at").
+		Scan(&likes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch likes: %v", err)
+	}
+	manifest.Counts["likes"] = len(likes)
+	if err := writeJSON("likes.json", likes); err != nil {
+		return nil, err
+	}
+
+	// レビュー（非表示にされたものも本人のデータとして含める）
+	reviews := make([]models.Review, 0)
+	if err := db.Preload("Photos", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Where("user_id = ?", userID).Order("created_at").Find(&reviews).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch reviews: %v", err)
+	}
+	for _, review := range reviews {
+		for _, photo := range review.Photos {
+			addImage("review_photo", review.ID.String(), photo.ImageURL)
+		}
+	}
+	manifest.Counts["reviews"] = len(reviews)
+	if err := writeJSON("reviews.json", reviews); err != nil {
+		return nil, err
+	}
+
+	// 具材の初期設定・除外するアレルゲンと食事制限・AIの利用回数・「作った」記録・コレクション
+	defaults := make([]models.UserIngredientDefault, 0)
+	if err := db.Where("user_id = ?", userID).Order("ingredient_id").Find(&defaults).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch ingredient defaults: %v", err)
+	}
+	manifest.Counts["ingredient_defaults"] = len(defaults)
+	if err := writeJSON("ingredient_defaults.json", defaults); err != nil {
+		return nil, err
+	}
+
+	restrictions := make([]models.UserDietaryRestriction, 0)
+	if err := db.Where("user_id = ?", userID).Order("code").Find(&restrictions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch dietary restrictions: %v", err)
+	}
+	manifest.Counts["dietary_restrictions"] = len(restrictions)
+	if err := writeJSON("dietary_restrictions.json", restrictions); err != nil {
+		return nil, err
+	}
+
+	usage := make([]models.AIUsage, 0)
+	if err := db.Where("user_id = ?", userID).Order("feature").Find(&usage).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch AI usage: %v", err)
+	}
+	manifest.Counts["ai_usage"] = len(usage)
+	if err := writeJSON("ai_usage.json", usage); err != nil {
+		return nil, err
+	}
+
+	cookLogs := make([]models.CookLog, 0)
+	if err := db.Where("user_id = ?", userID).Order("cooked_on, created_at").Find(&cookLogs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch cook logs: %v", err)
+	}
+	manifest.Counts["cook_logs"] = len(cookLogs)
+	if err := writeJSON("cook_logs.json", cookLogs); err != nil {
+		return nil, err
+	}
+
+	collections := make([]models.Collection, 0)
+	if err := db.Preload("Recipes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Where("user_id = ?", userID).Order("position").Find(&collections).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch collections: %v", err)
+	}
+	manifest.Counts["collections"] = len(collections)
+	if err := writeJSON("collections.json", collections); err != nil {
+		return nil, err
+	}
+
+	// フォロー・フォロワー（相手のユーザーIDのみ）
+	following := make([]models.Follow, 0)
+	if err := db.Where("follower_id = ?", userID).Order("created_at").Find(&following).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch follows: %v", err)
+	}
+	followers := make([]models.Follow, 0)
+	if err := db.Where("followee_id = ?", userID).Order("created_at").Find(&followers).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch followers: %v", err)
+	}
+	manifest.Counts["following"] = len(following)
+	manifest.Counts["followers"] = len(followers)
+	if err := writeJSON("follows.json", map[string]interface{}{"following": following, "followers": followers}); err != nil {
+		return nil, err
+	}
+
+	// 通知と通知設定
+	notifications := make([]models.Notification, 0)
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&notifications).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notifications: %v", err)
+	}
+	preferences := make([]models.NotificationPreference, 0)
+	if err := db.Where("user_id = ?", userID).Order("type").Find(&preferences).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notification preferences: %v", err)
+	}
+	manifest.Counts["notifications"] = len(notifications)
+	if err := writeJSON("notifications.json", map[string]interface{}{"notifications": notifications, "preferences": preferences}); err != nil {
+		return nil, err
+	}
+
+	// 投稿したレシピ（recipe-data と同じCSV形式）
+	var recipes []models.Recipe
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&recipes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch recipes: %v", err)
+	}
+	for _, recipe := range recipes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		id := recipe.ID.String()
+		addImage("recipe", id, recipe.MainImage)
+		for _, key := range recipe.ImageVariants.Keys() {
+			addImage("recipe_variant", id, key)
+		}
+		for _, step := range recipe.Instructions {
+			addImage("recipe_step", id, step.ImageURL)
+			for _, key := range step.ImageVariants.Keys() {
+				addImage("recipe_step_variant", id, key)
+			}
+		}
+
+		row, err := recipeCSVRow(&recipe)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeCSV(zw, manifest, "recipes/"+id+"/recipe.csv", recipeCSVHeader, [][]string{row}); err != nil {
+			return nil, err
+		}
+
+		var ingredients []models.RecipeIngredient
+		if err := db.Where("recipe_id = ?", id).Order("ingredient_id").Find(&ingredients).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch ingredients of recipe %s: %v", id, err)
+		}
+		rows := make([][]string, 0, len(ingredients))
+		for _, ing := range ingredients {
+			rows = append(rows, []string{
+				id,
+				strconv.Itoa(ing.IngredientID),
+				strconv.FormatFloat(ing.QuantityRequired, 'f', -1, 64),
+				strconv.Itoa(ing.UnitID),
+			})
+		}
+		if err := writeCSV(zw, manifest, "recipes/"+id+"/ingredients.csv", ingredientsCSVHeader, rows); err != nil {
+			return nil, err
+		}
+	}
+	manifest.Counts["recipes"] = len(recipes)
+
+	sort.SliceStable(images, func(i, j int) bool { return images[i].Type < images[j].Type })
+	if images == nil {
+		images = []ImageLink{}
+	}
+	manifest.Counts["images"] = len(images)
+	if err := writeJSON("images.json", images); err != nil {
+		return nil, err
+	}
+
+	manifest.Files = append(manifest.Files, "manifest.json")
+	if err := writeJSON("manifest.json", manifest); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// recipeCSVRow はレシピを recipe.csv の1行に変換する
+func recipeCSVRow(recipe *models.Recipe) ([]string, error) {
+	nutrition, err := json.Marshal(recipe.Nutrition)
+	if err != nil {
+		return nil, err
+	}
+	instructions, err := json.Marshal(recipe.Instructions)
+	if err != nil {
+		return nil, err
+	}
+	faq, err := json.Marshal(recipe.FAQ)
+	if err != nil {
+		return nil, err
+	}
+	userID := ""
+	if recipe.UserID != nil {
+		userID = recipe.UserID.String()
+	}
+	return []string{
+		recipe.ID.String(),
+		recipe.Name,
+		recipe.MainImage,
+		strconv.Itoa(recipe.GenreID),
+		strconv.Itoa(recipe.CookingTime),
+		strconv.Itoa(recipe.CostEstimate),
+		recipe.Summary,
+		string(nutrition),
+		recipe.Catchphrase,
+		string(instructions),
+		string(faq),
+		userID,
+		strconv.FormatBool(recipe.IsPublic),
+		strconv.FormatBool(recipe.IsDraft),
+		recipe.CreatedAt.UTC().Format(csvTimeFormat),
+		recipe.UpdatedAt.UTC().Format(csvTimeFormat),
+	}, nil
+}
+
+func writeCSV(zw *zip.Writer, manifest *Manifest, name string, header []string, rows [][]string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	manifest.Files = append(manifest.Files, name)
+	return nil
+}
@@ -0,0 +1,226 @@
+package exports
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/storage"
+	"portfolio-amarimono/testharness/pgtest"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+
+func TestCSVHeadersMatchRecipeData(t *testing.T) {
+	tests := []struct {
+		file   string
+		header []string
+	}{
+		{"recipe.csv", recipeCSVHeader},
+		{"ingredients.csv", ingredientsCSVHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			paths, _ := filepath.Glob(filepath.Join("..", "..", "recipe-data", "recipes", "*", tt.file))
+			if len(paths) == 0 {
+				t.Skip("recipe-data is not available")
+			}
+			f, err := os.Open(paths[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			header, err := csv.NewReader(f).Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(header, tt.header) {
+				t.Errorf("header = %v, want %v (%s)", header, tt.header, paths[0])
+			}
+		})
+	}
+}
+
+func TestRecipeCSVRow(t *testing.T) {
+	userID := models.FromUUID(uuid.MustParse(exportUser))
+	createdAt := time.Date(2026, 3, 10, 21, 0, 0, 123456000, time.FixedZone("JST", 9*60*60))
+	tests := []struct {
+		name   string
+		recipe models.Recipe
+		want   map[string]string
+	}{
+		{"user recipe", models.Recipe{
+			ID: models.FromUUID(uuid.MustParse(exportRecipe)), Name: "肉じゃが", GenreID: 1, CookingTime: 30,
+			UserID: &userID, IsPublic: true, CreatedAt: createdAt, UpdatedAt: createdAt,
+		}, map[string]string{
+			"id": exportRecipe, "name": "肉じゃが", "genre_id": "1", "cooking_time": "30", "user_id": exportUser,
+			"is_public": "true", "is_draft": "false", "created_at": "2026-03-10 12:00:00.123456",
+		}},
+		{"recipe without a user", models.Recipe{
+			ID: models.FromUUID(uuid.MustParse(exportRecipe)), IsDraft: true,
+			Instructions: models.JSONBInstructions{{StepNumber: 1, Description: "煮る"}},
+		}, map[string]string{
+			"user_id": "", "is_public": "false", "is_draft": "true", "instructions": `[{"stepNumber":1,"description":"煮る"}]`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row, err := recipeCSVRow(&tt.recipe)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(row) != len(recipeCSVHeader) {
+				t.Fatalf("row has %d columns, header has %d", len(row), len(recipeCSVHeader))
+			}
+			for i, column := range recipeCSVHeader {
+				if want, ok := tt.want[column]; ok && row[i] != want {
+					t.Errorf("%s = %q, want %q", column, row[i], want)
+				}
+			}
+		})
+	}
+}
+
+const (
+	exportUser   = "00000000-0000-4000-8000-000000000001"
+	otherUser    = "00000000-0000-4000-8000-000000000002"
+	exportRecipe = "00000000-0000-4000-8000-0000000000a1"
+	otherRecipe  = "00000000-0000-4000-8000-0000000000a2"
+)
+
+func TestBuild(t *testing.T) {
+	db := pgtest.Open(t)
+	store := storage.NewMemoryStore("https://images.example.com")
+	seedExport(t, db, store)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	manifest, err := Build(context.Background(), db, store, exportUser, now, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+
+	wantNames := []string{
+		"profile.json", "likes.json", "reviews.json", "ingredient_defaults.json", "dietary_restrictions.json",
+		"ai_usage.json", "cook_logs.json", "collections.json", "follows.json", "notifications.json",
+		"recipes/" + exportRecipe + "/recipe.csv", "recipes/" + exportRecipe + "/ingredients.csv",
+		"images.json", "manifest.json",
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("files = %v, want %v", names, wantNames)
+	}
+	if !reflect.DeepEqual(manifest.Files, wantNames) {
+		t.Errorf("manifest files = %v, want %v", manifest.Files, wantNames)
+	}
+
+	// 他のユーザーのいいね・レシピは含まない
+	wantCounts := map[string]int{
+		"likes": 1, "reviews": 1, "ingredient_defaults": 0, "dietary_restrictions": 0, "ai_usage": 0, "cook_logs": 0,
+		"collections": 0, "following": 1, "followers": 0, "notifications": 0, "recipes": 1, "images": 3,
+	}
+	if !reflect.DeepEqual(manifest.Counts, wantCounts) {
+		t.Errorf("counts = %v, want %v", manifest.Counts, wantCounts)
+	}
+	var written Manifest
+	if err := json.Unmarshal(files["manifest.json"], &written); err != nil {
+		t.Fatal(err)
+	}
+	if written.UserID != exportUser || !written.ExportedAt.Equal(now) || !reflect.DeepEqual(written.Counts, wantCounts) {
+		t.Errorf("manifest.json = %+v", written)
+	}
+
+	var profile models.User
+	if err := json.Unmarshal(files["profile.json"], &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.Role != "admin" {
+		t.Errorf("role = %q, want admin", profile.Role)
+	}
+
+	var images []ImageLink
+	if err := json.Unmarshal(files["images.json"], &images); err != nil {
+		t.Fatal(err)
+	}
+	wantImages := []ImageLink{
+		{Type: "profile", OwnerID: exportUser, Key: "profiles/user.jpg", URL: store.URL("profiles/user.jpg")},
+		{Type: "recipe", OwnerID: exportRecipe, Key: "recipes/main.webp", URL: store.URL("recipes/main.webp")},
+		{Type: "recipe_step", OwnerID: exportRecipe, Key: "recipes/step1.webp", URL: store.URL("recipes/step1.webp")},
+	}
+	if !reflect.DeepEqual(images, wantImages) {
+		t.Errorf("images = %+v, want %+v", images, wantImages)
+	}
+
+	ingredients, err := csv.NewReader(bytes.NewReader(files["recipes/"+exportRecipe+"/ingredients.csv"])).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantIngredients := [][]string{ingredientsCSVHeader, {exportRecipe, "1", "0.5", "1"}, {exportRecipe, "2", "2", "1"}}
+	if !reflect.DeepEqual(ingredients, wantIngredients) {
+		t.Errorf("ingredients.csv = %v, want %v", ingredients, wantIngredients)
+	}
+	recipe, err := csv.NewReader(bytes.NewReader(files["recipes/"+exportRecipe+"/recipe.csv"])).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipe) != 2 || !reflect.DeepEqual(recipe[0], recipeCSVHeader) || recipe[1][0] != exportRecipe {
+		t.Errorf("recipe.csv = %v", recipe)
+	}
+}
+
+// seedExport はエクスポートするユーザーのプロフィール・いいね・レビュー・レシピと、他のユーザーのデータを作成する
+func seedExport(t *testing.T, db *gorm.DB, store storage.ImageStore) {
+	t.Helper()
+	instructions := `[{"stepNumber":1,"description":"切る","image_url":"` + store.URL("recipes/step1.webp") + `"}]`
+	statements := []struct {
+		sql  string
+		args []interface{}
+	}{
+		{"INSERT INTO users (id, email, profile_image) VALUES (?, 'user@example.com', ?), (?, 'other@example.com', NULL)",
+			[]interface{}{exportUser, store.URL("profiles/user.jpg"), otherUser}},
+		{"INSERT INTO user_roles (user_id, role) VALUES (?, 'admin')", []interface{}{exportUser}},
+		{"INSERT INTO recipe_genres (id, name) VALUES (1, '主菜')", nil},
+		{"INSERT INTO units (id, name) VALUES (1, 'g')", nil},
+		{"INSERT INTO ingredient_genres (id, name) VALUES (1, '野菜')", nil},
+		{"INSERT INTO ingredients (id, name, genre_id, unit_id) VALUES (1, 'トマト', 1, 1), (2, '卵', 1, 1)", nil},
+		{"INSERT INTO recipes (id, name, genre_id, user_id, image_url, instructions) VALUES (?, 'mine', 1, ?, 'recipes/main.webp', ?::jsonb), (?, 'theirs', 1, ?, '', '[]')",
+			[]interface{}{exportRecipe, exportUser, instructions, otherRecipe, otherUser}},
+		{"INSERT INTO recipe_ingredients (recipe_id, ingredient_id, quantity_required, unit_id) VALUES (?, 2, 2, 1), (?, 1, 0.5, 1), (?, 1, 1, 1)",
+			[]interface{}{exportRecipe, exportRecipe, otherRecipe}},
+		{"INSERT INTO likes (user_id, recipe_id) VALUES (?, ?), (?, ?)", []interface{}{exportUser, otherRecipe, otherUser, exportRecipe}},
+		{"INSERT INTO reviews (user_id, recipe_id, rating, status) VALUES (?, ?, 4, 'hidden'), (?, ?, 5, 'visible')",
+			[]interface{}{exportUser, otherRecipe, otherUser, exportRecipe}},
+		{"INSERT INTO follows (follower_id, followee_id) VALUES (?, ?)", []interface{}{exportUser, otherUser}},
+	}
+	for _, st := range statements {
+		if err := db.Exec(st.sql, st.args...).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// Package exports はユーザーの個人データのエクスポート（ZIPの作成・保存・配信）を扱う。
+package exports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/storage"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultRetention は作成したZIPをダウンロードできる期間
+	DefaultRetention = 7 * 24 * time.Hour
+	// DefaultStorageDir は EXPORT_STORAGE_DIR が指定されていない場合にZIPを保存するディレクトリ
+	DefaultStorageDir = "./private/exports"
+	// StaleAfter を過ぎても作成中のままのエクスポートは、サーバーの再起動などで中断したものとして失敗扱いにする
+	StaleAfter = time.Hour
+)
+
+var (
+	ErrExportNotFound = errors.New("export not found")
+	ErrNotReady       = errors.New("export is not ready")
+	ErrExpired        = errors.New("export has expired")
+)
+
+// Service は個人データのエクスポートを扱う
+type Service struct {
+	DB *gorm.DB
+	// Images は画像の保存先（ZIPに含める画像のURLを作るためだけに使う）
+	Images storage.ImageStore
+	// Files はZIPの保存先。公開URLを持たない非公開のストレージで、ダウンロード用のエンドポイントからのみ配信する。
+	Files storage.ImageStore
+	// Retention は作成したZIPをダウンロードできる期間
+	Retention time.Duration
+	// Dispatch はエクスポートの作成を実行する（デフォルトはゴルーチンで非同期に実行）
+	Dispatch func(task func())
+	Now      func() time.Time
+}
+
+// NewService は Service を初期化するコンストラクタ
+func NewService(db *gorm.DB, images, files storage.ImageStore) *Service {
+	return &Service{
+		DB:        db,
+		Images:    images,
+		Files:     files,
+		Retention: DefaultRetention,
+		Dispatch:  func(task func()) { go task() },
+		Now:       time.Now,
+	}
+}
+
+// StoreFromEnv はZIPの保存先を EXPORT_STORAGE_DIR（未指定の場合は DefaultStorageDir）に作成する。
+// 画像のストレージとは別のディレクトリで、静的配信はしない。
+func StoreFromEnv() (storage.ImageStore, error) {
+	dir := os.Getenv("EXPORT_STORAGE_DIR")
+	if dir == "" {
+		dir = DefaultStorageDir
+	}
+	return storage.NewLocalStore(dir, "")
+}
+
+// Request はエクスポートの作成を受け付ける。作成中のエクスポートがある場合は新しく作らずにそれを返す。
+func (s *Service) Request(userID string) (export *models.DataExport, created bool, err error) {
+	// 中断されたまま残っているエクスポートは失敗扱いにする
+	if err := s.DB.Model(&models.DataExport{}).
+		Where("user_id = ? AND status IN ? AND created_at < ?", userID,
+			[]string{models.DataExportStatusPending, models.DataExportStatusProcessing}, s.Now().Add(-StaleAfter)).
+		Updates(map[string]interface{}{"status": models.DataExportStatusFailed, "error": "export was interrupted"}).Error; err != nil {
+		return nil, false, err
+	}
+
+	var inProgress models.DataExport
+	err = s.DB.Where("user_id = ? AND status IN ?", userID,
+		[]string{models.DataExportStatusPending, models.DataExportStatusProcessing}).
+		Order("created_at DESC").Take(&inProgress).Error
+	if err == nil {
+		return &inProgress, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	ownerID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, false, err
+	}
+	export = &models.DataExport{UserID: models.FromUUID(ownerID), Status: models.DataExportStatusPending, CreatedAt: s.Now()}
+	if err := s.DB.Create(export).Error; err != nil {
+		return nil, false, err
+	}
+
+	id := export.ID.String()
+	s.Dispatch(func() {
+		if err := s.run(context.Background(), id); err != nil {
+			log.Printf("⚠️ Failed to export data for user %s: %v", userID, err)
+		}
+	})
+	return export, true, nil
+}
+
+// Latest はユーザーの最新のエクスポートを返す
+func (s *Service) Latest(userID string) (*models.DataExport, error) {
+	var export models.DataExport
+	err := s.DB.Where("user_id = ?", userID).Order("created_at DESC").Take(&export).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// Open は完成したエクスポートのZIPを読み出す
+func (s *Service) Open(ctx context.Context, export *models.DataExport) (io.ReadCloser, error) {
+	if export.Status != models.DataExportStatusCompleted {
+		return nil, ErrNotReady
+	}
+	// 期限切れのZIPはここでも削除する（定期的な削除を待たない）
+	if export.ExpiresAt != nil && s.Now().After(*export.ExpiresAt) {
+		s.removeFile(ctx, export)
+		return nil, ErrExpired
+	}
+	if export.FileKey == "" {
+		return nil, ErrExpired
+	}
+	body, err := s.Files.Get(ctx, export.FileKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrExpired
+	}
+	return body, err
+}
+
+// FileName はダウンロード時のファイル名
+func FileName(export *models.DataExport) string {
+	return fmt.Sprintf("amarimono-export-%s.zip", export.CreatedAt.Format("20060102"))
+}
+
+// run はZIPを一時ファイルに作成してストレージに保存し、エクスポートを完了にする
+func (s *Service) run(ctx context.Context, exportID string) (err error) {
+	var export models.DataExport
+	if err := s.DB.Where("id = ?", exportID).Take(&export).Error; err != nil {
+		return err
+	}
+	if err := s.setStatus(exportID, map[string]interface{}{"status": models.DataExportStatusProcessing}); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			s.setStatus(exportID, map[string]interface{}{"status": models.DataExportStatusFailed, "error": err.Error()})
+		}
+	}()
+
+	tmp, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	userID := export.UserID.String()
+	if _, err := Build(ctx, s.DB, s.Images, userID, s.Now(), tmp); err != nil {
+		return err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("exports/%s/%s.zip", userID, exportID)
+	if err := s.Files.Put(ctx, key, tmp, "application/zip"); err != nil {
+		return fmt.Errorf("failed to store export: %v", err)
+	}
+
+	now := s.Now()
+	expiresAt := now.Add(s.Retention)
+	if err := s.setStatus(exportID, map[string]interface{}{
+		"status":       models.DataExportStatusCompleted,
+		"file_key":     key,
+		"size_bytes":   size,
+		"completed_at": now,
+		"expires_at":   expiresAt,
+	}); err != nil {
+		return err
+	}
+
+	// ダウンロードできるのは最新のエクスポートだけにし、古いZIPは削除する
+	s.removeOlder(ctx, userID, exportID)
+	return nil
+}
+
+func (s *Service) setStatus(exportID string, updates map[string]interface{}) error {
+	return s.DB.Model(&models.DataExport{}).Where("id = ?", exportID).Updates(updates).Error
+}
+
+// removeOlder はユーザーの古いエクスポートをZIPごと削除する（失敗してもログに残して続ける）
+func (s *Service) removeOlder(ctx context.Context, userID, keepID string) {
+	var older []models.DataExport
+	if err := s.DB.Where("user_id = ? AND id <> ? AND status IN ?", userID, keepID,
+		[]string{models.DataExportStatusCompleted, models.DataExportStatusFailed}).
+		Find(&older).Error; err != nil {
+		log.Printf("⚠️ Failed to list old exports of user %s: %v", userID, err)
+		return
+	}
+	for _, export := range older {
+		if export.FileKey != "" {
+			if err := s.Files.Delete(ctx, export.FileKey); err != nil {
+				log.Printf("⚠️ Failed to delete old export %s: %v", export.FileKey, err)
+				continue
+			}
+		}
+		s.DB.Delete(&export)
+	}
+}
+
+// RemoveExpired は保存期間を過ぎたZIPを削除し、削除した件数を返す。
+// エクスポートの記録は残すため、ダウンロードしようとすると ErrExpired になる。
+func (s *Service) RemoveExpired(ctx context.Context) (int, error) {
+	var expired []models.DataExport
+	if err := s.DB.Where("status = ? AND file_key <> '' AND expires_at <= ?", models.DataExportStatusCompleted, s.Now()).
+		Find(&expired).Error; err != nil {
+		return 0, err
+	}
+	removed := 0
+	for i := range expired {
+		if s.removeFile(ctx, &expired[i]) {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// removeFile は期限切れのエクスポートのZIPを削除して file_key を空にする（失敗してもログに残して続ける）
+func (s *Service) removeFile(ctx context.Context, export *models.DataExport) bool {
+	if export.FileKey == "" {
+		return false
+	}
+	if err := s.Files.Delete(ctx, export.FileKey); err != nil {
+		log.Printf("⚠️ Failed to delete expired export %s: %v", export.FileKey, err)
+		return false
+	}
+	if err := s.setStatus(export.ID.String(), map[string]interface{}{"file_key": ""}); err != nil {
+		log.Printf("⚠️ Failed to clear file key of export %s: %v", export.ID.String(), err)
+		return false
+	}
+	export.FileKey = ""
+	return true
+}
+
+// StartCleaner は保存期間を過ぎたZIPを interval ごとに削除する（interval が0以下なら何もしない）
+func (s *Service) StartCleaner(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			removed, err := s.RemoveExpired(ctx)
+			if err != nil {
+				log.Printf("⚠️ Failed to remove expired exports: %v", err)
+				continue
+			}
+			if removed > 0 {
+				log.Printf("🗑️ Expired data exports removed: %d", removed)
+			}
+		}
+	}()
+}
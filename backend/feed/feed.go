@@ -0,0 +1,110 @@
+// Package feed はフォローしているユーザーの新着フィードを扱う。
+//
+// 新着は公開した時点で activities に記録し、その時点のフォロワー全員の feed_items に書き込む（ファンアウト）。
+// フィードの取得は feed_items を読むだけで済み、リクエストのたびにフォロー中のユーザーの投稿を走査しない。
+// 書き込み側の関数は呼び出し元のトランザクション（tx）で実行し、公開状態の変更と同時に確定させる。
+package feed
+
+import (
+	"fmt"
+	"time"
+
+	"portfolio-amarimono/models"
+
+	"gorm.io/gorm"
+)
+
+// BackfillLimit はフォローした時点でフィードに取り込む、相手の過去の新着の件数
+const BackfillLimit = 20
+
+// Publish は新着を記録してフォロワーのフィードに配信する。同じ対象が既に記録されていれば何もしない。
+func Publish(tx *gorm.DB, kind, subjectID, actorID string, at time.Time) error {
+	var inserted []models.Activity
+	if err := tx.Raw(`
+		INSERT INTO activities (actor_id, kind, subject_id, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (kind, subject_id) DO NOTHING
+		RETURNING *`, actorID, kind, subjectID, at).Scan(&inserted).Error; err != nil {
+		return fmt.Errorf("failed to record activity: %v", err)
+	}
+	if len(inserted) == 0 {
+		return nil
+	}
+
+	activity := inserted[0]
+	if err := tx.Exec(`
+		INSERT INTO feed_items (user_id, activity_id, created_at)
+		SELECT follower_id, ?, ? FROM follows WHERE followee_id = ?
+		ON CONFLICT (user_id, activity_id) DO NOTHING`, activity.ID.String(), activity.CreatedAt, actorID).Error; err != nil {
+		return fmt.Errorf("failed to fan out activity: %v", err)
+	}
+	return nil
+}
+
+// Retract は新着を取り消す。フォロワーのフィードからは外部キーの ON DELETE CASCADE で一緒に消える。
+func Retract(tx *gorm.DB, kind, subjectID string) error {
+	if err := tx.Exec("DELETE FROM activities WHERE kind = ? AND subject_id = ?", kind, subjectID).Error; err != nil {
+		return fmt.Errorf("failed to retract activity: %v", err)
+	}
+	return nil
+}
+
+// SyncRecipe はレシピの公開状態に合わせて新着を記録・取り消す（投稿者のいないレシピは配信しない）
+func SyncRecipe(tx *gorm.DB, recipe *models.Recipe, now time.Time) error {
+	if recipe.IsPublic && !recipe.IsDraft && recipe.UserID != nil {
+		return Publish(tx, models.ActivityKindRecipe, recipe.ID.String(), recipe.UserID.String(), now)
+	}
+	return Retract(tx, models.ActivityKindRecipe, recipe.ID.String())
+}
+
+// RetractRecipe はレシピの削除に合わせて、レシピとそのレビューの新着を取り消す（レビューはレシピと一緒に削除されるため）
+func RetractRecipe(tx *gorm.DB, recipeID string) error {
+	if err := tx.Exec(`
+		DELETE FROM activities
+		WHERE (kind = ? AND subject_id = ?)
+			OR (kind = ? AND subject_id IN (SELECT id FROM reviews WHERE recipe_id = ?))`,
+		models.ActivityKindRecipe, recipeID, models.ActivityKindReview, recipeID).Error; err != nil {
+		return fmt.Errorf("failed to retract recipe activities: %v", err)
+	}
+	return nil
+}
+
+// SyncReview はレビューの公開状態に合わせて新着を記録・取り消す（新着の日時は投稿日時）
+func SyncReview(tx *gorm.DB, review *models.Review) error {
+	if review.Status == models.ReviewStatusVisible {
+		return Publish(tx, models.ActivityKindReview, review.ID.String(), review.UserID.String(), review.CreatedAt)
+	}
+	return Retract(tx, models.ActivityKindReview, review.ID.String())
+}
+
+// SyncCollection はコレクションの公開設定に合わせて新着を記録・取り消す
+func SyncCollection(tx *gorm.DB, collection *models.Collection, now time.Time) error {
+	if collection.IsPublic {
+		return Publish(tx, models.ActivityKindCollection, collection.ID.String(), collection.UserID.String(), now)
+	}
+	return Retract(tx, models.ActivityKindCollection, collection.ID.String())
+}
+
+// Followed はフォローした相手の最近の新着をフォロワーのフィードに取り込む
+func Followed(tx *gorm.DB, followerID, followeeID string) error {
+	if err := tx.Exec(`
+		INSERT INTO feed_items (user_id, activity_id, created_at)
+		SELECT ?, id, created_at FROM activities
+		WHERE actor_id = ?
+		ORDER BY created_at DESC
+		LIMIT ?
+		ON CONFLICT (user_id, activity_id) DO NOTHING`, followerID, followeeID, BackfillLimit).Error; err != nil {
+		return fmt.Errorf("failed to backfill feed: %v", err)
+	}
+	return nil
+}
+
+// Unfollowed はフォローを解除した相手の新着をフィードから取り除く
+func Unfollowed(tx *gorm.DB, followerID, followeeID string) error {
+	if err := tx.Exec(`
+		DELETE FROM feed_items
+		WHERE user_id = ? AND activity_id IN (SELECT id FROM activities WHERE actor_id = ?)`, followerID, followeeID).Error; err != nil {
+		return fmt.Errorf("failed to remove feed items: %v", err)
+	}
+	return nil
+}
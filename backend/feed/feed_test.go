@@ -0,0 +1,243 @@
+package feed
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/testharness/pgtest"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+
+func TestCursor(t *testing.T) {
+	want := cursor{CreatedAt: time.Date(2026, 3, 10, 12, 0, 0, 123456000, time.UTC), ID: "00000000-0000-4000-8000-000000000001"}
+	got, err := decodeCursor(encodeCursor(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != want.ID || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Errorf("decodeCursor = %+v, want %+v", got, want)
+	}
+
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	for name, value := range map[string]string{
+		"not base64":   "not a cursor!",
+		"not json":     encode("[1, 2]"),
+		"invalid id":   encode(`{"t":"2026-03-10T12:00:00Z","id":"1"}`),
+		"missing time": encode(`{"id":"00000000-0000-4000-8000-000000000001"}`),
+	} {
+		if _, err := decodeCursor(value); err == nil {
+			t.Errorf("%s: decodeCursor(%q) succeeded", name, value)
+		}
+	}
+}
+
+const (
+	actorA   = "00000000-0000-4000-8000-0000000000e1"
+	actorB   = "00000000-0000-4000-8000-0000000000e2"
+	follower = "00000000-0000-4000-8000-0000000000f1"
+	fan      = "00000000-0000-4000-8000-0000000000f2"
+	stranger = "00000000-0000-4000-8000-0000000000f3"
+
+	subject1 = "00000000-0000-4000-8000-0000000000d1"
+	subject2 = "00000000-0000-4000-8000-0000000000d2"
+)
+
+func TestPublish(t *testing.T) {
+	db := pgtest.Open(t)
+	// follower と fan は A を、stranger は B をフォローしている
+	for _, f := range [][2]string{{follower, actorA}, {fan, actorA}, {stranger, actorB}} {
+		if err := db.Exec("INSERT INTO follows (follower_id, followee_id) VALUES (?, ?)", f[0], f[1]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	at := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name string
+		run  func(tx *gorm.DB) error
+		want map[string][]string
+	}{
+		{"fans out to the followers", func(tx *gorm.DB) error {
+			return Publish(tx, models.ActivityKindRecipe, subject1, actorA, at)
+		}, map[string][]string{follower: {subject1}, fan: {subject1}, stranger: {}}},
+		{"publishing again does nothing", func(tx *gorm.DB) error {
+			return Publish(tx, models.ActivityKindRecipe, subject1, actorA, at.Add(time.Hour))
+		}, map[string][]string{follower: {subject1}, fan: {subject1}, stranger: {}}},
+		{"other actors reach only their followers", func(tx *gorm.DB) error {
+			return Publish(tx, models.ActivityKindReview, subject2, actorB, at.Add(time.Hour))
+		}, map[string][]string{follower: {subject1}, fan: {subject1}, stranger: {subject2}}},
+		{"retract removes from every feed", func(tx *gorm.DB) error {
+			return Retract(tx, models.ActivityKindRecipe, subject1)
+		}, map[string][]string{follower: {}, fan: {}, stranger: {subject2}}},
+		{"republished after retract", func(tx *gorm.DB) error {
+			return Publish(tx, models.ActivityKindRecipe, subject1, actorA, at.Add(2*time.Hour))
+		}, map[string][]string{follower: {subject1}, fan: {subject1}, stranger: {subject2}}},
+	}
+	for _, step := range steps {
+		if err := db.Transaction(step.run); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		for userID, want := range step.want {
+			if got := feedSubjects(t, db, userID); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: feed of %s = %v, want %v", step.name, userID, got, want)
+			}
+		}
+	}
+
+	// 新着の日時は最初に公開した時点のまま
+	var createdAt time.Time
+	if err := db.Raw("SELECT created_at FROM activities WHERE subject_id = ?", subject2).Scan(&createdAt).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !createdAt.Equal(at.Add(time.Hour)) {
+		t.Errorf("created_at = %v, want %v", createdAt, at.Add(time.Hour))
+	}
+}
+
+func TestFollowedAndUnfollowed(t *testing.T) {
+	db := pgtest.Open(t)
+	at := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	var subjectsA []string
+	for i := 0; i < BackfillLimit+2; i++ {
+		id := uuid.New().String()
+		subjectsA = append(subjectsA, id)
+		if err := Publish(db, models.ActivityKindRecipe, id, actorA, at.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Publish(db, models.ActivityKindCollection, subject2, actorB, at); err != nil {
+		t.Fatal(err)
+	}
+
+	// 新しい順に BackfillLimit 件
+	wantA := make([]string, 0, BackfillLimit)
+	for i := len(subjectsA) - 1; len(wantA) < BackfillLimit; i-- {
+		wantA = append(wantA, subjectsA[i])
+	}
+
+	steps := []struct {
+		name string
+		run  func(tx *gorm.DB) error
+		want []string
+	}{
+		{"backfills the latest activities", func(tx *gorm.DB) error { return Followed(tx, follower, actorA) }, wantA},
+		{"backfilling twice does nothing", func(tx *gorm.DB) error { return Followed(tx, follower, actorA) }, wantA},
+		{"another followee", func(tx *gorm.DB) error { return Followed(tx, follower, actorB) }, append(append([]string{}, wantA...), subject2)},
+		{"unfollow removes only that followee", func(tx *gorm.DB) error { return Unfollowed(tx, follower, actorA) }, []string{subject2}},
+		{"unfollowing twice does nothing", func(tx *gorm.DB) error { return Unfollowed(tx, follower, actorA) }, []string{subject2}},
+	}
+	for _, step := range steps {
+		if err := db.Transaction(step.run); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := feedSubjects(t, db, follower); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: feed = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	db := pgtest.Open(t)
+	at := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	deletedAt := at
+	for _, u := range []struct {
+		id        string
+		deletedAt *time.Time
+	}{{actorA, nil}, {actorB, &deletedAt}, {follower, nil}} {
+		if err := db.Exec("INSERT INTO users (id, email, deleted_at) VALUES (?, ?, ?)", u.id, u.id+"@example.com", u.deletedAt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{actorA, actorB} {
+		if err := db.Exec("INSERT INTO follows (follower_id, followee_id) VALUES (?, ?)", follower, f).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 非公開になったレシピと退会手続き中のユーザーのレシピはフィードに出さない
+	recipes := []struct {
+		name     string
+		actorID  string
+		isPublic bool
+	}{
+		{"r1", actorA, true},
+		{"r2", actorA, true},
+		{"private", actorA, false},
+		{"r3", actorA, true},
+		{"deleted actor", actorB, true},
+	}
+	for i, r := range recipes {
+		id := uuid.New().String()
+		if err := db.Exec("INSERT INTO recipes (id, name, user_id, is_public) VALUES (?, ?, ?, ?)", id, r.name, r.actorID, r.isPublic).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := Publish(db, models.ActivityKindRecipe, id, r.actorID, at.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewService(db)
+	tests := []struct {
+		limit     int
+		wantPages [][]string
+	}{
+		// 2件ずつ取得すると1ページ目は非公開のレシピの分だけ少なくなる
+		{2, [][]string{{"r3"}, {"r2", "r1"}}},
+		{10, [][]string{{"r3", "r2", "r1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("limit %d", tt.limit), func(t *testing.T) {
+			var pages [][]string
+			cursor := ""
+			for len(pages) < 10 {
+				page, err := s.List(follower, cursor, tt.limit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				names := []string{}
+				for _, item := range page.Items {
+					if item.Type != models.ActivityKindRecipe || item.Actor.ID != actorA {
+						t.Errorf("item = %+v", item)
+					}
+					names = append(names, item.Recipe.Name)
+				}
+				pages = append(pages, names)
+				if !page.HasNextPage {
+					break
+				}
+				cursor = page.NextCursor
+			}
+			if !reflect.DeepEqual(pages, tt.wantPages) {
+				t.Errorf("pages = %v, want %v", pages, tt.wantPages)
+			}
+		})
+	}
+
+	if _, err := s.List(follower, "broken", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("err = %v, want ErrInvalidCursor", err)
+	}
+}
+
+// feedSubjects はユーザーのフィードに入っている新着の対象IDを新しい順で返す
+func feedSubjects(t *testing.T, db *gorm.DB, userID string) []string {
+	t.Helper()
+	subjects := []string{}
+	if err := db.Raw(`
+		SELECT activities.subject_id FROM feed_items
+		JOIN activities ON activities.id = feed_items.activity_id
+		WHERE feed_items.user_id = ?
+		ORDER BY feed_items.created_at DESC, activities.subject_id`, userID).Scan(&subjects).Error; err != nil {
+		t.Fatal(err)
+	}
+	return subjects
+}
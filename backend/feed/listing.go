@@ -0,0 +1,260 @@
+package feed
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"portfolio-amarimono/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPageSize / MaxPageSize は1ページあたりの件数
+	DefaultPageSize = 20
+	MaxPageSize     = 50
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Service はフィードの取得を扱う
+type Service struct {
+	DB *gorm.DB
+}
+
+// NewService は Service を初期化するコンストラクタ
+func NewService(db *gorm.DB) *Service {
+	return &Service{
+		DB: db,
+	}
+}
+
+// Actor は新着の投稿者
+type Actor struct {
+	ID           string  `json:"id"`
+	Username     *string `json:"username"`
+	ProfileImage *string `json:"profileImage"`
+}
+
+// Item はフィードの1件。Type に応じて Recipe・Review・Collection のいずれかが入る（レビューの場合は Recipe に対象のレシピが入る）。
+type Item struct {
+	ID         string             `json:"id"`
+	Type       string             `json:"type"`
+	Actor      Actor              `json:"actor"`
+	CreatedAt  time.Time          `json:"createdAt"`
+	Recipe     *models.Recipe     `json:"recipe,omitempty"`
+	Review     *models.Review     `json:"review,omitempty"`
+	Collection *models.Collection `json:"collection,omitempty"`
+}
+
+// Page はカーソルページングされたフィード
+type Page struct {
+	Items       []Item `json:"items"`
+	NextCursor  string `json:"nextCursor,omitempty"`
+	HasNextPage bool   `json:"hasNextPage"`
+}
+
+// cursor は前のページの最後の新着
+type cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// feedRow は feed_items と activities を結合した1行
+type feedRow struct {
+	ActivityID string
+	ActorID    string
+	Kind       string
+	SubjectID  string
+	CreatedAt  time.Time
+}
+
+// List はユーザーのフィードを新しい順で返す。
+// 配信後に非公開・非表示になった対象や、退会手続き中のユーザーの新着は返さない（そのためページの件数が limit より少ないことがある）。
+func (s *Service) List(userID, cursorValue string, limit int) (*Page, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	query := s.DB.Table("feed_items").
+		Select("activities.id AS activity_id, activities.actor_id, activities.kind, activities.subject_id, feed_items.created_at").
+		Joins("JOIN activities ON activities.id = feed_items.activity_id").
+		Joins("JOIN users ON users.id = activities.actor_id AND users.deleted_at IS NULL").
+		Where("feed_items.user_id = ?", userID)
+	if cursorValue != "" {
+		cur, err := decodeCursor(cursorValue)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		query = query.Where("(feed_items.created_at, feed_items.activity_id) < (?::timestamptz, ?::uuid)", cur.CreatedAt, cur.ID)
+	}
+
+	// 次のページの有無を判定するため1件多く取得する
+	var rows []feedRow
+	if err := query.Order("feed_items.created_at DESC, feed_items.activity_id DESC").
+		Limit(limit + 1).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	page := &Page{Items: make([]Item, 0, len(rows))}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		page.HasNextPage = true
+		last := rows[len(rows)-1]
+		page.NextCursor = encodeCursor(cursor{CreatedAt: last.CreatedAt, ID: last.ActivityID})
+	}
+	if len(rows) == 0 {
+		return page, nil
+	}
+
+	subjects, err := s.loadSubjects(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		item := Item{ID: row.ActivityID, Type: row.Kind, CreatedAt: row.CreatedAt}
+		subjectID := strings.ToLower(row.SubjectID)
+		switch row.Kind {
+		case models.ActivityKindRecipe:
+			if item.Recipe = subjects.recipes[subjectID]; item.Recipe == nil {
+				continue
+			}
+		case models.ActivityKindReview:
+			if item.Review = subjects.reviews[subjectID]; item.Review == nil {
+				continue
+			}
+			// 非公開になったレシピへのレビューは表示しない
+			if item.Recipe = subjects.recipes[item.Review.RecipeID.String()]; item.Recipe == nil {
+				continue
+			}
+		case models.ActivityKindCollection:
+			if item.Collection = subjects.collections[subjectID]; item.Collection == nil {
+				continue
+			}
+		default:
+			continue
+		}
+		item.Actor = Actor{ID: strings.ToLower(row.ActorID)}
+		if u, ok := subjects.actors[item.Actor.ID]; ok {
+			item.Actor.Username = u.Username
+			item.Actor.ProfileImage = u.ProfileImage
+		}
+		page.Items = append(page.Items, item)
+	}
+	return page, nil
+}
+
+// subjects は新着の対象（公開中のものだけ）を ID ごとにまとめたもの
+type subjects struct {
+	recipes     map[string]*models.Recipe
+	reviews     map[string]*models.Review
+	collections map[string]*models.Collection
+	actors      map[string]models.User
+}
+
+// loadSubjects は新着の対象を種類ごとにまとめて取得する（1ページあたりのクエリ数を件数によらず一定にする）
+func (s *Service) loadSubjects(rows []feedRow) (*subjects, error) {
+	ids := map[string][]string{}
+	actorIDs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids[row.Kind] = append(ids[row.Kind], row.SubjectID)
+		actorIDs = append(actorIDs, row.ActorID)
+	}
+	out := &subjects{
+		recipes:     map[string]*models.Recipe{},
+		reviews:     map[string]*models.Review{},
+		collections: map[string]*models.Collection{},
+		actors:      map[string]models.User{},
+	}
+
+	if reviewIDs := ids[models.ActivityKindReview]; len(reviewIDs) > 0 {
+		var reviews []models.Review
+		if err := s.DB.Preload("Photos", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position")
+		}).Scopes(models.ActiveOwner("reviews.user_id")).Where("id IN ? AND status = ?", reviewIDs, models.ReviewStatusVisible).Find(&reviews).Error; err != nil {
+			return nil, err
+		}
+		for i := range reviews {
+			out.reviews[reviews[i].ID.String()] = &reviews[i]
+			ids[models.ActivityKindRecipe] = append(ids[models.ActivityKindRecipe], reviews[i].RecipeID.String())
+		}
+	}
+
+	if recipeIDs := ids[models.ActivityKindRecipe]; len(recipeIDs) > 0 {
+		var recipes []models.Recipe
+		if err := s.DB.Preload("Genre").
+			Scopes(models.ActiveOwner("recipes.user_id")).
+			Where("id IN ? AND is_public = ? AND is_draft = ?", recipeIDs, true, false).
+			Find(&recipes).Error; err != nil {
+			return nil, err
+		}
+		for i := range recipes {
+			out.recipes[recipes[i].ID.String()] = &recipes[i]
+		}
+	}
+
+	if collectionIDs := ids[models.ActivityKindCollection]; len(collectionIDs) > 0 {
+		var collections []models.Collection
+		if err := s.DB.Scopes(models.ActiveOwner("collections.user_id")).Where("id IN ? AND is_public = ?", collectionIDs, true).Find(&collections).Error; err != nil {
+			return nil, err
+		}
+		var counts []struct {
+			CollectionID string
+			Count        int
+		}
+		if err := s.DB.Model(&models.CollectionRecipe{}).
+			Select("collection_id, COUNT(*) AS count").
+			Where("collection_id IN ?", collectionIDs).
+			Group("collection_id").
+			Scan(&counts).Error; err != nil {
+			return nil, err
+		}
+		countByID := make(map[string]int, len(counts))
+		for _, row := range counts {
+			countByID[strings.ToLower(row.CollectionID)] = row.Count
+		}
+		for i := range collections {
+			id := collections[i].ID.String()
+			collections[i].RecipeCount = countByID[id]
+			out.collections[id] = &collections[i]
+		}
+	}
+
+	var users []models.User
+	if err := s.DB.Select("id", "username", "profile_image").Where("id IN ?", actorIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		out.actors[strings.ToLower(u.ID)] = u
+	}
+	return out, nil
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+	if _, err := uuid.Parse(c.ID); err != nil || c.CreatedAt.IsZero() {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -13,8 +13,11 @@ import (
 	"strconv"
 	"time"
 
+	"portfolio-amarimono/feed"
 	"portfolio-amarimono/handlers/utils"
 	"portfolio-amarimono/models"
+	"portfolio-amarimono/notifications"
+	"portfolio-amarimono/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
@@ -23,8 +26,10 @@ import (
 )
 
 type AdminHandler struct {
-	DB          *gorm.DB
-	RedisClient *redis.Client
+	DB            *gorm.DB
+	RedisClient   *redis.Client
+	Images        storage.ImageStore
+	Notifications *notifications.Service
 }
 
 const (
@@ -38,10 +43,10 @@ func sanitizeFolderName(name string) string {
 	return reg.ReplaceAllString(name, "-")
 }
 
-// ListIngredients /admin/ingredients(GET) 具材一覧を取得
+// ListIngredients /admin/ingredients(GET) 具材一覧を取得（アレルゲン・食事制限のタグ付き）
 func (h *AdminHandler) ListIngredients(c *gin.Context) {
 	var ingredients []models.Ingredient
-	if err := h.DB.Preload("Genre").Preload("Unit").Find(&ingredients).Error; err != nil {
+	if err := h.DB.Preload("Genre").Preload("Unit").Preload("DietaryTags").Find(&ingredients).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ingredients", "details": err.Error()})
 		return
 	}
@@ -116,6 +121,12 @@ func (h *AdminHandler) AddIngredient(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Image file is required"})
 		return
 	}
+	// 具材を作成する前に画像を検証する
+	img, err := utils.DecodeUploadedImage(file)
+	if err != nil {
+		respondImageError(c, err, "Failed to save image")
+		return
+	}
 
 	// 具材を先に作成してIDを取得
 	ingredient := models.Ingredient{
@@ -150,14 +161,14 @@ func (h *AdminHandler) AddIngredient(c *gin.Context) {
 	if err == nil { // 画像が選択された場合のみ処理
 		// 既存の画像がある場合は削除
 		if ingredient.ImageUrl != "" {
-			if err := utils.DeleteImage(ingredient.ImageUrl); err != nil {
+			if err := utils.DeleteImage(c.Request.Context(), h.Images, ingredient.ImageUrl); err != nil {
 				// 画像の削除に失敗しても処理は続行
 				log.Printf("Failed to delete old image: %v", err)
 			}
 		}
 
 		// SaveImage関数を使用して画像を保存
-		imagePath, err := utils.SaveImage(c, file, "ingredients", fmt.Sprintf("%d", ingredient.ID))
+		imagePath, err := utils.SaveDecodedImage(c.Request.Context(), h.Images, img, "ingredients", fmt.Sprintf("%d", ingredient.ID))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
 			return
@@ -258,16 +269,24 @@ func (h *AdminHandler) UpdateIngredient(c *gin.Context) {
 	if err == nil { // 画像が選択された場合のみ処理
 		log.Printf("Processing new image file: %s", file.Filename)
 
+		// 新しい画像を検証してから既存の画像を削除する
+		img, err := utils.DecodeUploadedImage(file)
+		if err != nil {
+			log.Printf("Invalid image: %v", err)
+			respondImageError(c, err, "Failed to save image")
+			return
+		}
+
 		// 既存の画像がある場合は削除
 		if ingredient.ImageUrl != "" {
 			log.Printf("Deleting existing image: %s", ingredient.ImageUrl)
-			if err := utils.DeleteImage(ingredient.ImageUrl); err != nil {
+			if err := utils.DeleteImage(c.Request.Context(), h.Images, ingredient.ImageUrl); err != nil {
 				log.Printf("Failed to delete old image: %v", err)
 			}
 		}
 
 		// SaveImage関数を使用して画像を保存
-		imagePath, err := utils.SaveImage(c, file, "ingredients", fmt.Sprintf("%d", ingredient.ID))
+		imagePath, err := utils.SaveDecodedImage(c.Request.Context(), h.Images, img, "ingredients", fmt.Sprintf("%d", ingredient.ID))
 		if err != nil {
 			log.Printf("Failed to save image: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
@@ -361,7 +380,7 @@ func (h *AdminHandler) DeleteIngredient(c *gin.Context) {
 
 	// 画像が存在する場合は削除
 	if ingredient.ImageUrl != "" {
-		if err := utils.DeleteImage(ingredient.ImageUrl); err != nil {
+		if err := utils.DeleteImage(c.Request.Context(), h.Images, ingredient.ImageUrl); err != nil {
 			// 画像の削除に失敗しても具材の削除は続行
 		}
 	}
@@ -396,7 +415,7 @@ func (h *AdminHandler) ListRecipes(c *gin.Context) {
 	} else {
 	}
 
-	if err := h.DB.Preload("Genre").Preload("Ingredients.Ingredient.Unit").Preload("Reviews").Find(&recipes).Error; err != nil {
+	if err := h.DB.Preload("Genre").Preload("Ingredients.Ingredient.Unit").Preload("Reviews").Scopes(recipeSort(c.Query("sort"))).Find(&recipes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipes", "details": err.Error()})
 		return
 	}
@@ -512,15 +531,16 @@ func (h *AdminHandler) AddRecipe(c *gin.Context) {
 	// 画像ファイルの取得とログ
 	files := form.File["image"]
 	if len(files) > 0 {
-		// 画像を保存
-		imagePath, err := utils.SaveRecipeImage(c, files[0], recipe.ID.ToUUID().String(), false)
+		// 画像を再エンコードしてサイズ別に保存
+		variants, err := utils.SaveRecipeImage(c.Request.Context(), h.Images, files[0], recipe.ID.ToUUID().String(), 0)
 		if err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+			respondImageError(c, err, "Failed to save image")
 			return
 		}
-		recipe.MainImage = imagePath
-		if err := tx.Model(&recipe).Update("image_url", imagePath).Error; err != nil {
+		recipe.MainImage = variants.Full
+		recipe.ImageVariants = variants
+		if err := tx.Model(&recipe).Updates(map[string]interface{}{"image_url": variants.Full, "image_variants": variants}).Error; err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update recipe with image path"})
 			return
@@ -531,14 +551,15 @@ func (h *AdminHandler) AddRecipe(c *gin.Context) {
 	for i := range instructions {
 		fileKey := fmt.Sprintf("instruction_image_%d", i)
 		if imageFile, err := c.FormFile(fileKey); err == nil {
-			// 画像を保存
-			imagePath, err := utils.SaveRecipeImage(c, imageFile, recipe.ID.ToUUID().String(), true)
+			// 画像を再エンコードしてサイズ別に保存
+			variants, err := utils.SaveRecipeImage(c.Request.Context(), h.Images, imageFile, recipe.ID.ToUUID().String(), i+1)
 			if err != nil {
 				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save instruction image"})
+				respondImageError(c, err, "Failed to save instruction image")
 				return
 			}
-			instructions[i].ImageURL = imagePath
+			instructions[i].ImageURL = variants.Full
+			instructions[i].ImageVariants = variants
 		}
 	}
 
@@ -580,6 +601,13 @@ func (h *AdminHandler) AddRecipe(c *gin.Context) {
 		}
 	}
 
+	// 公開するレシピは投稿者のフォロワーのフィードに配信
+	if err := feed.SyncRecipe(tx, &recipe, time.Now()); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update followers' feeds"})
+		return
+	}
+
 	// トランザクションのコミット
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
@@ -652,18 +680,20 @@ func (h *AdminHandler) DeleteRecipe(c *gin.Context) {
 
 	// メイン画像の削除
 	if recipe.MainImage != "" {
-		if err := utils.DeleteImage(recipe.MainImage); err != nil {
+		if err := utils.DeleteImage(c.Request.Context(), h.Images, recipe.MainImage); err != nil {
 			// 画像の削除に失敗してもレシピの削除は続行
 		}
 	}
+	utils.DeleteImageVariants(c.Request.Context(), h.Images, recipe.ImageVariants)
 
 	// 手順画像の削除
 	for _, instruction := range recipe.Instructions {
 		if instruction.ImageURL != "" {
-			if err := utils.DeleteImage(instruction.ImageURL); err != nil {
+			if err := utils.DeleteImage(c.Request.Context(), h.Images, instruction.ImageURL); err != nil {
 				// 画像の削除に失敗してもレシピの削除は続行
 			}
 		}
+		utils.DeleteImageVariants(c.Request.Context(), h.Images, instruction.ImageVariants)
 	}
 
 	// 関連するrecipe_ingredientsを削除
@@ -673,6 +703,13 @@ func (h *AdminHandler) DeleteRecipe(c *gin.Context) {
 		return
 	}
 
+	// レシピとそのレビューの新着をフィードから取り消す
+	if err := feed.RetractRecipe(tx, recipeID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update followers' feeds"})
+		return
+	}
+
 	// レシピを削除
 	if err := tx.Delete(&recipe).Error; err != nil {
 		tx.Rollback()
@@ -794,25 +831,38 @@ func (h *AdminHandler) UpdateRecipe(c *gin.Context) {
 	if err == nil { // 画像が選択された場合のみ処理
 		log.Printf("📸 Processing new image file: %s", imageFile.Filename)
 
-		// 既存の画像がある場合は削除
+		// 新しい画像を検証してから既存の画像を削除する（デコードした画像をそのまま保存に使う）
+		img, err := utils.DecodeUploadedImage(imageFile)
+		if err != nil {
+			log.Printf("❌ Invalid image: %v", err)
+			tx.Rollback()
+			respondImageError(c, err, "Failed to save image")
+			return
+		}
+
+		// 既存の画像がある場合は削除（WebP化以前の画像はキーが異なるため個別に削除）
 		if recipe.MainImage != "" {
 			log.Printf("🗑️ Deleting existing image: %s", recipe.MainImage)
-			if err := utils.DeleteImage(recipe.MainImage); err != nil {
+			if err := utils.DeleteImage(c.Request.Context(), h.Images, recipe.MainImage); err != nil {
 				log.Printf("⚠️ Failed to delete old image: %v", err)
 				// 画像の削除に失敗しても処理は続行
 			}
 		}
+		if err := utils.DeleteImageVariants(c.Request.Context(), h.Images, recipe.ImageVariants); err != nil {
+			log.Printf("⚠️ Failed to delete old image variants: %v", err)
+		}
 
-		// 新しい画像を保存
-		imagePath, err := utils.SaveRecipeImage(c, imageFile, recipe.ID.String(), false)
+		// 新しい画像をサイズ別に保存
+		variants, err := utils.SaveRecipeImageVariants(c.Request.Context(), h.Images, img, recipe.ID.String(), 0)
 		if err != nil {
 			log.Printf("❌ Failed to save new image: %v", err)
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+			respondImageError(c, err, "Failed to save image")
 			return
 		}
-		log.Printf("✅ Successfully saved new image at: %s", imagePath)
-		recipe.MainImage = imagePath
+		log.Printf("✅ Successfully saved new image at: %s", variants.Full)
+		recipe.MainImage = variants.Full
+		recipe.ImageVariants = variants
 	} else {
 		log.Printf("📸 No new image provided, keeping existing image: %s", recipe.MainImage)
 	}
@@ -842,6 +892,9 @@ func (h *AdminHandler) UpdateRecipe(c *gin.Context) {
 	if recipe.MainImage != "" {
 		updates["image_url"] = recipe.MainImage
 	}
+	if recipe.ImageVariants != nil {
+		updates["image_variants"] = recipe.ImageVariants
+	}
 	if len(recipe.Instructions) > 0 {
 		updates["instructions"] = recipe.Instructions
 	}
@@ -913,6 +966,14 @@ func (h *AdminHandler) UpdateRecipe(c *gin.Context) {
 		log.Printf("✅ Added new ingredients: %+v", tempIngredients)
 	}
 
+	// 下書きに戻した・下書きから公開した場合はフォロワーのフィードに反映
+	if err := feed.SyncRecipe(tx, &recipe, time.Now()); err != nil {
+		log.Printf("❌ Failed to sync feed: %v", err)
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update followers' feeds"})
+		return
+	}
+
 	// トランザクションのコミット
 	if err := tx.Commit().Error; err != nil {
 		log.Printf("❌ Transaction commit failed: %v", err)
@@ -1160,6 +1221,13 @@ func (h *AdminHandler) SaveDraftRecipe(c *gin.Context) {
 		}
 	}
 
+	// 下書きにしたレシピはフォロワーのフィードから取り消す
+	if err := feed.SyncRecipe(tx, &recipe, time.Now()); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update followers' feeds"})
+		return
+	}
+
 	// トランザクションのコミット
 	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
@@ -1355,9 +1423,9 @@ func (h *AdminHandler) UploadImage(c *gin.Context) {
 	}
 
 	// 一時的なディレクトリに画像を保存
-	imageURL, err := utils.SaveImage(c, file, "temp_uploads", "")
+	imageURL, err := utils.SaveImage(c.Request.Context(), h.Images, file, "temp_uploads", "")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "画像の保存に失敗しました"})
+		respondImageError(c, err, "画像の保存に失敗しました")
 		return
 	}
 
@@ -1387,14 +1455,105 @@ func (h *AdminHandler) ToggleRecipePublish(c *gin.Context) {
 	// 公開状態を切り替え
 	recipe.IsPublic = !recipe.IsPublic
 
-	// 更新を保存
-	if err := h.DB.Save(&recipe).Error; err != nil {
+	// 更新を保存し、公開した場合は投稿者のフォロワーのフィードに配信する（非公開にした場合は取り消す）
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&recipe).Error; err != nil {
+			return err
+		}
+		return feed.SyncRecipe(tx, &recipe, time.Now())
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update recipe"})
 		return
 	}
 
+	// 投稿者に公開・非公開になったことを通知（通知に失敗しても切り替えは成功として返す）
+	if h.Notifications != nil {
+		if _, err := h.Notifications.RecipePublicationChanged(c.Request.Context(), &recipe); err != nil {
+			log.Printf("⚠️ Failed to notify publication of recipe %s: %v", id, err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Recipe publish status updated successfully",
 		"recipe":  recipe,
 	})
 }
+
+// AcceptRecipeSuggestions はAIが生成したFAQと手順の候補のうち、編集者が採用したものをレシピに取り込む
+func (h *AdminHandler) AcceptRecipeSuggestions(c *gin.Context) {
+	id := c.Param("id")
+
+	// UUIDのバリデーション
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recipe ID format"})
+		return
+	}
+
+	var req struct {
+		FAQ   []models.FAQ `json:"faq"`
+		Steps []struct {
+			StepNumber  int    `json:"stepNumber"`
+			Description string `json:"description"`
+		} `json:"steps"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	var recipe models.Recipe
+	if err := h.DB.Where("id = ?", id).First(&recipe).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Recipe not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipe"})
+		}
+		return
+	}
+
+	// 同じ質問のFAQは追加しない
+	questions := make(map[string]bool, len(recipe.FAQ))
+	for _, f := range recipe.FAQ {
+		questions[f.Question] = true
+	}
+	addedFAQ := 0
+	for _, f := range req.FAQ {
+		if f.Question == "" || f.Answer == "" || questions[f.Question] {
+			continue
+		}
+		questions[f.Question] = true
+		recipe.FAQ = append(recipe.FAQ, f)
+		addedFAQ++
+	}
+
+	// 手順は番号が一致するものの説明文だけを置き換える（画像はそのまま）
+	updatedSteps := 0
+	for _, s := range req.Steps {
+		if s.Description == "" {
+			continue
+		}
+		for i := range recipe.Instructions {
+			if recipe.Instructions[i].StepNumber == s.StepNumber {
+				recipe.Instructions[i].Description = s.Description
+				updatedSteps++
+				break
+			}
+		}
+	}
+
+	if err := h.DB.Model(&recipe).Updates(map[string]interface{}{
+		"faq":          recipe.FAQ,
+		"instructions": recipe.Instructions,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update recipe"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Suggestions accepted successfully",
+		"added_faq":     addedFAQ,
+		"updated_steps": updatedSteps,
+		"recipe":        recipe,
+	})
+}
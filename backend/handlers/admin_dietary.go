@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"portfolio-amarimono/dietary"
+	"portfolio-amarimono/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetIngredientDietaryTags は具材に付いているアレルゲン・食事制限のタグを返す
+func (h *AdminHandler) GetIngredientDietaryTags(c *gin.Context) {
+	ingredient, ok := h.findIngredient(c)
+	if !ok {
+		return
+	}
+
+	tags, err := dietary.IngredientTags(h.DB, ingredient.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dietary tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ingredient_id": ingredient.ID, "tags": tags, "available": dietary.Restrictions})
+}
+
+// UpdateIngredientDietaryTags は具材のアレルゲン・食事制限のタグを置き換える
+func (h *AdminHandler) UpdateIngredientDietaryTags(c *gin.Context) {
+	ingredient, ok := h.findIngredient(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Tags == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tags is required"})
+		return
+	}
+
+	tags, err := dietary.SetIngredientTags(h.DB, ingredient.ID, req.Tags)
+	if errors.Is(err, dietary.ErrUnknownRestriction) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown dietary restriction"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update dietary tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ingredient_id": ingredient.ID, "tags": tags, "available": dietary.Restrictions})
+}
+
+// findIngredient は URL の :id の具材を取得する
+func (h *AdminHandler) findIngredient(c *gin.Context) (*models.Ingredient, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ingredient ID"})
+		return nil, false
+	}
+
+	var ingredient models.Ingredient
+	err = h.DB.Select("id", "name").Where("id = ?", id).Take(&ingredient).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ingredient not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ingredient"})
+		return nil, false
+	}
+	return &ingredient, true
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"portfolio-amarimono/imageprompts"
+	"portfolio-amarimono/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// imagePromptView は管理画面に返すステップごとのプロンプト
+type imagePromptView struct {
+	StepNumber  int                  `json:"step_number"`
+	Prompt      string               `json:"prompt"`
+	Version     int                  `json:"version"`
+	Source      string               `json:"source"`
+	ID          *models.UUIDString   `json:"id,omitempty"`
+	Saved       bool                 `json:"saved"`
+	LatestImage *imageGenerationView `json:"latest_image,omitempty"`
+}
+
+// imageGenerationView は生成した画像の記録。Stale は現在のプロンプトより古いバージョンから生成された場合にtrue。
+type imageGenerationView struct {
+	models.RecipeImageGeneration
+	Stale bool `json:"stale"`
+}
+
+// ListImagePrompts はレシピのメイン画像と各手順の現在のプロンプトを取得する。
+// まだ保存されていないステップには、レシピ名・手順から作った既定のプロンプトを保存せずに返す。
+func (h *AdminHandler) ListImagePrompts(c *gin.Context) {
+	recipe, ok := h.findRecipeForImagePrompts(c)
+	if !ok {
+		return
+	}
+	recipeID := recipe.ID.String()
+
+	current, err := imageprompts.Current(h.DB, recipeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch image prompts"})
+		return
+	}
+	generations, err := h.imageGenerations(recipeID, current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch image generations"})
+		return
+	}
+	latest := make(map[int]*imageGenerationView)
+	for i := range generations {
+		if _, exists := latest[generations[i].StepNumber]; !exists {
+			latest[generations[i].StepNumber] = &generations[i]
+		}
+	}
+
+	defaults := imageprompts.Defaults(recipe)
+	views := make([]imagePromptView, 0, len(defaults))
+	for _, step := range imagePromptSteps(recipe) {
+		view := imagePromptView{StepNumber: step, LatestImage: latest[step]}
+		if p, ok := current[step]; ok {
+			id := p.ID
+			view.Prompt, view.Version, view.Source, view.ID, view.Saved = p.Prompt, p.Version, p.Source, &id, true
+		} else {
+			view.Prompt, view.Source = defaults[step], imageprompts.SourceDefault
+		}
+		views = append(views, view)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recipe_id": recipe.ID,
+		"prompts":   views,
+	})
+}
+
+// UpdateImagePrompt はステップのプロンプトを編集し、新しいバージョンとして保存する
+func (h *AdminHandler) UpdateImagePrompt(c *gin.Context) {
+	recipe, ok := h.findRecipeForImagePrompts(c)
+	if !ok {
+		return
+	}
+	step, ok := imagePromptStepParam(c, recipe)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Prompt string `json:"prompt" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Prompt is required"})
+		return
+	}
+
+	saved, err := imageprompts.Save(h.DB, recipe.ID.String(), step, req.Prompt, imageprompts.SourceManual, optionalUserID(c))
+	if errors.Is(err, imageprompts.ErrUnchanged) {
+		c.JSON(http.StatusOK, gin.H{"message": "Image prompt is unchanged", "prompt": saved})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image prompt"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Image prompt updated successfully", "prompt": saved})
+}
+
+// GenerateDefaultImagePrompts はレシピ名と手順の説明文から既定のプロンプトを作って保存する。
+// ?overwrite=true の場合は保存済みのステップも新しいバージョンとして上書きする。
+func (h *AdminHandler) GenerateDefaultImagePrompts(c *gin.Context) {
+	recipe, ok := h.findRecipeForImagePrompts(c)
+	if !ok {
+		return
+	}
+	recipeID := recipe.ID.String()
+	defaults := imageprompts.Defaults(recipe)
+	createdBy := optionalUserID(c)
+
+	saved := 0
+	if c.Query("overwrite") == "true" {
+		for step, prompt := range defaults {
+			_, err := imageprompts.Save(h.DB, recipeID, step, prompt, imageprompts.SourceDefault, createdBy)
+			if errors.Is(err, imageprompts.ErrUnchanged) {
+				continue
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image prompts"})
+				return
+			}
+			saved++
+		}
+	} else {
+		var err error
+		saved, err = imageprompts.SaveMissing(h.DB, recipeID, defaults, imageprompts.SourceDefault, createdBy)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image prompts"})
+			return
+		}
+	}
+
+	current, err := imageprompts.Current(h.DB, recipeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch image prompts"})
+		return
+	}
+	prompts := make([]models.RecipeImagePrompt, 0, len(current))
+	for _, step := range imagePromptSteps(recipe) {
+		if p, ok := current[step]; ok {
+			prompts = append(prompts, p)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Default image prompts generated successfully",
+		"saved":   saved,
+		"prompts": prompts,
+	})
+}
+
+// GetImagePromptHistory はステップのプロンプトのすべてのバージョンを取得する
+func (h *AdminHandler) GetImagePromptHistory(c *gin.Context) {
+	recipe, ok := h.findRecipeForImagePrompts(c)
+	if !ok {
+		return
+	}
+	step, ok := imagePromptStepParam(c, recipe)
+	if !ok {
+		return
+	}
+
+	history, err := imageprompts.History(h.DB, recipe.ID.String(), step)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch image prompt history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"step_number": step, "history": history})
+}
+
+// RecordImageGeneration は画像をどのバージョンのプロンプトから生成したかを記録する。
+// prompt_version を省略した場合は現在のバージョンを使う。
+func (h *AdminHandler) RecordImageGeneration(c *gin.Context) {
+	recipe, ok := h.findRecipeForImagePrompts(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		StepNumber    *int   `json:"step_number" binding:"required"`
+		ImageURL      string `json:"image_url" binding:"required"`
+		Model         string `json:"model"`
+		PromptVersion int    `json:"prompt_version"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "step_number and image_url are required"})
+		return
+	}
+
+	query := h.DB.Where("recipe_id = ? AND step_number = ?", recipe.ID.String(), *req.StepNumber)
+	if req.PromptVersion > 0 {
+		query = query.Where("version = ?", req.PromptVersion)
+	}
+	var prompt models.RecipeImagePrompt
+	if err := query.Order("version DESC").First(&prompt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Image prompt not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch image prompt"})
+		}
+		return
+	}
+
+	generation := models.RecipeImageGeneration{
+		RecipeID:      recipe.ID,
+		StepNumber:    prompt.StepNumber,
+		PromptID:      prompt.ID,
+		PromptVersion: prompt.Version,
+		ImageURL:      req.ImageURL,
+		Model:         req.Model,
+	}
+	if err := h.DB.Create(&generation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record image generation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Image generation recorded successfully", "generation": generation})
+}
+
+// ListImageGenerations はレシピの画像の生成記録を新しい順に取得する
+func (h *AdminHandler) ListImageGenerations(c *gin.Context) {
+	recipe, ok := h.findRecipeForImagePrompts(c)
+	if !ok {
+		return
+	}
+	recipeID := recipe.ID.String()
+
+	current, err := imageprompts.Current(h.DB, recipeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch image prompts"})
+		return
+	}
+	generations, err := h.imageGenerations(recipeID, current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch image generations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"generations": generations})
+}
+
+func (h *AdminHandler) imageGenerations(recipeID string, current map[int]models.RecipeImagePrompt) ([]imageGenerationView, error) {
+	var generations []models.RecipeImageGeneration
+	if err := h.DB.Where("recipe_id = ?", recipeID).
+		Order("created_at DESC").Find(&generations).Error; err != nil {
+		return nil, err
+	}
+	views := make([]imageGenerationView, 0, len(generations))
+	for _, g := range generations {
+		p, ok := current[g.StepNumber]
+		views = append(views, imageGenerationView{
+			RecipeImageGeneration: g,
+			Stale:                 ok && g.PromptVersion < p.Version,
+		})
+	}
+	return views, nil
+}
+
+func (h *AdminHandler) findRecipeForImagePrompts(c *gin.Context) (*models.Recipe, bool) {
+	id := c.Param("id")
+
+	// UUIDのバリデーション
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recipe ID format"})
+		return nil, false
+	}
+
+	var recipe models.Recipe
+	if err := h.DB.Where("id = ?", id).First(&recipe).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Recipe not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipe"})
+		}
+		return nil, false
+	}
+	return &recipe, true
+}
+
+// imagePromptSteps はメイン画像と手順のステップ番号を順に返す
+func imagePromptSteps(recipe *models.Recipe) []int {
+	steps := []int{models.MainImageStep}
+	for _, s := range recipe.Instructions {
+		if s.StepNumber > 0 {
+			steps = append(steps, s.StepNumber)
+		}
+	}
+	return steps
+}
+
+// imagePromptStepParam は :step をメイン画像（0）またはレシピに存在する手順の番号として検証する
+func imagePromptStepParam(c *gin.Context, recipe *models.Recipe) (int, bool) {
+	step, err := strconv.Atoi(c.Param("step"))
+	if err == nil {
+		for _, s := range imagePromptSteps(recipe) {
+			if s == step {
+				return step, true
+			}
+		}
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid step number"})
+	return 0, false
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/moderation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// moderationRequest はモデレーション操作のメモ（監査ログに残す）
+type moderationRequest struct {
+	Note string `json:"note"`
+}
+
+// ListReviewReports は未対応の通報があるレビューの一覧（モデレーションキュー）を返す
+func (h *AdminHandler) ListReviewReports(c *gin.Context) {
+	if _, ok := h.authenticatedModerator(c); !ok {
+		return
+	}
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	items, err := moderation.NewService(h.DB).Queue(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// HideReview はレビューを非表示にする
+func (h *AdminHandler) HideReview(c *gin.Context) {
+	h.moderateReview(c, moderation.NewService(h.DB).Hide)
+}
+
+// RestoreReview は非表示にしたレビューを再表示する
+func (h *AdminHandler) RestoreReview(c *gin.Context) {
+	h.moderateReview(c, moderation.NewService(h.DB).Restore)
+}
+
+// DismissReviewReports はレビューを変更せずに通報を対応済みにする
+func (h *AdminHandler) DismissReviewReports(c *gin.Context) {
+	h.moderateReview(c, moderation.NewService(h.DB).Dismiss)
+}
+
+// DeleteReview はモデレーターとしてレビューを削除する（監査ログに内容を残す）
+func (h *AdminHandler) DeleteReview(c *gin.Context) {
+	moderatorID, ok := h.authenticatedModerator(c)
+	if !ok {
+		return
+	}
+	reviewID, req, ok := bindModerationRequest(c)
+	if !ok {
+		return
+	}
+
+	var photos []models.ReviewPhoto
+	h.DB.Where("review_id = ?", reviewID).Find(&photos)
+
+	err := moderation.NewService(h.DB).Delete(reviewID, moderatorID, req.Note)
+	if errors.Is(err, moderation.ErrReviewNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete review"})
+		return
+	}
+	deleteReviewPhotoImages(c, h.Images, photos)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review deleted"})
+}
+
+// GetReviewModerationLog はレビューに対するモデレーション操作の履歴を返す
+func (h *AdminHandler) GetReviewModerationLog(c *gin.Context) {
+	if _, ok := h.authenticatedModerator(c); !ok {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	actions, err := moderation.NewService(h.DB).History(id.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderation log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"actions": actions})
+}
+
+// moderateReview は非表示・再表示・通報の却下に共通する処理
+func (h *AdminHandler) moderateReview(c *gin.Context, action func(reviewID, moderatorID, note string) (*models.Review, error)) {
+	moderatorID, ok := h.authenticatedModerator(c)
+	if !ok {
+		return
+	}
+	reviewID, req, ok := bindModerationRequest(c)
+	if !ok {
+		return
+	}
+
+	review, err := action(reviewID, moderatorID, req.Note)
+	if errors.Is(err, moderation.ErrReviewNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"review": review})
+}
+
+// authenticatedModerator はログイン中のユーザーが管理者またはモデレーターであることを確認する
+// （未ログインは401、権限が無い場合は403）
+func (h *AdminHandler) authenticatedModerator(c *gin.Context) (string, bool) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return "", false
+	}
+
+	var role string
+	if err := h.DB.Table("user_roles").Select("role").Where("user_id = ?", userID).Scan(&role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user role"})
+		return "", false
+	}
+	if role != moderatorRoleAdmin && role != moderatorRoleModerator {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Moderator permission required"})
+		return "", false
+	}
+	return userID, true
+}
+
+// レビューのモデレーションを行えるロール
+const (
+	moderatorRoleAdmin     = "admin"
+	moderatorRoleModerator = "moderator"
+)
+
+// bindModerationRequest はレビューIDとメモを取り出す（メモは省略可）
+func bindModerationRequest(c *gin.Context) (string, moderationRequest, bool) {
+	var req moderationRequest
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return "", req, false
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return "", req, false
+		}
+	}
+	return id.String(), req, true
+}
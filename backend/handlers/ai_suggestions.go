@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"portfolio-amarimono/ai"
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/quota"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// suggestionIngredient はAIに渡す材料
+type suggestionIngredient struct {
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+}
+
+// suggestionRequest はFAQ・手順・コツの生成リクエスト。
+// recipe_id を指定した場合は保存済みのレシピを使い、name / ingredients / instructions を指定した項目だけ上書きする（編集中の下書き用）。
+type suggestionRequest struct {
+	RecipeID     string                   `json:"recipe_id"`
+	Name         string                   `json:"name"`
+	Ingredients  []suggestionIngredient   `json:"ingredients"`
+	Instructions []models.InstructionStep `json:"instructions"`
+}
+
+// StepSuggestion は手順の説明文の候補
+type StepSuggestion struct {
+	StepNumber  int    `json:"stepNumber"`
+	Original    string `json:"original"`
+	Description string `json:"description"`
+}
+
+// TipSuggestion は材料の代用のコツの候補
+type TipSuggestion struct {
+	Ingredient string `json:"ingredient"`
+	Substitute string `json:"substitute"`
+	Note       string `json:"note"`
+	// FAQ はFAQとして取り込む場合の形式
+	FAQ models.FAQ `json:"faq"`
+}
+
+// GenerateFAQ はレシピの材料と手順からFAQの候補を生成するハンドラー
+func (h *AIUsageHandler) GenerateFAQ(c *gin.Context) {
+	var result struct {
+		FAQ []models.FAQ `json:"faq"`
+	}
+	resp, status, _, ok := h.generateSuggestion(c, quota.FeatureFAQ, ai.PromptRecipeFAQ, &result)
+	if !ok {
+		return
+	}
+	suggestions := make([]models.FAQ, 0, len(result.FAQ))
+	for _, f := range result.FAQ {
+		f.Question = strings.TrimSpace(f.Question)
+		f.Answer = strings.TrimSpace(f.Answer)
+		if f.Question != "" && f.Answer != "" {
+			suggestions = append(suggestions, f)
+		}
+	}
+
+	respondSuggestions(c, resp, status, suggestions)
+}
+
+// GenerateSteps は手順の説明文を分かりやすく書き直した候補を生成するハンドラー
+func (h *AIUsageHandler) GenerateSteps(c *gin.Context) {
+	var result struct {
+		Steps []struct {
+			StepNumber  int    `json:"stepNumber"`
+			Description string `json:"description"`
+		} `json:"steps"`
+	}
+	resp, status, input, ok := h.generateSuggestion(c, quota.FeatureSteps, ai.PromptRecipeSteps, &result)
+	if !ok {
+		return
+	}
+
+	// 元の手順に存在する番号の候補だけを返す
+	originals := make(map[int]string, len(input.Instructions))
+	for _, step := range input.Instructions {
+		originals[step.StepNumber] = step.Description
+	}
+	suggestions := make([]StepSuggestion, 0, len(result.Steps))
+	for _, step := range result.Steps {
+		original, exists := originals[step.StepNumber]
+		description := strings.TrimSpace(step.Description)
+		if !exists || description == "" {
+			continue
+		}
+		suggestions = append(suggestions, StepSuggestion{
+			StepNumber:  step.StepNumber,
+			Original:    original,
+			Description: description,
+		})
+	}
+
+	respondSuggestions(c, resp, status, suggestions)
+}
+
+// GenerateTips は材料の代用のコツの候補を生成するハンドラー
+func (h *AIUsageHandler) GenerateTips(c *gin.Context) {
+	var result struct {
+		Tips []TipSuggestion `json:"tips"`
+	}
+	resp, status, _, ok := h.generateSuggestion(c, quota.FeatureTips, ai.PromptRecipeTips, &result)
+	if !ok {
+		return
+	}
+
+	suggestions := make([]TipSuggestion, 0, len(result.Tips))
+	for _, tip := range result.Tips {
+		if tip.Ingredient == "" || tip.Substitute == "" {
+			continue
+		}
+		tip.FAQ = models.FAQ{
+			Question: fmt.Sprintf("%sが無い場合は何で代用できますか？", tip.Ingredient),
+			Answer:   fmt.Sprintf("%sで代用できます。%s", tip.Substitute, tip.Note),
+		}
+		suggestions = append(suggestions, tip)
+	}
+
+	respondSuggestions(c, resp, status, suggestions)
+}
+
+// generateSuggestion は認証・入力の取得・使用回数の消費・生成をまとめて行う。
+// 失敗した場合はレスポンスを返してfalseを返す。
+func (h *AIUsageHandler) generateSuggestion(c *gin.Context, feature quota.Feature, promptID string, out interface{}) (*ai.Response, *quota.Status, *suggestionRequest, bool) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return nil, nil, nil, false
+	}
+
+	input, err := h.loadSuggestionInput(c)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "レシピが見つかりません"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return nil, nil, nil, false
+	}
+
+	status, ok := h.consumeQuota(c, userID, feature)
+	if !ok {
+		return nil, nil, nil, false
+	}
+
+	resp, err := h.generateJSON(c.Request.Context(), promptID, suggestionVars(input), out)
+	if err != nil {
+		// 生成できなかった分は使用回数に含めない
+		h.refundQuota(userID, feature)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "候補の生成に失敗しました"})
+		return nil, nil, nil, false
+	}
+	return resp, status, input, true
+}
+
+// loadSuggestionInput はリクエストボディと保存済みのレシピから生成に使う内容を組み立てる
+func (h *AIUsageHandler) loadSuggestionInput(c *gin.Context) (*suggestionRequest, error) {
+	var req suggestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return nil, fmt.Errorf("リクエストの形式が正しくありません")
+	}
+
+	if req.RecipeID != "" {
+		if _, err := uuid.Parse(req.RecipeID); err != nil {
+			return nil, fmt.Errorf("レシピIDの形式が正しくありません")
+		}
+		var recipe models.Recipe
+		if err := h.DB.Preload("Ingredients.Ingredient").Preload("Ingredients.Unit").
+			Where("id = ?", req.RecipeID).First(&recipe).Error; err != nil {
+			return nil, err
+		}
+		if req.Name == "" {
+			req.Name = recipe.Name
+		}
+		if len(req.Ingredients) == 0 {
+			for _, ri := range recipe.Ingredients {
+				req.Ingredients = append(req.Ingredients, suggestionIngredient{
+					Name:     ri.Ingredient.Name,
+					Quantity: ri.QuantityRequired,
+					Unit:     ri.Unit.Name,
+				})
+			}
+		}
+		if len(req.Instructions) == 0 {
+			req.Instructions = recipe.Instructions
+		}
+	}
+
+	if req.Name == "" {
+		return nil, fmt.Errorf("レシピ名が必要です")
+	}
+	if len(req.Ingredients) == 0 && len(req.Instructions) == 0 {
+		return nil, fmt.Errorf("材料または手順が必要です")
+	}
+	return &req, nil
+}
+
+// suggestionVars は材料と手順をプロンプト用の箇条書きにする
+func suggestionVars(input *suggestionRequest) map[string]string {
+	ingredients := make([]string, 0, len(input.Ingredients))
+	for _, ing := range input.Ingredients {
+		line := "- " + ing.Name
+		if ing.Quantity > 0 {
+			line += " " + strconv.FormatFloat(ing.Quantity, 'f', -1, 64) + ing.Unit
+		}
+		ingredients = append(ingredients, line)
+	}
+	steps := make([]string, 0, len(input.Instructions))
+	for _, step := range input.Instructions {
+		steps = append(steps, fmt.Sprintf("%d. %s", step.StepNumber, step.Description))
+	}
+	return map[string]string{
+		"recipe_name": input.Name,
+		"ingredients": strings.Join(ingredients, "\n"),
+		"steps":       strings.Join(steps, "\n"),
+	}
+}
+
+func respondSuggestions(c *gin.Context, resp *ai.Response, status *quota.Status, suggestions interface{}) {
+	c.JSON(http.StatusOK, gin.H{
+		"suggestions": suggestions,
+		"model":       resp.Model,
+		"usage_count": status.Used,
+		"usage_limit": status.Limit,
+		"unlimited":   status.Unlimited,
+	})
+}
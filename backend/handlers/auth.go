@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -27,55 +28,76 @@ func NewAuthHandler(supabase *supa.Client, db *gorm.DB) *AuthHandler {
 
 // GetUserRole ユーザーのロール情報を取得するハンドラー
 func (h *AuthHandler) GetUserRole(c *gin.Context) {
-	// Authorization ヘッダーからトークンを取得
-	authHeader := c.GetHeader("Authorization")
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
 
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+	// ユーザーのロール情報を取得
+	var userRole struct {
+		Role string `json:"role"`
+	}
+	err := h.db.Table("user_roles").
+		Select("role").
+		Where("user_id = ?", userID).
+		First(&userRole).Error
+
+	if err != nil {
+		// ロールが見つからない場合はデフォルトでuserを返す
+		c.JSON(http.StatusOK, gin.H{"role": "user"})
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{"role": userRole.Role})
+}
+
+// authenticatedUserID はAuthorizationヘッダーのJWTからユーザーIDを取り出す。
+// 取り出せない場合は401を返してfalseを返す。
+func authenticatedUserID(c *gin.Context) (string, bool) {
+	userID, err := userIDFromAuthHeader(c.GetHeader("Authorization"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return "", false
+	}
+	return userID, true
+}
+
+// optionalUserID は認証されている場合のみユーザーIDを返す（作成者の記録などに使う）
+func optionalUserID(c *gin.Context) *string {
+	userID, err := userIDFromAuthHeader(c.GetHeader("Authorization"))
+	if err != nil {
+		return nil
+	}
+	return &userID
+}
+
+// userIDFromAuthHeader はBearerトークンのJWTペイロードからsubを取り出す
+func userIDFromAuthHeader(authHeader string) (string, error) {
+	if authHeader == "" {
+		return "", errors.New("認証が必要です")
+	}
+
 	// Bearer トークンを抽出
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == authHeader {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "無効なトークン形式です"})
-		return
+		return "", errors.New("無効なトークン形式です")
 	}
 
 	// JWTトークンを解析
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "無効なトークン形式です"})
-		return
+		return "", errors.New("無効なトークン形式です")
 	}
 
 	// Base64デコードしてJSONを解析
 	claims := models.JWTClaims{}
 	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "無効なトークンです"})
-		return
+		return "", errors.New("無効なトークンです")
 	}
-
-	if err := json.Unmarshal(payload, &claims); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "無効なトークンです"})
-		return
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Sub == "" {
+		return "", errors.New("無効なトークンです")
 	}
 
-	// ユーザーのロール情報を取得
-	var userRole struct {
-		Role string `json:"role"`
-	}
-	err = h.db.Table("user_roles").
-		Select("role").
-		Where("user_id = ?", claims.Sub).
-		First(&userRole).Error
-
-	if err != nil {
-		// ロールが見つからない場合はデフォルトでuserを返す
-		c.JSON(http.StatusOK, gin.H{"role": "user"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"role": userRole.Role})
+	return claims.Sub, nil
 }
@@ -0,0 +1,546 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"portfolio-amarimono/feed"
+	"portfolio-amarimono/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxCollectionNameLength はコレクション名の最大文字数
+const maxCollectionNameLength = 50
+
+// maxCollectionsPerUser はユーザーが作成できるコレクションの上限
+const maxCollectionsPerUser = 100
+
+type CollectionHandler struct {
+	DB *gorm.DB
+}
+
+// NewCollectionHandler は CollectionHandler を初期化するコンストラクタ
+func NewCollectionHandler(db *gorm.DB) *CollectionHandler {
+	return &CollectionHandler{
+		DB: db,
+	}
+}
+
+// collectionRequest はコレクションの作成・更新リクエスト（更新時は指定した項目だけ変更する）
+type collectionRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	IsPublic    *bool   `json:"is_public"`
+}
+
+// ListCollections はログインユーザーのコレクション一覧を並び順で取得する
+func (h *CollectionHandler) ListCollections(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	var collections []models.Collection
+	if err := h.DB.Where("user_id = ?", userID).Order("position, created_at").Find(&collections).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "コレクションの取得に失敗しました"})
+		return
+	}
+	if err := h.fillRecipeCounts(collections); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "コレクションの取得に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"collections": collections})
+}
+
+// CreateCollection はコレクションを作成する（一覧の末尾に追加）
+func (h *CollectionHandler) CreateCollection(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	var req collectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストの形式が正しくありません"})
+		return
+	}
+	if req.Name == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "コレクション名を入力してください"})
+		return
+	}
+
+	ownerID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ユーザーIDの形式が無効です"})
+		return
+	}
+	collection := models.Collection{UserID: models.FromUUID(ownerID)}
+	if msg := applyCollectionRequest(&collection, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var stats struct {
+			Count       int
+			MaxPosition int
+		}
+		if err := tx.Raw("SELECT COUNT(*) AS count, COALESCE(MAX(position), -1) AS max_position FROM collections WHERE user_id = ?", userID).Scan(&stats).Error; err != nil {
+			return err
+		}
+		if stats.Count >= maxCollectionsPerUser {
+			return errCollectionLimit
+		}
+		collection.Position = stats.MaxPosition + 1
+		if err := tx.Create(&collection).Error; err != nil {
+			return err
+		}
+		return feed.SyncCollection(tx, &collection, time.Now())
+	})
+	if errors.Is(err, errCollectionLimit) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("コレクションは%d個まで作成できます", maxCollectionsPerUser)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "コレクションの作成に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"collection": collection})
+}
+
+// GetCollection はコレクションとレシピを取得する（本人以外は公開中のコレクションのみ）
+func (h *CollectionHandler) GetCollection(c *gin.Context) {
+	collection, ok := h.findCollection(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	viewerID := optionalUserID(c)
+	isOwner := viewerID != nil && *viewerID == collection.UserID.String()
+	if !isOwner && !collection.IsPublic {
+		c.JSON(http.StatusNotFound, gin.H{"error": "コレクションが見つかりません"})
+		return
+	}
+	if !isOwner {
+		// 削除を申請中のユーザーのコレクションは本人以外には見せない
+		var pending int64
+		if err := h.DB.Model(&models.User{}).Where("id = ? AND deleted_at IS NOT NULL", collection.UserID.String()).Count(&pending).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "コレクションの取得に失敗しました"})
+			return
+		}
+		if pending > 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "コレクションが見つかりません"})
+			return
+		}
+	}
+
+	h.respondCollection(c, collection, isOwner)
+}
+
+// GetSharedCollection は共有用のスラッグから公開中のコレクションを取得する（認証不要）
+func (h *CollectionHandler) GetSharedCollection(c *gin.Context) {
+	var collection models.Collection
+	err := h.DB.Scopes(models.ActiveOwner("collections.user_id")).
+		Where("slug = ? AND is_public = ?", c.Param("slug"), true).
+		First(&collection).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "コレクションが見つかりません"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "コレクションの取得に失敗しました"})
+		return
+	}
+
+	viewerID := optionalUserID(c)
+	h.respondCollection(c, &collection, viewerID != nil && *viewerID == collection.UserID.String())
+}
+
+// UpdateCollection はコレクションの名前・説明・公開設定を更新する
+// 初めて公開するときに共有用のスラッグを発行する（非公開に戻してもスラッグは維持する）
+func (h *CollectionHandler) UpdateCollection(c *gin.Context) {
+	collection, ok := h.findOwnCollection(c)
+	if !ok {
+		return
+	}
+
+	var req collectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストの形式が正しくありません"})
+		return
+	}
+	if msg := applyCollectionRequest(collection, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	// 公開設定の変更に合わせてフォロワーのフィードへの配信・取り消しを行う
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(collection).Select("name", "description", "is_public", "slug", "updated_at").Updates(collection).Error; err != nil {
+			return err
+		}
+		return feed.SyncCollection(tx, collection, time.Now())
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "コレクションの更新に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"collection": collection})
+}
+
+// DeleteCollection はコレクションを削除する（レシピ自体は削除しない）
+func (h *CollectionHandler) DeleteCollection(c *gin.Context) {
+	collection, ok := h.findOwnCollection(c)
+	if !ok {
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ?", collection.ID).Delete(&models.CollectionRecipe{}).Error; err != nil {
+			return err
+		}
+		if err := feed.Retract(tx, models.ActivityKindCollection, collection.ID.String()); err != nil {
+			return err
+		}
+		return tx.Delete(collection).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "コレクションの削除に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "コレクションを削除しました"})
+}
+
+// ReorderCollections はコレクションの並び順を更新する（collection_ids の順に並べる）
+func (h *CollectionHandler) ReorderCollections(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CollectionIDs []string `json:"collection_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "collection_idsを指定してください"})
+		return
+	}
+
+	var ownedIDs []string
+	if err := h.DB.Model(&models.Collection{}).Where("user_id = ?", userID).Pluck("id", &ownedIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "並び順の更新に失敗しました"})
+		return
+	}
+	if !samePermutation(ownedIDs, req.CollectionIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "すべてのコレクションを1回ずつ指定してください"})
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range req.CollectionIDs {
+			if err := tx.Model(&models.Collection{}).Where("id = ? AND user_id = ?", id, userID).Update("position", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "並び順の更新に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "並び順を更新しました"})
+}
+
+// AddCollectionRecipe はコレクションの末尾にレシピを追加する（追加済みの場合はそのまま成功とする）
+func (h *CollectionHandler) AddCollectionRecipe(c *gin.Context) {
+	collection, ok := h.findOwnCollection(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		RecipeID string `json:"recipe_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "recipe_idを指定してください"})
+		return
+	}
+	if _, err := uuid.Parse(req.RecipeID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "レシピIDの形式が無効です"})
+		return
+	}
+
+	var recipeCount int64
+	if err := h.DB.Model(&models.Recipe{}).Where("id = ?", req.RecipeID).Count(&recipeCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "レシピの追加に失敗しました"})
+		return
+	}
+	if recipeCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "レシピが見つかりません"})
+		return
+	}
+
+	res := h.DB.Exec(`
+		INSERT INTO collection_recipes (collection_id, recipe_id, position)
+		SELECT ?, ?, COALESCE(MAX(position), -1) + 1 FROM collection_recipes WHERE collection_id = ?
+		ON CONFLICT (collection_id, recipe_id) DO NOTHING`, collection.ID, req.RecipeID, collection.ID)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "レシピの追加に失敗しました"})
+		return
+	}
+	h.touchCollection(collection)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "コレクションに追加しました",
+		"recipe_id": req.RecipeID,
+		"added":     res.RowsAffected > 0,
+	})
+}
+
+// RemoveCollectionRecipe はコレクションからレシピを外す（含まれていない場合もそのまま成功とする）
+func (h *CollectionHandler) RemoveCollectionRecipe(c *gin.Context) {
+	collection, ok := h.findOwnCollection(c)
+	if !ok {
+		return
+	}
+
+	recipeID := c.Param("recipe_id")
+	res := h.DB.Where("collection_id = ? AND recipe_id = ?", collection.ID, recipeID).Delete(&models.CollectionRecipe{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "レシピの削除に失敗しました"})
+		return
+	}
+	h.touchCollection(collection)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "コレクションから削除しました",
+		"recipe_id": recipeID,
+		"removed":   res.RowsAffected > 0,
+	})
+}
+
+// ReorderCollectionRecipes はコレクション内のレシピの並び順を更新する（recipe_ids の順に並べる）
+func (h *CollectionHandler) ReorderCollectionRecipes(c *gin.Context) {
+	collection, ok := h.findOwnCollection(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		RecipeIDs []string `json:"recipe_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "recipe_idsを指定してください"})
+		return
+	}
+
+	currentIDs, err := collectionRecipeIDs(h.DB, collection.ID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "並び順の更新に失敗しました"})
+		return
+	}
+	if !samePermutation(currentIDs, req.RecipeIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "コレクション内のすべてのレシピを1回ずつ指定してください"})
+		return
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range req.RecipeIDs {
+			if err := tx.Model(&models.CollectionRecipe{}).Where("collection_id = ? AND recipe_id = ?", collection.ID, id).Update("position", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "並び順の更新に失敗しました"})
+		return
+	}
+	h.touchCollection(collection)
+
+	c.JSON(http.StatusOK, gin.H{"message": "並び順を更新しました"})
+}
+
+// collectionRecipeIDs はコレクション内のレシピIDを並び順で返す
+func collectionRecipeIDs(db *gorm.DB, collectionID string) ([]string, error) {
+	var ids []string
+	err := db.Model(&models.CollectionRecipe{}).
+		Where("collection_id = ?", collectionID).
+		Order("position, added_at").
+		Pluck("recipe_id", &ids).Error
+	return ids, err
+}
+
+var errCollectionLimit = errors.New("collection limit reached")
+
+// applyCollectionRequest はリクエストの内容をコレクションに反映する（不正な値の場合はエラーメッセージを返す）
+func applyCollectionRequest(collection *models.Collection, req collectionRequest) string {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return "コレクション名を入力してください"
+		}
+		if utf8.RuneCountInString(name) > maxCollectionNameLength {
+			return fmt.Sprintf("コレクション名は%d文字以内で入力してください", maxCollectionNameLength)
+		}
+		collection.Name = name
+	}
+	if req.Description != nil {
+		collection.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.IsPublic != nil {
+		collection.IsPublic = *req.IsPublic
+		if collection.IsPublic && collection.Slug == nil {
+			slug := strings.ReplaceAll(uuid.New().String(), "-", "")[:12]
+			collection.Slug = &slug
+		}
+	}
+	return ""
+}
+
+// findCollection はIDからコレクションを取得する（見つからない場合はレスポンスを書き込んで false を返す）
+func (h *CollectionHandler) findCollection(c *gin.Context, id string) (*models.Collection, bool) {
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "コレクションIDの形式が無効です"})
+		return nil, false
+	}
+
+	var collection models.Collection
+	err := h.DB.First(&collection, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "コレクションが見つかりません"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "コレクションの取得に失敗しました"})
+		return nil, false
+	}
+	return &collection, true
+}
+
+// findOwnCollection はログインユーザー自身のコレクションを取得する（他人のコレクションは見つからない扱い）
+func (h *CollectionHandler) findOwnCollection(c *gin.Context) (*models.Collection, bool) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return nil, false
+	}
+	collection, ok := h.findCollection(c, c.Param("id"))
+	if !ok {
+		return nil, false
+	}
+	if collection.UserID.String() != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "コレクションが見つかりません"})
+		return nil, false
+	}
+	return collection, true
+}
+
+// respondCollection はコレクションにレシピの詳細を付けて返す。
+// 本人以外には非公開・下書きのレシピや、削除申請中のユーザーのレシピを含めない。
+func (h *CollectionHandler) respondCollection(c *gin.Context, collection *models.Collection, isOwner bool) {
+	if err := h.DB.Where("collection_id = ?", collection.ID).Order("position, added_at").Find(&collection.Recipes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "コレクションの取得に失敗しました"})
+		return
+	}
+
+	recipeIDs := make([]string, 0, len(collection.Recipes))
+	for _, cr := range collection.Recipes {
+		recipeIDs = append(recipeIDs, cr.RecipeID.String())
+	}
+
+	query := h.DB.Scopes(preloadRecipeDetails).Where("id IN ?", recipeIDs)
+	if !isOwner {
+		query = query.Scopes(publicRecipes)
+	}
+	var recipes []models.Recipe
+	if len(recipeIDs) > 0 {
+		if err := query.Find(&recipes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "コレクションの取得に失敗しました"})
+			return
+		}
+	}
+
+	byID := make(map[string]*models.Recipe, len(recipes))
+	for i := range recipes {
+		byID[recipes[i].ID.String()] = &recipes[i]
+	}
+	visible := collection.Recipes[:0]
+	for _, cr := range collection.Recipes {
+		if recipe, ok := byID[cr.RecipeID.String()]; ok {
+			cr.Recipe = recipe
+			visible = append(visible, cr)
+		}
+	}
+	collection.Recipes = visible
+	collection.RecipeCount = len(visible)
+
+	c.JSON(http.StatusOK, gin.H{"collection": collection, "is_owner": isOwner})
+}
+
+// fillRecipeCounts は一覧表示用に各コレクションのレシピ数を設定する
+func (h *CollectionHandler) fillRecipeCounts(collections []models.Collection) error {
+	if len(collections) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(collections))
+	for _, col := range collections {
+		ids = append(ids, col.ID.String())
+	}
+
+	var rows []struct {
+		CollectionID string
+		Count        int
+	}
+	if err := h.DB.Model(&models.CollectionRecipe{}).
+		Select("collection_id, COUNT(*) AS count").
+		Where("collection_id IN ?", ids).
+		Group("collection_id").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.CollectionID] = row.Count
+	}
+	for i := range collections {
+		collections[i].RecipeCount = counts[collections[i].ID.String()]
+	}
+	return nil
+}
+
+// touchCollection はレシピの追加・削除・並び替えに合わせて更新日時を進める
+func (h *CollectionHandler) touchCollection(collection *models.Collection) {
+	h.DB.Model(collection).Update("updated_at", gorm.Expr("CURRENT_TIMESTAMP"))
+}
+
+// samePermutation は want が current と同じIDを重複なく過不足なく含んでいるかを判定する
+func samePermutation(current, want []string) bool {
+	if len(current) != len(want) {
+		return false
+	}
+	remaining := make(map[string]bool, len(current))
+	for _, id := range current {
+		remaining[strings.ToLower(id)] = true
+	}
+	for _, id := range want {
+		key := strings.ToLower(id)
+		if !remaining[key] {
+			return false
+		}
+		delete(remaining, key)
+	}
+	return true
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"portfolio-amarimono/aggregates"
+	"portfolio-amarimono/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxCookLogNoteLength は「作った」記録のメモの最大文字数
+	maxCookLogNoteLength = 500
+	// maxCookLogServings は記録できる人数の上限
+	maxCookLogServings = 20
+	// maxCookLogSubstitutions は記録できる材料の代用の数
+	maxCookLogSubstitutions = 10
+	// maxSubstitutionLength は代用の材料名の最大文字数
+	maxSubstitutionLength = 50
+)
+
+// jst は「作った日」を日本時間の日付として扱うためのタイムゾーン
+var jst = time.FixedZone("JST", 9*60*60)
+
+type CookLogHandler struct {
+	DB  *gorm.DB
+	Now func() time.Time
+}
+
+// NewCookLogHandler は CookLogHandler を初期化するコンストラクタ
+func NewCookLogHandler(db *gorm.DB) *CookLogHandler {
+	return &CookLogHandler{
+		DB:  db,
+		Now: time.Now,
+	}
+}
+
+// cookLogRequest は「作った」記録の作成・更新リクエスト（更新時は指定した項目だけ変更する）
+type cookLogRequest struct {
+	CookedOn      *models.DateOnly       `json:"cooked_on"` // 作成時に省略すると今日（日本時間）
+	Note          *string                `json:"note"`
+	Servings      *int                   `json:"servings"`
+	Substitutions *[]models.Substitution `json:"substitutions"`
+}
+
+// CreateCookLog はレシピを「作った」記録を追加する
+func (h *CookLogHandler) CreateCookLog(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	recipeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "レシピIDの形式が無効です"})
+		return
+	}
+	ownerID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ユーザーIDの形式が無効です"})
+		return
+	}
+
+	var req cookLogRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストの形式が正しくありません"})
+		return
+	}
+
+	cookLog := models.CookLog{
+		UserID:   models.FromUUID(ownerID),
+		RecipeID: models.FromUUID(recipeID),
+	}
+	if msg := h.applyCookLogRequest(&cookLog, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	// 作られた回数も同じトランザクションで更新
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var recipeCount int64
+		if err := tx.Model(&models.Recipe{}).Where("id = ?", recipeID).Count(&recipeCount).Error; err != nil {
+			return err
+		}
+		if recipeCount == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Create(&cookLog).Error; err != nil {
+			return err
+		}
+		return aggregates.CookLogged(tx, recipeID.String())
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "レシピが見つかりません"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "記録の保存に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"cook_log": cookLog})
+}
+
+// ListCookLogs はログインユーザーの「作った」履歴を新しい順で返す（?recipe_id= で絞り込み、?limit= / ?offset= でページング）
+func (h *CookLogHandler) ListCookLogs(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	query := h.DB.Model(&models.CookLog{}).Where("user_id = ?", userID)
+	if recipeID := c.Query("recipe_id"); recipeID != "" {
+		if _, err := uuid.Parse(recipeID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "レシピIDの形式が無効です"})
+			return
+		}
+		query = query.Where("recipe_id = ?", recipeID)
+	}
+
+	var summary struct {
+		Total   int `json:"total"`
+		Recipes int `json:"recipes"`
+	}
+	if err := query.Session(&gorm.Session{}).
+		Select("COUNT(*) AS total, COUNT(DISTINCT recipe_id) AS recipes").
+		Scan(&summary).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "履歴の取得に失敗しました"})
+		return
+	}
+
+	var logs []models.CookLog
+	if err := query.Session(&gorm.Session{}).
+		Preload("Recipe").
+		Preload("Recipe.Genre").
+		Order("cooked_on DESC, created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "履歴の取得に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cook_logs":     logs,
+		"total":         summary.Total,
+		"recipe_count":  summary.Recipes,
+		"limit":         limit,
+		"offset":        offset,
+		"has_next_page": offset+len(logs) < summary.Total,
+	})
+}
+
+// UpdateCookLog は「作った」記録を更新する（本人の記録のみ）
+func (h *CookLogHandler) UpdateCookLog(c *gin.Context) {
+	cookLog, ok := h.findOwnCookLog(c)
+	if !ok {
+		return
+	}
+
+	var req cookLogRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストの形式が正しくありません"})
+		return
+	}
+	if msg := h.applyCookLogRequest(cookLog, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.DB.Model(cookLog).Select("cooked_on", "note", "servings", "substitutions", "updated_at").Updates(cookLog).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "記録の更新に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cook_log": cookLog})
+}
+
+// DeleteCookLog は「作った」記録を削除する（本人の記録のみ）
+func (h *CookLogHandler) DeleteCookLog(c *gin.Context) {
+	cookLog, ok := h.findOwnCookLog(c)
+	if !ok {
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(cookLog)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		return aggregates.CookLogRemoved(tx, cookLog.RecipeID.String())
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "記録の削除に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "記録を削除しました"})
+}
+
+// applyCookLogRequest はリクエストを検証して指定された項目を記録に反映する（不正な値の場合はエラーメッセージを返す）
+func (h *CookLogHandler) applyCookLogRequest(cookLog *models.CookLog, req cookLogRequest) string {
+	now := h.Now().In(jst)
+	today := models.DateOnly{Time: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)}
+	cookedOn := cookLog.CookedOn
+	if cookedOn.IsZero() {
+		cookedOn = today
+	}
+	if req.CookedOn != nil && !req.CookedOn.IsZero() {
+		cookedOn = *req.CookedOn
+	}
+	if cookedOn.After(today.Time) {
+		return "未来の日付は指定できません"
+	}
+
+	note := cookLog.Note
+	if req.Note != nil {
+		note = strings.TrimSpace(*req.Note)
+		if utf8.RuneCountInString(note) > maxCookLogNoteLength {
+			return fmt.Sprintf("メモは%d文字以内で入力してください", maxCookLogNoteLength)
+		}
+	}
+	servings := cookLog.Servings
+	if req.Servings != nil {
+		if *req.Servings < 1 || *req.Servings > maxCookLogServings {
+			return fmt.Sprintf("人数は1〜%d人で指定してください", maxCookLogServings)
+		}
+		servings = req.Servings
+	}
+
+	substitutions := cookLog.Substitutions
+	if substitutions == nil {
+		substitutions = models.JSONBSubstitutions{}
+	}
+	if req.Substitutions != nil {
+		if len(*req.Substitutions) > maxCookLogSubstitutions {
+			return fmt.Sprintf("代用した材料は%d件まで記録できます", maxCookLogSubstitutions)
+		}
+		substitutions = make(models.JSONBSubstitutions, 0, len(*req.Substitutions))
+		for _, sub := range *req.Substitutions {
+			sub.Original = strings.TrimSpace(sub.Original)
+			sub.Substitute = strings.TrimSpace(sub.Substitute)
+			if sub.Original == "" || sub.Substitute == "" {
+				return "代用した材料は元の材料と代わりの材料を両方入力してください"
+			}
+			if utf8.RuneCountInString(sub.Original) > maxSubstitutionLength || utf8.RuneCountInString(sub.Substitute) > maxSubstitutionLength {
+				return fmt.Sprintf("材料名は%d文字以内で入力してください", maxSubstitutionLength)
+			}
+			substitutions = append(substitutions, sub)
+		}
+	}
+
+	cookLog.CookedOn = cookedOn
+	cookLog.Note = note
+	cookLog.Servings = servings
+	cookLog.Substitutions = substitutions
+	return ""
+}
+
+// findOwnCookLog はログインユーザー自身の記録を取得する（他人の記録は見つからない扱い）
+func (h *CookLogHandler) findOwnCookLog(c *gin.Context) (*models.CookLog, bool) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "記録IDの形式が無効です"})
+		return nil, false
+	}
+
+	var cookLog models.CookLog
+	err = h.DB.Where("id = ? AND user_id = ?", id, userID).Take(&cookLog).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "記録が見つかりません"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "記録の取得に失敗しました"})
+		return nil, false
+	}
+	return &cookLog, true
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"portfolio-amarimono/feed"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type FeedHandler struct {
+	Feed *feed.Service
+}
+
+// NewFeedHandler は FeedHandler を初期化するコンストラクタ
+func NewFeedHandler(db *gorm.DB) *FeedHandler {
+	return &FeedHandler{
+		Feed: feed.NewService(db),
+	}
+}
+
+// GetFeed はフォローしているユーザーの新着（レシピ・レビュー・コレクション）を新しい順で返す（?cursor= / ?limit= でページング）
+func (h *FeedHandler) GetFeed(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	limit := feed.DefaultPageSize
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > feed.MaxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limitは1〜%dで指定してください", feed.MaxPageSize)})
+			return
+		}
+		limit = n
+	}
+
+	page, err := h.Feed.List(userID, c.Query("cursor"), limit)
+	if errors.Is(err, feed.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "カーソルの形式が正しくありません"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "フィードの取得に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
@@ -8,18 +8,21 @@ import (
 
 	"portfolio-amarimono/handlers/utils"
 	"portfolio-amarimono/models"
+	"portfolio-amarimono/storage"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type IngredientHandler struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	Images storage.ImageStore
 }
 
-func NewIngredientHandler(db *gorm.DB) *IngredientHandler {
+func NewIngredientHandler(db *gorm.DB, images storage.ImageStore) *IngredientHandler {
 	return &IngredientHandler{
-		DB: db,
+		DB:     db,
+		Images: images,
 	}
 }
 
@@ -102,6 +105,12 @@ func (h *IngredientHandler) AddIngredient(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Image file is required"})
 		return
 	}
+	// 具材を作成する前に画像を検証する
+	img, err := utils.DecodeUploadedImage(file)
+	if err != nil {
+		respondImageError(c, err, "Failed to save image")
+		return
+	}
 
 	// 具材を先に作成してIDを取得
 	ingredient := models.Ingredient{
@@ -129,7 +138,7 @@ func (h *IngredientHandler) AddIngredient(c *gin.Context) {
 	}
 
 	// 画像を保存
-	imagePath, err := utils.SaveImage(c, file, "ingredients", fmt.Sprintf("%d", ingredient.ID))
+	imagePath, err := utils.SaveDecodedImage(c.Request.Context(), h.Images, img, "ingredients", fmt.Sprintf("%d", ingredient.ID))
 	if err != nil {
 		// 画像の保存に失敗した場合は具材を削除
 		h.DB.Delete(&ingredient)
@@ -212,9 +221,9 @@ func (h *IngredientHandler) UpdateIngredient(c *gin.Context) {
 	file, err := c.FormFile("image")
 	if err == nil { // 画像が選択された場合のみ処理
 		// SaveImage関数を使用して画像を保存
-		imagePath, err := utils.SaveImage(c, file, "ingredients", fmt.Sprintf("%d", ingredient.ID))
+		imagePath, err := utils.SaveImage(c.Request.Context(), h.Images, file, "ingredients", fmt.Sprintf("%d", ingredient.ID))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+			respondImageError(c, err, "Failed to save image")
 			return
 		}
 		// 新しい画像のパスをセット
@@ -261,7 +270,7 @@ func (h *IngredientHandler) DeleteIngredient(c *gin.Context) {
 
 	// 画像が存在する場合は削除
 	if ingredient.ImageUrl != "" {
-		if err := utils.DeleteImage(ingredient.ImageUrl); err != nil {
+		if err := utils.DeleteImage(c.Request.Context(), h.Images, ingredient.ImageUrl); err != nil {
 			// 画像の削除に失敗しても具材の削除は続行
 		}
 	}
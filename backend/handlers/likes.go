@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"portfolio-amarimono/aggregates"
 	"portfolio-amarimono/models"
+	"portfolio-amarimono/notifications"
 	"strings"
 	"time"
 
@@ -14,18 +18,24 @@ import (
 )
 
 type LikeHandler struct {
-	DB *gorm.DB
+	DB            *gorm.DB
+	Notifications *notifications.Service
 }
 
 func NewLikeHandler(db *gorm.DB) *LikeHandler {
 	return &LikeHandler{
-		DB: db,
+		DB:            db,
+		Notifications: notifications.NewService(db),
 	}
 }
 
 // ToggleUserLike ユーザーのいいねを追加/削除するエンドポイント
+// 二重送信で状態が戻ってしまうため、新しいクライアントは PutUserLike / DeleteUserLike を使う
 func (h *LikeHandler) ToggleUserLike(c *gin.Context) {
-	userID := c.Param("user_id")
+	userID, ok := authorizeLikeOwner(c)
+	if !ok {
+		return
+	}
 	recipeID := c.Param("recipe_id")
 
 	// デバッグログの追加
@@ -33,10 +43,6 @@ func (h *LikeHandler) ToggleUserLike(c *gin.Context) {
 	fmt.Printf("🔍 ToggleUserLike - Environment: %s\n", os.Getenv("ENVIRONMENT"))
 
 	// UUIDのバリデーション
-	if _, err := uuid.Parse(userID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ユーザーIDの形式が無効です"})
-		return
-	}
 	if _, err := uuid.Parse(recipeID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "レシピIDの形式が無効です"})
 		return
@@ -100,8 +106,14 @@ func (h *LikeHandler) ToggleUserLike(c *gin.Context) {
 				DryRun:                   false,
 			})
 
-			// 生のSQLクエリを使用して削除
-			err = tx.Exec("DELETE FROM likes WHERE user_id = ? AND recipe_id = ?", userID, recipeID).Error
+			// 生のSQLクエリを使用して削除（いいね数も同じトランザクションで更新）
+			err = tx.Transaction(func(t *gorm.DB) error {
+				res := t.Exec("DELETE FROM likes WHERE user_id = ? AND recipe_id = ?", userID, recipeID)
+				if res.Error != nil || res.RowsAffected == 0 {
+					return res.Error
+				}
+				return aggregates.LikeRemoved(t, recipeID)
+			})
 			if err == nil {
 				fmt.Printf("🔍 ToggleUserLike - Successfully deleted like\n")
 				c.JSON(http.StatusOK, gin.H{"message": "お気に入りから削除しました"})
@@ -136,10 +148,17 @@ func (h *LikeHandler) ToggleUserLike(c *gin.Context) {
 				DryRun:                   false,
 			})
 
-			// 生のSQLクエリを使用して作成
-			err = tx.Exec("INSERT INTO likes (user_id, recipe_id) VALUES (?, ?)", userID, recipeID).Error
+			// 生のSQLクエリを使用して作成（いいね数も同じトランザクションで更新）
+			err = tx.Transaction(func(t *gorm.DB) error {
+				res := t.Exec("INSERT INTO likes (user_id, recipe_id) VALUES (?, ?) ON CONFLICT (user_id, recipe_id) DO NOTHING", userID, recipeID)
+				if res.Error != nil || res.RowsAffected == 0 {
+					return res.Error
+				}
+				return aggregates.LikeAdded(t, recipeID)
+			})
 			if err == nil {
 				fmt.Printf("🔍 ToggleUserLike - Successfully created like\n")
+				h.notifyLiked(c, userID, recipeID)
 				c.JSON(http.StatusOK, gin.H{"message": "お気に入りに追加しました"})
 				return
 			}
@@ -161,6 +180,199 @@ func (h *LikeHandler) ToggleUserLike(c *gin.Context) {
 	}
 }
 
+// maxLikeStatusRecipes はいいね状態を一括取得できるレシピ数の上限
+const maxLikeStatusRecipes = 100
+
+// PutUserLike レシピにいいねする（本人のみ）。既にいいね済みでも成功として扱う（冪等）
+func (h *LikeHandler) PutUserLike(c *gin.Context) {
+	h.setUserLike(c, true)
+}
+
+// DeleteUserLike レシピのいいねを取り消す（本人のみ）。いいねしていなくても成功として扱う（冪等）
+func (h *LikeHandler) DeleteUserLike(c *gin.Context) {
+	h.setUserLike(c, false)
+}
+
+// setUserLike はいいねの状態を liked に揃え、変更後のいいね数を返す
+func (h *LikeHandler) setUserLike(c *gin.Context, liked bool) {
+	userID, ok := authorizeLikeOwner(c)
+	if !ok {
+		return
+	}
+	recipeID := c.Param("recipe_id")
+	if _, err := uuid.Parse(recipeID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "レシピIDの形式が無効です"})
+		return
+	}
+
+	var likeCount int
+	changed := false
+	err := withPreparedStatementRetry(h.DB, func(tx *gorm.DB) error {
+		return tx.Transaction(func(t *gorm.DB) error {
+			var counts []int
+			if err := t.Raw("SELECT like_count FROM recipes WHERE id = ?", recipeID).Scan(&counts).Error; err != nil {
+				return err
+			}
+			if len(counts) == 0 {
+				return gorm.ErrRecordNotFound
+			}
+
+			var res *gorm.DB
+			if liked {
+				// (user_id, recipe_id) の一意制約により、同時リクエストでも行は1つだけになる
+				res = t.Exec("INSERT INTO likes (user_id, recipe_id) VALUES (?, ?) ON CONFLICT (user_id, recipe_id) DO NOTHING", userID, recipeID)
+			} else {
+				res = t.Exec("DELETE FROM likes WHERE user_id = ? AND recipe_id = ?", userID, recipeID)
+			}
+			if res.Error != nil {
+				return res.Error
+			}
+			changed = res.RowsAffected > 0
+			if changed {
+				var err error
+				if liked {
+					err = aggregates.LikeAdded(t, recipeID)
+				} else {
+					err = aggregates.LikeRemoved(t, recipeID)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return t.Raw("SELECT like_count FROM recipes WHERE id = ?", recipeID).Scan(&likeCount).Error
+		})
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "レシピが見つかりません"})
+		return
+	}
+	if err != nil {
+		fmt.Printf("🔍 setUserLike - Failed to update like: %v\n", err)
+		if liked {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "お気に入りの追加に失敗しました"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "お気に入りの削除に失敗しました"})
+		}
+		return
+	}
+
+	if liked && changed {
+		h.notifyLiked(c, userID, recipeID)
+	}
+
+	message := "お気に入りに追加しました"
+	if !liked {
+		message = "お気に入りから削除しました"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":    message,
+		"recipe_id":  recipeID,
+		"liked":      liked,
+		"changed":    changed,
+		"like_count": likeCount,
+	})
+}
+
+// authorizeLikeOwner は URL の :user_id がログイン中のユーザー本人であることを確認する（本人以外は 403）
+func authorizeLikeOwner(c *gin.Context) (string, bool) {
+	userID := c.Param("user_id")
+	if _, err := uuid.Parse(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ユーザーIDの形式が無効です"})
+		return "", false
+	}
+	callerID, ok := authenticatedUserID(c)
+	if !ok {
+		return "", false
+	}
+	if !strings.EqualFold(callerID, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "他のユーザーのいいねは操作できません"})
+		return "", false
+	}
+	return userID, true
+}
+
+// notifyLiked はレシピの投稿者にいいねを通知する（通知に失敗してもいいねは成功として返す）
+func (h *LikeHandler) notifyLiked(c *gin.Context, userID, recipeID string) {
+	if _, err := h.Notifications.RecipeLiked(c.Request.Context(), userID, recipeID); err != nil {
+		log.Printf("⚠️ Failed to notify like of recipe %s: %v", recipeID, err)
+	}
+}
+
+// GetUserLikeStatuses 複数レシピに対するユーザーのいいね状態を一括で取得するエンドポイント（本人のみ）
+// ?recipe_ids=id1,id2,... を受け取り、レシピIDごとの true/false を返す
+func (h *LikeHandler) GetUserLikeStatuses(c *gin.Context) {
+	userID, ok := authorizeLikeOwner(c)
+	if !ok {
+		return
+	}
+
+	var recipeIDs []string
+	seen := map[string]bool{}
+	for _, id := range strings.Split(c.Query("recipe_ids"), ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		if _, err := uuid.Parse(id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "レシピIDの形式が無効です"})
+			return
+		}
+		seen[id] = true
+		recipeIDs = append(recipeIDs, id)
+	}
+	if len(recipeIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "recipe_idsを指定してください"})
+		return
+	}
+	if len(recipeIDs) > maxLikeStatusRecipes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("一度に確認できるレシピは%d件までです", maxLikeStatusRecipes)})
+		return
+	}
+
+	var likedIDs []string
+	err := withPreparedStatementRetry(h.DB, func(tx *gorm.DB) error {
+		return tx.Raw("SELECT recipe_id FROM likes WHERE user_id = ? AND recipe_id IN ?", userID, recipeIDs).Scan(&likedIDs).Error
+	})
+	if err != nil {
+		fmt.Printf("🔍 GetUserLikeStatuses - Failed to query likes: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "お気に入りの確認に失敗しました"})
+		return
+	}
+
+	statuses := make(map[string]bool, len(recipeIDs))
+	for _, id := range recipeIDs {
+		statuses[id] = false
+	}
+	for _, id := range likedIDs {
+		statuses[id] = true
+	}
+	c.JSON(http.StatusOK, gin.H{"likes": statuses})
+}
+
+// withPreparedStatementRetry は prepared statement の衝突時にリトライしながら fn を実行する
+func withPreparedStatementRetry(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	var err error
+	for retry := 0; retry < 5; retry++ {
+		tx := db.Session(&gorm.Session{
+			PrepareStmt:              false,
+			SkipDefaultTransaction:   true,
+			DisableNestedTransaction: true,
+			QueryFields:              true,
+			DryRun:                   false,
+		})
+		err = fn(tx)
+		if err == nil {
+			return nil
+		}
+		if retry < 4 && (strings.Contains(err.Error(), "prepared statement") && strings.Contains(err.Error(), "already exists")) {
+			time.Sleep(time.Duration(100*(retry+1)) * time.Millisecond)
+			continue
+		}
+		return err
+	}
+	return err
+}
+
 // GetUserLikes ユーザーのお気に入りレシピを取得するエンドポイント
 func (h *LikeHandler) GetUserLikes(c *gin.Context) {
 	userID := c.Param("user_id")
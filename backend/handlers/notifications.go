@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/notifications"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type NotificationHandler struct {
+	Notifications *notifications.Service
+}
+
+// NewNotificationHandler は NotificationHandler を初期化するコンストラクタ
+func NewNotificationHandler(service *notifications.Service) *NotificationHandler {
+	return &NotificationHandler{
+		Notifications: service,
+	}
+}
+
+// ListNotifications はログインユーザーの通知を新しい順で返す（?unread=true で未読のみ、?cursor= / ?limit= でページング）
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	opts := notifications.ListOptions{
+		Cursor:     c.Query("cursor"),
+		UnreadOnly: c.Query("unread") == "true",
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > notifications.MaxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limitは1〜%dで指定してください", notifications.MaxPageSize)})
+			return
+		}
+		opts.Limit = n
+	}
+
+	page, err := h.Notifications.List(userID, opts)
+	if errors.Is(err, notifications.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "カーソルの形式が正しくありません"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "通知の取得に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetUnreadCount は未読の通知の件数を返す（ヘッダーのバッジ表示用）
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	count, err := h.Notifications.UnreadCount(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "通知の取得に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread_count": count})
+}
+
+// MarkNotificationRead は通知を既読にする（既読の通知に対しても成功として扱う）
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "通知IDの形式が無効です"})
+		return
+	}
+
+	notification, err := h.Notifications.MarkRead(userID, id.String())
+	if errors.Is(err, notifications.ErrNotificationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "通知が見つかりません"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "通知の更新に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notification": notification})
+}
+
+// MarkAllNotificationsRead は未読の通知をすべて既読にする
+func (h *NotificationHandler) MarkAllNotificationsRead(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	updated, err := h.Notifications.MarkAllRead(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "通知の更新に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": updated, "unread_count": 0})
+}
+
+// GetNotificationPreferences は通知の種類ごとの配信先の設定を返す（保存していない種類は既定の設定）
+func (h *NotificationHandler) GetNotificationPreferences(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	prefs, err := h.Notifications.Preferences(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "通知設定の取得に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": prefs})
+}
+
+// UpdateNotificationPreferences は指定した種類の通知の設定を保存する（指定しなかった種類は変更しない）
+func (h *NotificationHandler) UpdateNotificationPreferences(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Preferences []models.NotificationPreference `json:"preferences"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Preferences) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "リクエストの形式が正しくありません"})
+		return
+	}
+
+	prefs, err := h.Notifications.UpdatePreferences(userID, req.Preferences)
+	if errors.Is(err, notifications.ErrUnknownType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "通知の種類が正しくありません"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "通知設定の更新に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": prefs})
+}
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"portfolio-amarimono/feed"
+	"portfolio-amarimono/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ProfileHandler struct {
+	DB *gorm.DB
+}
+
+// NewProfileHandler は ProfileHandler を初期化するコンストラクタ
+func NewProfileHandler(db *gorm.DB) *ProfileHandler {
+	return &ProfileHandler{
+		DB: db,
+	}
+}
+
+// PublicProfile は他のユーザーにも公開するプロフィール（メールアドレス・年齢・性別・ロールは含めない）
+type PublicProfile struct {
+	ID           string  `json:"id"`
+	Username     *string `json:"username"`
+	ProfileImage *string `json:"profile_image"`
+	Bio          *string `json:"bio"`
+}
+
+// profileStats は公開プロフィールに表示する集計値
+type profileStats struct {
+	RecipeCount    int64   `json:"recipe_count"`
+	TotalLikes     int64   `json:"total_likes"`
+	AverageRating  float64 `json:"average_rating"`
+	FollowerCount  int64   `json:"follower_count"`
+	FollowingCount int64   `json:"following_count"`
+}
+
+// GetPublicProfile はユーザーの公開プロフィール・公開中のレシピ・集計値を返す（?limit= / ?offset= でレシピをページング）
+func (h *ProfileHandler) GetPublicProfile(c *gin.Context) {
+	profile, ok := h.findPublicProfile(c)
+	if !ok {
+		return
+	}
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.stats(profile.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "プロフィールの取得に失敗しました"})
+		return
+	}
+
+	var recipes []models.Recipe
+	if err := h.DB.Preload("Genre").
+		Scopes(publicRecipes).
+		Where("user_id = ?", profile.ID).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&recipes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "プロフィールの取得に失敗しました"})
+		return
+	}
+
+	res := gin.H{
+		"profile":       profile,
+		"stats":         stats,
+		"recipes":       recipes,
+		"has_next_page": int64(offset+len(recipes)) < stats.RecipeCount,
+	}
+	if viewerID := optionalUserID(c); viewerID != nil && !strings.EqualFold(*viewerID, profile.ID) {
+		following, err := h.isFollowing(*viewerID, profile.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "プロフィールの取得に失敗しました"})
+			return
+		}
+		res["is_following"] = following
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// FollowUser はユーザーをフォローする（既にフォローしていても成功として扱う）
+func (h *ProfileHandler) FollowUser(c *gin.Context) {
+	h.setFollow(c, true)
+}
+
+// UnfollowUser はフォローを解除する（フォローしていなくても成功として扱う）
+func (h *ProfileHandler) UnfollowUser(c *gin.Context) {
+	h.setFollow(c, false)
+}
+
+// ListFollowers はユーザーのフォロワーを新しい順で返す
+func (h *ProfileHandler) ListFollowers(c *gin.Context) {
+	h.listFollows(c, "followee_id", "follower_id")
+}
+
+// ListFollowing はユーザーがフォローしているユーザーを新しい順で返す
+func (h *ProfileHandler) ListFollowing(c *gin.Context) {
+	h.listFollows(c, "follower_id", "followee_id")
+}
+
+func (h *ProfileHandler) setFollow(c *gin.Context, follow bool) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+	target, ok := h.findPublicProfile(c)
+	if !ok {
+		return
+	}
+	if strings.EqualFold(userID, target.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "自分自身はフォローできません"})
+		return
+	}
+
+	// フォローの変更と同じトランザクションで、相手の新着をフィードに取り込む・取り除く
+	var changed bool
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var res *gorm.DB
+		if follow {
+			res = tx.Exec(`
+				INSERT INTO follows (follower_id, followee_id)
+				VALUES (?, ?)
+				ON CONFLICT (follower_id, followee_id) DO NOTHING`, userID, target.ID)
+		} else {
+			res = tx.Where("follower_id = ? AND followee_id = ?", userID, target.ID).Delete(&models.Follow{})
+		}
+		if res.Error != nil {
+			return res.Error
+		}
+		changed = res.RowsAffected > 0
+		switch {
+		case changed && follow:
+			return feed.Followed(tx, userID, target.ID)
+		case changed:
+			return feed.Unfollowed(tx, userID, target.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "フォローの更新に失敗しました"})
+		return
+	}
+
+	var followerCount int64
+	if err := h.DB.Model(&models.Follow{}).Where("followee_id = ?", target.ID).Count(&followerCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "フォローの更新に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":        target.ID,
+		"is_following":   follow,
+		"changed":        changed,
+		"follower_count": followerCount,
+	})
+}
+
+// listFollows は matchColumn が対象ユーザーのフォロー関係から、相手側（otherColumn）のプロフィールを返す
+func (h *ProfileHandler) listFollows(c *gin.Context, matchColumn, otherColumn string) {
+	profile, ok := h.findPublicProfile(c)
+	if !ok {
+		return
+	}
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	query := h.DB.Table("follows").
+		Joins("JOIN users ON users.id = follows."+otherColumn+" AND users.deleted_at IS NULL").
+		Where("follows."+matchColumn+" = ?", profile.ID)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ユーザーの取得に失敗しました"})
+		return
+	}
+
+	users := make([]PublicProfile, 0)
+	if err := query.Session(&gorm.Session{}).
+		Select("users.id, users.username, users.profile_image, users.bio").
+		Order("follows.created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ユーザーの取得に失敗しました"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":         users,
+		"total":         total,
+		"has_next_page": int64(offset+len(users)) < total,
+	})
+}
+
+// findPublicProfile は URL の :id のユーザーの公開プロフィールを取得する（退会手続き中のユーザーは見つからない扱い）
+func (h *ProfileHandler) findPublicProfile(c *gin.Context) (*PublicProfile, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ユーザーIDの形式が無効です"})
+		return nil, false
+	}
+
+	var profile PublicProfile
+	err = h.DB.Model(&models.User{}).
+		Select("id", "username", "profile_image", "bio").
+		Where("id = ? AND deleted_at IS NULL", id).
+		Take(&profile).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ユーザーが見つかりません"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ユーザーの取得に失敗しました"})
+		return nil, false
+	}
+	return &profile, true
+}
+
+func (h *ProfileHandler) stats(userID string) (*profileStats, error) {
+	stats := &profileStats{}
+	if err := h.DB.Model(&models.Recipe{}).Scopes(publicRecipes).Where("user_id = ?", userID).Count(&stats.RecipeCount).Error; err != nil {
+		return nil, err
+	}
+	var err error
+	if stats.TotalLikes, err = userLikeCount(h.DB, userID); err != nil {
+		return nil, err
+	}
+	if stats.AverageRating, err = userRecipeAverageRating(h.DB, userID); err != nil {
+		return nil, err
+	}
+	stats.AverageRating = math.Round(stats.AverageRating*100) / 100
+	if err := h.DB.Model(&models.Follow{}).Where("followee_id = ?", userID).Count(&stats.FollowerCount).Error; err != nil {
+		return nil, err
+	}
+	if err := h.DB.Model(&models.Follow{}).Where("follower_id = ?", userID).Count(&stats.FollowingCount).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (h *ProfileHandler) isFollowing(followerID, followeeID string) (bool, error) {
+	var count int64
+	err := h.DB.Model(&models.Follow{}).Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Count(&count).Error
+	return count > 0, err
+}
+
+// publicRecipes は公開中（非公開・下書き・削除申請中のユーザーのものを除く）のレシピに絞り込むスコープ
+func publicRecipes(db *gorm.DB) *gorm.DB {
+	return db.Where("is_public = ? AND is_draft = ?", true, false).Scopes(models.ActiveOwner("recipes.user_id"))
+}
+
+// pageParams は ?limit=（1〜100、デフォルト20）と ?offset= を読み取る
+func pageParams(c *gin.Context) (limit, offset int, ok bool) {
+	limit = 20
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limitは1〜100で指定してください"})
+			return 0, 0, false
+		}
+		limit = n
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offsetの形式が正しくありません"})
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return limit, offset, true
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/ranking"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+type RankingHandler struct {
+	DB       *gorm.DB
+	Rankings *ranking.Service
+}
+
+func NewRankingHandler(db *gorm.DB, redisClient *redis.Client) *RankingHandler {
+	return &RankingHandler{
+		DB:       db,
+		Rankings: ranking.NewService(db, redisClient),
+	}
+}
+
+// rankedRecipe はレシピに順位とスコアを付けたもの
+type rankedRecipe struct {
+	models.Recipe
+	Rank        int     `json:"rank"`
+	Score       float64 `json:"score"`
+	LikeCount   int     `json:"like_count"`
+	ReviewCount int     `json:"review_count"`
+	RatingAvg   float64 `json:"rating_avg"`
+}
+
+// GetRanking はランキングを取得する（:kind は trending / top_rated / most_cooked）
+// ?genre_id= でレシピのジャンルを絞り込める
+func (h *RankingHandler) GetRanking(c *gin.Context) {
+	kind, ok := ranking.ParseKind(c.Param("kind"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ranking kind"})
+		return
+	}
+
+	genreID := 0
+	if v := c.Query("genre_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid genre ID"})
+			return
+		}
+		genreID = id
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	r, err := h.Rankings.Get(c.Request.Context(), kind)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ranking"})
+		return
+	}
+	entries := r.Filter(genreID, limit)
+
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.RecipeID
+	}
+	var recipes []models.Recipe
+	if len(ids) > 0 {
+		// キャッシュの生成後に非公開になったレシピや、削除申請中のユーザーのレシピは除く
+		if err := h.DB.Preload("Genre").
+			Scopes(publicRecipes).
+			Where("id IN ?", ids).
+			Find(&recipes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ranking"})
+			return
+		}
+	}
+	byID := make(map[string]models.Recipe, len(recipes))
+	for _, recipe := range recipes {
+		byID[recipe.ID.String()] = recipe
+	}
+
+	result := make([]rankedRecipe, 0, len(entries))
+	for _, e := range entries {
+		recipe, ok := byID[e.RecipeID]
+		if !ok {
+			continue
+		}
+		result = append(result, rankedRecipe{
+			Recipe:      recipe,
+			Rank:        len(result) + 1,
+			Score:       e.Score,
+			LikeCount:   e.LikeCount,
+			ReviewCount: e.ReviewCount,
+			RatingAvg:   e.RatingAvg,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"kind":         r.Kind,
+		"generated_at": r.GeneratedAt,
+		"recipes":      result,
+	})
+}
@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"portfolio-amarimono/dietary"
 	"portfolio-amarimono/models"
+	"portfolio-amarimono/recommend"
 	"portfolio-amarimono/utils"
 
 	"github.com/gin-gonic/gin"
@@ -38,6 +42,7 @@ type SearchRequest struct {
 	Ingredients    []RecipeIngredientRequest `json:"ingredients"`
 	IgnoreQuantity bool                      `json:"ignoreQuantity"`
 	SearchMode     string                    `json:"searchMode"`
+	SortBy         string                    `json:"sortBy"` // popular / rating / reviews / newest（省略時は並び替えない）
 }
 
 // SerchRecipes handles POST /api/recipes
@@ -91,11 +96,9 @@ func (h *RecipeHandler) SerchRecipes(c *gin.Context) {
 
 	// レシピと関連具材をロード（下書きを除外）
 	var recipes []models.Recipe
-	if err := h.DB.Preload("Ingredients.Ingredient").
-		Preload("Ingredients.Ingredient.Unit").
-		Preload("Ingredients.Ingredient.Genre").
-		Preload("Genre").
-		Preload("Reviews").
+	if err := h.DB.Scopes(preloadRecipeDetails).
+		Scopes(recipeSort(request.SortBy)).
+		Scopes(models.ActiveOwner("recipes.user_id")).
 		Where("id IN ? AND is_draft = ?", recipeIDs, false).
 		Find(&recipes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed", "details": err.Error()})
@@ -168,6 +171,21 @@ func (h *RecipeHandler) SerchRecipes(c *gin.Context) {
 		result = h.filterExactWithQuantity(recipes, request.Ingredients, selectedIngredients)
 	}
 
+	// ログインしている場合は、保存したアレルゲン・食事制限に抵触するレシピを除く
+	codes, err := viewerDietaryRestrictions(h.DB, c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed", "details": err.Error()})
+		return
+	}
+	result, excluded, err := dietary.Exclude(h.DB, result, codes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed", "details": err.Error()})
+		return
+	}
+	if result == nil {
+		result = []models.Recipe{}
+	}
+
 	// 栄養素の割合を計算
 	for i := range result {
 		if result[i].Nutrition != (models.NutritionInfo{}) {
@@ -182,9 +200,9 @@ func (h *RecipeHandler) SerchRecipes(c *gin.Context) {
 		}
 	}
 
-	log.Printf("🥦 Final result count: %d\n", len(result))
+	log.Printf("🥦 Final result count: %d (excluded: %d)\n", len(result), len(excluded))
 
-	c.JSON(http.StatusOK, result)
+	respondRecipes(c, result, excluded)
 }
 
 // 完全一致（数量考慮）
@@ -485,6 +503,8 @@ func (h *RecipeHandler) SearchRecipesByName(c *gin.Context) {
 		Preload("Ingredients.Ingredient.Unit").
 		Preload("Ingredients.Ingredient.Genre").
 		Preload("Genre").
+		Scopes(recipeSort(c.Query("sort"))).
+		Scopes(models.ActiveOwner("recipes.user_id")).
 		Where("is_draft = ?", false).
 		Find(&allRecipes).Error
 
@@ -494,13 +514,25 @@ func (h *RecipeHandler) SearchRecipesByName(c *gin.Context) {
 	}
 
 	// 正規化検索でフィルタリング
-	var filteredRecipes []models.Recipe
+	filteredRecipes := make([]models.Recipe, 0)
 	for _, recipe := range allRecipes {
 		if utils.MatchesSearchQuery(query, recipe.Name) {
 			filteredRecipes = append(filteredRecipes, recipe)
 		}
 	}
 
+	// ログインしている場合は、保存したアレルゲン・食事制限に抵触するレシピを除く
+	codes, err := viewerDietaryRestrictions(h.DB, c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "データベースエラー"})
+		return
+	}
+	filteredRecipes, excluded, err := dietary.Exclude(h.DB, filteredRecipes, codes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "データベースエラー"})
+		return
+	}
+
 	// 栄養情報の標準値を取得
 	var standard models.NutritionStandard
 	if err := h.DB.Where("age_group = ? AND gender = ?", "18-29", "male").First(&standard).Error; err != nil {
@@ -530,7 +562,7 @@ func (h *RecipeHandler) SearchRecipesByName(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, filteredRecipes)
+	respondRecipes(c, filteredRecipes, excluded)
 }
 
 // GetRecipeByID は特定のレシピを取得するハンドラー
@@ -542,11 +574,7 @@ func (h *RecipeHandler) GetRecipeByID(c *gin.Context) {
 	}
 
 	var recipe models.Recipe
-	if err := h.DB.Preload("Ingredients.Ingredient").
-		Preload("Ingredients.Ingredient.Unit").
-		Preload("Ingredients.Ingredient.Genre").
-		Preload("Genre").
-		Preload("Reviews").
+	if err := h.DB.Scopes(preloadRecipeDetails).
 		First(&recipe, "id = ?", id).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipe"})
 		return
@@ -618,12 +646,16 @@ func (h *RecipeHandler) GetUserRecipes(c *gin.Context) {
 
 	var recipes []models.Recipe
 
-	// ユーザーのレシピだけを取得
-	if err := h.DB.
+	// ユーザーのレシピだけを取得（非公開・下書きのレシピは本人にだけ返す）
+	query := h.DB.
 		Preload("Genre").
 		Preload("Ingredients.Ingredient.Unit").
-		Where("user_id = ?", userID).
-		Find(&recipes).Error; err != nil {
+		Scopes(recipeSort(c.Query("sort"))).
+		Where("user_id = ?", userID)
+	if viewerID := optionalUserID(c); viewerID == nil || !strings.EqualFold(*viewerID, userID.String()) {
+		query = query.Scopes(publicRecipes)
+	}
+	if err := query.Find(&recipes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipes"})
 		return
 	}
@@ -681,11 +713,7 @@ func (h *RecipeHandler) GetRecipeByUserID(c *gin.Context) {
 	}
 
 	var recipes []models.Recipe
-	if err := h.DB.Preload("Ingredients.Ingredient").
-		Preload("Ingredients.Ingredient.Unit").
-		Preload("Ingredients.Ingredient.Genre").
-		Preload("Genre").
-		Preload("Reviews").
+	if err := h.DB.Scopes(preloadRecipeDetails).
 		Where("user_id = ?", userID).
 		Find(&recipes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipes"})
@@ -704,11 +732,8 @@ func (h *RecipeHandler) GetRecipeByGenreID(c *gin.Context) {
 	}
 
 	var recipes []models.Recipe
-	if err := h.DB.Preload("Ingredients.Ingredient").
-		Preload("Ingredients.Ingredient.Unit").
-		Preload("Ingredients.Ingredient.Genre").
-		Preload("Genre").
-		Preload("Reviews").
+	if err := h.DB.Scopes(preloadRecipeDetails).
+		Scopes(models.ActiveOwner("recipes.user_id")).
 		Where("genre_id = ?", genreID).
 		Find(&recipes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipes"})
@@ -727,12 +752,9 @@ func (h *RecipeHandler) GetRecipeByIngredientID(c *gin.Context) {
 	}
 
 	var recipes []models.Recipe
-	if err := h.DB.Preload("Ingredients.Ingredient").
-		Preload("Ingredients.Ingredient.Unit").
-		Preload("Ingredients.Ingredient.Genre").
-		Preload("Genre").
-		Preload("Reviews").
+	if err := h.DB.Scopes(preloadRecipeDetails).
 		Joins("JOIN recipe_ingredients ON recipes.id = recipe_ingredients.recipe_id").
+		Scopes(models.ActiveOwner("recipes.user_id")).
 		Where("recipe_ingredients.ingredient_id = ?", ingredientID).
 		Find(&recipes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipes"})
@@ -751,11 +773,8 @@ func (h *RecipeHandler) GetRecipeByNutrition(c *gin.Context) {
 	}
 
 	var recipes []models.Recipe
-	if err := h.DB.Preload("Ingredients.Ingredient").
-		Preload("Ingredients.Ingredient.Unit").
-		Preload("Ingredients.Ingredient.Genre").
-		Preload("Genre").
-		Preload("Reviews").
+	if err := h.DB.Scopes(preloadRecipeDetails).
+		Scopes(models.ActiveOwner("recipes.user_id")).
 		Where("nutrition @> ?", nutrition).
 		Find(&recipes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipes"})
@@ -774,11 +793,8 @@ func (h *RecipeHandler) GetRecipeByCookingTime(c *gin.Context) {
 	}
 
 	var recipes []models.Recipe
-	if err := h.DB.Preload("Ingredients.Ingredient").
-		Preload("Ingredients.Ingredient.Unit").
-		Preload("Ingredients.Ingredient.Genre").
-		Preload("Genre").
-		Preload("Reviews").
+	if err := h.DB.Scopes(preloadRecipeDetails).
+		Scopes(models.ActiveOwner("recipes.user_id")).
 		Where("cooking_time <= ?", cookingTime).
 		Find(&recipes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipes"})
@@ -797,11 +813,8 @@ func (h *RecipeHandler) GetRecipeByCostEstimate(c *gin.Context) {
 	}
 
 	var recipes []models.Recipe
-	if err := h.DB.Preload("Ingredients.Ingredient").
-		Preload("Ingredients.Ingredient.Unit").
-		Preload("Ingredients.Ingredient.Genre").
-		Preload("Genre").
-		Preload("Reviews").
+	if err := h.DB.Scopes(preloadRecipeDetails).
+		Scopes(models.ActiveOwner("recipes.user_id")).
 		Where("cost_estimate <= ?", costEstimate).
 		Find(&recipes).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipes"})
@@ -810,3 +823,130 @@ func (h *RecipeHandler) GetRecipeByCostEstimate(c *gin.Context) {
 
 	c.JSON(http.StatusOK, recipes)
 }
+
+// similarRecipe はレシピに類似度と共通の具材を付けたもの
+type similarRecipe struct {
+	models.Recipe
+	Similarity        float64                      `json:"similarity"`
+	SameGenre         bool                         `json:"same_genre"`
+	SharedIngredients []recommend.SharedIngredient `json:"shared_ingredients"`
+}
+
+// GetSimilarRecipes は具材・ジャンル・調理時間・費用が近い公開レシピを取得する
+func (h *RecipeHandler) GetSimilarRecipes(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recipe ID format"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "6"))
+	if err != nil || limit <= 0 {
+		limit = 6
+	}
+	if limit > 30 {
+		limit = 30
+	}
+
+	codes, err := viewerDietaryRestrictions(h.DB, c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch similar recipes"})
+		return
+	}
+	// アレルゲン・食事制限で除外する分を見込んで多めに候補を取る
+	candidates := limit
+	if len(codes) > 0 {
+		candidates = limit * dietaryCandidateFactor
+	}
+
+	similar, err := recommend.Similar(h.DB, id, candidates)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Recipe not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch similar recipes"})
+		}
+		return
+	}
+
+	ids := make([]string, len(similar))
+	for i, s := range similar {
+		ids[i] = s.RecipeID
+	}
+	var recipes []models.Recipe
+	if len(ids) > 0 {
+		if err := h.DB.Preload("Genre").Scopes(models.ActiveOwner("recipes.user_id")).Where("id IN ?", ids).Find(&recipes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch similar recipes"})
+			return
+		}
+	}
+	byID := make(map[string]models.Recipe, len(recipes))
+	for _, recipe := range recipes {
+		byID[recipe.ID.String()] = recipe
+	}
+
+	// 保存したアレルゲン・食事制限に抵触するレシピを除く（除外の一覧も類似度順）
+	ordered := make([]models.Recipe, 0, len(similar))
+	for _, s := range similar {
+		if recipe, ok := byID[s.RecipeID]; ok {
+			ordered = append(ordered, recipe)
+		}
+	}
+	kept, excluded, err := dietary.Exclude(h.DB, ordered, codes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch similar recipes"})
+		return
+	}
+	keptIDs := make(map[string]bool, len(kept))
+	for _, recipe := range kept {
+		keptIDs[recipe.ID.String()] = true
+	}
+
+	result := make([]similarRecipe, 0, limit)
+	for _, s := range similar {
+		if !keptIDs[s.RecipeID] {
+			continue
+		}
+		if len(result) == limit {
+			break
+		}
+		result = append(result, similarRecipe{
+			Recipe:            byID[s.RecipeID],
+			Similarity:        s.Score,
+			SameGenre:         s.SameGenre,
+			SharedIngredients: s.SharedIngredients,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recipes": result, "excluded": excluded})
+}
+
+// dietaryCandidateFactor はアレルゲン・食事制限で除外する場合に、表示件数の何倍の候補を取るか
+const dietaryCandidateFactor = 3
+
+// recipeSortOrders は一覧の並び順（集計値の列を使う）
+var recipeSortOrders = map[string]string{
+	"popular": "recipes.like_count DESC, recipes.created_at DESC",
+	"rating":  "recipes.rating_avg DESC, recipes.review_count DESC, recipes.created_at DESC",
+	"reviews": "recipes.review_count DESC, recipes.created_at DESC",
+	"newest":  "recipes.created_at DESC",
+}
+
+// recipeSort は並び順を指定するスコープ（未知の値の場合は並び替えない）
+func recipeSort(key string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if order, ok := recipeSortOrders[key]; ok {
+			return db.Order(order)
+		}
+		return db
+	}
+}
+
+// preloadRecipeDetails はレシピ詳細の表示に必要な材料・ジャンル・レビューを読み込むスコープ（非表示のレビューは除く）
+func preloadRecipeDetails(db *gorm.DB) *gorm.DB {
+	return db.Preload("Ingredients.Ingredient").
+		Preload("Ingredients.Ingredient.Unit").
+		Preload("Ingredients.Ingredient.Genre").
+		Preload("Genre").
+		Preload("Reviews", "status = ?", models.ReviewStatusVisible)
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
-	"math"
 	"net/http"
+	"strconv"
+
+	"portfolio-amarimono/dietary"
 	"portfolio-amarimono/models"
+	"portfolio-amarimono/recommend"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -18,7 +21,15 @@ func NewRecommendationHandler(db *gorm.DB) *RecommendationHandler {
 	return &RecommendationHandler{DB: db}
 }
 
-// GetRecommendedRecipes ユーザーのいいね履歴をもとにおすすめレシピを取得
+// recommendedRecipe はレシピにおすすめの理由を付けたもの（レシピのフィールドはそのまま返す）
+type recommendedRecipe struct {
+	models.Recipe
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// GetRecommendedRecipes ユーザーのいいね・レビュー履歴をもとにおすすめレシピを取得
+// 事前計算したレシピ間の類似度を使い、いいね済みのレシピとアレルゲン・食事制限に抵触するレシピは除く。履歴が無い場合は人気順。
 func (h *RecommendationHandler) GetRecommendedRecipes(c *gin.Context) {
 	userID := c.Param("user_id")
 
@@ -27,73 +38,78 @@ func (h *RecommendationHandler) GetRecommendedRecipes(c *gin.Context) {
 		return
 	}
 
-	var likes []models.Like
-	if err := h.DB.Where("user_id = ?", userID).Find(&likes).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch likes"})
-		return
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	if limit > 50 {
+		limit = 50
 	}
 
-	if len(likes) == 0 {
-		c.JSON(http.StatusOK, gin.H{"message": "No liked recipes found", "recipes": []models.Recipe{}})
+	// ユーザーが保存したアレルゲン・食事制限に抵触するレシピは除く（除外する分を見込んで多めに候補を取る）
+	codes, err := dietary.UserRestrictions(h.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recommended recipes"})
 		return
 	}
-
-	// ユーザーがいいねしたレシピの取得
-	var recipes []models.Recipe
-	genreCount := make(map[string]int)
-	var totalCost, totalCookingTime float64
-
-	for _, like := range likes {
-		var recipe models.Recipe
-		if err := h.DB.Preload("Genre").First(&recipe, "id = ?", like.RecipeID).Error; err != nil {
-			continue
-		}
-		recipes = append(recipes, recipe)
-		genreCount[recipe.Genre.Name]++
-		totalCost += float64(recipe.CostEstimate)
-		totalCookingTime += float64(recipe.CookingTime)
+	candidates := limit
+	if len(codes) > 0 {
+		candidates = limit * dietaryCandidateFactor
 	}
 
-	if len(recipes) == 0 {
-		c.JSON(http.StatusOK, gin.H{"message": "No valid liked recipes found", "recipes": []models.Recipe{}})
+	recommendations, err := recommend.ForUser(h.DB, userID, candidates)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recommended recipes"})
 		return
 	}
 
-	// 平均値の計算
-	avgCost := totalCost / float64(len(recipes))
-	avgCookingTime := totalCookingTime / float64(len(recipes))
-
-	// ジャンルの割合を計算
-	totalLikes := len(recipes)
-	var genreRatios = make(map[string]float64)
-	for genre, count := range genreCount {
-		genreRatios[genre] = float64(count) / float64(totalLikes)
+	ids := make([]string, len(recommendations))
+	for i, r := range recommendations {
+		ids[i] = r.RecipeID
 	}
 
-	// おすすめレシピの取得
-	var recommendedRecipes []models.Recipe
-	query := h.DB.Preload("Genre").Where("cost_estimate BETWEEN ? AND ?", int(avgCost*0.8), int(avgCost*1.2)).
-		Where("cooking_time BETWEEN ? AND ?", int(avgCookingTime*0.8), int(avgCookingTime*1.2))
+	// おすすめのレシピを1回のクエリで取得し、おすすめ順に並べる
+	var recipes []models.Recipe
+	if len(ids) > 0 {
+		if err := h.DB.Preload("Genre").
+			Scopes(publicRecipes).
+			Where("id IN ?", ids).
+			Find(&recipes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recommended recipes"})
+			return
+		}
+	}
+	byID := make(map[string]models.Recipe, len(recipes))
+	for _, recipe := range recipes {
+		byID[recipe.ID.String()] = recipe
+	}
 
-	if err := query.Find(&recommendedRecipes).Error; err != nil {
+	ordered := make([]models.Recipe, 0, len(recommendations))
+	for _, r := range recommendations {
+		if recipe, ok := byID[r.RecipeID]; ok {
+			ordered = append(ordered, recipe)
+		}
+	}
+	kept, excluded, err := dietary.Exclude(h.DB, ordered, codes)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recommended recipes"})
 		return
 	}
-
-	// ジャンルの割合に基づいてフィルタリング
-	finalRecommendations := []models.Recipe{}
-	genreLimit := make(map[string]int)
-	for genre, ratio := range genreRatios {
-		genreLimit[genre] = int(math.Max(1, math.Round(ratio*float64(len(recommendedRecipes)))))
+	keptIDs := make(map[string]bool, len(kept))
+	for _, recipe := range kept {
+		keptIDs[recipe.ID.String()] = true
 	}
 
-	genreCountSelected := make(map[string]int)
-	for _, recipe := range recommendedRecipes {
-		if genreCountSelected[recipe.Genre.Name] < genreLimit[recipe.Genre.Name] {
-			finalRecommendations = append(finalRecommendations, recipe)
-			genreCountSelected[recipe.Genre.Name]++
+	result := make([]recommendedRecipe, 0, limit)
+	for _, r := range recommendations {
+		if !keptIDs[r.RecipeID] {
+			continue
+		}
+		if len(result) == limit {
+			break
 		}
+		result = append(result, recommendedRecipe{Recipe: byID[r.RecipeID], Score: r.Score, Reasons: r.Reasons})
 	}
 
-	c.JSON(http.StatusOK, finalRecommendations)
+	respondRecipes(c, result, excluded)
 }
@@ -1,66 +1,139 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
+	"portfolio-amarimono/handlers/utils"
 	"portfolio-amarimono/models"
+	"portfolio-amarimono/moderation"
+	"portfolio-amarimono/notifications"
+	"portfolio-amarimono/reviews"
+	"portfolio-amarimono/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ReviewHandler struct {
-	DB *gorm.DB
+	DB            *gorm.DB
+	Reviews       *reviews.Service
+	Images        storage.ImageStore
+	Notifications *notifications.Service
 }
 
 // NewReviewHandler は ReviewHandler を初期化するコンストラクタ
-func NewReviewHandler(db *gorm.DB) *ReviewHandler {
+func NewReviewHandler(db *gorm.DB, images storage.ImageStore) *ReviewHandler {
 	return &ReviewHandler{
-		DB: db,
+		DB:            db,
+		Reviews:       reviews.NewService(db),
+		Images:        images,
+		Notifications: notifications.NewService(db),
 	}
 }
 
-// AddReview レシピにレビューを追加する
+// reviewRequest はレビューの投稿・更新リクエスト（ID・投稿者・日時はクライアントから受け取らない）
+type reviewRequest struct {
+	RecipeID string `json:"recipeId"`
+	Rating   int    `json:"rating"`
+	Comment  string `json:"comment"`
+}
+
+// reviewResponse はレビューに編集できる期限を付けたもの
+type reviewResponse struct {
+	models.Review
+	EditableUntil *time.Time `json:"editableUntil,omitempty"`
+}
+
+// AddReview レシピにレビューを追加する（投稿者は認証情報から取得）
+// 同じレシピに既にレビューしている場合は、そのレビューを更新する
 func (h *ReviewHandler) AddReview(c *gin.Context) {
-	var review models.Review
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
 
-	// リクエストボディを review 構造体に直接バインド
-	if err := c.ShouldBindJSON(&review); err != nil {
+	var req reviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format", "details": err.Error()})
 		return
 	}
+	if _, err := uuid.Parse(req.RecipeID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recipe ID"})
+		return
+	}
 
-	if err := h.DB.Create(&review).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add review"})
+	review, created, err := h.Reviews.Submit(userID, req.RecipeID, reviews.Input{Rating: req.Rating, Comment: req.Comment})
+	if err != nil {
+		h.respondReviewError(c, err, "Failed to add review")
 		return
 	}
 
-	c.JSON(http.StatusCreated, review)
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		// レシピの投稿者に通知（通知に失敗してもレビューの投稿は成功として返す）
+		if _, err := h.Notifications.RecipeReviewed(c.Request.Context(), review); err != nil {
+			log.Printf("⚠️ Failed to notify review %s: %v", review.ID.String(), err)
+		}
+	}
+	c.JSON(status, h.toResponse(review))
 }
 
 // GetReviewsByRecipeID レシピIDに紐づくレビューを取得する
+// ?sort=newest|highest|lowest|helpful で並び替え、?cursor= / ?limit= でページングし、評価の分布を summary に付ける
 func (h *ReviewHandler) GetReviewsByRecipeID(c *gin.Context) {
-	recipeID := c.Param("recipe_id")
-	var reviews []models.Review
+	recipeID, err := uuid.Parse(c.Param("recipe_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recipe ID"})
+		return
+	}
 
-	// レシピIDに紐づくレビューを検索
-	if err := h.DB.Where("recipe_id = ?", recipeID).Find(&reviews).Error; err != nil {
+	opts := reviews.ListOptions{
+		Sort:     c.DefaultQuery("sort", reviews.SortNewest),
+		Cursor:   c.Query("cursor"),
+		ViewerID: optionalUserID(c),
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > reviews.MaxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Limit must be between 1 and %d", reviews.MaxPageSize)})
+			return
+		}
+		opts.Limit = n
+	}
+
+	page, err := h.Reviews.ListByRecipe(recipeID.String(), opts)
+	switch {
+	case errors.Is(err, reviews.ErrInvalidSort):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sort must be one of newest, highest, lowest, helpful"})
+		return
+	case errors.Is(err, reviews.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
 		return
 	}
 
-	// デバッグログ: 取得したレビューデータをログ出力
-	for i, review := range reviews {
-		c.Writer.Header().Set("X-Debug-Review-"+string(rune(i)),
-			fmt.Sprintf("ID:%s,RecipeID:%s,UserID:%s,Rating:%d,Comment:%s,CreatedAt:%s,UpdatedAt:%s",
-				review.ID, review.RecipeID, review.UserID, review.Rating, review.Comment,
-				review.CreatedAt.Format("2006-01-02 15:04:05"),
-				review.UpdatedAt.Format("2006-01-02 15:04:05")))
+	summary, err := h.Reviews.SummarizeRecipe(recipeID.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
 	}
 
-	c.JSON(http.StatusOK, reviews)
+	c.JSON(http.StatusOK, gin.H{
+		"reviews":     page.Reviews,
+		"summary":     summary,
+		"nextCursor":  page.NextCursor,
+		"hasNextPage": page.HasNextPage,
+	})
 }
 
 // GetReviewsByUserID ユーザーIDに紐づくレビューを取得する
@@ -68,8 +141,8 @@ func (h *ReviewHandler) GetReviewsByUserID(c *gin.Context) {
 	userIDStr := c.Param("user_id")
 	var reviews []models.Review
 
-	// ユーザーIDに紐づくレビューを検索
-	if err := h.DB.Where("user_id = ?", userIDStr).Find(&reviews).Error; err != nil {
+	// ユーザーIDに紐づくレビューを検索（非表示のレビューと、削除申請中のユーザーのレビューは除く）
+	if err := h.DB.Scopes(preloadReviewPhotos, models.ActiveOwner("reviews.user_id")).Where("user_id = ? AND status = ?", userIDStr, models.ReviewStatusVisible).Find(&reviews).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
 		return
 	}
@@ -77,51 +150,330 @@ func (h *ReviewHandler) GetReviewsByUserID(c *gin.Context) {
 	c.JSON(http.StatusOK, reviews)
 }
 
-// UpdateReview レビューを更新する
+// UpdateReview レビューを更新する（本人のレビューのみ、投稿から編集期間内に限る）
 func (h *ReviewHandler) UpdateReview(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+	userID, ok := authenticatedUserID(c)
+	if !ok {
 		return
 	}
 
-	var review models.Review
-	// レビューIDで検索
-	if err := h.DB.First(&review, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
 		return
 	}
 
-	// リクエストボディを読み込み
-	if err := c.ShouldBindJSON(&review); err != nil {
+	var req reviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	// レビューを更新
-	if err := h.DB.Save(&review).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review"})
+	review, err := h.Reviews.Update(userID, id.String(), reviews.Input{Rating: req.Rating, Comment: req.Comment})
+	if err != nil {
+		h.respondReviewError(c, err, "Failed to update review")
 		return
 	}
 
-	c.JSON(http.StatusOK, review)
+	c.JSON(http.StatusOK, h.toResponse(review))
 }
 
-// DeleteReview レビューを削除する
+// DeleteReview レビューを削除する（本人のレビューのみ）
 func (h *ReviewHandler) DeleteReview(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
 		return
 	}
 
-	// レビューIDで削除
-	if err := h.DB.Delete(&models.Review{}, id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete review"})
-		return
+	// 写真の画像はレビューの削除後に消す（行は外部キーで一緒に削除される）
+	var photos []models.ReviewPhoto
+	h.DB.Where("review_id = ?", id).Find(&photos)
+
+	if err := h.Reviews.Delete(userID, id.String()); err != nil {
+		if !errors.Is(err, reviews.ErrReviewNotFound) {
+			h.respondReviewError(c, err, "Failed to delete review")
+			return
+		}
+		photos = nil
 	}
+	deleteReviewPhotoImages(c, h.Images, photos)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Review deleted"})
 }
+
+// UploadReviewPhotos レビューに写真を追加する（multipart の images / image、合計 models.MaxReviewPhotos 枚まで）
+func (h *ReviewHandler) UploadReviewPhotos(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No image file provided"})
+		return
+	}
+	files := append(form.File["images"], form.File["image"]...)
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No image file provided"})
+		return
+	}
+
+	review, err := h.Reviews.Editable(userID, id.String())
+	if err != nil {
+		h.respondReviewError(c, err, "Failed to upload photos")
+		return
+	}
+
+	// 画像を保存する前に枚数を確認する（確定は保存後のトランザクション内で行う）
+	var existing int64
+	if err := h.DB.Model(&models.ReviewPhoto{}).Where("review_id = ?", review.ID).Count(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload photos"})
+		return
+	}
+	if int(existing)+len(files) > models.MaxReviewPhotos {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A review can have at most %d photos", models.MaxReviewPhotos)})
+		return
+	}
+
+	photos := make([]models.ReviewPhoto, 0, len(files))
+	for _, file := range files {
+		key, err := utils.SaveReviewPhoto(c.Request.Context(), h.Images, file, review.ID.String())
+		if err != nil {
+			deleteReviewPhotoImages(c, h.Images, photos)
+			respondImageError(c, err, "Failed to save photo")
+			return
+		}
+		photos = append(photos, models.ReviewPhoto{
+			ReviewID: review.ID,
+			UserID:   review.UserID,
+			ImageURL: key,
+		})
+	}
+
+	// 同時にアップロードされても上限を超えないよう、レビューの行をロックしてから数え直して追加する
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", review.ID).Take(&models.Review{}).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.ReviewPhoto{}).Where("review_id = ?", review.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count)+len(photos) > models.MaxReviewPhotos {
+			return errReviewPhotoLimit
+		}
+		for i := range photos {
+			photos[i].Position = int(count) + i
+		}
+		return tx.Create(&photos).Error
+	})
+	if err != nil {
+		deleteReviewPhotoImages(c, h.Images, photos)
+		switch {
+		case errors.Is(err, errReviewPhotoLimit):
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A review can have at most %d photos", models.MaxReviewPhotos)})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload photos"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"photos": photos})
+}
+
+// errReviewPhotoLimit はレビューの写真が上限の枚数に達している場合のエラー
+var errReviewPhotoLimit = errors.New("review photo limit reached")
+
+// DeleteReviewPhoto レビューの写真を削除する（本人のレビューのみ）
+func (h *ReviewHandler) DeleteReviewPhoto(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+	photoID, err := uuid.Parse(c.Param("photo_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid photo ID"})
+		return
+	}
+
+	review, err := h.Reviews.Editable(userID, id.String())
+	if err != nil {
+		h.respondReviewError(c, err, "Failed to delete photo")
+		return
+	}
+
+	var photo models.ReviewPhoto
+	if err := h.DB.Where("id = ? AND review_id = ?", photoID, review.ID).Take(&photo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete photo"})
+		return
+	}
+	if err := h.DB.Delete(&photo).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete photo"})
+		return
+	}
+	deleteReviewPhotoImages(c, h.Images, []models.ReviewPhoto{photo})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Photo deleted"})
+}
+
+// MarkReviewHelpful レビューに「参考になった」を付ける（既に付けていても成功する）
+func (h *ReviewHandler) MarkReviewHelpful(c *gin.Context) {
+	h.setHelpful(c, true)
+}
+
+// UnmarkReviewHelpful レビューの「参考になった」を取り消す（付けていなくても成功する）
+func (h *ReviewHandler) UnmarkReviewHelpful(c *gin.Context) {
+	h.setHelpful(c, false)
+}
+
+func (h *ReviewHandler) setHelpful(c *gin.Context, helpful bool) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	review, changed, err := h.Reviews.SetHelpful(userID, id.String(), helpful)
+	switch {
+	case errors.Is(err, reviews.ErrOwnReview):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot vote on your own review"})
+		return
+	case errors.Is(err, reviews.ErrReviewNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update helpful vote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reviewId":     review.ID,
+		"votedHelpful": helpful,
+		"changed":      changed,
+		"helpfulCount": review.HelpfulCount,
+	})
+}
+
+// ReportReview レビューを通報する（同じレビューを再度通報した場合は理由を更新する）
+func (h *ReviewHandler) ReportReview(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+		Detail string `json:"detail"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+		return
+	}
+
+	report, created, err := moderation.NewService(h.DB).Report(userID, id.String(), req.Reason, req.Detail)
+	switch {
+	case errors.Is(err, moderation.ErrInvalidReason):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason must be one of spam, offensive, off_topic, other"})
+		return
+	case errors.Is(err, moderation.ErrDetailTooLong):
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Detail must be at most %d characters", moderation.MaxReportDetailLength)})
+		return
+	case errors.Is(err, moderation.ErrOwnReview):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot report your own review"})
+		return
+	case errors.Is(err, moderation.ErrReviewNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to report review"})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{"message": "Review reported", "report": report})
+}
+
+// toResponse はレビューに写真と編集できる期限を付ける
+func (h *ReviewHandler) toResponse(review *models.Review) reviewResponse {
+	if review.Photos == nil {
+		h.DB.Where("review_id = ?", review.ID).Order("position").Find(&review.Photos)
+	}
+	return reviewResponse{Review: *review, EditableUntil: h.Reviews.EditableUntil(review)}
+}
+
+// preloadReviewPhotos はレビューの写真を並び順で読み込むスコープ
+func preloadReviewPhotos(db *gorm.DB) *gorm.DB {
+	return db.Preload("Photos", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	})
+}
+
+// deleteReviewPhotoImages は写真の画像をストレージから削除する（失敗してもログに残して続ける）
+func deleteReviewPhotoImages(c *gin.Context, store storage.ImageStore, photos []models.ReviewPhoto) {
+	for _, photo := range photos {
+		if err := utils.DeleteImage(c.Request.Context(), store, photo.ImageURL); err != nil {
+			log.Printf("⚠️ Failed to delete review photo %s: %v", photo.ImageURL, err)
+		}
+	}
+}
+
+// respondReviewError はレビューサービスのエラーをステータスコードに変換して返す
+func (h *ReviewHandler) respondReviewError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, reviews.ErrInvalidRating):
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Rating must be between %d and %d", reviews.MinRating, reviews.MaxRating)})
+	case errors.Is(err, reviews.ErrCommentTooLong):
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Comment must be at most %d characters", reviews.MaxCommentLength)})
+	case errors.Is(err, reviews.ErrInappropriate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment contains inappropriate words"})
+	case errors.Is(err, reviews.ErrRecipeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recipe not found"})
+	case errors.Is(err, reviews.ErrReviewNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+	case errors.Is(err, reviews.ErrNotOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only modify your own review"})
+	case errors.Is(err, reviews.ErrEditWindowClosed):
+		c.JSON(http.StatusForbidden, gin.H{"error": "The edit period for this review has ended"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -1,17 +1,23 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"portfolio-amarimono/handlers/utils"
+	"portfolio-amarimono/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
-type UploadHandler struct{}
+type UploadHandler struct {
+	Images storage.ImageStore
+}
 
-func NewUploadHandler() *UploadHandler {
-	return &UploadHandler{}
+func NewUploadHandler(images storage.ImageStore) *UploadHandler {
+	return &UploadHandler{
+		Images: images,
+	}
 }
 
 // UploadImage は画像をアップロードするエンドポイント
@@ -31,11 +37,23 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 	}
 
 	// 画像を保存
-	imageURL, err := utils.SaveImage(c, file, path, "")
+	imageURL, err := utils.SaveImage(c.Request.Context(), h.Images, file, path, "")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+		respondImageError(c, err, "Failed to save image")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"imageUrl": imageURL})
 }
+
+// respondImageError は画像の検証エラーを400、それ以外の保存エラーを500として返す
+func respondImageError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, utils.ErrImageTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image file size exceeds 10MB limit"})
+	case errors.Is(err, utils.ErrUnsupportedImage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported image type"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
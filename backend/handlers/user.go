@@ -1,15 +1,21 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
+	"portfolio-amarimono/accounts"
+	"portfolio-amarimono/exports"
 	"portfolio-amarimono/handlers/utils"
 	"portfolio-amarimono/models"
+	"portfolio-amarimono/storage"
 
 	"log"
 
 	"strings"
+	"unicode/utf8"
 
 	"os"
 
@@ -17,14 +23,23 @@ import (
 	"gorm.io/gorm"
 )
 
+// maxBioLength は自己紹介の最大文字数
+const maxBioLength = 200
+
 type UserHandler struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	Images   storage.ImageStore
+	Accounts *accounts.Service
+	Exports  *exports.Service
 }
 
-// NewUserHandler は UserHandler を初期化するコンストラクタ
-func NewUserHandler(db *gorm.DB) *UserHandler {
+// NewUserHandler は UserHandler を初期化するコンストラクタ（exportFiles は個人データのZIPを保存する非公開のストレージ）
+func NewUserHandler(db *gorm.DB, images, exportFiles storage.ImageStore) *UserHandler {
 	return &UserHandler{
-		DB: db,
+		DB:       db,
+		Images:   images,
+		Accounts: accounts.NewService(db, images, exportFiles),
+		Exports:  exports.NewService(db, images, exportFiles),
 	}
 }
 
@@ -69,10 +84,10 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		}
 
 		// 画像を保存
-		imagePath, err := utils.SaveImage(c, file, "users/"+user.ID, "")
+		imagePath, err := utils.SaveProfileImage(c.Request.Context(), h.Images, file, user.ID)
 		if err != nil {
 			log.Printf("🔍 CreateUser - Failed to save image: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+			respondImageError(c, err, "Failed to save image")
 			return
 		}
 		user.ProfileImage = &imagePath
@@ -139,10 +154,10 @@ func (h *UserHandler) SyncUser(c *gin.Context) {
 		}
 
 		// 画像を保存
-		imagePath, err := utils.SaveImage(c, file, "users/"+user.ID, "")
+		imagePath, err := utils.SaveProfileImage(c.Request.Context(), h.Images, file, user.ID)
 		if err != nil {
 			log.Printf("🔍 SyncUser - Failed to save image: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+			respondImageError(c, err, "Failed to save image")
 			return
 		}
 		user.ProfileImage = &imagePath
@@ -233,7 +248,7 @@ func (h *UserHandler) GetUserProfile(c *gin.Context) {
 	err := h.DB.Table("users").
 		Select("users.*, user_roles.role").
 		Joins("LEFT JOIN user_roles ON users.id = user_roles.user_id").
-		Where("users.id = ?", userID).
+		Where("users.id = ? AND users.deleted_at IS NULL", userID).
 		First(&user).Error
 
 	if err != nil {
@@ -270,16 +285,16 @@ func (h *UserHandler) UpdateUserProfile(c *gin.Context) {
 
 		// 既存の画像がある場合は削除
 		if existingUser.ProfileImage != nil && *existingUser.ProfileImage != "" && *existingUser.ProfileImage != "[object File]" {
-			if err := utils.DeleteImage(*existingUser.ProfileImage); err != nil {
+			if err := utils.DeleteImage(c.Request.Context(), h.Images, *existingUser.ProfileImage); err != nil {
 				// 画像の削除に失敗しても処理は続行
 			} else {
 			}
 		}
 
 		// 新しい画像を保存
-		imagePath, err := utils.SaveImage(c, file, "users/"+userID, "")
+		imagePath, err := utils.SaveProfileImage(c.Request.Context(), h.Images, file, userID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+			respondImageError(c, err, "Failed to save image")
 			return
 		}
 		existingUser.ProfileImage = &imagePath
@@ -321,6 +336,15 @@ func (h *UserHandler) UpdateUserProfile(c *gin.Context) {
 	if gender != "" {
 		existingUser.Gender = &gender
 	}
+	// 自己紹介は空文字で送ると削除できる
+	if bio, ok := c.GetPostForm("bio"); ok {
+		bio = strings.TrimSpace(bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Bio must be at most %d characters", maxBioLength)})
+			return
+		}
+		existingUser.Bio = &bio
+	}
 
 	if err := models.UpdateUser(h.DB, existingUser); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
@@ -330,27 +354,71 @@ func (h *UserHandler) UpdateUserProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, existingUser)
 }
 
-// DeleteUser handles user deletion (logical deletion)
+// DeleteUser handles account deletion requests (本人のみ)
+// アカウントは猶予期間のあいだ論理削除の状態になり、期間を過ぎるとデータがまとめて完全削除される
 func (h *UserHandler) DeleteUser(c *gin.Context) {
-	userID := c.Param("id")
-	if err := models.DeleteUser(h.DB, userID); err != nil {
+	userID, ok := h.authorizeAccount(c)
+	if !ok {
+		return
+	}
+
+	deletion, err := h.Accounts.RequestDeletion(userID)
+	if err != nil {
+		if errors.Is(err, accounts.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
 	}
 
-	c.Status(http.StatusNoContent)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Account scheduled for deletion", "deletion": deletion})
+}
+
+// RestoreUser handles cancelling an account deletion request within the grace period (本人のみ)
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	userID, ok := h.authorizeAccount(c)
+	if !ok {
+		return
+	}
+
+	err := h.Accounts.CancelDeletion(userID)
+	switch {
+	case errors.Is(err, accounts.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	case errors.Is(err, accounts.ErrNotPendingDeletion):
+		c.JSON(http.StatusConflict, gin.H{"error": "Account is not scheduled for deletion"})
+		return
+	case errors.Is(err, accounts.ErrGracePeriodOver):
+		c.JSON(http.StatusGone, gin.H{"error": "The grace period for restoring this account has ended"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account restored"})
+}
+
+// authorizeAccount は URL の :id がログインユーザー本人であることを確認する
+func (h *UserHandler) authorizeAccount(c *gin.Context) (string, bool) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return "", false
+	}
+	if !strings.EqualFold(c.Param("id"), userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only manage your own account"})
+		return "", false
+	}
+	return userID, true
 }
 
 // ユーザーが投稿したレシピのいいね数を取得
 func (h *UserHandler) GetUserLikeCount(c *gin.Context) {
 	userID := c.Param("id") // URLからユーザーIDを取得
-	var likeCount int64
-
-	err := h.DB.Table("likes").
-		Joins("JOIN recipes ON likes.recipe_id = recipes.id").
-		Where("recipes.user_id = ?", userID).
-		Count(&likeCount).Error
 
+	likeCount, err := userLikeCount(h.DB, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve like count"})
 		return
@@ -362,15 +430,8 @@ func (h *UserHandler) GetUserLikeCount(c *gin.Context) {
 // ユーザーが投稿したレシピの平均レビュー評価を取得
 func (h *UserHandler) GetUserRecipeAverageRating(c *gin.Context) {
 	userID := c.Param("id") // URLからユーザーIDを取得
-	var avgRating float64
-
-	// ユーザーが作成したレシピの平均レビュー評価を取得
-	err := h.DB.Table("recipes").
-		Select("COALESCE(AVG(reviews.rating), 0)").
-		Joins("LEFT JOIN reviews ON recipes.id = reviews.recipe_id").
-		Where("recipes.user_id = ?", userID).
-		Scan(&avgRating).Error
 
+	avgRating, err := userRecipeAverageRating(h.DB, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve average rating"})
 		return
@@ -379,6 +440,27 @@ func (h *UserHandler) GetUserRecipeAverageRating(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"average_rating": avgRating})
 }
 
+// userLikeCount はユーザーが投稿したレシピについたいいねの合計を返す
+func userLikeCount(db *gorm.DB, userID string) (int64, error) {
+	var likeCount int64
+	err := db.Table("likes").
+		Joins("JOIN recipes ON likes.recipe_id = recipes.id").
+		Where("recipes.user_id = ?", userID).
+		Count(&likeCount).Error
+	return likeCount, err
+}
+
+// userRecipeAverageRating はユーザーが作成したレシピの平均レビュー評価を返す（非表示のレビューは除く）
+func userRecipeAverageRating(db *gorm.DB, userID string) (float64, error) {
+	var avgRating float64
+	err := db.Table("recipes").
+		Select("COALESCE(AVG(reviews.rating), 0)").
+		Joins("LEFT JOIN reviews ON recipes.id = reviews.recipe_id AND reviews.status = ?", models.ReviewStatusVisible).
+		Where("recipes.user_id = ?", userID).
+		Scan(&avgRating).Error
+	return avgRating, err
+}
+
 // SetUserRole ユーザーのロールを設定するハンドラー
 func (h *UserHandler) SetUserRole(c *gin.Context) {
 	// リクエストユーザーの認証チェック
@@ -404,7 +486,7 @@ func (h *UserHandler) SetUserRole(c *gin.Context) {
 	// リクエストボディから対象ユーザーIDとロールを取得
 	var requestBody struct {
 		UserID string `json:"user_id" binding:"required"`
-		Role   string `json:"role" binding:"required,oneof=admin user"`
+		Role   string `json:"role" binding:"required,oneof=admin moderator user"`
 	}
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "無効なリクエストです"})
@@ -473,16 +555,10 @@ func (h *UserHandler) UploadProfileImage(c *gin.Context) {
 		return
 	}
 
-	// ファイルサイズのチェック（10MB制限）
-	if file.Size > 10*1024*1024 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Image file size exceeds 10MB limit"})
-		return
-	}
-
-	// 画像を保存
-	imagePath, err := utils.SaveImage(c, file, "users/"+userID, "")
+	// 画像を検証して再エンコードし保存（10MB制限・画像以外は拒否）
+	imagePath, err := utils.SaveProfileImage(c.Request.Context(), h.Images, file, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+		respondImageError(c, err, "Failed to save image")
 		return
 	}
 
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"portfolio-amarimono/dietary"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetDietaryRestrictions handles retrieving the user's allergen and diet exclusions (本人のみ)
+// 選択できるアレルゲン・食事制限の一覧も一緒に返す
+func (h *UserHandler) GetDietaryRestrictions(c *gin.Context) {
+	userID, ok := h.authorizeAccount(c)
+	if !ok {
+		return
+	}
+
+	codes, err := dietary.UserRestrictions(h.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dietary restrictions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"restrictions": codes, "available": dietary.Restrictions})
+}
+
+// UpdateDietaryRestrictions handles replacing the user's allergen and diet exclusions (本人のみ)
+func (h *UserHandler) UpdateDietaryRestrictions(c *gin.Context) {
+	userID, ok := h.authorizeAccount(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Restrictions []string `json:"restrictions"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Restrictions == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "restrictions is required"})
+		return
+	}
+
+	codes, err := dietary.SetUserRestrictions(h.DB, userID, req.Restrictions, time.Now())
+	if errors.Is(err, dietary.ErrUnknownRestriction) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown dietary restriction"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update dietary restrictions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"restrictions": codes, "available": dietary.Restrictions})
+}
+
+// viewerDietaryRestrictions はログインしているユーザーが保存した除外コードを返す（未ログインの場合は何も除外しない）
+func viewerDietaryRestrictions(db *gorm.DB, c *gin.Context) ([]string, error) {
+	userID := optionalUserID(c)
+	if userID == nil {
+		return nil, nil
+	}
+	return dietary.UserRestrictions(db, *userID)
+}
+
+// respondRecipes はレシピ一覧を配列のまま返す。
+// ?include_excluded=true の場合だけ、アレルゲン・食事制限で除外したレシピを含む {recipes, excluded} で返す
+func respondRecipes(c *gin.Context, recipes interface{}, excluded []dietary.Exclusion) {
+	if c.Query("include_excluded") == "true" {
+		c.JSON(http.StatusOK, gin.H{"recipes": recipes, "excluded": excluded})
+		return
+	}
+	c.JSON(http.StatusOK, recipes)
+}
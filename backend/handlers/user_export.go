@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"portfolio-amarimono/exports"
+	"portfolio-amarimono/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestDataExport handles requesting a personal data export (本人のみ)
+// ZIPの作成はバックグラウンドで行うため、進捗は GetDataExport で確認する
+func (h *UserHandler) RequestDataExport(c *gin.Context) {
+	userID, ok := h.authorizeAccount(c)
+	if !ok {
+		return
+	}
+
+	export, created, err := h.Exports.Request(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start data export"})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, gin.H{"export": dataExportResponse(userID, export)})
+}
+
+// GetDataExport handles retrieving the status of the latest data export (本人のみ)
+func (h *UserHandler) GetDataExport(c *gin.Context) {
+	userID, ok := h.authorizeAccount(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.Exports.Latest(userID)
+	if err != nil {
+		if errors.Is(err, exports.ErrExportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No data export has been requested"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve data export"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"export": dataExportResponse(userID, export)})
+}
+
+// DownloadDataExport handles downloading the ZIP of the latest completed data export (本人のみ)
+func (h *UserHandler) DownloadDataExport(c *gin.Context) {
+	userID, ok := h.authorizeAccount(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.Exports.Latest(userID)
+	if err != nil {
+		if errors.Is(err, exports.ErrExportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No data export has been requested"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve data export"})
+		return
+	}
+
+	body, err := h.Exports.Open(c.Request.Context(), export)
+	switch {
+	case errors.Is(err, exports.ErrNotReady):
+		c.JSON(http.StatusConflict, gin.H{"error": "The data export is not ready yet", "status": export.Status})
+		return
+	case errors.Is(err, exports.ErrExpired):
+		c.JSON(http.StatusGone, gin.H{"error": "The data export has expired; please request a new one"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download data export"})
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, export.SizeBytes, "application/zip", body, map[string]string{
+		"Content-Disposition": `attachment; filename="` + exports.FileName(export) + `"`,
+		"Cache-Control":       "no-store",
+	})
+}
+
+// dataExportResponse は完成したエクスポートにダウンロード用のURLを付ける
+func dataExportResponse(userID string, export *models.DataExport) gin.H {
+	res := gin.H{
+		"id":           export.ID,
+		"status":       export.Status,
+		"size_bytes":   export.SizeBytes,
+		"created_at":   export.CreatedAt,
+		"completed_at": export.CompletedAt,
+		"expires_at":   export.ExpiresAt,
+	}
+	if export.Error != "" {
+		res["error"] = export.Error
+	}
+	if export.Status == models.DataExportStatusCompleted {
+		res["download_url"] = "/api/users/" + userID + "/export/download"
+	}
+	return res
+}
@@ -2,25 +2,28 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"io"
+	"image"
 	"mime/multipart"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/gin-gonic/gin"
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/storage"
+
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	storage_go "github.com/supabase-community/storage-go"
-	supabase "github.com/supabase-community/supabase-go"
 )
 
 var log = logrus.New()
 
-// SaveImage は画像を保存し、URLを返す（R2対応）
-func SaveImage(c *gin.Context, file *multipart.FileHeader, path string, fileName string) (string, error) {
+// SaveImage はアップロードされた画像を検証して再エンコードし、ImageStoreに保存して保存先のキーを返す。
+// 画像として扱えないファイルは ErrUnsupportedImage・ErrImageTooLarge を返す。
+func SaveImage(ctx context.Context, store storage.ImageStore, file *multipart.FileHeader, path string, fileName string) (string, error) {
 	// 本番環境でのデバッグ情報を出力
 	if os.Getenv("ENVIRONMENT") == "production" {
 		log.Printf("=== SaveImage Production Debug ===")
@@ -31,254 +34,187 @@ func SaveImage(c *gin.Context, file *multipart.FileHeader, path string, fileName
 		log.Printf("Content Type: %s", file.Header.Get("Content-Type"))
 	}
 
-	// 環境変数でストレージを切り替え
-	storageType := os.Getenv("STORAGE_TYPE")
-
-	if os.Getenv("ENVIRONMENT") == "production" {
-		log.Printf("Storage Type: %s", storageType)
-		log.Printf("Environment: %s", os.Getenv("ENVIRONMENT"))
-		// 環境変数の確認
-		log.Printf("CLOUDFLARE_R2_ACCOUNT_ID exists: %v", os.Getenv("CLOUDFLARE_R2_ACCOUNT_ID") != "")
-		log.Printf("CLOUDFLARE_R2_ACCESS_KEY_ID exists: %v", os.Getenv("CLOUDFLARE_R2_ACCESS_KEY_ID") != "")
-		log.Printf("CLOUDFLARE_R2_SECRET_ACCESS_KEY exists: %v", os.Getenv("CLOUDFLARE_R2_SECRET_ACCESS_KEY") != "")
-		log.Printf("CLOUDFLARE_R2_ENDPOINT: %s", os.Getenv("CLOUDFLARE_R2_ENDPOINT"))
-		log.Printf("CLOUDFLARE_R2_BUCKET_NAME: %s", os.Getenv("CLOUDFLARE_R2_BUCKET_NAME"))
-		log.Printf("CLOUDFLARE_R2_PUBLIC_URL: %s", os.Getenv("CLOUDFLARE_R2_PUBLIC_URL"))
+	if store == nil {
+		return "", fmt.Errorf("image store is not configured")
 	}
 
-	if storageType == "r2" {
-		if os.Getenv("ENVIRONMENT") == "production" {
-			log.Printf("Using R2 storage")
-		}
-		return SaveImageToR2(c, file, path, fileName)
-	}
-
-	if os.Getenv("ENVIRONMENT") == "production" {
-		log.Printf("Using Supabase storage (fallback)")
+	img, err := DecodeUploadedImage(file)
+	if err != nil {
+		log.Printf("ERROR: Invalid image: %v", err)
+		return "", err
 	}
-	// 既存のSupabase実装（フォールバック）
-	return SaveImageToSupabase(c, file, path, fileName)
+	return SaveDecodedImage(ctx, store, img, path, fileName)
 }
 
-// SaveImageToSupabase は画像をSupabaseに保存し、URLを返す
-func SaveImageToSupabase(c *gin.Context, file *multipart.FileHeader, path string, fileName string) (string, error) {
-	if os.Getenv("ENVIRONMENT") == "production" {
-		log.Printf("=== SaveImageToSupabase Production Debug ===")
-		log.Printf("Path: %s", path)
-		log.Printf("FileName: %s", fileName)
-		log.Printf("Original Filename: %s", file.Filename)
-		log.Printf("File Size: %d", file.Size)
-	} else {
-		log.Printf("Starting SaveImageToSupabase function with path: %s, fileName: %s", path, fileName)
+// SaveDecodedImage はデコード済みの画像を UploadImageWidth 以下に縮小して保存し、保存先のキーを返す。
+// fileName の拡張子はエンコードした形式に置き換える（空の場合は一意な名前を生成する）。
+func SaveDecodedImage(ctx context.Context, store storage.ImageStore, img image.Image, path string, fileName string) (string, error) {
+	if store == nil {
+		return "", fmt.Errorf("image store is not configured")
 	}
 
-	// ファイルを開く
-	src, err := file.Open()
+	encoded, err := EncodeImage(img, UploadImageWidth)
 	if err != nil {
-		log.Printf("ERROR: Failed to open file: %v", err)
-		return "", fmt.Errorf("failed to open file: %v", err)
-	}
-	defer src.Close()
-	log.Printf("Successfully opened file: %s", file.Filename)
-
-	// ファイルの内容を読み込む
-	fileContent, err := io.ReadAll(src)
-	if err != nil {
-		log.Printf("ERROR: Failed to read file content: %v", err)
-		return "", fmt.Errorf("failed to read file content: %v", err)
+		return "", err
 	}
-	log.Printf("Successfully read file content, size: %d bytes", len(fileContent))
 
 	// ファイル名を生成
 	if fileName == "" {
-		ext := filepath.Ext(file.Filename)
-		fileName = fmt.Sprintf("%d_%s%s", time.Now().Unix(), uuid.New().String(), ext)
-		log.Printf("Generated new fileName: %s", fileName)
+		fileName = fmt.Sprintf("%d_%s", time.Now().Unix(), uuid.New().String())
 	}
+	fileName = strings.TrimSuffix(fileName, filepath.Ext(fileName)) + encoded.Ext
 
 	// ファイルパスを生成
 	filePath := filepath.Join(path, fileName)
 	filePath = strings.ReplaceAll(filePath, "\\", "/") // Windowsのパス区切り文字を修正
-	log.Printf("Generated filePath: %s", filePath)
 
-	// Get Supabase credentials
-	supabaseURL := os.Getenv("SUPABASE_URL")
-	supabaseKey := os.Getenv("SUPABASE_SERVICE_ROLE_KEY")
-
-	log.Printf("Supabase URL: %s", supabaseURL)
-	log.Printf("Supabase Key exists: %v", supabaseKey != "")
-
-	// For local development, update the URL
-	if os.Getenv("ENVIRONMENT") == "development" {
-		supabaseURL = "http://host.docker.internal:54321"
-		log.Printf("Updated Supabase URL for Docker: %s", supabaseURL)
-	}
-
-	// Initialize Supabase client
-	client, err := supabase.NewClient(supabaseURL, supabaseKey, nil)
-	if err != nil {
-		log.Printf("ERROR: Failed to initialize Supabase client: %v", err)
-		return "", fmt.Errorf("failed to initialize Supabase client: %v", err)
+	if err := store.Put(ctx, filePath, bytes.NewReader(encoded.Data), encoded.ContentType); err != nil {
+		log.Printf("ERROR: Failed to save image: %v", err)
+		return "", err
 	}
-	log.Printf("Successfully initialized Supabase client")
-
-	// Get file content type
-	contentType := file.Header.Get("Content-Type")
-	log.Printf("File content type: %s", contentType)
 
-	// Try to delete existing file first
-	_, err = client.Storage.RemoveFile("images", []string{filePath})
-	if err != nil {
-		log.Printf("Warning: Failed to delete existing file: %v", err)
-		// Continue with upload even if deletion fails
-	}
+	log.Printf("Successfully saved image: %s", filePath)
+	return filePath, nil
+}
 
-	// Create file options
-	upsert := true
-	fileOptions := storage_go.FileOptions{
-		ContentType: &contentType,
-		Upsert:      &upsert,
+// SaveRecipeImage はアップロードされたレシピの画像を検証し、SaveRecipeImageVariants でサイズ別に保存する
+func SaveRecipeImage(ctx context.Context, store storage.ImageStore, file *multipart.FileHeader, recipeID string, step int) (*models.ImageVariants, error) {
+	if store == nil {
+		return nil, fmt.Errorf("image store is not configured")
 	}
 
-	// Upload the file to Supabase Storage
-	_, err = client.Storage.UploadFile("images", filePath, bytes.NewReader(fileContent), fileOptions)
+	img, err := DecodeUploadedImage(file)
 	if err != nil {
-		log.Printf("ERROR: Failed to upload to Supabase Storage: %v", err)
-		return "", fmt.Errorf("failed to upload to Supabase Storage: %v", err)
+		return nil, err
 	}
-
-	log.Printf("Successfully uploaded file to Supabase Storage")
-	return filePath, nil
+	return SaveRecipeImageVariants(ctx, store, img, recipeID, step)
 }
 
-// SaveRecipeImage はレシピの画像を保存する
-func SaveRecipeImage(c *gin.Context, file *multipart.FileHeader, recipeID string, isInstruction bool) (string, error) {
-	if os.Getenv("ENVIRONMENT") == "production" {
-		log.Printf("=== SaveRecipeImage Production Debug ===")
-		log.Printf("Recipe ID: %s", recipeID)
-		log.Printf("Is Instruction: %v", isInstruction)
-		log.Printf("Original Filename: %s", file.Filename)
+// SaveRecipeImageVariants はデコード済みのレシピの画像をサイズ別に保存する。
+// step が0の場合はメイン画像（main.jpg など）、1以上の場合は手順画像（instructions/stepN.jpg など）として保存する。
+func SaveRecipeImageVariants(ctx context.Context, store storage.ImageStore, img image.Image, recipeID string, step int) (*models.ImageVariants, error) {
+	if store == nil {
+		return nil, fmt.Errorf("image store is not configured")
 	}
 
 	// パスを設定
-	basePath := "recipes"
-	if isInstruction {
-		basePath = filepath.Join(basePath, recipeID, "instructions")
-	} else {
-		basePath = filepath.Join(basePath, recipeID)
-	}
-
-	if os.Getenv("ENVIRONMENT") == "production" {
-		log.Printf("Base Path: %s", basePath)
+	dir := path.Join("recipes", recipeID)
+	name := "main"
+	if step > 0 {
+		dir = path.Join(dir, "instructions")
+		name = fmt.Sprintf("step%d", step)
+	}
+
+	variants := &models.ImageVariants{}
+	for _, spec := range RecipeImageVariants {
+		encoded, err := EncodeImage(img, spec.Width)
+		if err != nil {
+			return nil, err
+		}
+		key := path.Join(dir, name+spec.Suffix+encoded.Ext)
+		if err := store.Put(ctx, key, bytes.NewReader(encoded.Data), encoded.ContentType); err != nil {
+			if os.Getenv("ENVIRONMENT") == "production" {
+				log.Printf("ERROR: Failed to save recipe image: %v", err)
+			}
+			return nil, err
+		}
+		switch spec.Name {
+		case "thumbnail":
+			variants.Thumbnail = key
+		case "card":
+			variants.Card = key
+		case "full":
+			variants.Full = key
+		}
 	}
 
-	// ファイル名を生成（拡張子を保持）
-	ext := filepath.Ext(file.Filename)
-	fileName := fmt.Sprintf("%s%s", uuid.New().String(), ext)
+	log.Printf("Successfully saved recipe image variants: %+v", variants)
+	return variants, nil
+}
 
-	if os.Getenv("ENVIRONMENT") == "production" {
-		log.Printf("Generated filename: %s", fileName)
+// SaveProfileImage はプロフィール画像を縮小・再エンコードして保存し、キーを返す
+func SaveProfileImage(ctx context.Context, store storage.ImageStore, file *multipart.FileHeader, userID string) (string, error) {
+	if store == nil {
+		return "", fmt.Errorf("image store is not configured")
 	}
 
-	// 画像を保存
-	imagePath, err := SaveImage(c, file, basePath, fileName)
+	img, err := DecodeUploadedImage(file)
 	if err != nil {
-		if os.Getenv("ENVIRONMENT") == "production" {
-			log.Printf("ERROR: Failed to save recipe image: %v", err)
-		}
 		return "", err
 	}
 
-	if os.Getenv("ENVIRONMENT") == "production" {
-		log.Printf("Successfully saved recipe image: %s", imagePath)
+	encoded, err := EncodeImage(img, ProfileImageWidth)
+	if err != nil {
+		return "", err
 	}
-	// 相対パスのみを返す
-	return imagePath, nil
-}
 
-// DeleteImage は画像を削除する（R2対応）
-func DeleteImage(url string) error {
-	if os.Getenv("ENVIRONMENT") == "production" {
-		log.Printf("=== DeleteImage Production Debug ===")
-		log.Printf("URL to delete: %s", url)
+	// CDNのキャッシュが残らないよう、アップロードごとに別のキーにする
+	key := path.Join("users", userID, fmt.Sprintf("%d_%s%s", time.Now().Unix(), uuid.New().String(), encoded.Ext))
+	if err := store.Put(ctx, key, bytes.NewReader(encoded.Data), encoded.ContentType); err != nil {
+		log.Printf("ERROR: Failed to save profile image: %v", err)
+		return "", err
 	}
 
-	storageType := os.Getenv("STORAGE_TYPE")
+	return key, nil
+}
 
-	if os.Getenv("ENVIRONMENT") == "production" {
-		log.Printf("Storage Type: %s", storageType)
+// SaveReviewPhoto はレビューの写真を縮小・再エンコードして保存し、キーを返す
+func SaveReviewPhoto(ctx context.Context, store storage.ImageStore, file *multipart.FileHeader, reviewID string) (string, error) {
+	if store == nil {
+		return "", fmt.Errorf("image store is not configured")
 	}
 
-	if storageType == "r2" {
-		if os.Getenv("ENVIRONMENT") == "production" {
-			log.Printf("Using R2 delete")
-		}
-		// URLからファイルパスを抽出
-		filePath := extractFilePathFromURL(url)
-		return DeleteImageFromR2(filePath)
+	img, err := DecodeUploadedImage(file)
+	if err != nil {
+		return "", err
 	}
 
-	if os.Getenv("ENVIRONMENT") == "production" {
-		log.Printf("Using Supabase delete (fallback)")
+	encoded, err := EncodeImage(img, ReviewPhotoWidth)
+	if err != nil {
+		return "", err
 	}
-	// 既存のSupabase実装（フォールバック）
-	return DeleteImageFromSupabase(url)
-}
 
-// DeleteImageFromSupabase は画像をSupabaseから削除する
-func DeleteImageFromSupabase(url string) error {
-	if os.Getenv("ENVIRONMENT") == "production" {
-		log.Printf("=== DeleteImageFromSupabase Production Debug ===")
-		log.Printf("URL: %s", url)
-	} else {
-		log.Printf("Starting DeleteImageFromSupabase function with URL: %s", url)
+	key := path.Join("reviews", reviewID, fmt.Sprintf("%d_%s%s", time.Now().Unix(), uuid.New().String(), encoded.Ext))
+	if err := store.Put(ctx, key, bytes.NewReader(encoded.Data), encoded.ContentType); err != nil {
+		log.Printf("ERROR: Failed to save review photo: %v", err)
+		return "", err
 	}
 
-	// Extract file path from URL
-	var filePath string
-	if strings.HasPrefix(url, "http") {
-		// If it's a full URL, extract the path after /object/public/
-		parts := strings.Split(url, "/object/public/")
-		if len(parts) != 2 {
-			log.Printf("ERROR: Invalid image URL format: %s", url)
-			return fmt.Errorf("invalid image URL format")
+	return key, nil
+}
+
+// DeleteImageVariants はサイズ別の画像をすべて削除する（失敗しても残りの削除を続ける）
+func DeleteImageVariants(ctx context.Context, store storage.ImageStore, variants *models.ImageVariants) error {
+	var firstErr error
+	for _, key := range variants.Keys() {
+		if err := DeleteImage(ctx, store, key); err != nil && firstErr == nil {
+			firstErr = err
 		}
-		filePath = parts[1]
-	} else {
-		// If it's just a path, use it directly
-		filePath = url
 	}
+	return firstErr
+}
 
-	log.Printf("Extracted file path: %s", filePath)
-
-	// Get Supabase credentials
-	supabaseURL := os.Getenv("SUPABASE_URL")
-	supabaseKey := os.Getenv("SUPABASE_SERVICE_ROLE_KEY")
-
-	log.Printf("Supabase URL: %s", supabaseURL)
-	log.Printf("Supabase Key exists: %v", supabaseKey != "")
+// DeleteImage は画像を削除する（URL・キーのどちらでも受け付ける）
+func DeleteImage(ctx context.Context, store storage.ImageStore, url string) error {
+	if os.Getenv("ENVIRONMENT") == "production" {
+		log.Printf("=== DeleteImage Production Debug ===")
+		log.Printf("URL to delete: %s", url)
+	}
 
-	// For local development, update the URL
-	if os.Getenv("ENVIRONMENT") == "development" {
-		supabaseURL = "http://host.docker.internal:54321"
-		log.Printf("Updated Supabase URL for Docker: %s", supabaseURL)
+	if store == nil {
+		return fmt.Errorf("image store is not configured")
 	}
 
-	// Initialize Supabase client
-	client, err := supabase.NewClient(supabaseURL, supabaseKey, nil)
-	if err != nil {
-		log.Printf("ERROR: Failed to initialize Supabase client: %v", err)
-		return fmt.Errorf("failed to initialize Supabase client: %v", err)
+	key := storage.KeyFromStoreURL(store, url)
+	if key == "" {
+		return fmt.Errorf("invalid image URL format")
 	}
-	log.Printf("Successfully initialized Supabase client")
 
-	// Delete the file from Supabase Storage
-	_, err = client.Storage.RemoveFile("images", []string{filePath})
-	if err != nil {
-		log.Printf("ERROR: Failed to delete file from Supabase Storage: %v", err)
-		return fmt.Errorf("failed to delete from Supabase Storage: %v", err)
+	if err := store.Delete(ctx, key); err != nil {
+		log.Printf("ERROR: Failed to delete image: %v", err)
+		return err
 	}
 
-	log.Printf("Successfully deleted file from Supabase Storage")
+	log.Printf("Successfully deleted image: %s", key)
 	return nil
 }
@@ -0,0 +1,263 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/chai2010/webp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxImageSize はアップロードを受け付ける画像の最大サイズ
+const MaxImageSize = 10 * 1024 * 1024 // 10MB
+
+// maxImagePixels はデコードを許可する最大ピクセル数（巨大な画像によるメモリ枯渇を防ぐ）
+const maxImagePixels = 40_000_000
+
+var (
+	// ErrImageTooLarge はファイルサイズまたは解像度が上限を超えている場合のエラー
+	ErrImageTooLarge = errors.New("image is too large")
+	// ErrUnsupportedImage は画像として認識できない、または対応していない形式の場合のエラー
+	ErrUnsupportedImage = errors.New("unsupported image type")
+)
+
+// 受け付ける画像の形式（拡張子やContent-Typeヘッダーではなく実際の内容で判定する）
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// ImageVariantSpec は生成する画像サイズの定義
+type ImageVariantSpec struct {
+	Name   string
+	Width  int
+	Suffix string
+}
+
+// RecipeImageVariants はレシピ画像で生成するサイズ（一覧のサムネイル・カード・詳細）
+var RecipeImageVariants = []ImageVariantSpec{
+	{Name: "thumbnail", Width: 200, Suffix: "_thumb"},
+	{Name: "card", Width: 600, Suffix: "_card"},
+	{Name: "full", Width: 1200, Suffix: ""},
+}
+
+// ProfileImageWidth はプロフィール画像の最大幅
+const ProfileImageWidth = 400
+
+// ReviewPhotoWidth はレビューの写真の最大幅
+const ReviewPhotoWidth = 1200
+
+// UploadImageWidth は具材画像や汎用のアップロードで保存する画像の最大幅
+const UploadImageWidth = 1200
+
+// WebPQuality は不透明な画像（写真）を非可逆のWebPにエンコードする際の品質
+const WebPQuality = 80
+
+// DecodeUploadedImage はアップロードされたファイルを検証してデコードする。
+// デコードした画素だけを使って再エンコードするため、EXIFなどのメタデータは保存されない。
+func DecodeUploadedImage(file *multipart.FileHeader) (image.Image, error) {
+	if file.Size > MaxImageSize {
+		return nil, ErrImageTooLarge
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer src.Close()
+
+	return DecodeImage(src, MaxImageSize)
+}
+
+// DecodeImage は最大maxBytesまで読み込んで画像をデコードし、EXIFの回転情報を反映する
+func DecodeImage(r io.Reader, maxBytes int64) (image.Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file content: %v", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrImageTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !allowedImageTypes[contentType] {
+		return nil, ErrUnsupportedImage
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	if contentType == "image/jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+	return img, nil
+}
+
+// EncodedImage はエンコードした画像のデータと形式
+type EncodedImage struct {
+	Data        []byte
+	ContentType string
+	// Ext はキーに付ける拡張子
+	Ext string
+}
+
+// EncodeImage は指定した幅以下に縮小してWebPにエンコードする（拡大はしない）。
+// 不透明な画像（写真）は非可逆圧縮、透過を含む画像（具材のアイコンなど）は輪郭が崩れないよう可逆圧縮にする。
+func EncodeImage(img image.Image, maxWidth int) (*EncodedImage, error) {
+	img = resizeToWidth(img, maxWidth)
+	opts := &webp.Options{Lossless: true}
+	if isOpaque(img) {
+		opts = &webp.Options{Quality: WebPQuality}
+	}
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, img, opts); err != nil {
+		return nil, fmt.Errorf("failed to encode webp: %v", err)
+	}
+	return &EncodedImage{Data: buf.Bytes(), ContentType: "image/webp", Ext: ".webp"}, nil
+}
+
+// isOpaque は画像に透過している画素が無いかを返す
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+func resizeToWidth(img image.Image, maxWidth int) image.Image {
+	b := img.Bounds()
+	if maxWidth <= 0 || b.Dx() <= maxWidth {
+		return img
+	}
+	height := b.Dy() * maxWidth / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, maxWidth, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// jpegOrientation はJPEGのEXIFからOrientationタグ（1〜8）を読み取る。見つからない場合は1。
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		// SOS以降は画像データなのでEXIFは無い
+		if marker == 0xDA {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			value := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation はEXIFのOrientationに従って画像を正しい向きに変換する
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// 5〜8は縦横が入れ替わる
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 左右反転
+				dx, dy = w-1-x, y
+			case 3: // 180度回転
+				dx, dy = w-1-x, h-1-y
+			case 4: // 上下反転
+				dx, dy = x, h-1-y
+			case 5: // 転置
+				dx, dy = y, x
+			case 6: // 時計回りに90度回転
+				dx, dy = h-1-y, x
+			case 7: // 反転転置
+				dx, dy = h-1-y, w-1-x
+			case 8: // 反時計回りに90度回転
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"net/http"
+	"testing"
+)
+
+// noisyImage は写真に近い、可逆圧縮では小さくならない画像を作る
+func noisyImage(w, h int, alpha uint8) *image.NRGBA {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x + rng.Intn(32)), G: uint8(y + rng.Intn(32)), B: uint8(rng.Intn(256)), A: alpha})
+		}
+	}
+	return img
+}
+
+func TestEncodeImage(t *testing.T) {
+	tests := []struct {
+		name            string
+		img             image.Image
+		maxWidth        int
+		wantContentType string
+		wantExt         string
+		wantWidth       int
+	}{
+		{"opaque photo is webp", noisyImage(400, 300, 0xff), 200, "image/webp", ".webp", 200},
+		{"transparent image is webp", noisyImage(400, 300, 0x80), 200, "image/webp", ".webp", 200},
+		{"does not upscale", noisyImage(100, 80, 0xff), 600, "image/webp", ".webp", 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := EncodeImage(tt.img, tt.maxWidth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encoded.ContentType != tt.wantContentType || encoded.Ext != tt.wantExt {
+				t.Errorf("got %s %s, want %s %s", encoded.ContentType, encoded.Ext, tt.wantContentType, tt.wantExt)
+			}
+			if got := http.DetectContentType(encoded.Data); got != tt.wantContentType {
+				t.Errorf("encoded data is %s", got)
+			}
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(encoded.Data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Width != tt.wantWidth {
+				t.Errorf("width = %d, want %d", cfg.Width, tt.wantWidth)
+			}
+		})
+	}
+}
+
+func TestEncodeImagePhotoIsSmallerThanLossless(t *testing.T) {
+	img := noisyImage(600, 400, 0xff)
+	var lossless bytes.Buffer
+	if err := png.Encode(&lossless, img); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := EncodeImage(img, 600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(encoded.Data) >= lossless.Len() {
+		t.Errorf("encoded photo is %d bytes, lossless png is %d bytes", len(encoded.Data), lossless.Len())
+	}
+}
+
+func TestDecodeImage(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, noisyImage(10, 10, 0xff)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		maxBytes int64
+		wantErr  error
+	}{
+		{"png", pngData.Bytes(), MaxImageSize, nil},
+		{"text", []byte("<html><script>alert(1)</script></html>"), MaxImageSize, ErrUnsupportedImage},
+		{"truncated png", pngData.Bytes()[:20], MaxImageSize, ErrUnsupportedImage},
+		{"too large", pngData.Bytes(), 10, ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeImage(bytes.NewReader(tt.data), tt.maxBytes)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
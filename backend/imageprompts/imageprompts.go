@@ -0,0 +1,177 @@
+package imageprompts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"portfolio-amarimono/models"
+
+	"gorm.io/gorm"
+)
+
+// プロンプトの作成元
+const (
+	SourceImported = "imported" // recipe-data の prompts.json から取り込んだもの
+	SourceDefault  = "default"  // レシピ名・手順から自動生成したもの
+	SourceManual   = "manual"   // 管理画面で編集したもの
+)
+
+// 既存の prompts.json で使われている共通の書式
+const (
+	MainImagePrefix = "日本の家庭的な料理写真、"
+	MainImageSuffix = "一皿に丁寧に盛り付け、料理が画像の中央に配置、背景に余計な料理や食器がない、木製テーブルの上、自然光、浅い被写界深度、柔らかい影、プロフェッショナルな料理写真"
+	StepImagePrefix = "料理の手順写真、"
+	StepImageSuffix = "手はフレームの下側から自然に入る、一人称視点、手の動きが明確に見える、シンプルで清潔な背景、自然光、家庭のキッチン、浅い被写界深度、柔らかい影、プロフェッショナルな料理写真"
+)
+
+// File は recipe-data/recipes/<slug>/prompts.json の形式
+type File struct {
+	MainImage  string   `json:"main_image"`
+	StepImages []string `json:"step_images"`
+}
+
+// LoadFile は prompts.json を読み込む
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return &f, nil
+}
+
+// Prompts はステップ番号ごとのプロンプトに変換する（メイン画像は models.MainImageStep）
+func (f *File) Prompts() map[int]string {
+	prompts := make(map[int]string, len(f.StepImages)+1)
+	if f.MainImage != "" {
+		prompts[models.MainImageStep] = f.MainImage
+	}
+	for i, p := range f.StepImages {
+		if p != "" {
+			prompts[i+1] = p
+		}
+	}
+	return prompts
+}
+
+// DefaultMainPrompt はレシピ名とキャッチフレーズからメイン画像のプロンプトを作る
+func DefaultMainPrompt(name, catchphrase string) string {
+	parts := []string{name}
+	if c := phrase(catchphrase); c != "" {
+		parts = append(parts, c)
+	}
+	return MainImagePrefix + strings.Join(parts, "、") + "、" + MainImageSuffix
+}
+
+// DefaultStepPrompt は手順の説明文から手順画像のプロンプトを作る
+func DefaultStepPrompt(description string) string {
+	return StepImagePrefix + phrase(description) + "、" + StepImageSuffix
+}
+
+// Defaults はレシピのメイン画像とすべての手順のプロンプトを作る
+func Defaults(recipe *models.Recipe) map[int]string {
+	prompts := map[int]string{
+		models.MainImageStep: DefaultMainPrompt(recipe.Name, recipe.Catchphrase),
+	}
+	for _, step := range recipe.Instructions {
+		if step.StepNumber > 0 && strings.TrimSpace(step.Description) != "" {
+			prompts[step.StepNumber] = DefaultStepPrompt(step.Description)
+		}
+	}
+	return prompts
+}
+
+// phrase は文章を読点区切りの語句に整える（句点や改行を読点にし、末尾の記号を除く）
+func phrase(text string) string {
+	replacer := strings.NewReplacer("。", "、", "\n", "、", "！", "、", "!", "、")
+	text = replacer.Replace(strings.TrimSpace(text))
+	for strings.Contains(text, "、、") {
+		text = strings.ReplaceAll(text, "、、", "、")
+	}
+	return strings.Trim(text, "、 ")
+}
+
+// Current はレシピの各ステップの最新バージョンのプロンプトを返す
+func Current(db *gorm.DB, recipeID string) (map[int]models.RecipeImagePrompt, error) {
+	var prompts []models.RecipeImagePrompt
+	if err := db.Raw(`
+		SELECT DISTINCT ON (step_number) *
+		FROM recipe_image_prompts
+		WHERE recipe_id = ?
+		ORDER BY step_number, version DESC`, recipeID).Scan(&prompts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch image prompts: %v", err)
+	}
+	current := make(map[int]models.RecipeImagePrompt, len(prompts))
+	for _, p := range prompts {
+		current[p.StepNumber] = p
+	}
+	return current, nil
+}
+
+// History はステップのすべてのバージョンを新しい順に返す
+func History(db *gorm.DB, recipeID string, step int) ([]models.RecipeImagePrompt, error) {
+	var prompts []models.RecipeImagePrompt
+	if err := db.Where("recipe_id = ? AND step_number = ?", recipeID, step).
+		Order("version DESC").Find(&prompts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch image prompt history: %v", err)
+	}
+	return prompts, nil
+}
+
+// ErrUnchanged は現在のプロンプトと同じ内容を保存しようとした場合のエラー
+var ErrUnchanged = errors.New("image prompt is unchanged")
+
+// Save はステップのプロンプトを新しいバージョンとして保存する。
+// バージョンは同じSQL内で採番し、同時に保存された場合は一意制約でエラーになる。
+func Save(db *gorm.DB, recipeID string, step int, prompt, source string, createdBy *string) (*models.RecipeImagePrompt, error) {
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return nil, fmt.Errorf("prompt is empty")
+	}
+
+	current, err := Current(db, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := current[step]; ok && p.Prompt == prompt {
+		return &p, ErrUnchanged
+	}
+
+	var saved models.RecipeImagePrompt
+	if err := db.Raw(`
+		INSERT INTO recipe_image_prompts (recipe_id, step_number, prompt, version, source, created_by)
+		SELECT ?, ?, ?, COALESCE(MAX(version), 0) + 1, ?, ?
+		FROM recipe_image_prompts
+		WHERE recipe_id = ? AND step_number = ?
+		RETURNING *`,
+		recipeID, step, prompt, source, createdBy, recipeID, step,
+	).Scan(&saved).Error; err != nil {
+		return nil, fmt.Errorf("failed to save image prompt: %v", err)
+	}
+	return &saved, nil
+}
+
+// SaveMissing はプロンプトがまだ無いステップだけを保存し、保存した件数を返す
+func SaveMissing(db *gorm.DB, recipeID string, prompts map[int]string, source string, createdBy *string) (int, error) {
+	current, err := Current(db, recipeID)
+	if err != nil {
+		return 0, err
+	}
+	saved := 0
+	for step, prompt := range prompts {
+		if _, exists := current[step]; exists {
+			continue
+		}
+		if _, err := Save(db, recipeID, step, prompt, source, createdBy); err != nil {
+			return saved, err
+		}
+		saved++
+	}
+	return saved, nil
+}
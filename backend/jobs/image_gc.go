@@ -0,0 +1,119 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"portfolio-amarimono/storage"
+
+	"gorm.io/gorm"
+)
+
+// DefaultImageGCPrefixes はガベージコレクションの対象にするプレフィックス
+var DefaultImageGCPrefixes = []string{"recipes/", "users/", "profiles/", "reviews/"}
+
+// ImageGCOptions は孤立画像の回収の設定
+type ImageGCOptions struct {
+	// Prefixes は走査するストレージのプレフィックス
+	Prefixes []string
+	// GracePeriod より新しいオブジェクトは、参照されていなくても削除しない
+	// （アップロード直後でDBのコミット前の画像を消さないため）
+	GracePeriod time.Duration
+	// DryRun がtrueの場合は報告のみで削除しない
+	DryRun bool
+}
+
+// ImageGCResult は孤立画像の回収結果
+type ImageGCResult struct {
+	Scanned    int                  `json:"scanned"`
+	Referenced int                  `json:"referenced"`
+	Orphans    []storage.ObjectInfo `json:"orphans"`
+	// InGracePeriod は参照されていないが猶予期間内のため残したオブジェクト
+	InGracePeriod []storage.ObjectInfo `json:"in_grace_period"`
+	Deleted       []string             `json:"deleted"`
+	Failed        map[string]string    `json:"failed,omitempty"`
+	DryRun        bool                 `json:"dry_run"`
+}
+
+// CollectOrphanedImages はDBから参照されていない画像を探し、猶予期間を過ぎたものを削除する
+func CollectOrphanedImages(ctx context.Context, db *gorm.DB, store storage.ImageStore, opts ImageGCOptions) (*ImageGCResult, error) {
+	if len(opts.Prefixes) == 0 {
+		opts.Prefixes = DefaultImageGCPrefixes
+	}
+
+	referenced, err := ReferencedImageKeys(db, store)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImageGCResult{
+		Referenced: len(referenced),
+		Orphans:    make([]storage.ObjectInfo, 0),
+		Deleted:    make([]string, 0),
+		DryRun:     opts.DryRun,
+	}
+
+	var objects []storage.ObjectInfo
+	for _, prefix := range opts.Prefixes {
+		listed, err := store.List(ctx, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %v", prefix, err)
+		}
+		objects = append(objects, listed...)
+	}
+	result.Scanned = len(objects)
+
+	// 参照が1件も無いのに画像がある場合は接続先の誤りの可能性が高いため、全削除を防ぐ
+	if len(referenced) == 0 && len(objects) > 0 {
+		return nil, fmt.Errorf("no image references found in database; refusing to treat %d objects as orphaned", len(objects))
+	}
+
+	cutoff := time.Now().Add(-opts.GracePeriod)
+	for _, obj := range objects {
+		if referenced[obj.Key] {
+			continue
+		}
+		// 更新日時が取得できないオブジェクトは猶予期間内として扱う
+		if obj.LastModified.IsZero() || obj.LastModified.After(cutoff) {
+			result.InGracePeriod = append(result.InGracePeriod, obj)
+			continue
+		}
+		result.Orphans = append(result.Orphans, obj)
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	for _, obj := range result.Orphans {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if err := store.Delete(ctx, obj.Key); err != nil {
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[obj.Key] = err.Error()
+			log.Printf("⚠️ Failed to delete orphaned image %s: %v", obj.Key, err)
+			continue
+		}
+		result.Deleted = append(result.Deleted, obj.Key)
+	}
+
+	return result, nil
+}
+
+// ReferencedImageKeys はレシピ（メイン画像・手順画像・サイズ別画像）、ユーザーのプロフィール画像、具材画像、レビューの写真から参照されているキーを返す
+func ReferencedImageKeys(db *gorm.DB, store storage.ImageStore) (map[string]bool, error) {
+	refs, err := collectImageReferences(db)
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool)
+	for _, key := range refs.keys(store) {
+		referenced[key] = true
+	}
+	return referenced, nil
+}
@@ -0,0 +1,403 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/storage"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ImageMigrationOptions はストレージ間の画像移行の設定
+type ImageMigrationOptions struct {
+	// CheckpointPath は進捗を保存するファイル。途中で中断しても再実行時にコピー済みのキーを飛ばす
+	CheckpointPath string
+	// CheckpointEvery は何件コピーするごとに進捗を保存するか
+	CheckpointEvery int
+	// RewriteURLs がtrueの場合、全件のコピーが成功した後にDBの画像URLを移行先の形式に書き換える
+	// （移行元に無かった画像（Missing）のURLは書き換えない）
+	RewriteURLs bool
+	// AbsoluteURLs がtrueの場合は移行先の公開URLで、falseの場合はキー（相対パス）で書き換える
+	AbsoluteURLs bool
+	// DryRun がtrueの場合はコピー対象の一覧を返すだけで何もしない
+	DryRun bool
+}
+
+// ImageMigrationResult は画像移行の結果
+type ImageMigrationResult struct {
+	Total   int               `json:"total"`
+	Copied  int               `json:"copied"`
+	Skipped int               `json:"skipped"`
+	Missing []string          `json:"missing"`
+	Failed  map[string]string `json:"failed,omitempty"`
+	// RewrittenRows は書き換えた行数（テーブル名 -> 行数）
+	RewrittenRows map[string]int `json:"rewritten_rows,omitempty"`
+}
+
+// MigrationCheckpoint はコピー済みのキーとそのSHA-256を保存する
+type MigrationCheckpoint struct {
+	Source      string            `json:"source"`
+	Destination string            `json:"destination"`
+	Copied      map[string]string `json:"copied"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// MigrateImages はDBから参照されているすべての画像を src から dst にコピーし、チェックサムを検証する
+func MigrateImages(ctx context.Context, db *gorm.DB, src, dst storage.ImageStore, srcName, dstName string, opts ImageMigrationOptions) (*ImageMigrationResult, error) {
+	if opts.CheckpointEvery <= 0 {
+		opts.CheckpointEvery = 20
+	}
+
+	refs, err := collectImageReferences(db)
+	if err != nil {
+		return nil, err
+	}
+	keys := refs.keys(src)
+
+	result := &ImageMigrationResult{
+		Total:   len(keys),
+		Missing: make([]string, 0),
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	checkpoint, err := loadCheckpoint(opts.CheckpointPath, srcName, dstName)
+	if err != nil {
+		return nil, err
+	}
+
+	sinceSave := 0
+	for i, key := range keys {
+		if err := ctx.Err(); err != nil {
+			saveCheckpoint(opts.CheckpointPath, checkpoint)
+			return result, err
+		}
+		if _, done := checkpoint.Copied[key]; done {
+			result.Skipped++
+			continue
+		}
+
+		sum, err := copyImage(ctx, src, dst, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			result.Missing = append(result.Missing, key)
+			continue
+		}
+		if err != nil {
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[key] = err.Error()
+			log.Printf("⚠️ Failed to copy %s: %v", key, err)
+			continue
+		}
+
+		checkpoint.Copied[key] = sum
+		result.Copied++
+		sinceSave++
+		if sinceSave >= opts.CheckpointEvery {
+			if err := saveCheckpoint(opts.CheckpointPath, checkpoint); err != nil {
+				return result, err
+			}
+			sinceSave = 0
+			log.Printf("📦 Progress: %d/%d", i+1, len(keys))
+		}
+	}
+	if err := saveCheckpoint(opts.CheckpointPath, checkpoint); err != nil {
+		return result, err
+	}
+
+	if !opts.RewriteURLs {
+		return result, nil
+	}
+	// 一部でもコピーに失敗している場合は、移行先に無い画像を指さないよう書き換えない
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("%d images failed to copy; skipping URL rewrite", len(result.Failed))
+	}
+
+	toURL := func(key string) string { return key }
+	if opts.AbsoluteURLs {
+		toURL = dst.URL
+	}
+	rewritten, err := rewriteImageURLs(db, src, checkpoint.Copied, toURL)
+	if err != nil {
+		return result, err
+	}
+	result.RewrittenRows = rewritten
+	return result, nil
+}
+
+// copyImage は1件の画像をコピーし、移行先から読み直してチェックサムを検証する
+func copyImage(ctx context.Context, src, dst storage.ImageStore, key string) (string, error) {
+	r, err := src.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to read source: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	expected := hex.EncodeToString(sum[:])
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if err := dst.Put(ctx, key, bytes.NewReader(data), contentType); err != nil {
+		return "", err
+	}
+
+	w, err := dst.Get(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read back destination: %v", err)
+	}
+	defer w.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, w); err != nil {
+		return "", fmt.Errorf("failed to read back destination: %v", err)
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+		return "", fmt.Errorf("checksum mismatch: source %s, destination %s", expected, actual)
+	}
+	return expected, nil
+}
+
+func loadCheckpoint(filePath, srcName, dstName string) (*MigrationCheckpoint, error) {
+	checkpoint := &MigrationCheckpoint{
+		Source:      srcName,
+		Destination: dstName,
+		Copied:      make(map[string]string),
+	}
+	if filePath == "" {
+		return checkpoint, nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+	if checkpoint.Source != srcName || checkpoint.Destination != dstName {
+		return nil, fmt.Errorf("checkpoint is for %s -> %s, not %s -> %s", checkpoint.Source, checkpoint.Destination, srcName, dstName)
+	}
+	if checkpoint.Copied == nil {
+		checkpoint.Copied = make(map[string]string)
+	}
+	return checkpoint, nil
+}
+
+// saveCheckpoint は一時ファイルに書いてからリネームし、途中で落ちても壊れないようにする
+func saveCheckpoint(filePath string, checkpoint *MigrationCheckpoint) error {
+	if filePath == "" {
+		return nil
+	}
+	checkpoint.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %v", err)
+	}
+	tmp := filePath + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %v", err)
+	}
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	if err := os.Rename(tmp, filePath); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	return nil
+}
+
+// imageReferences はDB上の画像URLを保持する行
+type imageReferences struct {
+	recipes      []models.Recipe
+	users        []models.User
+	ingredients  []models.Ingredient
+	reviewPhotos []models.ReviewPhoto
+}
+
+func collectImageReferences(db *gorm.DB) (*imageReferences, error) {
+	refs := &imageReferences{}
+	if err := db.Select("id", "image_url", "image_variants", "instructions").Find(&refs.recipes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch recipe images: %v", err)
+	}
+	if err := db.Select("id", "profile_image").Where("profile_image IS NOT NULL AND profile_image <> ''").Find(&refs.users).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch profile images: %v", err)
+	}
+	if err := db.Select("id", "image_url").Where("image_url <> ''").Find(&refs.ingredients).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch ingredient images: %v", err)
+	}
+	if err := db.Select("id", "image_url").Where("image_url <> ''").Find(&refs.reviewPhotos).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch review photos: %v", err)
+	}
+	return refs, nil
+}
+
+// keys は参照されているすべてのキーを重複なしで返す（URLは store の公開URLとしてキーに戻す）
+func (r *imageReferences) keys(store storage.ImageStore) []string {
+	set := make(map[string]bool)
+	add := func(url string) {
+		if url == "" {
+			return
+		}
+		if key := storage.KeyFromStoreURL(store, url); key != "" {
+			set[key] = true
+		}
+	}
+	for _, recipe := range r.recipes {
+		add(recipe.MainImage)
+		for _, key := range recipe.ImageVariants.Keys() {
+			add(key)
+		}
+		for _, instruction := range recipe.Instructions {
+			add(instruction.ImageURL)
+			for _, key := range instruction.ImageVariants.Keys() {
+				add(key)
+			}
+		}
+	}
+	for _, user := range r.users {
+		if user.ProfileImage != nil {
+			add(*user.ProfileImage)
+		}
+	}
+	for _, ingredient := range r.ingredients {
+		add(ingredient.ImageUrl)
+	}
+	for _, photo := range r.reviewPhotos {
+		add(photo.ImageURL)
+	}
+
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// rewriteImageURLs は画像URLを移行先の形式に書き換える（src は現在のURLの保存先）。すべての更新を1つのトランザクションで行う。
+// コピー中に編集された内容を上書きしないよう、行はトランザクション内でロックして読み直す。
+// 書き換えるのは copied（コピーと検証が済んだキー）の画像だけで、移行元に無かった画像のURLはそのまま残す。
+func rewriteImageURLs(db *gorm.DB, src storage.ImageStore, copied map[string]string, toURL func(key string) string) (map[string]int, error) {
+	convert := func(url string) string {
+		if url == "" {
+			return url
+		}
+		key := storage.KeyFromStoreURL(src, url)
+		if _, ok := copied[key]; !ok {
+			return url
+		}
+		return toURL(key)
+	}
+	convertVariants := func(v *models.ImageVariants) *models.ImageVariants {
+		if v == nil {
+			return nil
+		}
+		return &models.ImageVariants{
+			Thumbnail: convert(v.Thumbnail),
+			Card:      convert(v.Card),
+			Full:      convert(v.Full),
+		}
+	}
+
+	rewritten := map[string]int{"recipes": 0, "users": 0, "ingredients": 0, "review_photos": 0}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		refs, err := collectImageReferences(tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+		if err != nil {
+			return err
+		}
+		for _, recipe := range refs.recipes {
+			updates := map[string]interface{}{}
+			if url := convert(recipe.MainImage); url != recipe.MainImage {
+				updates["image_url"] = url
+			}
+			if variants := convertVariants(recipe.ImageVariants); variants != nil && *variants != *recipe.ImageVariants {
+				updates["image_variants"] = variants
+			}
+			instructions := make(models.JSONBInstructions, len(recipe.Instructions))
+			changed := false
+			for i, instruction := range recipe.Instructions {
+				instructions[i] = instruction
+				instructions[i].ImageURL = convert(instruction.ImageURL)
+				instructions[i].ImageVariants = convertVariants(instruction.ImageVariants)
+				if instructions[i].ImageURL != instruction.ImageURL ||
+					(instruction.ImageVariants != nil && *instructions[i].ImageVariants != *instruction.ImageVariants) {
+					changed = true
+				}
+			}
+			if changed {
+				updates["instructions"] = instructions
+			}
+			if len(updates) == 0 {
+				continue
+			}
+			if err := tx.Model(&models.Recipe{}).Where("id = ?", recipe.ID).UpdateColumns(updates).Error; err != nil {
+				return fmt.Errorf("failed to rewrite recipe %s: %v", recipe.ID.String(), err)
+			}
+			rewritten["recipes"]++
+		}
+
+		for _, user := range refs.users {
+			url := convert(*user.ProfileImage)
+			if url == *user.ProfileImage {
+				continue
+			}
+			if err := tx.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("profile_image", url).Error; err != nil {
+				return fmt.Errorf("failed to rewrite user %s: %v", user.ID, err)
+			}
+			rewritten["users"]++
+		}
+
+		for _, ingredient := range refs.ingredients {
+			url := convert(ingredient.ImageUrl)
+			if url == ingredient.ImageUrl {
+				continue
+			}
+			if err := tx.Model(&models.Ingredient{}).Where("id = ?", ingredient.ID).UpdateColumn("image_url", url).Error; err != nil {
+				return fmt.Errorf("failed to rewrite ingredient %d: %v", ingredient.ID, err)
+			}
+			rewritten["ingredients"]++
+		}
+
+		for _, photo := range refs.reviewPhotos {
+			url := convert(photo.ImageURL)
+			if url == photo.ImageURL {
+				continue
+			}
+			if err := tx.Model(&models.ReviewPhoto{}).Where("id = ?", photo.ID).UpdateColumn("image_url", url).Error; err != nil {
+				return fmt.Errorf("failed to rewrite review photo %s: %v", photo.ID.String(), err)
+			}
+			rewritten["review_photos"]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rewritten, nil
+}
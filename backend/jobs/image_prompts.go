@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"portfolio-amarimono/imageprompts"
+
+	"gorm.io/gorm"
+)
+
+// ImagePromptImportResult は prompts.json の取り込み結果
+type ImagePromptImportResult struct {
+	Recipes int `json:"recipes"`
+	Saved   int `json:"saved"`
+	// Skipped はレシピがDBに存在しない、またはprompts.jsonが無いため取り込まなかったディレクトリ
+	Skipped []string `json:"skipped"`
+}
+
+// ImportImagePrompts は recipe-data/recipes/<slug>/prompts.json をレシピごとのプロンプトとして取り込む。
+// すでにプロンプトがあるステップは上書きしないため、何度実行してもよい。
+func ImportImagePrompts(db *gorm.DB, recipesDir string) (*ImagePromptImportResult, error) {
+	entries, err := os.ReadDir(recipesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", recipesDir, err)
+	}
+	slugs := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			slugs = append(slugs, e.Name())
+		}
+	}
+	sort.Strings(slugs)
+
+	result := &ImagePromptImportResult{Skipped: make([]string, 0)}
+	for _, slug := range slugs {
+		dir := filepath.Join(recipesDir, slug)
+		file, err := imageprompts.LoadFile(filepath.Join(dir, "prompts.json"))
+		if os.IsNotExist(err) {
+			result.Skipped = append(result.Skipped, slug)
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+
+		recipeID, err := recipeIDFromCSV(filepath.Join(dir, "recipe.csv"))
+		if err != nil {
+			result.Skipped = append(result.Skipped, slug)
+			continue
+		}
+		var count int64
+		if err := db.Table("recipes").Where("id = ?", recipeID).Count(&count).Error; err != nil {
+			return result, fmt.Errorf("failed to check recipe %s: %v", slug, err)
+		}
+		if count == 0 {
+			result.Skipped = append(result.Skipped, slug)
+			continue
+		}
+
+		saved, err := imageprompts.SaveMissing(db, recipeID, file.Prompts(), imageprompts.SourceImported, nil)
+		if err != nil {
+			return result, fmt.Errorf("failed to import prompts for %s: %v", slug, err)
+		}
+		result.Recipes++
+		result.Saved += saved
+	}
+	return result, nil
+}
+
+// recipeIDFromCSV は recipe.csv の1行目のレシピIDを返す
+func recipeIDFromCSV(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return "", err
+	}
+	row, err := r.Read()
+	if err != nil {
+		return "", err
+	}
+	for i, column := range header {
+		if strings.TrimPrefix(column, "\ufeff") == "id" && i < len(row) {
+			return strings.ToLower(row[i]), nil
+		}
+	}
+	return "", fmt.Errorf("id column not found in %s", path)
+}
@@ -7,9 +7,15 @@ import (
 	"strings"
 	"time"
 
+	"portfolio-amarimono/aggregates"
+	"portfolio-amarimono/ai"
 	"portfolio-amarimono/db"
+	"portfolio-amarimono/exports"
 	"portfolio-amarimono/handlers"
+	"portfolio-amarimono/notifications"
+	"portfolio-amarimono/recommend"
 	"portfolio-amarimono/routes"
+	"portfolio-amarimono/storage"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -102,8 +108,22 @@ func main() {
 		MaxAge:           12 * 60 * 60, // 12 hours
 	}))
 
-	// 静的ファイルを提供
-	r.Static("/uploads", "./uploads")
+	// 画像ストレージの初期化（STORAGE_TYPE で local / r2 / s3 / supabase を切り替え）
+	imageStore, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize image storage: %v", err)
+	}
+
+	// ローカルストレージの場合は保存先を静的ファイルとして提供
+	if localStore, ok := imageStore.(*storage.LocalStore); ok {
+		r.Static(localStore.BaseURL(), localStore.Root())
+	}
+
+	// 個人データのエクスポートの保存先（公開しないため画像とは別に EXPORT_STORAGE_DIR に保存）
+	exportStore, err := exports.StoreFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize export storage: %v", err)
+	}
 
 	// データベース接続の初期化
 	dbConn, err := db.InitDB()
@@ -141,29 +161,72 @@ func main() {
 	} else {
 	}
 
+	// AIプロバイダーの初期化（AI_PROVIDER で openai / stub を切り替え）
+	textGenerator, err := ai.NewFromEnv()
+	if err != nil {
+		log.Printf("⚠️ Failed to initialize AI provider, using stub: %v", err)
+		textGenerator = ai.NewStub()
+	}
+
+	// おすすめ用のレシピ間の類似度を定期的に再計算（RECOMMENDATION_REFRESH_INTERVAL、0で無効）
+	refreshInterval := durationFromEnv("RECOMMENDATION_REFRESH_INTERVAL", 6*time.Hour)
+	recommend.StartRefresher(ctx, dbConn.DB, refreshInterval)
+
+	// いいね数・レビュー数・平均評価のずれを定期的に修復（AGGREGATE_RECONCILE_INTERVAL、0で無効）
+	reconcileInterval := durationFromEnv("AGGREGATE_RECONCILE_INTERVAL", 24*time.Hour)
+	aggregates.StartReconciler(ctx, dbConn.DB, reconcileInterval)
+
+	// 通知（受信箱に加えて、SMTP_HOST・NOTIFICATION_WEBHOOK_URL を設定したチャネルにも配信）
+	notifier := notifications.NewService(dbConn.DB)
+	if channels, err := notifications.ChannelsFromEnv(); err != nil {
+		log.Printf("⚠️ Failed to configure notification channels, using in-app inbox only: %v", err)
+	} else {
+		notifier.Channels = channels
+	}
+
 	// ハンドラの初期化
 	recipeHandler := handlers.NewRecipeHandler(dbConn.DB)
 	likeHandler := handlers.NewLikeHandler(dbConn.DB)
-	userHandler := handlers.NewUserHandler(dbConn.DB)
+	likeHandler.Notifications = notifier
+	userHandler := handlers.NewUserHandler(dbConn.DB, imageStore, exportStore)
 	adminHandler := &handlers.AdminHandler{
-		DB:          dbConn.DB,
-		RedisClient: redisClient,
+		DB:            dbConn.DB,
+		RedisClient:   redisClient,
+		Images:        imageStore,
+		Notifications: notifier,
 	}
 	genreHandler := &handlers.GenreHandler{
 		DB: dbConn.DB,
 	}
-	reviewHandler := &handlers.ReviewHandler{
-		DB: dbConn.DB,
-	}
+	reviewHandler := handlers.NewReviewHandler(dbConn.DB, imageStore)
+	reviewHandler.Notifications = notifier
 	recommendationHandler := &handlers.RecommendationHandler{
 		DB: dbConn.DB,
 	}
 	userIngredientDefaultHandler := handlers.NewUserIngredientDefaultHandler(dbConn.DB)
-	uploadHandler := handlers.NewUploadHandler()
-	aiUsageHandler := handlers.NewAIUsageHandler(dbConn.DB)
+	uploadHandler := handlers.NewUploadHandler(imageStore)
+	aiUsageHandler := handlers.NewAIUsageHandler(dbConn.DB, textGenerator)
+	rankingHandler := handlers.NewRankingHandler(dbConn.DB, redisClient)
+	collectionHandler := handlers.NewCollectionHandler(dbConn.DB)
+	cookLogHandler := handlers.NewCookLogHandler(dbConn.DB)
+	profileHandler := handlers.NewProfileHandler(dbConn.DB)
+	feedHandler := handlers.NewFeedHandler(dbConn.DB)
+	notificationHandler := handlers.NewNotificationHandler(notifier)
+
+	// ランキングを定期的に再計算してRedisにキャッシュ（RANKING_REFRESH_INTERVAL、0で無効）
+	rankingInterval := durationFromEnv("RANKING_REFRESH_INTERVAL", 10*time.Minute)
+	rankingHandler.Rankings.StartRefresher(ctx, rankingInterval)
+
+	// 退会から猶予期間を過ぎたアカウントのデータを定期的に完全削除（ACCOUNT_PURGE_INTERVAL、0で無効）
+	purgeInterval := durationFromEnv("ACCOUNT_PURGE_INTERVAL", 6*time.Hour)
+	userHandler.Accounts.StartPurger(ctx, purgeInterval)
+
+	// 保存期間を過ぎた個人データのエクスポートのZIPを定期的に削除（EXPORT_CLEANUP_INTERVAL、0で無効）
+	exportCleanupInterval := durationFromEnv("EXPORT_CLEANUP_INTERVAL", time.Hour)
+	userHandler.Exports.StartCleaner(ctx, exportCleanupInterval)
 
 	// ルートの設定
-	routes.SetupRoutes(r, recipeHandler, likeHandler, userHandler, genreHandler, adminHandler, reviewHandler, recommendationHandler, userIngredientDefaultHandler, aiUsageHandler, dbConn.DB)
+	routes.SetupRoutes(r, recipeHandler, likeHandler, userHandler, genreHandler, adminHandler, reviewHandler, recommendationHandler, userIngredientDefaultHandler, aiUsageHandler, rankingHandler, collectionHandler, cookLogHandler, profileHandler, feedHandler, notificationHandler, dbConn.DB)
 	routes.SetupAuthRoutes(r, authHandler)
 
 	// 画像アップロード用のエンドポイント
@@ -181,3 +244,17 @@ func main() {
 		log.Fatalf("❌ Failed to start server: %v", err)
 	}
 }
+
+// durationFromEnv は環境変数 name を time.ParseDuration で読み取る（未設定・不正な値の場合は def）
+func durationFromEnv(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("⚠️ Invalid %s %q: %v", name, v, err)
+		return def
+	}
+	return d
+}
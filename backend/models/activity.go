@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// 新着の種類
+const (
+	ActivityKindRecipe     = "recipe"     // レシピを公開した
+	ActivityKindReview     = "review"     // レビューを投稿した
+	ActivityKindCollection = "collection" // コレクションを公開した
+)
+
+// Activity はユーザーの新着（公開したレシピ・投稿したレビュー・公開したコレクション）。対象ごとに1件。
+type Activity struct {
+	ID        UUIDString `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	ActorID   UUIDString `json:"actor_id" gorm:"type:uuid;not null"`
+	Kind      string     `json:"kind" gorm:"not null"`
+	SubjectID UUIDString `json:"subject_id" gorm:"type:uuid;not null"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (Activity) TableName() string {
+	return "activities"
+}
+
+// FeedItem はフォロワーのフィードに配信された新着（新着の公開時にフォロワーごとに1行書き込む）
+type FeedItem struct {
+	UserID     UUIDString `json:"user_id" gorm:"type:uuid;primaryKey"`
+	ActivityID UUIDString `json:"activity_id" gorm:"type:uuid;primaryKey"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (FeedItem) TableName() string {
+	return "feed_items"
+}
@@ -3,12 +3,13 @@ package models
 import "time"
 
 type AIUsage struct {
-	ID         uint      `json:"id" gorm:"primaryKey"`
-	UserID     string    `json:"user_id"`
-	UsageCount int       `json:"usage_count"`
-	LastReset  time.Time `json:"last_reset"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID            UUIDString `json:"id" gorm:"primaryKey"`
+	UserID        string     `json:"user_id"`
+	Feature       string     `json:"feature"`
+	UsageCount    int        `json:"usage_count"`
+	LastResetDate time.Time  `json:"last_reset_date"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
 func (AIUsage) TableName() string {
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// Collection はユーザーが作成するレシピのフォルダ（「平日の晩ごはん」「作り置き」など）
+type Collection struct {
+	ID          UUIDString         `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	UserID      UUIDString         `json:"user_id" gorm:"type:uuid;not null"`
+	Name        string             `json:"name" gorm:"not null"`
+	Description string             `json:"description"`
+	Position    int                `json:"position" gorm:"not null;default:0"`
+	IsPublic    bool               `json:"is_public" gorm:"not null;default:false"`
+	Slug        *string            `json:"slug,omitempty"` // 公開時の共有用URLに使う（公開したことがなければ nil）
+	RecipeCount int                `json:"recipe_count" gorm:"-"`
+	Recipes     []CollectionRecipe `json:"recipes,omitempty" gorm:"foreignKey:CollectionID;references:ID"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+func (Collection) TableName() string {
+	return "collections"
+}
+
+// CollectionRecipe はコレクションに含まれるレシピと並び順
+type CollectionRecipe struct {
+	CollectionID UUIDString `json:"collection_id" gorm:"type:uuid;primaryKey"`
+	RecipeID     UUIDString `json:"recipe_id" gorm:"type:uuid;primaryKey"`
+	Position     int        `json:"position" gorm:"not null;default:0"`
+	AddedAt      time.Time  `json:"added_at" gorm:"autoCreateTime"`
+	Recipe       *Recipe    `json:"recipe,omitempty" gorm:"foreignKey:RecipeID;references:ID"`
+}
+
+func (CollectionRecipe) TableName() string {
+	return "collection_recipes"
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Substitution は作ったときに使った材料の代用
+type Substitution struct {
+	Original   string `json:"original"`
+	Substitute string `json:"substitute"`
+}
+
+// JSONBSubstitutions は材料の代用の一覧（JSONB）
+type JSONBSubstitutions []Substitution
+
+func (s *JSONBSubstitutions) Scan(value interface{}) error {
+	if value == nil {
+		*s = JSONBSubstitutions{}
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("failed to scan JSONBSubstitutions: expected []byte or string, got %T", value)
+	}
+
+	if len(bytes) == 0 || string(bytes) == "null" {
+		*s = JSONBSubstitutions{}
+		return nil
+	}
+	return json.Unmarshal(bytes, s)
+}
+
+// Value は JSONBSubstitutions をデータベースに保存する
+func (s JSONBSubstitutions) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return "[]", nil
+	}
+	bytes, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	// PreferSimpleProtocol: trueの場合、文字列として返す
+	return string(bytes), nil
+}
+
+// DateOnly は時刻を持たない日付（DATE列、JSONでは YYYY-MM-DD）
+type DateOnly struct {
+	time.Time
+}
+
+// DateOnlyLayout は DateOnly の文字列表現
+const DateOnlyLayout = "2006-01-02"
+
+// ParseDateOnly は YYYY-MM-DD 形式の日付を読み取る
+func ParseDateOnly(s string) (DateOnly, error) {
+	t, err := time.Parse(DateOnlyLayout, s)
+	if err != nil {
+		return DateOnly{}, err
+	}
+	return DateOnly{t}, nil
+}
+
+func (d *DateOnly) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*d = DateOnly{}
+		return nil
+	case time.Time:
+		*d = DateOnly{time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)}
+		return nil
+	case []byte:
+		parsed, err := ParseDateOnly(string(v))
+		*d = parsed
+		return err
+	case string:
+		parsed, err := ParseDateOnly(v)
+		*d = parsed
+		return err
+	default:
+		return fmt.Errorf("failed to scan DateOnly: unexpected type %T", value)
+	}
+}
+
+// Value は DateOnly を YYYY-MM-DD の文字列として保存する
+func (d DateOnly) Value() (driver.Value, error) {
+	if d.IsZero() {
+		return nil, nil
+	}
+	return d.Format(DateOnlyLayout), nil
+}
+
+// String は YYYY-MM-DD 形式の文字列を返す
+func (d DateOnly) String() string {
+	return d.Format(DateOnlyLayout)
+}
+
+func (d DateOnly) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.String())
+}
+
+func (d *DateOnly) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*d = DateOnly{}
+		return nil
+	}
+	parsed, err := ParseDateOnly(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// CookLog は「作った」記録（同じレシピを何度作っても1回ずつ記録する）
+type CookLog struct {
+	ID            UUIDString         `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	UserID        UUIDString         `json:"user_id" gorm:"type:uuid;not null"`
+	RecipeID      UUIDString         `json:"recipe_id" gorm:"type:uuid;not null"`
+	CookedOn      DateOnly           `json:"cooked_on" gorm:"type:date;not null"` // 日本時間の日付
+	Note          string             `json:"note"`
+	Servings      *int               `json:"servings,omitempty"`
+	Substitutions JSONBSubstitutions `json:"substitutions" gorm:"type:jsonb;default:'[]'"`
+	Recipe        *Recipe            `json:"recipe,omitempty" gorm:"foreignKey:RecipeID;references:ID"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+func (CookLog) TableName() string {
+	return "cook_logs"
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// データエクスポートの状態
+const (
+	DataExportStatusPending    = "pending"
+	DataExportStatusProcessing = "processing"
+	DataExportStatusCompleted  = "completed"
+	DataExportStatusFailed     = "failed"
+)
+
+// DataExport はユーザーの個人データのエクスポート（ZIPの作成はバックグラウンドで行う）
+type DataExport struct {
+	ID     UUIDString `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	UserID UUIDString `json:"user_id" gorm:"type:uuid;not null"`
+	Status string     `json:"status" gorm:"not null;default:pending"`
+	// FileKey はストレージ上のZIPのキー（公開URLは返さず、ダウンロード用のエンドポイントから配信する）
+	FileKey     string     `json:"-"`
+	SizeBytes   int64      `json:"size_bytes"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+func (DataExport) TableName() string {
+	return "data_exports"
+}
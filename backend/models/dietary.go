@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// IngredientDietaryTag は具材に付けたアレルゲン・食事制限のタグ（Code の制限がある人はこの具材を避ける）
+type IngredientDietaryTag struct {
+	IngredientID int    `json:"ingredient_id" gorm:"primaryKey"`
+	Code         string `json:"code" gorm:"primaryKey"`
+}
+
+func (IngredientDietaryTag) TableName() string {
+	return "ingredient_dietary_tags"
+}
+
+// UserDietaryRestriction はユーザーが検索・おすすめから除外したいアレルゲン・食事制限
+type UserDietaryRestriction struct {
+	UserID    UUIDString `json:"-" gorm:"type:uuid;primaryKey"`
+	Code      string     `json:"code" gorm:"primaryKey"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (UserDietaryRestriction) TableName() string {
+	return "user_dietary_restrictions"
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Follow はユーザー間のフォロー関係（FollowerID が FolloweeID をフォローしている）
+type Follow struct {
+	FollowerID UUIDString `json:"follower_id" gorm:"type:uuid;primaryKey"`
+	FolloweeID UUIDString `json:"followee_id" gorm:"type:uuid;primaryKey"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (Follow) TableName() string {
+	return "follows"
+}
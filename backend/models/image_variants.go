@@ -0,0 +1,59 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// ImageVariants はアップロード時に生成したWebP画像のサイズ別のキー
+type ImageVariants struct {
+	Thumbnail string `json:"thumbnail"`
+	Card      string `json:"card"`
+	Full      string `json:"full"`
+}
+
+// Keys は空でないキーをすべて返す
+func (v *ImageVariants) Keys() []string {
+	if v == nil {
+		return nil
+	}
+	keys := make([]string, 0, 3)
+	for _, key := range []string{v.Thumbnail, v.Card, v.Full} {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (v *ImageVariants) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	var bytes []byte
+	switch t := value.(type) {
+	case []byte:
+		bytes = t
+	case string:
+		bytes = []byte(t)
+	default:
+		return fmt.Errorf("failed to scan ImageVariants: expected []byte or string, got %T", value)
+	}
+
+	if len(bytes) == 0 || string(bytes) == "null" {
+		return nil
+	}
+
+	return json.Unmarshal(bytes, v)
+}
+
+// Value はImageVariantsをJSONBとして保存する
+func (v ImageVariants) Value() (driver.Value, error) {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(bytes), nil
+}
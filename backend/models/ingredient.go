@@ -7,19 +7,20 @@ import (
 )
 
 type Ingredient struct {
-	ID             int             `json:"id" gorm:"primaryKey"`
-	Name           string          `json:"name" binding:"required" gorm:"unique;not null"`
-	GenreID        int             `json:"genre_id" binding:"required"`
-	Genre          IngredientGenre `json:"genre" gorm:"foreignKey:GenreID;references:ID"`
-	ImageUrl       string          `json:"image_url"`
-	UnitID         int             `json:"unit_id" gorm:"not null"`
-	Unit           Unit            `json:"unit" gorm:"foreignKey:UnitID;references:ID"`
-	UnitType       UnitType        `json:"unit_type" gorm:"-"`
-	Nutrition      NutritionInfo   `json:"nutrition" gorm:"type:jsonb"`
-	GramEquivalent float64         `json:"gram_equivalent" gorm:"not null;default:100"` // 100gに相当する量
-	CreatedAt      time.Time       `json:"created_at" gorm:"not null;default:CURRENT_TIMESTAMP"`
-	UpdatedAt      time.Time       `json:"updated_at" gorm:"not null;default:CURRENT_TIMESTAMP"`
-	DeletedAt      *time.Time      `json:"deleted_at" gorm:"index"`
+	ID             int                    `json:"id" gorm:"primaryKey"`
+	Name           string                 `json:"name" binding:"required" gorm:"unique;not null"`
+	GenreID        int                    `json:"genre_id" binding:"required"`
+	Genre          IngredientGenre        `json:"genre" gorm:"foreignKey:GenreID;references:ID"`
+	ImageUrl       string                 `json:"image_url"`
+	UnitID         int                    `json:"unit_id" gorm:"not null"`
+	Unit           Unit                   `json:"unit" gorm:"foreignKey:UnitID;references:ID"`
+	UnitType       UnitType               `json:"unit_type" gorm:"-"`
+	Nutrition      NutritionInfo          `json:"nutrition" gorm:"type:jsonb"`
+	GramEquivalent float64                `json:"gram_equivalent" gorm:"not null;default:100"`           // 100gに相当する量
+	DietaryTags    []IngredientDietaryTag `json:"dietary_tags,omitempty" gorm:"foreignKey:IngredientID"` // アレルゲン・食事制限のタグ（管理画面で読み込む）
+	CreatedAt      time.Time              `json:"created_at" gorm:"not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time              `json:"updated_at" gorm:"not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt      *time.Time             `json:"deleted_at" gorm:"index"`
 }
 
 func (Ingredient) TableName() string {
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+)
+
+// 通知の種類
+const (
+	NotificationTypeReviewReceived    = "review_received"    // 自分のレシピにレビューが付いた
+	NotificationTypeLikeReceived      = "like_received"      // 自分のレシピにいいねが付いた
+	NotificationTypeRecipePublished   = "recipe_published"   // 管理者が自分のレシピを公開した
+	NotificationTypeRecipeUnpublished = "recipe_unpublished" // 管理者が自分のレシピを非公開にした
+)
+
+// NotificationTypes は設定画面に並べる順の通知の種類
+var NotificationTypes = []string{
+	NotificationTypeReviewReceived,
+	NotificationTypeLikeReceived,
+	NotificationTypeRecipePublished,
+	NotificationTypeRecipeUnpublished,
+}
+
+// Notification はアプリ内の受信箱に届いた通知
+type Notification struct {
+	ID     UUIDString `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	UserID UUIDString `json:"user_id" gorm:"type:uuid;not null"`
+	Type   string     `json:"type" gorm:"not null"`
+	// ActorID は通知のきっかけになったユーザー（管理者の操作や退会したユーザーの場合は nil）
+	ActorID   *UUIDString `json:"actor_id" gorm:"type:uuid"`
+	RecipeID  *UUIDString `json:"recipe_id" gorm:"type:uuid"`
+	ReviewID  *UUIDString `json:"review_id" gorm:"type:uuid"`
+	Title     string      `json:"title" gorm:"not null"`
+	Body      string      `json:"body" gorm:"not null"`
+	ReadAt    *time.Time  `json:"read_at"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// NotificationPreference は通知の種類ごとの配信先の設定（行がない種類は既定の設定を使う）
+type NotificationPreference struct {
+	UserID    UUIDString `json:"-" gorm:"type:uuid;primaryKey"`
+	Type      string     `json:"type" gorm:"primaryKey"`
+	InApp     bool       `json:"in_app" gorm:"not null"`
+	Email     bool       `json:"email" gorm:"not null"`
+	Webhook   bool       `json:"webhook" gorm:"not null"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
@@ -9,6 +9,7 @@ type Recipe struct {
 	Name                string             `json:"name" binding:"required"`
 	Instructions        JSONBInstructions  `json:"instructions" gorm:"type:jsonb" binding:"required"`
 	MainImage           string             `json:"image_url" gorm:"column:image_url"`
+	ImageVariants       *ImageVariants     `json:"image_variants,omitempty" gorm:"type:jsonb"`
 	GenreID             int                `json:"genre_id" binding:"required"`
 	Genre               RecipeGenre        `json:"genre" gorm:"foreignKey:GenreID;references:ID"`
 	Ingredients         []RecipeIngredient `json:"ingredients" gorm:"foreignKey:RecipeID;references:ID" binding:"required,dive"`
@@ -20,6 +21,10 @@ type Recipe struct {
 	Catchphrase         string             `json:"catchphrase"`
 	FAQ                 JSONBFaq           `json:"faq" gorm:"type:jsonb;default:'[]'"`
 	Likes               []Like             `json:"likes"`
+	LikeCount           int                `json:"like_count" gorm:"->"`   // 集計値（いいね・レビューと同じトランザクションで更新するため、Saveでは書き込まない）
+	ReviewCount         int                `json:"review_count" gorm:"->"` // 集計値
+	RatingAvg           float64            `json:"rating_avg" gorm:"->"`   // 集計値（小数第2位まで）
+	CookCount           int                `json:"cook_count" gorm:"->"`   // 集計値（「作った」記録の数）
 	UserID              *UUIDString        `json:"user_id" gorm:"type:uuid"`
 	IsPublic            bool               `json:"is_public" gorm:"default:true"`
 	IsDraft             bool               `json:"is_draft" gorm:"default:false"`
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// MainImageStep はメイン画像のプロンプトを表すステップ番号（手順の画像は1から）
+const MainImageStep = 0
+
+// RecipeImagePrompt はレシピのメイン画像・手順画像を生成するためのプロンプト。
+// 編集するたびに新しいバージョンの行を追加し、最新のバージョンを現在のプロンプトとして扱う。
+type RecipeImagePrompt struct {
+	ID         UUIDString  `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	RecipeID   UUIDString  `json:"recipe_id" gorm:"type:uuid;not null"`
+	StepNumber int         `json:"step_number" gorm:"not null"`
+	Prompt     string      `json:"prompt" gorm:"type:text;not null"`
+	Version    int         `json:"version" gorm:"not null"`
+	Source     string      `json:"source" gorm:"not null"`
+	CreatedBy  *UUIDString `json:"created_by,omitempty" gorm:"type:uuid"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+func (RecipeImagePrompt) TableName() string {
+	return "recipe_image_prompts"
+}
+
+// RecipeImageGeneration はどのバージョンのプロンプトからどの画像を生成したかの記録
+type RecipeImageGeneration struct {
+	ID            UUIDString `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	RecipeID      UUIDString `json:"recipe_id" gorm:"type:uuid;not null"`
+	StepNumber    int        `json:"step_number" gorm:"not null"`
+	PromptID      UUIDString `json:"prompt_id" gorm:"type:uuid;not null"`
+	PromptVersion int        `json:"prompt_version" gorm:"not null"`
+	ImageURL      string     `json:"image_url" gorm:"not null"`
+	Model         string     `json:"model"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (RecipeImageGeneration) TableName() string {
+	return "recipe_image_generations"
+}
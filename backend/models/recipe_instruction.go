@@ -10,6 +10,8 @@ type InstructionStep struct {
 	StepNumber  int    `json:"stepNumber"`
 	Description string `json:"description"`
 	ImageURL    string `json:"image_url,omitempty"`
+	// ImageVariants はサイズ別のWebP画像（アップロード時に生成した場合のみ）
+	ImageVariants *ImageVariants `json:"image_variants,omitempty"`
 }
 
 type JSONBInstructions []InstructionStep
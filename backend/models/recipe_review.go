@@ -4,16 +4,37 @@ import (
 	"time"
 )
 
+// レビューの公開状態
+const (
+	ReviewStatusVisible = "visible"
+	ReviewStatusHidden  = "hidden" // モデレーターが非表示にしたレビュー（一覧・集計から除外する）
+)
+
 type Review struct {
-	ID        UUIDString `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
-	RecipeID  UUIDString `json:"recipeId" gorm:"type:uuid;not null"`
-	UserID    UUIDString `json:"userId" gorm:"type:uuid;not null"`
-	Rating    int        `json:"rating" gorm:"type:int;check:rating >= 1 AND rating <= 5"`
-	Comment   string     `json:"comment" gorm:"type:text"`
-	CreatedAt time.Time  `json:"createdAt" gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt time.Time  `json:"updatedAt" gorm:"default:CURRENT_TIMESTAMP"`
+	ID       UUIDString `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	RecipeID UUIDString `json:"recipeId" gorm:"type:uuid;not null"`
+	UserID   UUIDString `json:"userId" gorm:"type:uuid;not null"`
+	Rating   int        `json:"rating" gorm:"type:int;check:rating >= 1 AND rating <= 5"`
+	Comment  string     `json:"comment" gorm:"type:text"`
+	Status   string     `json:"status" gorm:"default:visible"`
+	// HelpfulCount は「参考になった」の数（投票と同じトランザクションで更新する集計値）
+	HelpfulCount int           `json:"helpfulCount" gorm:"->"`
+	Photos       []ReviewPhoto `json:"photos" gorm:"foreignKey:ReviewID;references:ID"`
+	CreatedAt    time.Time     `json:"createdAt" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt    time.Time     `json:"updatedAt" gorm:"default:CURRENT_TIMESTAMP"`
 }
 
 func (Review) TableName() string {
 	return "reviews"
 }
+
+// ReviewHelpfulVote はレビューへの「参考になった」の投票（1ユーザー1レビューにつき1票）
+type ReviewHelpfulVote struct {
+	ReviewID  UUIDString `json:"reviewId" gorm:"type:uuid;primaryKey"`
+	UserID    UUIDString `json:"userId" gorm:"type:uuid;primaryKey"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+func (ReviewHelpfulVote) TableName() string {
+	return "review_helpful_votes"
+}
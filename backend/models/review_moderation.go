@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+)
+
+// 通報の理由
+const (
+	ReportReasonSpam      = "spam"
+	ReportReasonOffensive = "offensive"
+	ReportReasonOffTopic  = "off_topic"
+	ReportReasonOther     = "other"
+)
+
+// 通報の状態
+const (
+	ReportStatusOpen     = "open"
+	ReportStatusResolved = "resolved"
+)
+
+// ReviewReport はユーザーによるレビューの通報（1ユーザー1レビューにつき1件）
+type ReviewReport struct {
+	ID         UUIDString  `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	ReviewID   UUIDString  `json:"review_id" gorm:"type:uuid;not null"`
+	ReporterID UUIDString  `json:"reporter_id" gorm:"type:uuid;not null"`
+	Reason     string      `json:"reason" gorm:"not null"`
+	Detail     string      `json:"detail"`
+	Status     string      `json:"status" gorm:"not null;default:open"`
+	ResolvedBy *UUIDString `json:"resolved_by,omitempty" gorm:"type:uuid"`
+	ResolvedAt *time.Time  `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+func (ReviewReport) TableName() string {
+	return "review_reports"
+}
+
+// ReviewModerationAction はレビューに対するモデレーション操作の監査ログ。
+// レビューが削除された後も追えるよう、操作時点の内容を残す。
+type ReviewModerationAction struct {
+	ID           UUIDString  `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	ReviewID     UUIDString  `json:"review_id" gorm:"type:uuid;not null"`
+	Action       string      `json:"action" gorm:"not null"`
+	ModeratorID  *UUIDString `json:"moderator_id,omitempty" gorm:"type:uuid"` // 自動非表示の場合は nil
+	Note         string      `json:"note"`
+	ReviewUserID UUIDString  `json:"review_user_id" gorm:"type:uuid"`
+	Rating       int         `json:"rating"`
+	Comment      string      `json:"comment"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+func (ReviewModerationAction) TableName() string {
+	return "review_moderation_actions"
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// MaxReviewPhotos はレビュー1件に添付できる写真の上限
+const MaxReviewPhotos = 4
+
+// ReviewPhoto はレビューに添付された写真
+type ReviewPhoto struct {
+	ID        UUIDString `json:"id" gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	ReviewID  UUIDString `json:"reviewId" gorm:"type:uuid;not null"`
+	UserID    UUIDString `json:"userId" gorm:"type:uuid;not null"`
+	ImageURL  string     `json:"imageUrl" gorm:"not null"`
+	Position  int        `json:"position" gorm:"not null;default:0"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+func (ReviewPhoto) TableName() string {
+	return "review_photos"
+}
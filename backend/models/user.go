@@ -19,6 +19,7 @@ type User struct {
 	Age          *int       `json:"age"`
 	Gender       *string    `json:"gender"`
 	ProfileImage *string    `json:"profile_image"`
+	Bio          *string    `json:"bio"`           // 公開プロフィールに表示する自己紹介
 	Role         string     `json:"role" gorm:"-"` // データベースには保存しないが、JSONには含める
 	CreatedAt    time.Time  `json:"created_at" gorm:"not null;default:CURRENT_TIMESTAMP"`
 	UpdatedAt    time.Time  `json:"updated_at" gorm:"not null;default:CURRENT_TIMESTAMP"`
@@ -117,6 +118,9 @@ func SyncUser(db *gorm.DB, user *User) error {
 
 	// ユーザーが既に存在する場合は更新
 	user.CreatedAt = existingUser.CreatedAt // 作成日時は保持
+	if user.Bio == nil {
+		user.Bio = existingUser.Bio // 同期のリクエストに含まれない自己紹介は保持
+	}
 	return UpdateUser(db, user)
 }
 
@@ -148,6 +152,7 @@ func UpdateUser(db *gorm.DB, user *User) error {
 		"age":           user.Age,
 		"gender":        user.Gender,
 		"profile_image": user.ProfileImage,
+		"bio":           user.Bio,
 		"updated_at":    time.Now(),
 	}).Error
 
@@ -160,14 +165,10 @@ func UpdateUser(db *gorm.DB, user *User) error {
 	return err
 }
 
-// DeleteUser ユーザーを削除する（論理削除）
-func DeleteUser(db *gorm.DB, id string) error {
-	log.Printf("🔍 DeleteUser - Deleting user with ID: %s", id)
-	err := db.Delete(&User{}, "id = ?", id).Error
-	if err != nil {
-		log.Printf("🔍 DeleteUser - Error deleting user: %v", err)
-	} else {
-		log.Printf("🔍 DeleteUser - User deleted successfully: %s", id)
+// ActiveOwner は削除を申請中（猶予期間中）のユーザーが所有する行を除くスコープ
+// column は所有者のユーザーIDの列（"recipes.user_id" など）で、所有者のいない行は残す
+func ActiveOwner(column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("NOT EXISTS (SELECT 1 FROM users WHERE users.id = " + column + " AND users.deleted_at IS NOT NULL)")
 	}
-	return err
 }
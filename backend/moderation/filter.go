@@ -0,0 +1,178 @@
+package moderation
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"portfolio-amarimono/utils"
+)
+
+// DefaultNGWords は NG_WORDS_FILE が指定されていない場合に使うNGワード
+var DefaultNGWords = []string{
+	"ばか",
+	"あほ",
+	"しね",
+	"ころす",
+	"きもい",
+	"うざい",
+}
+
+// DefaultAllowWords はNGワードを含むが問題のない語（「作ったばかり」の「ばかり」など）
+var DefaultAllowWords = []string{
+	"ばかり",
+	"ばかず",
+	"あほうどり",
+	"しねま",
+	"しねか", // 「少しねかせる」
+	"しねじ", // 「押しねじ」
+	"しねぎ", // 「干しねぎ」
+}
+
+// Filter はコメントにNGワードが含まれているかを判定する。
+// NGワードと対象のテキストはどちらも utils.NormalizeForFilter で正規化してから比較するため、
+// 「バカ」「ﾊﾞｶ」「ば か」はすべて「ばか」として検出される。
+// 日本語には単語の区切りが無いため、NGワードが許可語（allow）の一部として現れた場合だけ除外する。
+type Filter struct {
+	words []string
+	allow []string
+}
+
+// NewFilter はNGワードと許可語の一覧から Filter を作成する
+func NewFilter(words, allow []string) *Filter {
+	return &Filter{words: normalizeWords(words), allow: normalizeWords(allow)}
+}
+
+// normalizeWords は語を正規化し、空の語と重複を除く
+func normalizeWords(words []string) []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, w := range words {
+		normalized := utils.NormalizeForFilter(w)
+		if normalized == "" || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		result = append(result, normalized)
+	}
+	return result
+}
+
+// LoadFilter は1行に1語のファイルから Filter を作成する（空行と # で始まる行は無視する）。
+// ! で始まる行は許可語として扱う（例: "!ばかり"）。
+func LoadFilter(path string) (*Filter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words, allow []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "!") {
+			allow = append(allow, strings.TrimPrefix(line, "!"))
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewFilter(words, allow), nil
+}
+
+// Matches はテキストに含まれていたNGワード（正規化後）を返す
+func (f *Filter) Matches(text string) []string {
+	if f == nil || text == "" {
+		return nil
+	}
+	normalized := utils.NormalizeForFilter(text)
+	allowed := f.allowedSpans(normalized)
+	var matched []string
+	for _, w := range f.words {
+		if containsOutside(normalized, w, allowed) {
+			matched = append(matched, w)
+		}
+	}
+	return matched
+}
+
+// span はテキスト中のバイト位置の範囲 [start, end)
+type span struct {
+	start, end int
+}
+
+// allowedSpans は正規化済みのテキストで許可語が現れる範囲を返す
+func (f *Filter) allowedSpans(normalized string) []span {
+	var spans []span
+	for _, a := range f.allow {
+		for offset := 0; ; {
+			i := strings.Index(normalized[offset:], a)
+			if i < 0 {
+				break
+			}
+			start := offset + i
+			spans = append(spans, span{start, start + len(a)})
+			offset = start + 1
+		}
+	}
+	return spans
+}
+
+// containsOutside は word が許可語の範囲に収まらない位置に1回でも現れるかを返す
+func containsOutside(normalized, word string, allowed []span) bool {
+	for offset := 0; ; {
+		i := strings.Index(normalized[offset:], word)
+		if i < 0 {
+			return false
+		}
+		start := offset + i
+		if !within(span{start, start + len(word)}, allowed) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func within(s span, spans []span) bool {
+	for _, a := range spans {
+		if a.start <= s.start && s.end <= a.end {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains はテキストにNGワードが含まれているかを返す
+func (f *Filter) Contains(text string) bool {
+	return len(f.Matches(text)) > 0
+}
+
+var (
+	defaultFilter     *Filter
+	defaultFilterOnce sync.Once
+)
+
+// DefaultFilter は NG_WORDS_FILE（未指定の場合は DefaultNGWords・DefaultAllowWords）から作成した Filter を返す
+func DefaultFilter() *Filter {
+	defaultFilterOnce.Do(func() {
+		if path := os.Getenv("NG_WORDS_FILE"); path != "" {
+			f, err := LoadFilter(path)
+			if err == nil {
+				log.Printf("✅ Loaded %d NG words from %s", len(f.words), path)
+				defaultFilter = f
+				return
+			}
+			log.Printf("⚠️ Failed to load NG_WORDS_FILE %q, using default NG words: %v", path, err)
+		}
+		defaultFilter = NewFilter(DefaultNGWords, DefaultAllowWords)
+	})
+	return defaultFilter
+}
@@ -0,0 +1,98 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFilterMatches(t *testing.T) {
+	f := NewFilter(DefaultNGWords, DefaultAllowWords)
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		// 許可語の一部としてだけ現れる場合は検出しない
+		{"ばかり", "作ったばかりです", nil},
+		{"ばかり katakana", "作ったバカリです", nil},
+		{"ばかず", "バカズを踏んで上達しました", nil},
+		{"あほうどり", "アホウドリの形に盛り付けました", nil},
+		{"しねま", "シネマを観ながら食べました", nil},
+		{"しねか", "生地を少しねかせると美味しい", nil},
+		{"しねじ", "押しねじで留めました", nil},
+		{"しねぎ", "干しねぎを散らしました", nil},
+		{"no ng words", "とてもおいしかったです", nil},
+		{"empty", "", nil},
+
+		// NGワードは表記ゆれや区切りがあっても検出する
+		{"hiragana", "ばかみたいな味", []string{"ばか"}},
+		{"katakana", "バカみたいな味", []string{"ばか"}},
+		{"halfwidth", "ﾊﾞｶみたいな味", []string{"ばか"}},
+		{"spaced", "ば か みたい", []string{"ばか"}},
+		{"allow word and ng word", "作ったばかりなのにばかにされた", []string{"ばか"}},
+		{"ng word before allow word", "ばかだ、作ったばかり", []string{"ばか"}},
+		{"multiple words", "うざいしきもい", []string{"きもい", "うざい"}},
+		{"ng word inside other text", "ころすぞ", []string{"ころす"}},
+		{"しね", "しねばいいのに", []string{"しね"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.Matches(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Matches(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+			if got := f.Contains(tt.text); got != (len(tt.want) > 0) {
+				t.Errorf("Contains(%q) = %v", tt.text, got)
+			}
+		})
+	}
+}
+
+func TestFilterWithoutAllowWords(t *testing.T) {
+	f := NewFilter([]string{"ばか"}, nil)
+	if got := f.Matches("作ったばかりです"); !reflect.DeepEqual(got, []string{"ばか"}) {
+		t.Errorf("Matches = %v, want [ばか]", got)
+	}
+}
+
+func TestNilFilter(t *testing.T) {
+	var f *Filter
+	if got := f.Matches("ばか"); got != nil {
+		t.Errorf("Matches on nil filter = %v", got)
+	}
+}
+
+func TestLoadFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ng_words.txt")
+	content := "# NGワード\nまずい\n\n!まずいくらい\nバカ\n!ばかり\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := LoadFilter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"まずい", "ばか"}; !reflect.DeepEqual(f.words, want) {
+		t.Errorf("words = %v, want %v", f.words, want)
+	}
+	if want := []string{"まずいくらい", "ばかり"}; !reflect.DeepEqual(f.allow, want) {
+		t.Errorf("allow = %v, want %v", f.allow, want)
+	}
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"まずいくらいおいしい", false},
+		{"まずい", true},
+		{"作ったばかり", false},
+		{"ばかだ", true},
+	}
+	for _, tt := range tests {
+		if got := f.Contains(tt.text); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
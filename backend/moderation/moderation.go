@@ -0,0 +1,326 @@
+package moderation
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"portfolio-amarimono/aggregates"
+	"portfolio-amarimono/feed"
+	"portfolio-amarimono/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// モデレーション操作の種類（監査ログの action）
+const (
+	ActionHide     = "hide"
+	ActionAutoHide = "auto_hide"
+	ActionRestore  = "restore"
+	ActionDelete   = "delete"
+	ActionDismiss  = "dismiss"
+)
+
+// DefaultAutoHideThreshold は異なるユーザーからの未対応の通報がこの件数に達したら自動で非表示にする
+const DefaultAutoHideThreshold = 3
+
+// MaxReportDetailLength は通報の詳細の最大文字数
+const MaxReportDetailLength = 500
+
+var (
+	ErrReviewNotFound = errors.New("review not found")
+	ErrInvalidReason  = errors.New("invalid report reason")
+	ErrDetailTooLong  = errors.New("report detail is too long")
+	ErrOwnReview      = errors.New("cannot report own review")
+	// ErrModeratorRequired は手動の操作でモデレーターのIDが指定されていない場合のエラー
+	ErrModeratorRequired = errors.New("moderator is required")
+)
+
+// ValidReasons は通報に指定できる理由
+var ValidReasons = map[string]bool{
+	models.ReportReasonSpam:      true,
+	models.ReportReasonOffensive: true,
+	models.ReportReasonOffTopic:  true,
+	models.ReportReasonOther:     true,
+}
+
+// QueueItem はモデレーション待ちのレビューと通報の集計
+type QueueItem struct {
+	Review       models.Review         `json:"review"`
+	ReportCount  int                   `json:"report_count"`
+	Reasons      map[string]int        `json:"reasons"`
+	LastReported time.Time             `json:"last_reported_at"`
+	Reports      []models.ReviewReport `json:"reports"`
+}
+
+// Service はレビューの通報とモデレーターによる対応を扱う
+type Service struct {
+	DB                *gorm.DB
+	AutoHideThreshold int
+	Now               func() time.Time
+}
+
+// NewService は Service を初期化するコンストラクタ
+func NewService(db *gorm.DB) *Service {
+	return &Service{
+		DB:                db,
+		AutoHideThreshold: DefaultAutoHideThreshold,
+		Now:               time.Now,
+	}
+}
+
+// Report はレビューを通報する。同じユーザーが同じレビューを再度通報した場合は理由を更新する。
+// 未対応の通報が AutoHideThreshold 件に達したレビューは、モデレーターの確認まで自動で非表示にする。
+func (s *Service) Report(reporterID, reviewID, reason, detail string) (report *models.ReviewReport, created bool, err error) {
+	if !ValidReasons[reason] {
+		return nil, false, ErrInvalidReason
+	}
+	detail = strings.TrimSpace(detail)
+	if len([]rune(detail)) > MaxReportDetailLength {
+		return nil, false, ErrDetailTooLong
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		var review models.Review
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", reviewID).Take(&review).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrReviewNotFound
+			}
+			return err
+		}
+		if strings.EqualFold(review.UserID.String(), reporterID) {
+			return ErrOwnReview
+		}
+
+		// xmax = 0 の行は今回新しく作成された通報（更新された行には xmax が入る）
+		var rows []struct {
+			models.ReviewReport
+			Inserted bool
+		}
+		if err := tx.Raw(`
+			INSERT INTO review_reports (review_id, reporter_id, reason, detail)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (review_id, reporter_id) DO UPDATE SET
+				reason = EXCLUDED.reason,
+				detail = EXCLUDED.detail,
+				status = 'open',
+				resolved_by = NULL,
+				resolved_at = NULL
+			RETURNING *, (xmax = 0) AS inserted`, reviewID, reporterID, reason, detail).Scan(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return errors.New("failed to save report")
+		}
+		report = &rows[0].ReviewReport
+		created = rows[0].Inserted
+
+		if review.Status != models.ReviewStatusVisible || s.AutoHideThreshold <= 0 {
+			return nil
+		}
+		var openCount int64
+		if err := tx.Model(&models.ReviewReport{}).
+			Where("review_id = ? AND status = ?", reviewID, models.ReportStatusOpen).
+			Count(&openCount).Error; err != nil {
+			return err
+		}
+		if int(openCount) < s.AutoHideThreshold {
+			return nil
+		}
+		return s.setStatus(tx, &review, models.ReviewStatusHidden, ActionAutoHide, nil, "reported by multiple users")
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return report, created, nil
+}
+
+// Queue は未対応の通報があるレビューを、通報の多い順・新しい順で返す
+func (s *Service) Queue(limit int) ([]QueueItem, error) {
+	var reports []models.ReviewReport
+	if err := s.DB.Where("status = ?", models.ReportStatusOpen).Order("created_at DESC").Find(&reports).Error; err != nil {
+		return nil, err
+	}
+
+	byReview := map[string]*QueueItem{}
+	var order []string
+	for _, r := range reports {
+		id := r.ReviewID.String()
+		item, ok := byReview[id]
+		if !ok {
+			item = &QueueItem{Reasons: map[string]int{}, LastReported: r.CreatedAt}
+			byReview[id] = item
+			order = append(order, id)
+		}
+		item.ReportCount++
+		item.Reasons[r.Reason]++
+		item.Reports = append(item.Reports, r)
+	}
+	if len(order) == 0 {
+		return []QueueItem{}, nil
+	}
+
+	var reviews []models.Review
+	if err := s.DB.Preload("Photos").Where("id IN ?", order).Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range reviews {
+		byReview[r.ID.String()].Review = r
+	}
+
+	items := make([]QueueItem, 0, len(order))
+	for _, id := range order {
+		// 通報後にレビューが削除されていた場合は除外する
+		if item := byReview[id]; item.Review.ID.String() == id {
+			items = append(items, *item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].ReportCount != items[j].ReportCount {
+			return items[i].ReportCount > items[j].ReportCount
+		}
+		return items[i].LastReported.After(items[j].LastReported)
+	})
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+// Hide はレビューを非表示にし、未対応の通報を対応済みにする
+func (s *Service) Hide(reviewID, moderatorID, note string) (*models.Review, error) {
+	return s.moderate(reviewID, moderatorID, note, ActionHide, models.ReviewStatusHidden)
+}
+
+// Restore は非表示にしたレビューを再表示し、未対応の通報を対応済みにする
+func (s *Service) Restore(reviewID, moderatorID, note string) (*models.Review, error) {
+	return s.moderate(reviewID, moderatorID, note, ActionRestore, models.ReviewStatusVisible)
+}
+
+// Dismiss はレビューを変更せずに未対応の通報を対応済みにする（問題なしと判断した場合）
+func (s *Service) Dismiss(reviewID, moderatorID, note string) (*models.Review, error) {
+	return s.moderate(reviewID, moderatorID, note, ActionDismiss, "")
+}
+
+// Delete はレビューを削除する。通報は削除されたレビューと一緒に消えるが、監査ログには内容を残す。
+func (s *Service) Delete(reviewID, moderatorID, note string) error {
+	moderator, err := parseModerator(moderatorID)
+	if err != nil {
+		return err
+	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		review, err := lockReview(tx, reviewID)
+		if err != nil {
+			return err
+		}
+		if err := logAction(tx, review, ActionDelete, moderator, note); err != nil {
+			return err
+		}
+		if err := tx.Where("review_id = ?", reviewID).Delete(&models.ReviewReport{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(review).Error; err != nil {
+			return err
+		}
+		if err := feed.Retract(tx, models.ActivityKindReview, review.ID.String()); err != nil {
+			return err
+		}
+		return aggregates.RefreshReviewStats(tx, review.RecipeID.String())
+	})
+}
+
+// History はレビューに対するモデレーション操作の履歴を古い順で返す
+func (s *Service) History(reviewID string) ([]models.ReviewModerationAction, error) {
+	var actions []models.ReviewModerationAction
+	err := s.DB.Where("review_id = ?", reviewID).Order("created_at").Find(&actions).Error
+	return actions, err
+}
+
+// moderate はレビューの状態を変更し、通報の対応と監査ログの記録を同じトランザクションで行う
+func (s *Service) moderate(reviewID, moderatorID, note, action, status string) (*models.Review, error) {
+	moderator, err := parseModerator(moderatorID)
+	if err != nil {
+		return nil, err
+	}
+	var review *models.Review
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if review, err = lockReview(tx, reviewID); err != nil {
+			return err
+		}
+		if status != "" && status != review.Status {
+			if err := s.setStatus(tx, review, status, action, moderator, note); err != nil {
+				return err
+			}
+		} else if err := logAction(tx, review, action, moderator, note); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"status":      models.ReportStatusResolved,
+			"resolved_at": s.Now(),
+			"resolved_by": moderator,
+		}
+		return tx.Model(&models.ReviewReport{}).
+			Where("review_id = ? AND status = ?", reviewID, models.ReportStatusOpen).
+			Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// setStatus はレビューの公開状態を変更して監査ログを残し、レビュー数・平均評価とフォロワーのフィードを更新する
+func (s *Service) setStatus(tx *gorm.DB, review *models.Review, status, action string, moderatorID *models.UUIDString, note string) error {
+	if err := tx.Model(review).Update("status", status).Error; err != nil {
+		return err
+	}
+	review.Status = status
+	if err := logAction(tx, review, action, moderatorID, note); err != nil {
+		return err
+	}
+	if err := aggregates.RefreshReviewStats(tx, review.RecipeID.String()); err != nil {
+		return err
+	}
+	return feed.SyncReview(tx, review)
+}
+
+func lockReview(tx *gorm.DB, reviewID string) (*models.Review, error) {
+	var review models.Review
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", reviewID).Take(&review).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// parseModerator は手動の操作を行ったモデレーターのIDを検証する（監査ログに必ず残すため省略できない）
+func parseModerator(moderatorID string) (*models.UUIDString, error) {
+	id, err := uuid.Parse(moderatorID)
+	if err != nil {
+		return nil, ErrModeratorRequired
+	}
+	moderator := models.FromUUID(id)
+	return &moderator, nil
+}
+
+// logAction は監査ログを記録する（moderatorID が nil なのは自動非表示の場合のみ）
+func logAction(tx *gorm.DB, review *models.Review, action string, moderatorID *models.UUIDString, note string) error {
+	entry := models.ReviewModerationAction{
+		ReviewID:     review.ID,
+		ModeratorID:  moderatorID,
+		Action:       action,
+		Note:         strings.TrimSpace(note),
+		ReviewUserID: review.UserID,
+		Rating:       review.Rating,
+		Comment:      review.Comment,
+	}
+	return tx.Create(&entry).Error
+}
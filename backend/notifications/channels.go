@@ -0,0 +1,32 @@
+package notifications
+
+import (
+	"fmt"
+	"os"
+)
+
+// ChannelsFromEnv は環境変数で設定されたチャネルを作成する。
+// SMTP_HOST を設定するとメール、NOTIFICATION_WEBHOOK_URL を設定するとWebhookに配信する（どちらも未設定なら受信箱のみ）。
+// NOTIFICATION_CHANNELS=fake の場合は外部に送らず、配信内容をメモリに記録する（ローカル開発用）。
+func ChannelsFromEnv() ([]Channel, error) {
+	if os.Getenv("NOTIFICATION_CHANNELS") == "fake" {
+		return []Channel{NewFake(ChannelEmail), NewFake(ChannelWebhook)}, nil
+	}
+
+	var channels []Channel
+	if cfg := SMTPConfigFromEnv(); cfg.Host != "" {
+		ch, err := NewSMTP(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure email notifications: %v", err)
+		}
+		channels = append(channels, ch)
+	}
+	if cfg := WebhookConfigFromEnv(); cfg.URL != "" {
+		ch, err := NewWebhook(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure webhook notifications: %v", err)
+		}
+		channels = append(channels, ch)
+	}
+	return channels, nil
+}
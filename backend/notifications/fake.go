@@ -0,0 +1,51 @@
+package notifications
+
+import (
+	"context"
+	"sync"
+
+	"portfolio-amarimono/models"
+)
+
+// Delivery はチャネルに配信された通知
+type Delivery struct {
+	Recipient    Recipient
+	Notification models.Notification
+}
+
+// FakeChannel は外部に送らずに配信内容をメモリに記録するチャネル（テストハーネス・ローカル開発用）
+type FakeChannel struct {
+	name       string
+	mu         sync.Mutex
+	deliveries []Delivery
+}
+
+// NewFake は name（ChannelEmail・ChannelWebhook）として振る舞うチャネルを作成する
+func NewFake(name string) *FakeChannel {
+	return &FakeChannel{name: name}
+}
+
+func (ch *FakeChannel) Name() string {
+	return ch.name
+}
+
+func (ch *FakeChannel) Deliver(ctx context.Context, to Recipient, n *models.Notification) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.deliveries = append(ch.deliveries, Delivery{Recipient: to, Notification: *n})
+	return nil
+}
+
+// Deliveries はこれまでに配信された通知を古い順で返す
+func (ch *FakeChannel) Deliveries() []Delivery {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return append([]Delivery(nil), ch.deliveries...)
+}
+
+// Reset は記録した配信内容を消す
+func (ch *FakeChannel) Reset() {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.deliveries = nil
+}
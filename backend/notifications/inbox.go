@@ -0,0 +1,144 @@
+package notifications
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"portfolio-amarimono/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPageSize / MaxPageSize は受信箱の1ページあたりの件数
+	DefaultPageSize = 20
+	MaxPageSize     = 50
+)
+
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrInvalidCursor        = errors.New("invalid cursor")
+)
+
+// ListOptions は受信箱の取得条件
+type ListOptions struct {
+	Cursor     string
+	Limit      int
+	UnreadOnly bool
+}
+
+// Page はカーソルページングされた受信箱
+type Page struct {
+	Notifications []models.Notification `json:"notifications"`
+	UnreadCount   int64                 `json:"unread_count"`
+	NextCursor    string                `json:"next_cursor,omitempty"`
+	HasNextPage   bool                  `json:"has_next_page"`
+}
+
+// cursor は前のページの最後の通知
+type cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// List はユーザーの受信箱を新しい順で返す
+func (s *Service) List(userID string, opts ListOptions) (*Page, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	query := s.DB.Where("user_id = ?", userID)
+	if opts.UnreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if opts.Cursor != "" {
+		cur, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		query = query.Where("(created_at, id) < (?::timestamptz, ?::uuid)", cur.CreatedAt, cur.ID)
+	}
+
+	// 次のページの有無を判定するため1件多く取得する
+	rows := make([]models.Notification, 0, limit+1)
+	if err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	page := &Page{Notifications: rows}
+	if len(rows) > limit {
+		page.Notifications = rows[:limit]
+		page.HasNextPage = true
+		last := page.Notifications[limit-1]
+		page.NextCursor = encodeCursor(cursor{CreatedAt: last.CreatedAt, ID: last.ID.String()})
+	}
+
+	unread, err := s.UnreadCount(userID)
+	if err != nil {
+		return nil, err
+	}
+	page.UnreadCount = unread
+	return page, nil
+}
+
+// UnreadCount はユーザーの未読の通知の件数を返す
+func (s *Service) UnreadCount(userID string) (int64, error) {
+	var count int64
+	err := s.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// MarkRead は本人の通知を既読にする（既読の通知はそのまま返す）
+func (s *Service) MarkRead(userID, notificationID string) (*models.Notification, error) {
+	var n models.Notification
+	err := s.DB.Where("id = ? AND user_id = ?", notificationID, userID).Take(&n).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n.ReadAt != nil {
+		return &n, nil
+	}
+
+	now := s.Now()
+	if err := s.DB.Model(&n).Where("read_at IS NULL").Update("read_at", now).Error; err != nil {
+		return nil, err
+	}
+	n.ReadAt = &now
+	return &n, nil
+}
+
+// MarkAllRead はユーザーの未読の通知をすべて既読にし、既読にした件数を返す
+func (s *Service) MarkAllRead(userID string) (int64, error) {
+	res := s.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Update("read_at", s.Now())
+	return res.RowsAffected, res.Error
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+	if _, err := uuid.Parse(c.ID); err != nil || c.CreatedAt.IsZero() {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,260 @@
+// Package notifications はユーザーへの通知を扱う。
+//
+// 通知はアプリ内の受信箱（notifications テーブル）に保存し、受け取る人の設定に応じて
+// メール・Webhook などのチャネルにも配信する。チャネルへの配信はリクエストとは別に実行し、
+// 失敗してもログに残すだけで元の操作（レビューの投稿・いいねなど）は失敗させない。
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"portfolio-amarimono/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 配信チャネル
+const (
+	ChannelInApp   = "in_app"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Recipient は通知を受け取るユーザー
+type Recipient struct {
+	ID       string
+	Email    string
+	Username string
+}
+
+// Channel はアプリ内の受信箱以外の配信手段（メール・Webhook など）
+type Channel interface {
+	// Name は設定と対応させるチャネル名（ChannelEmail・ChannelWebhook）
+	Name() string
+	Deliver(ctx context.Context, to Recipient, n *models.Notification) error
+}
+
+// Event は通知のきっかけになった出来事
+type Event struct {
+	Type        string
+	RecipientID string
+	// ActorID は操作したユーザー（管理者の操作の場合は空）
+	ActorID  string
+	RecipeID string
+	ReviewID string
+	// Rating はレビューの評価（review_received のみ）
+	Rating int
+}
+
+// Service は通知の作成・配信・受信箱・設定を扱う
+type Service struct {
+	DB       *gorm.DB
+	Channels []Channel
+	// Dispatch はチャネルへの配信を実行する（デフォルトはゴルーチンで非同期に実行）
+	Dispatch func(task func())
+	Now      func() time.Time
+}
+
+// NewService は Service を初期化するコンストラクタ（チャネルは受信箱のみ）
+func NewService(db *gorm.DB) *Service {
+	return &Service{
+		DB:       db,
+		Dispatch: func(task func()) { go task() },
+		Now:      time.Now,
+	}
+}
+
+// RecipeReviewed はレシピにレビューが投稿されたことを投稿者に通知する
+func (s *Service) RecipeReviewed(ctx context.Context, review *models.Review) (*models.Notification, error) {
+	ownerID, err := s.recipeOwner(review.RecipeID.String())
+	if err != nil || ownerID == "" {
+		return nil, err
+	}
+	return s.Notify(ctx, Event{
+		Type:        models.NotificationTypeReviewReceived,
+		RecipientID: ownerID,
+		ActorID:     review.UserID.String(),
+		RecipeID:    review.RecipeID.String(),
+		ReviewID:    review.ID.String(),
+		Rating:      review.Rating,
+	})
+}
+
+// RecipeLiked はレシピにいいねが付いたことを投稿者に通知する。
+// 同じユーザーからの同じレシピへのいいねは、取り消して付け直しても1回だけ通知する。
+func (s *Service) RecipeLiked(ctx context.Context, userID, recipeID string) (*models.Notification, error) {
+	ownerID, err := s.recipeOwner(recipeID)
+	if err != nil || ownerID == "" {
+		return nil, err
+	}
+	var count int64
+	if err := s.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND type = ? AND actor_id = ? AND recipe_id = ?", ownerID, models.NotificationTypeLikeReceived, userID, recipeID).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, nil
+	}
+	return s.Notify(ctx, Event{
+		Type:        models.NotificationTypeLikeReceived,
+		RecipientID: ownerID,
+		ActorID:     userID,
+		RecipeID:    recipeID,
+	})
+}
+
+// RecipePublicationChanged は管理者がレシピを公開・非公開にしたことを投稿者に通知する
+func (s *Service) RecipePublicationChanged(ctx context.Context, recipe *models.Recipe) (*models.Notification, error) {
+	if recipe.UserID == nil {
+		return nil, nil
+	}
+	typ := models.NotificationTypeRecipeUnpublished
+	if recipe.IsPublic && !recipe.IsDraft {
+		typ = models.NotificationTypeRecipePublished
+	}
+	return s.Notify(ctx, Event{
+		Type:        typ,
+		RecipientID: recipe.UserID.String(),
+		RecipeID:    recipe.ID.String(),
+	})
+}
+
+// Notify は通知を作成し、受け取る人の設定に従って受信箱への保存とチャネルへの配信を行う。
+// 自分自身の操作・退会手続き中のユーザー・すべての配信先を無効にしている種類の場合は何もせずに nil を返す。
+func (s *Service) Notify(ctx context.Context, ev Event) (*models.Notification, error) {
+	if ev.ActorID != "" && strings.EqualFold(ev.ActorID, ev.RecipientID) {
+		return nil, nil
+	}
+
+	db := s.DB.WithContext(ctx)
+	var user models.User
+	err := db.Select("id", "email", "username").Where("id = ? AND deleted_at IS NULL", ev.RecipientID).Take(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pref, err := s.Preference(ev.RecipientID, ev.Type)
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]Channel, 0, len(s.Channels))
+	for _, ch := range s.Channels {
+		if enabled(pref, ch.Name()) {
+			channels = append(channels, ch)
+		}
+	}
+	if !pref.InApp && len(channels) == 0 {
+		return nil, nil
+	}
+
+	n, err := s.compose(ev)
+	if err != nil {
+		return nil, err
+	}
+	if pref.InApp {
+		if err := db.Create(n).Error; err != nil {
+			return nil, fmt.Errorf("failed to save notification: %v", err)
+		}
+	}
+
+	to := Recipient{ID: user.ID, Email: user.Email}
+	if user.Username != nil {
+		to.Username = *user.Username
+	}
+	for _, ch := range channels {
+		ch := ch
+		s.Dispatch(func() {
+			if err := ch.Deliver(context.Background(), to, n); err != nil {
+				log.Printf("⚠️ Failed to deliver %s notification to %s via %s: %v", n.Type, to.ID, ch.Name(), err)
+			}
+		})
+	}
+	return n, nil
+}
+
+// compose は出来事から通知の文面を作る
+func (s *Service) compose(ev Event) (*models.Notification, error) {
+	recipientID, err := uuid.Parse(ev.RecipientID)
+	if err != nil {
+		return nil, err
+	}
+	n := &models.Notification{
+		UserID:    models.FromUUID(recipientID),
+		Type:      ev.Type,
+		ActorID:   optionalUUID(ev.ActorID),
+		RecipeID:  optionalUUID(ev.RecipeID),
+		ReviewID:  optionalUUID(ev.ReviewID),
+		CreatedAt: s.Now(),
+	}
+
+	recipeName := "レシピ"
+	if ev.RecipeID != "" {
+		var names []string
+		if err := s.DB.Model(&models.Recipe{}).Where("id = ?", ev.RecipeID).Pluck("name", &names).Error; err != nil {
+			return nil, err
+		}
+		if len(names) > 0 && names[0] != "" {
+			recipeName = names[0]
+		}
+	}
+	actorName := "ユーザー"
+	if ev.ActorID != "" {
+		var names []*string
+		if err := s.DB.Model(&models.User{}).Where("id = ?", ev.ActorID).Pluck("username", &names).Error; err != nil {
+			return nil, err
+		}
+		if len(names) > 0 && names[0] != nil && *names[0] != "" {
+			actorName = *names[0]
+		}
+	}
+
+	switch ev.Type {
+	case models.NotificationTypeReviewReceived:
+		n.Title = "レシピにレビューが届きました"
+		n.Body = fmt.Sprintf("%sさんが「%s」を★%dで評価しました", actorName, recipeName, ev.Rating)
+	case models.NotificationTypeLikeReceived:
+		n.Title = "レシピにいいねが付きました"
+		n.Body = fmt.Sprintf("%sさんが「%s」にいいねしました", actorName, recipeName)
+	case models.NotificationTypeRecipePublished:
+		n.Title = "レシピが公開されました"
+		n.Body = fmt.Sprintf("「%s」が公開されました", recipeName)
+	case models.NotificationTypeRecipeUnpublished:
+		n.Title = "レシピが非公開になりました"
+		n.Body = fmt.Sprintf("「%s」は管理者により非公開になりました", recipeName)
+	default:
+		return nil, ErrUnknownType
+	}
+	return n, nil
+}
+
+// recipeOwner はレシピの投稿者のIDを返す（投稿者のいないレシピ・存在しないレシピは空文字）
+func (s *Service) recipeOwner(recipeID string) (string, error) {
+	var recipe models.Recipe
+	err := s.DB.Select("id", "user_id").Where("id = ?", recipeID).Take(&recipe).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil || recipe.UserID == nil {
+		return "", err
+	}
+	return recipe.UserID.String(), nil
+}
+
+func optionalUUID(s string) *models.UUIDString {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return nil
+	}
+	v := models.FromUUID(id)
+	return &v
+}
@@ -0,0 +1,275 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/testharness/pgtest"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		body   string
+		want   string
+	}{
+		// RFC 4231 のテストケース2
+		{"rfc 4231", "Jefe", "what do ya want for nothing?", "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"},
+		{"empty body", "secret", "", "f9e66e179b6747ae54108f82f8ade8b3c25d76fd30afde6c395822c530196169"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, []byte(tt.body)); got != tt.want {
+				t.Errorf("Sign = %s, want %s", got, tt.want)
+			}
+		})
+	}
+	if Sign("a", []byte("body")) == Sign("b", []byte("body")) {
+		t.Error("signatures with different secrets are equal")
+	}
+}
+
+func TestWebhookDeliver(t *testing.T) {
+	tests := []struct {
+		name       string
+		secret     string
+		status     int
+		wantSigned bool
+		wantErr    bool
+	}{
+		{"signed", "secret", http.StatusNoContent, true, false},
+		{"unsigned", "", http.StatusOK, false, false},
+		{"error status", "secret", http.StatusBadGateway, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body []byte
+			var signature string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+				signature = r.Header.Get(SignatureHeader)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			ch, err := NewWebhook(WebhookConfig{URL: server.URL, Secret: tt.secret, Timeout: time.Second})
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := &models.Notification{Type: models.NotificationTypeLikeReceived, Title: "title", Body: "body"}
+			err = ch.Deliver(context.Background(), Recipient{ID: "user", Email: "user@example.com", Username: "name"}, n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantSigned {
+				if want := "sha256=" + Sign(tt.secret, body); signature != want {
+					t.Errorf("signature = %q, want %q", signature, want)
+				}
+			} else if signature != "" {
+				t.Errorf("unexpected signature %q", signature)
+			}
+			// メールアドレスは送らない
+			if strings.Contains(string(body), "user@example.com") {
+				t.Errorf("payload contains the email address: %s", body)
+			}
+			var payload webhookPayload
+			if err := json.Unmarshal(body, &payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload.Recipient.ID != "user" || payload.Recipient.Username != "name" || payload.Notification.Title != "title" {
+				t.Errorf("payload = %s", body)
+			}
+		})
+	}
+
+	if _, err := NewWebhook(WebhookConfig{}); err == nil {
+		t.Error("expected an error without a URL")
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	pref := models.NotificationPreference{InApp: true, Email: false, Webhook: true}
+	for channel, want := range map[string]bool{ChannelInApp: true, ChannelEmail: false, ChannelWebhook: true, "push": true} {
+		if got := enabled(pref, channel); got != want {
+			t.Errorf("enabled(%s) = %v, want %v", channel, got, want)
+		}
+	}
+}
+
+const (
+	actorID  = "00000000-0000-4000-8000-000000000001"
+	recipeID = "00000000-0000-4000-8000-0000000000a1"
+)
+
+func TestNotify(t *testing.T) {
+	db := pgtest.Open(t)
+	createUser(t, db, actorID, "たろう", nil)
+	if err := db.Exec("INSERT INTO recipes (id, name, user_id) VALUES (?, '肉じゃが', ?)", recipeID, actorID).Error; err != nil {
+		t.Fatal(err)
+	}
+	email, webhook := NewFake(ChannelEmail), NewFake(ChannelWebhook)
+	s := NewService(db)
+	s.Channels = []Channel{email, webhook}
+	s.Dispatch = func(task func()) { task() }
+
+	deletedAt := time.Now()
+	tests := []struct {
+		name string
+		// pref が nil なら設定を保存しない（すべて有効）
+		pref         *models.NotificationPreference
+		deletedAt    *time.Time
+		ownAction    bool
+		typ          string
+		wantCreated  bool
+		wantInbox    bool
+		wantChannels []string
+		wantErr      error
+	}{
+		{"defaults", nil, nil, false, models.NotificationTypeReviewReceived, true, true, []string{ChannelEmail, ChannelWebhook}, nil},
+		{"email off", &models.NotificationPreference{InApp: true, Webhook: true}, nil, false, models.NotificationTypeReviewReceived, true, true, []string{ChannelWebhook}, nil},
+		{"inbox off", &models.NotificationPreference{Email: true}, nil, false, models.NotificationTypeReviewReceived, true, false, []string{ChannelEmail}, nil},
+		{"only inbox", &models.NotificationPreference{InApp: true}, nil, false, models.NotificationTypeReviewReceived, true, true, []string{}, nil},
+		{"everything off", &models.NotificationPreference{}, nil, false, models.NotificationTypeReviewReceived, false, false, []string{}, nil},
+		{"other types keep defaults", &models.NotificationPreference{}, nil, false, models.NotificationTypeLikeReceived, true, true, []string{ChannelEmail, ChannelWebhook}, nil},
+		{"own action", nil, nil, true, models.NotificationTypeReviewReceived, false, false, []string{}, nil},
+		{"pending deletion", nil, &deletedAt, false, models.NotificationTypeReviewReceived, false, false, []string{}, nil},
+		{"unknown type", nil, nil, false, "unknown", false, false, []string{}, ErrUnknownType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email.Reset()
+			webhook.Reset()
+			recipientID := uuid.New().String()
+			createUser(t, db, recipientID, "", tt.deletedAt)
+			if tt.pref != nil {
+				pref := *tt.pref
+				pref.Type = models.NotificationTypeReviewReceived
+				if _, err := s.UpdatePreferences(recipientID, []models.NotificationPreference{pref}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ev := Event{Type: tt.typ, RecipientID: recipientID, ActorID: actorID, RecipeID: recipeID, Rating: 4}
+			if tt.ownAction {
+				ev.ActorID = strings.ToUpper(recipientID)
+			}
+
+			n, err := s.Notify(context.Background(), ev)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if (n != nil) != tt.wantCreated {
+				t.Fatalf("notification = %+v, want created %v", n, tt.wantCreated)
+			}
+
+			var inbox int64
+			if err := db.Model(&models.Notification{}).Where("user_id = ?", recipientID).Count(&inbox).Error; err != nil {
+				t.Fatal(err)
+			}
+			if (inbox > 0) != tt.wantInbox {
+				t.Errorf("inbox = %d, want saved %v", inbox, tt.wantInbox)
+			}
+
+			channels := []string{}
+			for _, ch := range []*FakeChannel{email, webhook} {
+				for _, d := range ch.Deliveries() {
+					channels = append(channels, ch.Name())
+					if d.Recipient.ID != recipientID || d.Recipient.Email != recipientID+"@example.com" {
+						t.Errorf("%s recipient = %+v", ch.Name(), d.Recipient)
+					}
+				}
+			}
+			if !reflect.DeepEqual(channels, tt.wantChannels) {
+				t.Errorf("channels = %v, want %v", channels, tt.wantChannels)
+			}
+		})
+	}
+}
+
+func TestComposeBody(t *testing.T) {
+	db := pgtest.Open(t)
+	createUser(t, db, actorID, "たろう", nil)
+	if err := db.Exec("INSERT INTO recipes (id, name) VALUES (?, '肉じゃが')", recipeID).Error; err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(db)
+	recipientID := uuid.New().String()
+
+	tests := []struct {
+		ev        Event
+		wantTitle string
+		wantBody  string
+	}{
+		{Event{Type: models.NotificationTypeReviewReceived, ActorID: actorID, RecipeID: recipeID, Rating: 4}, "レシピにレビューが届きました", "たろうさんが「肉じゃが」を★4で評価しました"},
+		{Event{Type: models.NotificationTypeLikeReceived, ActorID: uuid.New().String(), RecipeID: recipeID}, "レシピにいいねが付きました", "ユーザーさんが「肉じゃが」にいいねしました"},
+		{Event{Type: models.NotificationTypeRecipePublished, RecipeID: recipeID}, "レシピが公開されました", "「肉じゃが」が公開されました"},
+		{Event{Type: models.NotificationTypeRecipeUnpublished, RecipeID: uuid.New().String()}, "レシピが非公開になりました", "「レシピ」は管理者により非公開になりました"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ev.Type, func(t *testing.T) {
+			tt.ev.RecipientID = recipientID
+			n, err := s.compose(tt.ev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n.Title != tt.wantTitle || n.Body != tt.wantBody {
+				t.Errorf("notification = %q / %q, want %q / %q", n.Title, n.Body, tt.wantTitle, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRecipeLikedOnce(t *testing.T) {
+	db := pgtest.Open(t)
+	ownerID := uuid.New().String()
+	createUser(t, db, ownerID, "", nil)
+	createUser(t, db, actorID, "", nil)
+	if err := db.Exec("INSERT INTO recipes (id, name, user_id) VALUES (?, '肉じゃが', ?)", recipeID, ownerID).Error; err != nil {
+		t.Fatal(err)
+	}
+	webhook := NewFake(ChannelWebhook)
+	s := NewService(db)
+	s.Channels = []Channel{webhook}
+	s.Dispatch = func(task func()) { task() }
+
+	// いいねを取り消して付け直しても通知は1回だけ
+	for i, wantCreated := range []bool{true, false, false} {
+		n, err := s.RecipeLiked(context.Background(), actorID, recipeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (n != nil) != wantCreated {
+			t.Errorf("like %d: notification = %+v, want created %v", i+1, n, wantCreated)
+		}
+	}
+	if got := len(webhook.Deliveries()); got != 1 {
+		t.Errorf("deliveries = %d, want 1", got)
+	}
+}
+
+func createUser(t *testing.T, db *gorm.DB, id, username string, deletedAt *time.Time) {
+	t.Helper()
+	var name interface{}
+	if username != "" {
+		name = username
+	}
+	if err := db.Exec("INSERT INTO users (id, email, username, deleted_at) VALUES (?, ?, ?, ?)", id, id+"@example.com", name, deletedAt).Error; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,109 @@
+package notifications
+
+import (
+	"errors"
+
+	"portfolio-amarimono/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrUnknownType = errors.New("unknown notification type")
+
+// DefaultPreference は設定を保存していない種類の通知の配信先（すべて有効）
+func DefaultPreference(typ string) models.NotificationPreference {
+	return models.NotificationPreference{Type: typ, InApp: true, Email: true, Webhook: true}
+}
+
+// IsKnownType は通知の種類として有効かどうかを返す
+func IsKnownType(typ string) bool {
+	for _, t := range models.NotificationTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Preference はユーザーの1種類の通知の設定を返す
+func (s *Service) Preference(userID, typ string) (models.NotificationPreference, error) {
+	var prefs []models.NotificationPreference
+	if err := s.DB.Where("user_id = ? AND type = ?", userID, typ).Limit(1).Find(&prefs).Error; err != nil {
+		return models.NotificationPreference{}, err
+	}
+	if len(prefs) == 0 {
+		return DefaultPreference(typ), nil
+	}
+	return prefs[0], nil
+}
+
+// Preferences はユーザーのすべての種類の通知の設定を models.NotificationTypes の順で返す
+func (s *Service) Preferences(userID string) ([]models.NotificationPreference, error) {
+	var saved []models.NotificationPreference
+	if err := s.DB.Where("user_id = ?", userID).Find(&saved).Error; err != nil {
+		return nil, err
+	}
+	byType := make(map[string]models.NotificationPreference, len(saved))
+	for _, p := range saved {
+		byType[p.Type] = p
+	}
+	prefs := make([]models.NotificationPreference, 0, len(models.NotificationTypes))
+	for _, typ := range models.NotificationTypes {
+		if p, ok := byType[typ]; ok {
+			prefs = append(prefs, p)
+		} else {
+			prefs = append(prefs, DefaultPreference(typ))
+		}
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences は指定した種類の通知の設定を保存し、更新後のすべての設定を返す
+func (s *Service) UpdatePreferences(userID string, prefs []models.NotificationPreference) ([]models.NotificationPreference, error) {
+	ownerID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range prefs {
+		if !IsKnownType(p.Type) {
+			return nil, ErrUnknownType
+		}
+	}
+
+	now := s.Now()
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		for _, p := range prefs {
+			if err := tx.Exec(`
+				INSERT INTO notification_preferences (user_id, type, in_app, email, webhook, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?)
+				ON CONFLICT (user_id, type) DO UPDATE SET
+					in_app = EXCLUDED.in_app,
+					email = EXCLUDED.email,
+					webhook = EXCLUDED.webhook,
+					updated_at = EXCLUDED.updated_at`,
+				ownerID.String(), p.Type, p.InApp, p.Email, p.Webhook, now).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Preferences(userID)
+}
+
+// enabled は設定でチャネルへの配信が有効かどうかを返す（設定項目のないチャネルは常に有効）
+func enabled(pref models.NotificationPreference, channel string) bool {
+	switch channel {
+	case ChannelInApp:
+		return pref.InApp
+	case ChannelEmail:
+		return pref.Email
+	case ChannelWebhook:
+		return pref.Webhook
+	default:
+		return true
+	}
+}
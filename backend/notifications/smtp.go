@@ -0,0 +1,86 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+
+	"portfolio-amarimono/models"
+)
+
+// SMTPConfig はメール送信に使うSMTPサーバーの設定
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPConfigFromEnv は環境変数から設定を作成する
+func SMTPConfigFromEnv() SMTPConfig {
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	return SMTPConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+}
+
+// SMTPChannel は通知をメールで送るチャネル
+type SMTPChannel struct {
+	cfg  SMTPConfig
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTP はSMTPでメールを送るチャネルを作成する
+func NewSMTP(cfg SMTPConfig) (*SMTPChannel, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("SMTP_HOST is not set")
+	}
+	if cfg.From == "" {
+		return nil, fmt.Errorf("SMTP_FROM is not set")
+	}
+	return &SMTPChannel{cfg: cfg, send: smtp.SendMail}, nil
+}
+
+func (ch *SMTPChannel) Name() string {
+	return ChannelEmail
+}
+
+// Deliver は通知のタイトルを件名、本文をテキストとしてメールを送る（メールアドレスのないユーザーには送らない）
+func (ch *SMTPChannel) Deliver(ctx context.Context, to Recipient, n *models.Notification) error {
+	if to.Email == "" {
+		return nil
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", ch.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to.Email)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", n.Title))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(n.Body)
+	msg.WriteString("\r\n\r\n通知の受け取り方はマイページの通知設定から変更できます。\r\n")
+
+	var auth smtp.Auth
+	if ch.cfg.Username != "" {
+		auth = smtp.PlainAuth("", ch.cfg.Username, ch.cfg.Password, ch.cfg.Host)
+	}
+	addr := net.JoinHostPort(ch.cfg.Host, ch.cfg.Port)
+	if err := ch.send(addr, auth, ch.cfg.From, []string{to.Email}, msg.Bytes()); err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"portfolio-amarimono/models"
+)
+
+// SignatureHeader はWebhookの本文の HMAC-SHA256 署名を入れるヘッダー
+const SignatureHeader = "X-Amarimono-Signature"
+
+// WebhookConfig は通知をPOSTするWebhookの設定
+type WebhookConfig struct {
+	URL string
+	// Secret を設定すると本文の署名を SignatureHeader に付ける（受信側で送信元を検証できる）
+	Secret  string
+	Timeout time.Duration
+}
+
+// WebhookConfigFromEnv は環境変数から設定を作成する
+func WebhookConfigFromEnv() WebhookConfig {
+	return WebhookConfig{
+		URL:     os.Getenv("NOTIFICATION_WEBHOOK_URL"),
+		Secret:  os.Getenv("NOTIFICATION_WEBHOOK_SECRET"),
+		Timeout: 10 * time.Second,
+	}
+}
+
+// WebhookChannel は通知をJSONでWebhookにPOSTするチャネル（プッシュ通知やチャットへの転送に使う）
+type WebhookChannel struct {
+	cfg    WebhookConfig
+	client *http.Client
+}
+
+// webhookPayload はWebhookに送る本文（メールアドレスは含めない）
+type webhookPayload struct {
+	Recipient struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"recipient"`
+	Notification *models.Notification `json:"notification"`
+}
+
+// NewWebhook はWebhookに配信するチャネルを作成する
+func NewWebhook(cfg WebhookConfig) (*WebhookChannel, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("NOTIFICATION_WEBHOOK_URL is not set")
+	}
+	return &WebhookChannel{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}, nil
+}
+
+func (ch *WebhookChannel) Name() string {
+	return ChannelWebhook
+}
+
+// Deliver は通知をPOSTし、2xx 以外の応答はエラーにする
+func (ch *WebhookChannel) Deliver(ctx context.Context, to Recipient, n *models.Notification) error {
+	var payload webhookPayload
+	payload.Recipient.ID = to.ID
+	payload.Recipient.Username = to.Username
+	payload.Notification = n
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ch.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if ch.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(ch.cfg.Secret, body))
+	}
+
+	resp, err := ch.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign は本文の HMAC-SHA256 署名を16進数で返す
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
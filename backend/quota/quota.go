@@ -0,0 +1,269 @@
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Feature はAI機能ごとの使用回数の区分
+type Feature string
+
+const (
+	FeatureDescription Feature = "description"
+	FeatureFAQ         Feature = "faq"
+	FeatureSteps       Feature = "steps"
+	FeatureTips        Feature = "tips"
+)
+
+// Features は使用回数を管理するすべての機能
+var Features = []Feature{FeatureDescription, FeatureFAQ, FeatureSteps, FeatureTips}
+
+// Valid は定義されている機能かを返す
+func (f Feature) Valid() bool {
+	for _, known := range Features {
+		if f == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Window は使用回数がリセットされる周期
+type Window string
+
+const (
+	WindowDaily   Window = "daily"
+	WindowMonthly Window = "monthly"
+)
+
+// Limit は1周期あたりの上限
+type Limit struct {
+	Max    int
+	Window Window
+}
+
+// Plan はロールごとの上限の設定
+type Plan struct {
+	Name      string
+	Unlimited bool
+	// Limits は機能ごとの上限。定義されていない機能には Default を使う
+	Limits  map[Feature]Limit
+	Default Limit
+}
+
+// LimitFor は機能の上限を返す
+func (p Plan) LimitFor(feature Feature) Limit {
+	if l, ok := p.Limits[feature]; ok {
+		return l
+	}
+	return p.Default
+}
+
+// Plans はuser_roles.roleに対応するプラン。管理者は無制限。
+var Plans = map[string]Plan{
+	"user": {
+		Name: "user",
+		Limits: map[Feature]Limit{
+			FeatureDescription: {Max: 10, Window: WindowMonthly},
+			FeatureFAQ:         {Max: 10, Window: WindowMonthly},
+			FeatureSteps:       {Max: 10, Window: WindowMonthly},
+			FeatureTips:        {Max: 10, Window: WindowMonthly},
+		},
+		Default: Limit{Max: 10, Window: WindowMonthly},
+	},
+	"admin": {
+		Name:      "admin",
+		Unlimited: true,
+		Default:   Limit{Window: WindowMonthly},
+	},
+}
+
+// ErrQuotaExceeded は上限に達している場合のエラー
+var ErrQuotaExceeded = errors.New("ai quota exceeded")
+
+// リセットの基準は日本時間
+var jst = time.FixedZone("JST", 9*60*60)
+
+// Status は機能ごとの使用状況
+type Status struct {
+	Feature   Feature   `json:"feature"`
+	Plan      string    `json:"plan"`
+	Used      int       `json:"usage_count"`
+	Limit     int       `json:"usage_limit"`
+	Unlimited bool      `json:"unlimited"`
+	Window    Window    `json:"window"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// Service はAIの使用回数を管理する
+type Service struct {
+	DB  *gorm.DB
+	Now func() time.Time
+}
+
+// NewService はServiceを作成する
+func NewService(db *gorm.DB) *Service {
+	return &Service{DB: db, Now: time.Now}
+}
+
+// WindowStart は now を含む周期の開始時刻を返す
+func WindowStart(w Window, now time.Time) time.Time {
+	t := now.In(jst)
+	if w == WindowDaily {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, jst)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, jst)
+}
+
+// NextReset は now を含む周期の次のリセット時刻を返す
+func NextReset(w Window, now time.Time) time.Time {
+	start := WindowStart(w, now)
+	if w == WindowDaily {
+		return start.AddDate(0, 0, 1)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// PlanFor はユーザーのロールからプランを返す（ロールが無い場合はuser）
+func (s *Service) PlanFor(userID string) (Plan, error) {
+	var roles []string
+	if err := s.DB.Table("user_roles").Where("user_id = ?", userID).Pluck("role", &roles).Error; err != nil {
+		return Plan{}, fmt.Errorf("failed to fetch user role: %v", err)
+	}
+	for _, role := range roles {
+		if plan, ok := Plans[role]; ok {
+			return plan, nil
+		}
+	}
+	return Plans["user"], nil
+}
+
+// Status は機能の現在の使用状況を返す。前回のリセットが現在の周期より前の場合は0回として扱う。
+func (s *Service) Status(userID string, feature Feature) (*Status, error) {
+	plan, err := s.PlanFor(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.Now()
+	limit := plan.LimitFor(feature)
+	status := &Status{
+		Feature:   feature,
+		Plan:      plan.Name,
+		Limit:     limit.Max,
+		Unlimited: plan.Unlimited,
+		Window:    limit.Window,
+		ResetsAt:  NextReset(limit.Window, now),
+	}
+
+	var usage struct {
+		UsageCount    int
+		LastResetDate time.Time
+	}
+	err = s.DB.Table("ai_usage").
+		Select("usage_count", "last_reset_date").
+		Where("user_id = ? AND feature = ?", userID, feature).
+		Take(&usage).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to fetch ai usage: %v", err)
+	}
+	if err == nil && !usage.LastResetDate.Before(WindowStart(limit.Window, now)) {
+		status.Used = usage.UsageCount
+	}
+	return status, nil
+}
+
+// Statuses はプランに定義されているすべての機能の使用状況を返す
+func (s *Service) Statuses(userID string) ([]Status, error) {
+	plan, err := s.PlanFor(userID)
+	if err != nil {
+		return nil, err
+	}
+	features := []Feature{FeatureDescription}
+	for f := range plan.Limits {
+		if f != FeatureDescription {
+			features = append(features, f)
+		}
+	}
+	// 説明文生成を先頭に、それ以外は名前順に並べる
+	sort.Slice(features, func(i, j int) bool {
+		if features[i] == FeatureDescription || features[j] == FeatureDescription {
+			return features[i] == FeatureDescription
+		}
+		return features[i] < features[j]
+	})
+
+	statuses := make([]Status, 0, len(features))
+	for _, f := range features {
+		status, err := s.Status(userID, f)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// Consume は上限の確認と使用回数の加算を1つのSQLで行う。
+// 周期が変わっている場合は1回目として数え直す。上限に達している場合は ErrQuotaExceeded を返す。
+func (s *Service) Consume(userID string, feature Feature) (*Status, error) {
+	plan, err := s.PlanFor(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.Now()
+	limit := plan.LimitFor(feature)
+	windowStart := WindowStart(limit.Window, now)
+
+	max := limit.Max
+	if plan.Unlimited {
+		// 無制限でも集計のために回数は記録する
+		max = int(^uint32(0) >> 1)
+	}
+	if max <= 0 {
+		return nil, ErrQuotaExceeded
+	}
+
+	var counts []int
+	err = s.DB.Raw(`
+		INSERT INTO ai_usage (id, user_id, feature, usage_count, last_reset_date, created_at, updated_at)
+		VALUES (?, ?, ?, 1, ?, ?, ?)
+		ON CONFLICT (user_id, feature) DO UPDATE SET
+			usage_count = CASE WHEN ai_usage.last_reset_date < EXCLUDED.last_reset_date THEN 1 ELSE ai_usage.usage_count + 1 END,
+			last_reset_date = CASE WHEN ai_usage.last_reset_date < EXCLUDED.last_reset_date THEN EXCLUDED.last_reset_date ELSE ai_usage.last_reset_date END,
+			updated_at = EXCLUDED.updated_at
+		WHERE ai_usage.last_reset_date < EXCLUDED.last_reset_date OR ai_usage.usage_count < ?
+		RETURNING usage_count`,
+		uuid.New().String(), userID, feature, windowStart, now, now, max,
+	).Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to update ai usage: %v", err)
+	}
+	if len(counts) == 0 {
+		return nil, ErrQuotaExceeded
+	}
+
+	return &Status{
+		Feature:   feature,
+		Plan:      plan.Name,
+		Used:      counts[0],
+		Limit:     limit.Max,
+		Unlimited: plan.Unlimited,
+		Window:    limit.Window,
+		ResetsAt:  NextReset(limit.Window, now),
+	}, nil
+}
+
+// Refund は生成に失敗した場合などに、Consumeで加算した1回分を戻す
+func (s *Service) Refund(userID string, feature Feature) error {
+	err := s.DB.Exec(`UPDATE ai_usage SET usage_count = GREATEST(usage_count - 1, 0), updated_at = ? WHERE user_id = ? AND feature = ?`,
+		s.Now(), userID, feature).Error
+	if err != nil {
+		return fmt.Errorf("failed to refund ai usage: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package quota
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"portfolio-amarimono/testharness/pgtest"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		name      string
+		window    Window
+		now       time.Time
+		wantStart time.Time
+		wantReset time.Time
+	}{
+		{"daily", WindowDaily, time.Date(2026, 3, 10, 12, 0, 0, 0, jst), time.Date(2026, 3, 10, 0, 0, 0, 0, jst), time.Date(2026, 3, 11, 0, 0, 0, 0, jst)},
+		{"daily before JST midnight in UTC", WindowDaily, time.Date(2026, 3, 10, 15, 30, 0, 0, time.UTC), time.Date(2026, 3, 11, 0, 0, 0, 0, jst), time.Date(2026, 3, 12, 0, 0, 0, 0, jst)},
+		{"monthly", WindowMonthly, time.Date(2026, 3, 10, 12, 0, 0, 0, jst), time.Date(2026, 3, 1, 0, 0, 0, 0, jst), time.Date(2026, 4, 1, 0, 0, 0, 0, jst)},
+		{"monthly crosses into next month in JST", WindowMonthly, time.Date(2026, 1, 31, 15, 30, 0, 0, time.UTC), time.Date(2026, 2, 1, 0, 0, 0, 0, jst), time.Date(2026, 3, 1, 0, 0, 0, 0, jst)},
+		{"monthly at year end", WindowMonthly, time.Date(2026, 12, 31, 23, 59, 0, 0, jst), time.Date(2026, 12, 1, 0, 0, 0, 0, jst), time.Date(2027, 1, 1, 0, 0, 0, 0, jst)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WindowStart(tt.window, tt.now); !got.Equal(tt.wantStart) {
+				t.Errorf("WindowStart = %v, want %v", got, tt.wantStart)
+			}
+			if got := NextReset(tt.window, tt.now); !got.Equal(tt.wantReset) {
+				t.Errorf("NextReset = %v, want %v", got, tt.wantReset)
+			}
+		})
+	}
+}
+
+func TestPlanLimitFor(t *testing.T) {
+	plan := Plan{
+		Limits:  map[Feature]Limit{FeatureFAQ: {Max: 3, Window: WindowDaily}},
+		Default: Limit{Max: 10, Window: WindowMonthly},
+	}
+	if got := plan.LimitFor(FeatureFAQ); got != (Limit{Max: 3, Window: WindowDaily}) {
+		t.Errorf("LimitFor(faq) = %+v", got)
+	}
+	if got := plan.LimitFor(FeatureTips); got != plan.Default {
+		t.Errorf("LimitFor(tips) = %+v, want default", got)
+	}
+}
+
+func TestFeatureValid(t *testing.T) {
+	for _, f := range Features {
+		if !f.Valid() {
+			t.Errorf("%s is not valid", f)
+		}
+	}
+	for _, f := range []Feature{"", "unknown", "FAQ"} {
+		if f.Valid() {
+			t.Errorf("%q is valid", f)
+		}
+	}
+}
+
+func TestConsume(t *testing.T) {
+	db := pgtest.Open(t)
+	userID := createUser(t, db, "")
+	s := NewService(db)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, jst)
+	s.Now = func() time.Time { return now }
+	max := Plans["user"].LimitFor(FeatureDescription).Max
+
+	for i := 1; i <= max; i++ {
+		status, err := s.Consume(userID, FeatureDescription)
+		if err != nil {
+			t.Fatalf("consume %d: %v", i, err)
+		}
+		if status.Used != i || status.Limit != max {
+			t.Fatalf("consume %d: used %d/%d", i, status.Used, status.Limit)
+		}
+	}
+
+	steps := []struct {
+		name     string
+		now      time.Time
+		refund   bool
+		feature  Feature
+		wantErr  error
+		wantUsed int
+	}{
+		{"over the limit", now, false, FeatureDescription, ErrQuotaExceeded, max},
+		{"other features are counted separately", now, false, FeatureFAQ, nil, 1},
+		{"refund frees one use", now, true, FeatureDescription, nil, max - 1},
+		{"consume after refund", now, false, FeatureDescription, nil, max},
+		{"still over the limit later in the month", now.AddDate(0, 0, 15), false, FeatureDescription, ErrQuotaExceeded, max},
+		{"status is reset in the next month", time.Date(2026, 4, 1, 0, 0, 0, 0, jst), false, "", nil, 0},
+		{"next month starts from one", time.Date(2026, 4, 1, 0, 0, 0, 0, jst), false, FeatureDescription, nil, 1},
+	}
+	for _, step := range steps {
+		now = step.now
+		switch {
+		case step.refund:
+			if err := s.Refund(userID, FeatureDescription); err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+		case step.feature != "":
+			status, err := s.Consume(userID, step.feature)
+			if !errors.Is(err, step.wantErr) {
+				t.Fatalf("%s: err = %v, want %v", step.name, err, step.wantErr)
+			}
+			if err == nil && status.Used != step.wantUsed {
+				t.Errorf("%s: consume used = %d, want %d", step.name, status.Used, step.wantUsed)
+			}
+		}
+		status, err := s.Status(userID, FeatureDescription)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if step.feature != FeatureFAQ && status.Used != step.wantUsed {
+			t.Errorf("%s: status used = %d, want %d", step.name, status.Used, step.wantUsed)
+		}
+	}
+}
+
+func TestRefundDoesNotGoBelowZero(t *testing.T) {
+	db := pgtest.Open(t)
+	userID := createUser(t, db, "")
+	s := NewService(db)
+
+	if _, err := s.Consume(userID, FeatureTips); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Refund(userID, FeatureTips); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status, err := s.Status(userID, FeatureTips)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Used != 0 {
+		t.Errorf("used = %d, want 0", status.Used)
+	}
+}
+
+func TestConsumeUnlimited(t *testing.T) {
+	db := pgtest.Open(t)
+	adminID := createUser(t, db, "admin")
+	s := NewService(db)
+
+	var status *Status
+	var err error
+	for i := 0; i < Plans["user"].Default.Max+5; i++ {
+		if status, err = s.Consume(adminID, FeatureDescription); err != nil {
+			t.Fatalf("consume %d: %v", i+1, err)
+		}
+	}
+	if !status.Unlimited || status.Plan != "admin" || status.Used != Plans["user"].Default.Max+5 {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+// createUser はユーザー（role が空でなければそのロールも）を作成してIDを返す
+func createUser(t *testing.T, db *gorm.DB, role string) string {
+	t.Helper()
+	id := uuid.New().String()
+	if err := db.Exec("INSERT INTO users (id, email) VALUES (?, ?)", id, id+"@example.com").Error; err != nil {
+		t.Fatal(err)
+	}
+	if role != "" {
+		if err := db.Exec("INSERT INTO user_roles (user_id, role) VALUES (?, ?)", id, role).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return id
+}
@@ -0,0 +1,257 @@
+package ranking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// Kind はランキングの種類
+type Kind string
+
+const (
+	// KindTrending はいいねの勢い（新しいいいねほど重い）
+	KindTrending Kind = "trending"
+	// KindTopRated は評価のベイズ平均（レビューが少ないレシピは全体の平均に寄せる）
+	KindTopRated Kind = "top_rated"
+	// KindMostCooked は作ったユーザーの数（「作った」記録かレビューのあるユーザーを作ったユーザーとして数える）
+	KindMostCooked Kind = "most_cooked"
+)
+
+// Kinds はすべてのランキングの種類
+var Kinds = []Kind{KindTrending, KindTopRated, KindMostCooked}
+
+// ParseKind は文字列をランキングの種類に変換する
+func ParseKind(s string) (Kind, bool) {
+	for _, k := range Kinds {
+		if string(k) == s {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+const (
+	// trendingHalfLife はいいねの重みが半分になるまでの時間
+	trendingHalfLife = 3 * 24 * time.Hour
+	// trendingWindow より古いいいねはトレンドに数えない
+	trendingWindow = 30 * 24 * time.Hour
+	// bayesianPrior はベイズ平均で全体の平均をレビュー何件分として加えるか
+	bayesianPrior = 5
+	// maxEntries は1種類あたり全体とジャンルごとにそれぞれ保持する件数
+	maxEntries = 200
+)
+
+// Entry はランキングの1件
+type Entry struct {
+	RecipeID    string  `json:"recipe_id"`
+	GenreID     int     `json:"genre_id"`
+	Score       float64 `json:"score"`
+	LikeCount   int     `json:"like_count"`
+	ReviewCount int     `json:"review_count"`
+	RatingAvg   float64 `json:"rating_avg"`
+}
+
+// Ranking は計算済みのランキング。
+// Entries は全体の上位 maxEntries 件と各ジャンルの上位 maxEntries 件をあわせてスコア順に並べたもの。
+type Ranking struct {
+	Kind        Kind      `json:"kind"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Entries     []Entry   `json:"entries"`
+}
+
+// Filter はジャンルで絞り込み、上位limit件を返す（genreIDが0の場合は絞り込まない）。
+// ジャンルごとの上位も保持しているため、全体の上位に入らないジャンルでも limit が maxEntries 以下なら欠けない。
+func (r *Ranking) Filter(genreID, limit int) []Entry {
+	entries := make([]Entry, 0, limit)
+	for _, e := range r.Entries {
+		if genreID != 0 && e.GenreID != genreID {
+			continue
+		}
+		entries = append(entries, e)
+		if len(entries) == limit {
+			break
+		}
+	}
+	return entries
+}
+
+// Service はランキングを計算し、Redisにキャッシュする
+type Service struct {
+	DB    *gorm.DB
+	Redis *redis.Client
+	// TTL はキャッシュの有効期限。定期更新が止まっても古いランキングを返し続けないようにする
+	TTL time.Duration
+	Now func() time.Time
+}
+
+// NewService はServiceを作成する（redisClientがnilの場合はキャッシュせずに毎回計算する）
+func NewService(db *gorm.DB, redisClient *redis.Client) *Service {
+	return &Service{DB: db, Redis: redisClient, TTL: time.Hour, Now: time.Now}
+}
+
+func cacheKey(kind Kind) string {
+	return "ranking:" + string(kind)
+}
+
+// Get はキャッシュからランキングを返す。キャッシュに無い場合やRedisに接続できない場合はDBから計算する。
+func (s *Service) Get(ctx context.Context, kind Kind) (*Ranking, error) {
+	if s.Redis != nil {
+		val, err := s.Redis.Get(ctx, cacheKey(kind)).Result()
+		if err == nil {
+			var r Ranking
+			if err := json.Unmarshal([]byte(val), &r); err == nil {
+				return &r, nil
+			}
+		} else if err != redis.Nil {
+			log.Printf("⚠️ Failed to read ranking cache: %v", err)
+		}
+	}
+	return s.Refresh(ctx, kind)
+}
+
+// Refresh はランキングを計算してキャッシュを更新する
+func (s *Service) Refresh(ctx context.Context, kind Kind) (*Ranking, error) {
+	r, err := Compute(s.DB, kind, s.Now())
+	if err != nil {
+		return nil, err
+	}
+	if s.Redis != nil {
+		data, err := json.Marshal(r)
+		if err == nil {
+			err = s.Redis.Set(ctx, cacheKey(kind), data, s.TTL).Err()
+		}
+		if err != nil {
+			log.Printf("⚠️ Failed to write ranking cache: %v", err)
+		}
+	}
+	return r, nil
+}
+
+// StartRefresher は一定間隔ですべてのランキングを再計算する。起動直後にも1回計算する。
+func (s *Service) StartRefresher(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	// 更新が1回遅れてもキャッシュが切れないようにする
+	if s.TTL < 2*interval {
+		s.TTL = 2 * interval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			for _, kind := range Kinds {
+				if _, err := s.Refresh(ctx, kind); err != nil {
+					log.Printf("⚠️ Failed to refresh %s ranking: %v", kind, err)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Compute はDBからランキングを計算する（公開中のレシピのみ）
+func Compute(db *gorm.DB, kind Kind, now time.Time) (*Ranking, error) {
+	return compute(db, kind, now, maxEntries)
+}
+
+// compute は全体とジャンルごとにそれぞれ上位 perGroup 件を残してランキングを計算する
+func compute(db *gorm.DB, kind Kind, now time.Time, perGroup int) (*Ranking, error) {
+	var query, order string
+	var args []interface{}
+
+	switch kind {
+	case KindTrending:
+		// いいねごとに 0.5^(経過時間/半減期) を足し合わせる
+		query = `
+			SELECT r.id AS recipe_id, r.genre_id,
+				SUM(POWER(0.5, EXTRACT(EPOCH FROM (?::timestamptz - l.created_at)) / ?)) AS score,
+				COUNT(*) AS like_count,
+				COALESCE(rv.review_count, 0) AS review_count,
+				COALESCE(rv.rating_avg, 0) AS rating_avg
+			FROM likes l
+			JOIN recipes r ON r.id = l.recipe_id
+			LEFT JOIN (SELECT recipe_id, COUNT(*) AS review_count, AVG(rating) AS rating_avg FROM reviews WHERE status = 'visible' GROUP BY recipe_id) rv ON rv.recipe_id = r.id
+			WHERE r.is_public = true AND r.is_draft = false AND l.created_at >= ?
+			GROUP BY r.id, r.genre_id, rv.review_count, rv.rating_avg`
+		order = "score DESC, recipe_id"
+		args = []interface{}{now, trendingHalfLife.Seconds(), now.Add(-trendingWindow)}
+	case KindTopRated:
+		// (C × 全体の平均 + 評価の合計) / (C + レビュー数)
+		query = `
+			WITH global AS (SELECT COALESCE(AVG(rating), 0) AS mean FROM reviews WHERE status = 'visible')
+			SELECT r.id AS recipe_id, r.genre_id,
+				(? * global.mean + SUM(rv.rating)) / (? + COUNT(*)) AS score,
+				COALESCE(l.like_count, 0) AS like_count,
+				COUNT(*) AS review_count,
+				AVG(rv.rating) AS rating_avg
+			FROM reviews rv
+			JOIN recipes r ON r.id = rv.recipe_id
+			CROSS JOIN global
+			LEFT JOIN (SELECT recipe_id, COUNT(*) AS like_count FROM likes GROUP BY recipe_id) l ON l.recipe_id = r.id
+			WHERE r.is_public = true AND r.is_draft = false AND rv.status = 'visible'
+			GROUP BY r.id, r.genre_id, global.mean, l.like_count`
+		order = "score DESC, review_count DESC, recipe_id"
+		args = []interface{}{bayesianPrior, bayesianPrior}
+	case KindMostCooked:
+		// UNION で (ユーザー, レシピ) の重複を除くため、COUNT(*) が作ったユーザーの数になる
+		query = `
+			WITH cooks AS (
+				SELECT user_id, recipe_id FROM cook_logs
+				UNION
+				SELECT user_id, recipe_id FROM reviews WHERE status = 'visible'
+			)
+			SELECT r.id AS recipe_id, r.genre_id,
+				COUNT(*) AS score,
+				COALESCE(l.like_count, 0) AS like_count,
+				COALESCE(rv.review_count, 0) AS review_count,
+				COALESCE(rv.rating_avg, 0) AS rating_avg
+			FROM cooks c
+			JOIN recipes r ON r.id = c.recipe_id
+			LEFT JOIN (SELECT recipe_id, COUNT(*) AS like_count FROM likes GROUP BY recipe_id) l ON l.recipe_id = r.id
+			LEFT JOIN (SELECT recipe_id, COUNT(*) AS review_count, AVG(rating) AS rating_avg FROM reviews WHERE status = 'visible' GROUP BY recipe_id) rv ON rv.recipe_id = r.id
+			WHERE r.is_public = true AND r.is_draft = false
+			GROUP BY r.id, r.genre_id, l.like_count, rv.review_count, rv.rating_avg`
+		order = "score DESC, recipe_id"
+	default:
+		return nil, fmt.Errorf("unknown ranking kind: %s", kind)
+	}
+
+	// 全体の上位とジャンルごとの上位を残し、全体の順位で並べる
+	query = fmt.Sprintf(`
+		SELECT recipe_id, genre_id, score, like_count, review_count, rating_avg FROM (
+			SELECT scored.*,
+				ROW_NUMBER() OVER (ORDER BY %[1]s) AS overall_rank,
+				ROW_NUMBER() OVER (PARTITION BY genre_id ORDER BY %[1]s) AS genre_rank
+			FROM (%[2]s) scored
+		) ranked
+		WHERE overall_rank <= ? OR genre_rank <= ?
+		ORDER BY overall_rank`, order, query)
+	args = append(args, perGroup, perGroup)
+
+	entries := make([]Entry, 0)
+	if err := db.Raw(query, args...).Scan(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute %s ranking: %v", kind, err)
+	}
+	for i := range entries {
+		entries[i].Score = round(entries[i].Score)
+		entries[i].RatingAvg = round(entries[i].RatingAvg)
+	}
+
+	return &Ranking{Kind: kind, GeneratedAt: now, Entries: entries}, nil
+}
+
+func round(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -0,0 +1,204 @@
+package ranking
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"portfolio-amarimono/testharness/pgtest"
+
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+
+func TestParseKind(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   Kind
+		wantOK bool
+	}{
+		{"trending", KindTrending, true},
+		{"top_rated", KindTopRated, true},
+		{"most_cooked", KindMostCooked, true},
+		{"TRENDING", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := ParseKind(tt.in); got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseKind(%q) = %q, %v", tt.in, got, ok)
+		}
+	}
+}
+
+func TestRankingFilter(t *testing.T) {
+	r := &Ranking{Entries: []Entry{
+		{RecipeID: "a", GenreID: 1},
+		{RecipeID: "b", GenreID: 2},
+		{RecipeID: "c", GenreID: 1},
+		{RecipeID: "d", GenreID: 1},
+	}}
+	tests := []struct {
+		name    string
+		genreID int
+		limit   int
+		want    []string
+	}{
+		{"all genres", 0, 10, []string{"a", "b", "c", "d"}},
+		{"limit", 0, 2, []string{"a", "b"}},
+		{"genre", 1, 10, []string{"a", "c", "d"}},
+		{"genre and limit", 1, 2, []string{"a", "c"}},
+		{"unknown genre", 9, 10, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, e := range r.Filter(tt.genreID, tt.limit) {
+				got = append(got, e.RecipeID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+const (
+	recipe1       = "00000000-0000-4000-8000-0000000000a1"
+	recipe2       = "00000000-0000-4000-8000-0000000000a2"
+	recipe3       = "00000000-0000-4000-8000-0000000000a3"
+	recipePrivate = "00000000-0000-4000-8000-0000000000a4"
+
+	userA = "00000000-0000-4000-8000-000000000001"
+	userB = "00000000-0000-4000-8000-000000000002"
+	userC = "00000000-0000-4000-8000-000000000003"
+	userD = "00000000-0000-4000-8000-000000000004"
+)
+
+func TestCompute(t *testing.T) {
+	db := pgtest.Open(t)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	seedInteractions(t, db, now)
+
+	tests := []struct {
+		kind Kind
+		want []Entry
+	}{
+		// 半減期（3日）前のいいねは0.5、30日より前のいいねと非公開のレシピは数えない
+		{KindTrending, []Entry{
+			{RecipeID: recipe1, GenreID: 1, Score: 1.5, LikeCount: 2, ReviewCount: 2, RatingAvg: 5},
+			{RecipeID: recipe2, GenreID: 2, Score: 1, LikeCount: 1, ReviewCount: 1, RatingAvg: 3},
+		}},
+		// 全体の平均（非表示のレビューを除く (5+5+3)/3）を5件分加えたベイズ平均
+		{KindTopRated, []Entry{
+			{RecipeID: recipe1, GenreID: 1, Score: 4.5238, LikeCount: 2, ReviewCount: 2, RatingAvg: 5},
+			{RecipeID: recipe2, GenreID: 2, Score: 4.1111, LikeCount: 1, ReviewCount: 1, RatingAvg: 3},
+		}},
+		// 同じユーザーの「作った」記録とレビューは1人として数える
+		{KindMostCooked, []Entry{
+			{RecipeID: recipe2, GenreID: 2, Score: 3, LikeCount: 1, ReviewCount: 1, RatingAvg: 3},
+			{RecipeID: recipe1, GenreID: 1, Score: 2, LikeCount: 2, ReviewCount: 2, RatingAvg: 5},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			got, err := Compute(db, tt.kind, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Kind != tt.kind || !got.GeneratedAt.Equal(now) {
+				t.Errorf("ranking = %s at %v", got.Kind, got.GeneratedAt)
+			}
+			if !reflect.DeepEqual(got.Entries, tt.want) {
+				t.Errorf("entries = %+v, want %+v", got.Entries, tt.want)
+			}
+		})
+	}
+
+	if _, err := Compute(db, "unknown", now); err == nil {
+		t.Error("expected an error for an unknown kind")
+	}
+}
+
+func TestComputeKeepsEachGenre(t *testing.T) {
+	db := pgtest.Open(t)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	seedInteractions(t, db, now)
+
+	// 全体の1位だけでなく、全体の上位に入らないジャンル2の1位も残す
+	r, err := compute(db, KindTrending, now, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		genreID int
+		want    []string
+	}{
+		{0, []string{recipe1}},
+		{1, []string{recipe1}},
+		{2, []string{recipe2}},
+	}
+	for _, tt := range tests {
+		got := []string{}
+		for _, e := range r.Filter(tt.genreID, 1) {
+			got = append(got, e.RecipeID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Filter(%d) = %v, want %v", tt.genreID, got, tt.want)
+		}
+	}
+}
+
+// seedInteractions はいいね・レビュー・「作った」記録の付いたレシピを作成する
+func seedInteractions(t *testing.T, db *gorm.DB, now time.Time) {
+	t.Helper()
+	exec := func(sql string, args ...interface{}) {
+		t.Helper()
+		if err := db.Exec(sql, args...).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exec(`INSERT INTO recipe_genres (id, name) VALUES (1, '主菜'), (2, '汁物')`)
+	for _, r := range []struct {
+		id       string
+		genreID  int
+		isPublic bool
+	}{{recipe1, 1, true}, {recipe2, 2, true}, {recipe3, 1, true}, {recipePrivate, 1, false}} {
+		exec("INSERT INTO recipes (id, name, genre_id, is_public) VALUES (?, ?, ?, ?)", r.id, "recipe", r.genreID, r.isPublic)
+	}
+	for _, id := range []string{userA, userB, userC, userD} {
+		exec("INSERT INTO users (id, email) VALUES (?, ?)", id, id+"@example.com")
+	}
+
+	for _, l := range []struct {
+		userID, recipeID string
+		at               time.Time
+	}{
+		{userA, recipe1, now},
+		{userB, recipe1, now.Add(-trendingHalfLife)},
+		{userC, recipe2, now},
+		{userD, recipe3, now.Add(-trendingWindow - time.Hour)},
+		{userA, recipePrivate, now},
+	} {
+		exec("INSERT INTO likes (user_id, recipe_id, created_at) VALUES (?, ?, ?)", l.userID, l.recipeID, l.at)
+	}
+
+	for _, r := range []struct {
+		userID, recipeID string
+		rating           int
+		status           string
+	}{
+		{userA, recipe1, 5, "visible"},
+		{userB, recipe1, 5, "visible"},
+		{userC, recipe2, 3, "visible"},
+		{userD, recipe3, 1, "hidden"},
+	} {
+		exec("INSERT INTO reviews (user_id, recipe_id, rating, status) VALUES (?, ?, ?, ?)", r.userID, r.recipeID, r.rating, r.status)
+	}
+
+	for _, c := range [][2]string{{userA, recipe2}, {userA, recipe2}, {userB, recipe2}, {userA, recipe1}} {
+		exec("INSERT INTO cook_logs (user_id, recipe_id) VALUES (?, ?)", c[0], c[1])
+	}
+}
@@ -0,0 +1,213 @@
+package recommend
+
+import (
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// Recommendation はおすすめのレシピと、おすすめした理由
+type Recommendation struct {
+	RecipeID string   `json:"recipe_id"`
+	Score    float64  `json:"score"`
+	Reasons  []string `json:"reasons"`
+}
+
+// 人気順で補うときの理由
+const popularReason = "人気のレシピです"
+
+// seed はユーザーが好んだレシピ（おすすめの起点）
+type seed struct {
+	RecipeID string
+	Name     string
+	Weight   float64
+}
+
+// contribution は候補ごとの集計
+type contribution struct {
+	score     float64
+	best      seed
+	bestScore float64
+	bestIsCF  bool
+	seeds     int
+}
+
+// ForUser はユーザーのいいね・レビューを起点に、事前計算した類似度からおすすめを返す。
+// いいね・レビュー済みのレシピは除き、足りない分（履歴が無いユーザーを含む）は人気順で補う。
+func ForUser(db *gorm.DB, userID string, limit int) ([]Recommendation, error) {
+	seeds, exclude, err := userSeeds(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendations := make([]Recommendation, 0, limit)
+	if len(seeds) > 0 {
+		recommendations, err = fromSimilarities(db, seeds, exclude, limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(recommendations) < limit {
+		for _, r := range recommendations {
+			exclude[r.RecipeID] = true
+		}
+		popular, err := Popular(db, exclude, limit-len(recommendations))
+		if err != nil {
+			return nil, err
+		}
+		recommendations = append(recommendations, popular...)
+	}
+	return recommendations, nil
+}
+
+// userSeeds はユーザーがいいね、または高評価したレシピと、おすすめから除くレシピを返す
+func userSeeds(db *gorm.DB, userID string) ([]seed, map[string]bool, error) {
+	var rows []struct {
+		RecipeID string
+		Name     string
+		Rating   *int
+	}
+	err := db.Raw(`
+		SELECT l.recipe_id, r.name, NULL AS rating
+		FROM likes l JOIN recipes r ON r.id = l.recipe_id
+		WHERE l.user_id = ?
+		UNION ALL
+		SELECT rv.recipe_id, r.name, rv.rating
+		FROM reviews rv JOIN recipes r ON r.id = rv.recipe_id
+		WHERE rv.user_id = ?`, userID, userID).Scan(&rows).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch user history: %v", err)
+	}
+
+	exclude := make(map[string]bool, len(rows))
+	weights := make(map[string]float64, len(rows))
+	names := make(map[string]string, len(rows))
+	for _, row := range rows {
+		exclude[row.RecipeID] = true
+		names[row.RecipeID] = row.Name
+		w := 1.0
+		if row.Rating != nil {
+			w = interactionWeight(*row.Rating)
+		}
+		if w > weights[row.RecipeID] {
+			weights[row.RecipeID] = w
+		}
+	}
+
+	seeds := make([]seed, 0, len(weights))
+	for id, w := range weights {
+		if w > 0 {
+			seeds = append(seeds, seed{RecipeID: id, Name: names[id], Weight: w})
+		}
+	}
+	sort.Slice(seeds, func(i, j int) bool { return seeds[i].RecipeID < seeds[j].RecipeID })
+	return seeds, exclude, nil
+}
+
+func fromSimilarities(db *gorm.DB, seeds []seed, exclude map[string]bool, limit int) ([]Recommendation, error) {
+	ids := make([]string, len(seeds))
+	bySeed := make(map[string]seed, len(seeds))
+	for i, s := range seeds {
+		ids[i] = s.RecipeID
+		bySeed[s.RecipeID] = s
+	}
+
+	var similarities []Similarity
+	if err := db.Where("recipe_id IN ?", ids).Find(&similarities).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch recipe similarities: %v", err)
+	}
+
+	candidates := map[string]*contribution{}
+	for _, sim := range similarities {
+		if exclude[sim.SimilarRecipeID] {
+			continue
+		}
+		s := bySeed[sim.RecipeID]
+		score := sim.Score * s.Weight
+		c, ok := candidates[sim.SimilarRecipeID]
+		if !ok {
+			c = &contribution{}
+			candidates[sim.SimilarRecipeID] = c
+		}
+		c.score += score
+		c.seeds++
+		if score > c.bestScore {
+			c.best, c.bestScore = s, score
+			c.bestIsCF = collaborativeWeight*sim.CFScore >= ingredientWeight*sim.IngredientScore
+		}
+	}
+
+	recommendations := make([]Recommendation, 0, len(candidates))
+	for id, c := range candidates {
+		recommendations = append(recommendations, Recommendation{
+			RecipeID: id,
+			Score:    round(c.score),
+			Reasons:  reasons(c),
+		})
+	}
+	sort.Slice(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return recommendations[i].RecipeID < recommendations[j].RecipeID
+	})
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+	return recommendations, nil
+}
+
+func reasons(c *contribution) []string {
+	reasons := make([]string, 0, 2)
+	if c.bestIsCF {
+		reasons = append(reasons, fmt.Sprintf("「%s」を気に入った人に人気です", c.best.Name))
+	} else {
+		reasons = append(reasons, fmt.Sprintf("「%s」と材料が似ています", c.best.Name))
+	}
+	if c.seeds > 1 {
+		reasons = append(reasons, fmt.Sprintf("お気に入りの%d件のレシピと似ています", c.seeds))
+	}
+	return reasons
+}
+
+// Popular はいいね数とレビュー（高評価ほど重く）から人気のレシピを返す
+func Popular(db *gorm.DB, exclude map[string]bool, limit int) ([]Recommendation, error) {
+	if limit <= 0 {
+		return []Recommendation{}, nil
+	}
+	var rows []struct {
+		ID    string
+		Score float64
+	}
+	// 除外するレシピの分だけ多めに取得する
+	err := db.Raw(`
+		SELECT r.id,
+			COALESCE(l.cnt, 0) + COALESCE(rv.score, 0) AS score
+		FROM recipes r
+		LEFT JOIN (SELECT recipe_id, COUNT(*) AS cnt FROM likes GROUP BY recipe_id) l ON l.recipe_id = r.id
+		LEFT JOIN (SELECT recipe_id, SUM(CASE WHEN rating >= 4 THEN 1.0 WHEN rating = 3 THEN 0.5 ELSE 0 END) AS score FROM reviews GROUP BY recipe_id) rv ON rv.recipe_id = r.id
+		WHERE r.is_public = true AND r.is_draft = false
+		ORDER BY score DESC, r.created_at DESC, r.id
+		LIMIT ?`, limit+len(exclude)).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch popular recipes: %v", err)
+	}
+
+	popular := make([]Recommendation, 0, limit)
+	for _, row := range rows {
+		if exclude[row.ID] {
+			continue
+		}
+		popular = append(popular, Recommendation{
+			RecipeID: row.ID,
+			Score:    row.Score,
+			Reasons:  []string{popularReason},
+		})
+		if len(popular) == limit {
+			break
+		}
+	}
+	return popular, nil
+}
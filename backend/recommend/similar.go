@@ -0,0 +1,197 @@
+package recommend
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// presenceWeight は醤油・塩のように「適量」「大さじ」で使う調味料の重み。
+// どのレシピにも入っているため、同じ重みで数えると似ていないレシピ同士が似ていると判定される。
+const presenceWeight = 0.2
+
+// 類似レシピのスコアの重み（合計1）
+const (
+	similarIngredientWeight  = 0.7
+	similarGenreWeight       = 0.15
+	similarCookingTimeWeight = 0.075
+	similarCostWeight        = 0.075
+)
+
+// SharedIngredient は2つのレシピに共通する具材
+type SharedIngredient struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// SimilarRecipe は類似レシピとスコアの内訳
+type SimilarRecipe struct {
+	RecipeID          string             `json:"recipe_id"`
+	Score             float64            `json:"score"`
+	IngredientScore   float64            `json:"ingredient_score"`
+	SameGenre         bool               `json:"same_genre"`
+	SharedIngredients []SharedIngredient `json:"shared_ingredients"`
+}
+
+type ingredientInfo struct {
+	Name   string
+	Weight float64
+}
+
+// ingredientCatalog は具材ごとの名前と重みを返す（単位がpresenceの具材は presenceWeight）
+func ingredientCatalog(db *gorm.DB) (map[int]ingredientInfo, error) {
+	var rows []struct {
+		ID   int
+		Name string
+		Type string
+	}
+	if err := db.Raw(`
+		SELECT i.id, i.name, COALESCE(u.type, '') AS type
+		FROM ingredients i LEFT JOIN units u ON u.id = i.unit_id`).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch ingredients: %v", err)
+	}
+	catalog := make(map[int]ingredientInfo, len(rows))
+	for _, row := range rows {
+		weight := 1.0
+		if row.Type == "presence" {
+			weight = presenceWeight
+		}
+		catalog[row.ID] = ingredientInfo{Name: row.Name, Weight: weight}
+	}
+	return catalog, nil
+}
+
+// weightedJaccard は具材の重みを考慮したJaccard係数と、共通する具材を返す
+func weightedJaccard(a, b map[int]bool, catalog map[int]ingredientInfo) (float64, []int) {
+	weight := func(id int) float64 {
+		if info, ok := catalog[id]; ok {
+			return info.Weight
+		}
+		return 1.0
+	}
+	var intersection, union float64
+	shared := make([]int, 0)
+	for id := range a {
+		union += weight(id)
+		if b[id] {
+			intersection += weight(id)
+			shared = append(shared, id)
+		}
+	}
+	for id := range b {
+		if !a[id] {
+			union += weight(id)
+		}
+	}
+	if union == 0 {
+		return 0, shared
+	}
+	sort.Ints(shared)
+	return intersection / union, shared
+}
+
+// closeness は2つの値の近さ（同じなら1、差が大きいほど0に近づく）。どちらかが未設定の場合は0。
+func closeness(a, b int) float64 {
+	if a <= 0 || b <= 0 {
+		return 0
+	}
+	return 1 - math.Abs(float64(a-b))/math.Max(float64(a), float64(b))
+}
+
+// Similar は具材の重なりが大きい公開レシピを返す。ジャンルが同じ、調理時間・費用が近いほどスコアが高くなる。
+// レシピが存在しない場合は gorm.ErrRecordNotFound を返す。
+func Similar(db *gorm.DB, recipeID string, limit int) ([]SimilarRecipe, error) {
+	type recipeMeta struct {
+		ID           string
+		GenreID      int
+		CookingTime  int
+		CostEstimate int
+	}
+
+	var target recipeMeta
+	if err := db.Table("recipes").Select("id, genre_id, cooking_time, cost_estimate").
+		Where("id = ?", recipeID).Take(&target).Error; err != nil {
+		return nil, err
+	}
+
+	catalog, err := ingredientCatalog(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// 対象レシピと具材を1つ以上共有する公開レシピだけを候補にする
+	var candidates []recipeMeta
+	if err := db.Raw(`
+		SELECT r.id, r.genre_id, r.cooking_time, r.cost_estimate
+		FROM recipes r
+		WHERE r.is_public = true AND r.is_draft = false AND r.id <> ?
+			AND EXISTS (
+				SELECT 1 FROM recipe_ingredients ri
+				JOIN recipe_ingredients target ON target.ingredient_id = ri.ingredient_id AND target.recipe_id = ?
+				WHERE ri.recipe_id = r.id
+			)`, target.ID, target.ID).Scan(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch candidate recipes: %v", err)
+	}
+	if len(candidates) == 0 {
+		return []SimilarRecipe{}, nil
+	}
+
+	ids := make([]string, 0, len(candidates)+1)
+	ids = append(ids, target.ID)
+	for _, c := range candidates {
+		ids = append(ids, c.ID)
+	}
+	var rows []struct {
+		RecipeID     string
+		IngredientID int
+	}
+	if err := db.Table("recipe_ingredients").Select("recipe_id, ingredient_id").
+		Where("recipe_id IN ?", ids).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch recipe ingredients: %v", err)
+	}
+	ingredients := make(map[string]map[int]bool, len(ids))
+	for _, row := range rows {
+		if ingredients[row.RecipeID] == nil {
+			ingredients[row.RecipeID] = map[int]bool{}
+		}
+		ingredients[row.RecipeID][row.IngredientID] = true
+	}
+
+	results := make([]SimilarRecipe, 0, len(candidates))
+	for _, c := range candidates {
+		ingredientScore, shared := weightedJaccard(ingredients[target.ID], ingredients[c.ID], catalog)
+		sameGenre := c.GenreID != 0 && c.GenreID == target.GenreID
+
+		score := similarIngredientWeight * ingredientScore
+		if sameGenre {
+			score += similarGenreWeight
+		}
+		score += similarCookingTimeWeight * closeness(target.CookingTime, c.CookingTime)
+		score += similarCostWeight * closeness(target.CostEstimate, c.CostEstimate)
+
+		sharedIngredients := make([]SharedIngredient, 0, len(shared))
+		for _, id := range shared {
+			sharedIngredients = append(sharedIngredients, SharedIngredient{ID: id, Name: catalog[id].Name})
+		}
+		results = append(results, SimilarRecipe{
+			RecipeID:          c.ID,
+			Score:             round(score),
+			IngredientScore:   round(ingredientScore),
+			SameGenre:         sameGenre,
+			SharedIngredients: sharedIngredients,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].RecipeID < results[j].RecipeID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
@@ -0,0 +1,235 @@
+package recommend
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 類似度の重み。協調フィルタリング（同じユーザーに好まれたか）と具材の重なりを組み合わせる。
+const (
+	collaborativeWeight = 0.7
+	ingredientWeight    = 0.3
+)
+
+// DefaultNeighbors はレシピごとに保存する類似レシピの数
+const DefaultNeighbors = 30
+
+// Similarity はrecipe_similaritiesテーブルの1行
+type Similarity struct {
+	RecipeID        string    `json:"recipe_id" gorm:"type:uuid"`
+	SimilarRecipeID string    `json:"similar_recipe_id" gorm:"type:uuid"`
+	Score           float64   `json:"score"`
+	CFScore         float64   `json:"cf_score" gorm:"column:cf_score"`
+	IngredientScore float64   `json:"ingredient_score"`
+	ComputedAt      time.Time `json:"computed_at"`
+}
+
+func (Similarity) TableName() string {
+	return "recipe_similarities"
+}
+
+// RefreshResult は類似度の再計算の結果
+type RefreshResult struct {
+	Recipes  int           `json:"recipes"`
+	Pairs    int           `json:"pairs"`
+	Duration time.Duration `json:"duration"`
+}
+
+// interactionWeight はレビューの評価をユーザーの好みの強さに変換する（低評価は好みとして扱わない）
+func interactionWeight(rating int) float64 {
+	switch {
+	case rating >= 4:
+		return 1.0
+	case rating == 3:
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+// RefreshSimilarities はいいね・レビュー・具材から公開レシピ同士の類似度を計算し、recipe_similaritiesを置き換える
+func RefreshSimilarities(db *gorm.DB, neighbors int) (*RefreshResult, error) {
+	started := time.Now()
+	if neighbors <= 0 {
+		neighbors = DefaultNeighbors
+	}
+
+	var recipeIDs []string
+	if err := db.Table("recipes").Where("is_public = ? AND is_draft = ?", true, false).
+		Order("id").Pluck("id", &recipeIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch recipes: %v", err)
+	}
+	index := make(map[string]int, len(recipeIDs))
+	for i, id := range recipeIDs {
+		index[id] = i
+	}
+
+	// レシピごとの「好んだユーザー → 重み」
+	users := make([]map[string]float64, len(recipeIDs))
+	for i := range users {
+		users[i] = map[string]float64{}
+	}
+	var likes []struct {
+		UserID   string
+		RecipeID string
+	}
+	if err := db.Table("likes").Select("user_id, recipe_id").Scan(&likes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch likes: %v", err)
+	}
+	for _, l := range likes {
+		if i, ok := index[l.RecipeID]; ok {
+			users[i][l.UserID] = 1.0
+		}
+	}
+	var reviews []struct {
+		UserID   string
+		RecipeID string
+		Rating   int
+	}
+	if err := db.Table("reviews").Select("user_id, recipe_id, rating").Scan(&reviews).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch reviews: %v", err)
+	}
+	for _, r := range reviews {
+		i, ok := index[r.RecipeID]
+		if !ok {
+			continue
+		}
+		if w := interactionWeight(r.Rating); w > users[i][r.UserID] {
+			users[i][r.UserID] = w
+		}
+	}
+
+	// レシピごとの具材
+	ingredients := make([]map[int]bool, len(recipeIDs))
+	for i := range ingredients {
+		ingredients[i] = map[int]bool{}
+	}
+	var recipeIngredients []struct {
+		RecipeID     string
+		IngredientID int
+	}
+	if err := db.Table("recipe_ingredients").Select("recipe_id, ingredient_id").Scan(&recipeIngredients).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch recipe ingredients: %v", err)
+	}
+	for _, ri := range recipeIngredients {
+		if i, ok := index[ri.RecipeID]; ok {
+			ingredients[i][ri.IngredientID] = true
+		}
+	}
+
+	catalog, err := ingredientCatalog(db)
+	if err != nil {
+		return nil, err
+	}
+
+	norms := make([]float64, len(recipeIDs))
+	for i, u := range users {
+		for _, w := range u {
+			norms[i] += w * w
+		}
+		norms[i] = math.Sqrt(norms[i])
+	}
+
+	now := time.Now()
+	rows := make([]Similarity, 0, len(recipeIDs)*neighbors)
+	for i := range recipeIDs {
+		candidates := make([]Similarity, 0, len(recipeIDs))
+		for j := range recipeIDs {
+			if i == j {
+				continue
+			}
+			cf := cosine(users[i], users[j], norms[i], norms[j])
+			ing, _ := weightedJaccard(ingredients[i], ingredients[j], catalog)
+			score := collaborativeWeight*cf + ingredientWeight*ing
+			if score <= 0 {
+				continue
+			}
+			candidates = append(candidates, Similarity{
+				RecipeID:        recipeIDs[i],
+				SimilarRecipeID: recipeIDs[j],
+				Score:           round(score),
+				CFScore:         round(cf),
+				IngredientScore: round(ing),
+				ComputedAt:      now,
+			})
+		}
+		sort.Slice(candidates, func(a, b int) bool {
+			if candidates[a].Score != candidates[b].Score {
+				return candidates[a].Score > candidates[b].Score
+			}
+			return candidates[a].SimilarRecipeID < candidates[b].SimilarRecipeID
+		})
+		if len(candidates) > neighbors {
+			candidates = candidates[:neighbors]
+		}
+		rows = append(rows, candidates...)
+	}
+
+	// 読み込み中のリクエストが空の表を見ないよう、削除と挿入を1つのトランザクションで行う
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM recipe_similarities").Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rows, 500).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save recipe similarities: %v", err)
+	}
+
+	return &RefreshResult{
+		Recipes:  len(recipeIDs),
+		Pairs:    len(rows),
+		Duration: time.Since(started),
+	}, nil
+}
+
+// StartRefresher は一定間隔で類似度を再計算する。起動直後にも1回計算する。ctxがキャンセルされると停止する。
+func StartRefresher(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			result, err := RefreshSimilarities(db, DefaultNeighbors)
+			if err != nil {
+				log.Printf("⚠️ Failed to refresh recipe similarities: %v", err)
+			} else {
+				log.Printf("✅ Recipe similarities refreshed: %d recipes, %d pairs (%s)", result.Recipes, result.Pairs, result.Duration)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func cosine(a, b map[string]float64, normA, normB float64) float64 {
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	var dot float64
+	for user, w := range a {
+		dot += w * b[user]
+	}
+	return dot / (normA * normB)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -0,0 +1,238 @@
+package recommend
+
+import (
+	"math"
+	"os"
+	"reflect"
+	"testing"
+
+	"portfolio-amarimono/testharness/pgtest"
+
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+
+func TestWeightedJaccard(t *testing.T) {
+	catalog := map[int]ingredientInfo{
+		1: {Name: "トマト", Weight: 1},
+		2: {Name: "卵", Weight: 1},
+		3: {Name: "塩", Weight: presenceWeight},
+	}
+	set := func(ids ...int) map[int]bool {
+		m := map[int]bool{}
+		for _, id := range ids {
+			m[id] = true
+		}
+		return m
+	}
+
+	tests := []struct {
+		name       string
+		a, b       map[int]bool
+		want       float64
+		wantShared []int
+	}{
+		{"identical", set(1, 2), set(1, 2), 1, []int{1, 2}},
+		{"disjoint", set(1), set(2), 0, []int{}},
+		{"half", set(1, 2), set(1), 0.5, []int{1}},
+		{"seasoning counts less", set(1, 3), set(2, 3), 0.2 / 2.2, []int{3}},
+		{"seasoning difference counts less", set(1, 2, 3), set(1, 2), 2 / 2.2, []int{1, 2}},
+		{"unknown ingredient weighs 1", set(1, 9), set(9), 0.5, []int{9}},
+		{"both empty", set(), set(), 0, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, shared := weightedJaccard(tt.a, tt.b, catalog)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("score = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(shared, tt.wantShared) {
+				t.Errorf("shared = %v, want %v", shared, tt.wantShared)
+			}
+			if back, _ := weightedJaccard(tt.b, tt.a, catalog); math.Abs(back-got) > 1e-9 {
+				t.Errorf("not symmetric: %v vs %v", got, back)
+			}
+		})
+	}
+}
+
+func TestCloseness(t *testing.T) {
+	tests := []struct {
+		a, b int
+		want float64
+	}{
+		{30, 30, 1},
+		{20, 40, 0.5},
+		{40, 20, 0.5},
+		{10, 40, 0.25},
+		{0, 30, 0},
+		{30, -1, 0},
+	}
+	for _, tt := range tests {
+		if got := closeness(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("closeness(%d, %d) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCosine(t *testing.T) {
+	norm := func(m map[string]float64) float64 {
+		var sum float64
+		for _, w := range m {
+			sum += w * w
+		}
+		return math.Sqrt(sum)
+	}
+	tests := []struct {
+		name string
+		a, b map[string]float64
+		want float64
+	}{
+		{"same users", map[string]float64{"u1": 1, "u2": 1}, map[string]float64{"u1": 1, "u2": 1}, 1},
+		{"no common users", map[string]float64{"u1": 1}, map[string]float64{"u2": 1}, 0},
+		{"one of two", map[string]float64{"u1": 1, "u2": 1}, map[string]float64{"u1": 1}, 1 / math.Sqrt2},
+		{"weighted", map[string]float64{"u1": 0.5}, map[string]float64{"u1": 1, "u2": 1}, 1 / math.Sqrt2},
+		{"no interactions", map[string]float64{}, map[string]float64{"u1": 1}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cosine(tt.a, tt.b, norm(tt.a), norm(tt.b))
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("cosine = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInteractionWeight(t *testing.T) {
+	for rating, want := range map[int]float64{5: 1, 4: 1, 3: 0.5, 2: 0, 1: 0} {
+		if got := interactionWeight(rating); got != want {
+			t.Errorf("interactionWeight(%d) = %v, want %v", rating, got, want)
+		}
+	}
+}
+
+const (
+	recipeTarget  = "00000000-0000-4000-8000-00000000000a"
+	recipeClose   = "00000000-0000-4000-8000-00000000000b"
+	recipeSalt    = "00000000-0000-4000-8000-00000000000c"
+	recipePrivate = "00000000-0000-4000-8000-00000000000d"
+	recipeOther   = "00000000-0000-4000-8000-00000000000e"
+
+	userA = "00000000-0000-4000-8000-000000000001"
+	userB = "00000000-0000-4000-8000-000000000002"
+)
+
+func TestSimilar(t *testing.T) {
+	db := pgtest.Open(t)
+	seedRecipes(t, db)
+
+	got, err := Similar(db, recipeTarget, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, r := range got {
+		ids = append(ids, r.RecipeID)
+	}
+	// 非公開のレシピと具材を共有しないレシピは候補にならない
+	if want := []string{recipeClose, recipeSalt}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("similar = %v, want %v", ids, want)
+	}
+
+	closeRecipe := got[0]
+	if closeRecipe.IngredientScore != 0.9091 || closeRecipe.Score != 0.9364 || !closeRecipe.SameGenre {
+		t.Errorf("close recipe = %+v", closeRecipe)
+	}
+	if want := []SharedIngredient{{ID: 1, Name: "トマト"}, {ID: 2, Name: "卵"}}; !reflect.DeepEqual(closeRecipe.SharedIngredients, want) {
+		t.Errorf("shared = %v, want %v", closeRecipe.SharedIngredients, want)
+	}
+	if got[1].SameGenre || got[1].IngredientScore != 0.0625 {
+		t.Errorf("salt recipe = %+v", got[1])
+	}
+
+	limited, err := Similar(db, recipeTarget, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited) != 1 || limited[0].RecipeID != recipeClose {
+		t.Errorf("limited = %+v", limited)
+	}
+}
+
+func TestRefreshSimilarities(t *testing.T) {
+	db := pgtest.Open(t)
+	seedRecipes(t, db)
+	// 同じユーザーにいいねされた、具材を共有しないレシピも類似とみなす
+	for _, like := range [][2]string{{userA, recipeTarget}, {userA, recipeOther}, {userB, recipeTarget}, {userB, recipeOther}} {
+		if err := db.Exec("INSERT INTO likes (user_id, recipe_id) VALUES (?, ?)", like[0], like[1]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := RefreshSimilarities(db, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Recipes != 4 {
+		t.Errorf("recipes = %d, want 4 (private recipe excluded)", result.Recipes)
+	}
+
+	var rows []Similarity
+	if err := db.Where("recipe_id = ?", recipeTarget).Order("score DESC").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("neighbors = %d, want 2", len(rows))
+	}
+	if rows[0].SimilarRecipeID != recipeOther || rows[0].CFScore != 1 || rows[0].Score != collaborativeWeight {
+		t.Errorf("top neighbor = %+v", rows[0])
+	}
+	if rows[1].SimilarRecipeID != recipeClose || rows[1].CFScore != 0 || rows[1].IngredientScore != 0.9091 {
+		t.Errorf("second neighbor = %+v", rows[1])
+	}
+}
+
+// seedRecipes は具材の重なり方が異なるレシピを作成する
+func seedRecipes(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	statements := []string{
+		`INSERT INTO units (id, name, type) VALUES (1, 'g', 'quantity'), (2, '適量', 'presence')`,
+		`INSERT INTO ingredient_genres (id, name) VALUES (1, '野菜')`,
+		`INSERT INTO ingredients (id, name, genre_id, unit_id) VALUES
+			(1, 'トマト', 1, 1), (2, '卵', 1, 1), (3, '玉ねぎ', 1, 1), (4, '塩', 1, 2), (5, '豚肉', 1, 1)`,
+		`INSERT INTO recipe_genres (id, name) VALUES (1, '主菜'), (2, '汁物')`,
+	}
+	for _, sql := range statements {
+		if err := db.Exec(sql).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recipes := []struct {
+		id          string
+		genreID     int
+		cookingTime int
+		cost        int
+		isPublic    bool
+		ingredients []int
+	}{
+		{recipeTarget, 1, 20, 300, true, []int{1, 2, 4}},
+		{recipeClose, 1, 20, 300, true, []int{1, 2}},
+		{recipeSalt, 2, 40, 0, true, []int{4, 5}},
+		{recipePrivate, 1, 20, 300, false, []int{1, 2, 4}},
+		{recipeOther, 2, 10, 100, true, []int{3}},
+	}
+	for _, r := range recipes {
+		if err := db.Exec("INSERT INTO recipes (id, name, genre_id, cooking_time, cost_estimate, is_public) VALUES (?, ?, ?, ?, ?, ?)",
+			r.id, "recipe "+r.id[len(r.id)-1:], r.genreID, r.cookingTime, r.cost, r.isPublic).Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, ingredientID := range r.ingredients {
+			if err := db.Exec("INSERT INTO recipe_ingredients (recipe_id, ingredient_id, quantity_required) VALUES (?, ?, 1)", r.id, ingredientID).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
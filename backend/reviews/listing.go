@@ -0,0 +1,302 @@
+package reviews
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"portfolio-amarimono/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// レビュー一覧の並び順
+const (
+	SortNewest  = "newest"
+	SortHighest = "highest"
+	SortLowest  = "lowest"
+	SortHelpful = "helpful"
+)
+
+const (
+	// DefaultPageSize / MaxPageSize は一覧の1ページあたりの件数
+	DefaultPageSize = 20
+	MaxPageSize     = 50
+)
+
+var (
+	ErrInvalidSort   = errors.New("invalid sort order")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrOwnReview     = errors.New("cannot vote on own review")
+)
+
+// ListOptions はレビュー一覧の取得条件
+type ListOptions struct {
+	Sort   string
+	Cursor string
+	Limit  int
+	// ViewerID を指定すると、そのユーザーが「参考になった」を押したかを返す
+	ViewerID *string
+}
+
+// Reviewer は一覧に表示する投稿者の情報（退会済みの場合は ID のみ）
+type Reviewer struct {
+	ID           string  `json:"id"`
+	Username     *string `json:"username"`
+	ProfileImage *string `json:"profileImage"`
+}
+
+// ListedReview は一覧用に投稿者の情報と閲覧者の投票状況を付けたレビュー
+type ListedReview struct {
+	models.Review
+	Reviewer     Reviewer `json:"reviewer"`
+	VotedHelpful bool     `json:"votedHelpful"`
+}
+
+// Page はカーソルページングされたレビュー一覧
+type Page struct {
+	Reviews     []ListedReview `json:"reviews"`
+	NextCursor  string         `json:"nextCursor,omitempty"`
+	HasNextPage bool           `json:"hasNextPage"`
+}
+
+// Summary はレシピのレビューの集計（非表示のレビューは含まない）
+type Summary struct {
+	Average float64 `json:"average"`
+	Total   int     `json:"total"`
+	// Distribution は評価（1〜5）ごとの件数
+	Distribution map[int]int `json:"distribution"`
+}
+
+// cursor は前のページの最後のレビューの並び替えキー
+type cursor struct {
+	Sort      string    `json:"s"`
+	Key       int       `json:"k"`
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// ListByRecipe はレシピの公開中のレビューを指定の順で返す
+func (s *Service) ListByRecipe(recipeID string, opts ListOptions) (*Page, error) {
+	sort := opts.Sort
+	if sort == "" {
+		sort = SortNewest
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	query := s.DB.Model(&models.Review{}).
+		Scopes(models.ActiveOwner("reviews.user_id")).
+		Where("recipe_id = ? AND status = ?", recipeID, models.ReviewStatusVisible)
+	switch sort {
+	case SortNewest:
+		query = query.Order("created_at DESC, id DESC")
+	case SortHighest:
+		query = query.Order("rating DESC, created_at DESC, id DESC")
+	case SortLowest:
+		query = query.Order("rating ASC, created_at DESC, id DESC")
+	case SortHelpful:
+		query = query.Order("helpful_count DESC, created_at DESC, id DESC")
+	default:
+		return nil, ErrInvalidSort
+	}
+
+	if opts.Cursor != "" {
+		cur, err := decodeCursor(opts.Cursor)
+		if err != nil || cur.Sort != sort {
+			return nil, ErrInvalidCursor
+		}
+		switch sort {
+		case SortNewest:
+			query = query.Where("(created_at, id) < (?::timestamptz, ?::uuid)", cur.CreatedAt, cur.ID)
+		case SortHighest:
+			query = query.Where("(rating, created_at, id) < (?, ?::timestamptz, ?::uuid)", cur.Key, cur.CreatedAt, cur.ID)
+		case SortLowest:
+			query = query.Where("rating > ? OR (rating = ? AND (created_at, id) < (?::timestamptz, ?::uuid))", cur.Key, cur.Key, cur.CreatedAt, cur.ID)
+		case SortHelpful:
+			query = query.Where("(helpful_count, created_at, id) < (?, ?::timestamptz, ?::uuid)", cur.Key, cur.CreatedAt, cur.ID)
+		}
+	}
+
+	// 次のページの有無を判定するため1件多く取得する
+	var rows []models.Review
+	if err := query.Preload("Photos", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Limit(limit + 1).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	page := &Page{Reviews: make([]ListedReview, 0, len(rows))}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		page.HasNextPage = true
+		last := rows[len(rows)-1]
+		next := cursor{Sort: sort, CreatedAt: last.CreatedAt, ID: last.ID.String()}
+		switch sort {
+		case SortHighest, SortLowest:
+			next.Key = last.Rating
+		case SortHelpful:
+			next.Key = last.HelpfulCount
+		}
+		page.NextCursor = encodeCursor(next)
+	}
+	if len(rows) == 0 {
+		return page, nil
+	}
+
+	reviewIDs := make([]string, 0, len(rows))
+	userIDs := make([]string, 0, len(rows))
+	for _, r := range rows {
+		reviewIDs = append(reviewIDs, r.ID.String())
+		userIDs = append(userIDs, r.UserID.String())
+	}
+
+	var users []models.User
+	if err := s.DB.Select("id", "username", "profile_image").
+		Where("id IN ? AND deleted_at IS NULL", userIDs).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	usersByID := make(map[string]models.User, len(users))
+	for _, u := range users {
+		usersByID[strings.ToLower(u.ID)] = u
+	}
+
+	voted := map[string]bool{}
+	if opts.ViewerID != nil {
+		var votedIDs []string
+		if err := s.DB.Model(&models.ReviewHelpfulVote{}).
+			Where("user_id = ? AND review_id IN ?", *opts.ViewerID, reviewIDs).
+			Pluck("review_id", &votedIDs).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range votedIDs {
+			voted[strings.ToLower(id)] = true
+		}
+	}
+
+	for _, r := range rows {
+		reviewer := Reviewer{ID: r.UserID.String()}
+		if u, ok := usersByID[reviewer.ID]; ok {
+			reviewer.Username = u.Username
+			reviewer.ProfileImage = u.ProfileImage
+		}
+		page.Reviews = append(page.Reviews, ListedReview{
+			Review:       r,
+			Reviewer:     reviewer,
+			VotedHelpful: voted[r.ID.String()],
+		})
+	}
+	return page, nil
+}
+
+// SummarizeRecipe はレシピの公開中のレビューの評価分布と平均を返す
+func (s *Service) SummarizeRecipe(recipeID string) (*Summary, error) {
+	var rows []struct {
+		Rating int
+		Count  int
+	}
+	if err := s.DB.Model(&models.Review{}).
+		Select("rating, COUNT(*) AS count").
+		Scopes(models.ActiveOwner("reviews.user_id")).
+		Where("recipe_id = ? AND status = ?", recipeID, models.ReviewStatusVisible).
+		Group("rating").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{Distribution: make(map[int]int, MaxRating-MinRating+1)}
+	for rating := MinRating; rating <= MaxRating; rating++ {
+		summary.Distribution[rating] = 0
+	}
+	sum := 0
+	for _, row := range rows {
+		summary.Distribution[row.Rating] = row.Count
+		summary.Total += row.Count
+		sum += row.Rating * row.Count
+	}
+	if summary.Total > 0 {
+		summary.Average = math.Round(float64(sum)/float64(summary.Total)*100) / 100
+	}
+	return summary, nil
+}
+
+// SetHelpful はレビューに「参考になった」を付ける・外す（何度呼んでも結果は同じ）。
+// changed は今回の呼び出しで投票状態が変わったかどうか。
+func (s *Service) SetHelpful(userID, reviewID string, helpful bool) (review *models.Review, changed bool, err error) {
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		var r models.Review
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status = ?", reviewID, models.ReviewStatusVisible).
+			Take(&r).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrReviewNotFound
+			}
+			return err
+		}
+		if strings.EqualFold(r.UserID.String(), userID) {
+			return ErrOwnReview
+		}
+
+		var res *gorm.DB
+		if helpful {
+			res = tx.Exec(`
+				INSERT INTO review_helpful_votes (review_id, user_id)
+				VALUES (?, ?)
+				ON CONFLICT (review_id, user_id) DO NOTHING`, reviewID, userID)
+		} else {
+			res = tx.Where("review_id = ? AND user_id = ?", reviewID, userID).Delete(&models.ReviewHelpfulVote{})
+		}
+		if res.Error != nil {
+			return res.Error
+		}
+		changed = res.RowsAffected > 0
+
+		if changed {
+			delta := 1
+			if !helpful {
+				delta = -1
+			}
+			if err := tx.Raw("UPDATE reviews SET helpful_count = GREATEST(helpful_count + ?, 0) WHERE id = ? RETURNING helpful_count",
+				delta, reviewID).Scan(&r.HelpfulCount).Error; err != nil {
+				return err
+			}
+		}
+		review = &r
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return review, changed, nil
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+	if _, err := uuid.Parse(c.ID); err != nil || c.CreatedAt.IsZero() {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,197 @@
+package reviews
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"portfolio-amarimono/testharness/pgtest"
+
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []cursor{
+		{Sort: SortNewest, CreatedAt: time.Date(2026, 3, 10, 12, 0, 0, 123456000, time.UTC), ID: "00000000-0000-4000-8000-000000000001"},
+		{Sort: SortHighest, Key: 5, CreatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), ID: "00000000-0000-4000-8000-000000000002"},
+		{Sort: SortHelpful, Key: 0, CreatedAt: time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC), ID: "00000000-0000-4000-8000-000000000003"},
+	}
+	for _, want := range tests {
+		t.Run(want.Sort, func(t *testing.T) {
+			got, err := decodeCursor(encodeCursor(want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Sort != want.Sort || got.Key != want.Key || got.ID != want.ID || !got.CreatedAt.Equal(want.CreatedAt) {
+				t.Errorf("decodeCursor = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"not base64", "not a cursor!"},
+		{"not json", encode("[1, 2]")},
+		{"empty object", encode("{}")},
+		{"invalid id", encode(`{"s":"newest","t":"2026-03-10T12:00:00Z","id":"1"}`)},
+		{"missing time", encode(`{"s":"newest","id":"00000000-0000-4000-8000-000000000001"}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.value); err == nil {
+				t.Errorf("decodeCursor(%q) succeeded", tt.value)
+			}
+		})
+	}
+}
+
+const (
+	listRecipeID = "00000000-0000-4000-8000-0000000000c0"
+
+	review1       = "00000000-0000-4000-8000-0000000000c1"
+	review2       = "00000000-0000-4000-8000-0000000000c2"
+	review3       = "00000000-0000-4000-8000-0000000000c3"
+	review4       = "00000000-0000-4000-8000-0000000000c4"
+	reviewHidden  = "00000000-0000-4000-8000-0000000000c5"
+	reviewDeleted = "00000000-0000-4000-8000-0000000000c6"
+
+	reviewer1       = "00000000-0000-4000-8000-000000000001"
+	reviewer2       = "00000000-0000-4000-8000-000000000002"
+	reviewer3       = "00000000-0000-4000-8000-000000000003"
+	reviewer4       = "00000000-0000-4000-8000-000000000004"
+	reviewer5       = "00000000-0000-4000-8000-000000000005"
+	reviewerDeleted = "00000000-0000-4000-8000-000000000006"
+)
+
+func TestListByRecipeSorting(t *testing.T) {
+	db := pgtest.Open(t)
+	seedReviews(t, db)
+	s := NewService(db)
+
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{SortNewest, []string{review4, review3, review2, review1}},
+		// 同じ評価の中では新しい順
+		{SortHighest, []string{review3, review1, review2, review4}},
+		{SortLowest, []string{review4, review2, review3, review1}},
+		{SortHelpful, []string{review4, review2, review3, review1}},
+	}
+	for _, tt := range tests {
+		for _, limit := range []int{1, 2, 3, 10} {
+			t.Run(fmt.Sprintf("%s/limit %d", tt.sort, limit), func(t *testing.T) {
+				var got []string
+				cursor := ""
+				for pages := 0; pages < 10; pages++ {
+					page, err := s.ListByRecipe(listRecipeID, ListOptions{Sort: tt.sort, Cursor: cursor, Limit: limit})
+					if err != nil {
+						t.Fatal(err)
+					}
+					for _, r := range page.Reviews {
+						got = append(got, r.ID.String())
+					}
+					if page.HasNextPage != (page.NextCursor != "") {
+						t.Fatalf("hasNextPage = %v with cursor %q", page.HasNextPage, page.NextCursor)
+					}
+					if !page.HasNextPage {
+						break
+					}
+					cursor = page.NextCursor
+				}
+				// 非表示のレビューと削除を申請中のユーザーのレビューは含まない
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("reviews = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestListByRecipeInvalidOptions(t *testing.T) {
+	db := pgtest.Open(t)
+	seedReviews(t, db)
+	s := NewService(db)
+
+	page, err := s.ListByRecipe(listRecipeID, ListOptions{Sort: SortNewest, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    ListOptions
+		wantErr error
+	}{
+		{"unknown sort", ListOptions{Sort: "random"}, ErrInvalidSort},
+		{"broken cursor", ListOptions{Sort: SortNewest, Cursor: "broken"}, ErrInvalidCursor},
+		{"cursor from another sort", ListOptions{Sort: SortHighest, Cursor: page.NextCursor}, ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.ListByRecipe(listRecipeID, tt.opts); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSummarizeRecipe(t *testing.T) {
+	db := pgtest.Open(t)
+	seedReviews(t, db)
+
+	got, err := NewService(db).SummarizeRecipe(listRecipeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Summary{Average: 3.5, Total: 4, Distribution: map[int]int{1: 1, 2: 0, 3: 1, 4: 0, 5: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SummarizeRecipe = %+v, want %+v", got, want)
+	}
+}
+
+// seedReviews は評価・「参考になった」の数・投稿日時が異なるレビューを作成する。
+// 非表示のレビューと、削除を申請中のユーザーのレビューも含む。
+func seedReviews(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	if err := db.Exec("INSERT INTO recipes (id, name) VALUES (?, ?)", listRecipeID, "recipe").Error; err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	deletedAt := base.Add(24 * time.Hour)
+	for i, r := range []struct {
+		id        string
+		userID    string
+		rating    int
+		helpful   int
+		status    string
+		deletedAt *time.Time
+	}{
+		{review1, reviewer1, 5, 0, "visible", nil},
+		{review2, reviewer2, 3, 4, "visible", nil},
+		{review3, reviewer3, 5, 1, "visible", nil},
+		{review4, reviewer4, 1, 4, "visible", nil},
+		{reviewHidden, reviewer5, 4, 9, "hidden", nil},
+		{reviewDeleted, reviewerDeleted, 2, 9, "visible", &deletedAt},
+	} {
+		if err := db.Exec("INSERT INTO users (id, email, deleted_at) VALUES (?, ?, ?)", r.userID, r.userID+"@example.com", r.deletedAt).Error; err != nil {
+			t.Fatal(err)
+		}
+		createdAt := base.Add(time.Duration(i+1) * time.Hour)
+		if err := db.Exec("INSERT INTO reviews (id, recipe_id, user_id, rating, status, helpful_count, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			r.id, listRecipeID, r.userID, r.rating, r.status, r.helpful, createdAt, createdAt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package reviews
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"portfolio-amarimono/aggregates"
+	"portfolio-amarimono/feed"
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/moderation"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// MinRating / MaxRating は評価の範囲
+	MinRating = 1
+	MaxRating = 5
+	// MaxCommentLength はコメントの最大文字数
+	MaxCommentLength = 1000
+	// DefaultEditWindow は投稿後にレビューを編集できる期間
+	DefaultEditWindow = 30 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidRating    = fmt.Errorf("rating must be between %d and %d", MinRating, MaxRating)
+	ErrCommentTooLong   = fmt.Errorf("comment must be at most %d characters", MaxCommentLength)
+	ErrRecipeNotFound   = errors.New("recipe not found")
+	ErrReviewNotFound   = errors.New("review not found")
+	ErrNotOwner         = errors.New("review belongs to another user")
+	ErrEditWindowClosed = errors.New("review can no longer be edited")
+	ErrInappropriate    = errors.New("comment contains inappropriate words")
+)
+
+// Input はユーザーが指定できるレビューの内容（ID・投稿者・日時はクライアントから受け取らない）
+type Input struct {
+	Rating  int
+	Comment string
+}
+
+// Validate は評価とコメントを検証し、コメントの前後の空白を取り除く
+func (in *Input) Validate() error {
+	if in.Rating < MinRating || in.Rating > MaxRating {
+		return ErrInvalidRating
+	}
+	in.Comment = strings.TrimSpace(in.Comment)
+	if utf8.RuneCountInString(in.Comment) > MaxCommentLength {
+		return ErrCommentTooLong
+	}
+	return nil
+}
+
+// Service はレビューの投稿・編集・削除を扱う。
+// レビューは (user_id, recipe_id) ごとに1件で、同じレシピへの2回目の投稿は既存のレビューの更新になる。
+type Service struct {
+	DB *gorm.DB
+	// EditWindow は投稿後に編集できる期間（0以下なら無期限）
+	EditWindow time.Duration
+	// Filter はコメントのNGワード判定に使う（nil なら判定しない）
+	Filter *moderation.Filter
+	Now    func() time.Time
+}
+
+// NewService は Service を初期化するコンストラクタ
+func NewService(db *gorm.DB) *Service {
+	return &Service{
+		DB:         db,
+		EditWindow: DefaultEditWindow,
+		Filter:     moderation.DefaultFilter(),
+		Now:        time.Now,
+	}
+}
+
+// EditableUntil はレビューを編集できる期限を返す（無期限の場合は nil）
+func (s *Service) EditableUntil(review *models.Review) *time.Time {
+	if s.EditWindow <= 0 {
+		return nil
+	}
+	until := review.CreatedAt.Add(s.EditWindow)
+	return &until
+}
+
+// Submit はレビューを投稿する。既にレビュー済みの場合は内容を更新し、created は false になる。
+func (s *Service) Submit(userID, recipeID string, in Input) (review *models.Review, created bool, err error) {
+	if err := s.validate(&in); err != nil {
+		return nil, false, err
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		var recipeCount int64
+		if err := tx.Model(&models.Recipe{}).Where("id = ?", recipeID).Count(&recipeCount).Error; err != nil {
+			return err
+		}
+		if recipeCount == 0 {
+			return ErrRecipeNotFound
+		}
+
+		// (user_id, recipe_id) の一意制約により、同時に投稿されても1件だけ作成される
+		var inserted []models.Review
+		if err := tx.Raw(`
+			INSERT INTO reviews (recipe_id, user_id, rating, comment)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (user_id, recipe_id) DO NOTHING
+			RETURNING *`, recipeID, userID, in.Rating, in.Comment).Scan(&inserted).Error; err != nil {
+			return err
+		}
+		if len(inserted) > 0 {
+			review = &inserted[0]
+			created = true
+			if err := aggregates.RefreshReviewStats(tx, recipeID); err != nil {
+				return err
+			}
+			return feed.SyncReview(tx, review)
+		}
+
+		var existing models.Review
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND recipe_id = ?", userID, recipeID).
+			Take(&existing).Error; err != nil {
+			return err
+		}
+		if err := s.update(tx, &existing, in); err != nil {
+			return err
+		}
+		review = &existing
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return review, created, nil
+}
+
+// Update は本人のレビューを編集期間内に限り更新する
+func (s *Service) Update(userID, reviewID string, in Input) (*models.Review, error) {
+	if err := s.validate(&in); err != nil {
+		return nil, err
+	}
+
+	var review models.Review
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.findOwn(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID, reviewID, &review); err != nil {
+			return err
+		}
+		return s.update(tx, &review, in)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// Editable は本人のレビューを取得し、編集期間内であることを確認する（写真の追加・削除の前に使う）
+func (s *Service) Editable(userID, reviewID string) (*models.Review, error) {
+	var review models.Review
+	if err := s.findOwn(s.DB, userID, reviewID, &review); err != nil {
+		return nil, err
+	}
+	if until := s.EditableUntil(&review); until != nil && s.Now().After(*until) {
+		return nil, ErrEditWindowClosed
+	}
+	return &review, nil
+}
+
+// Delete は本人のレビューを削除する（削除は編集期間を過ぎていても行える）
+func (s *Service) Delete(userID, reviewID string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var review models.Review
+		if err := s.findOwn(tx, userID, reviewID, &review); err != nil {
+			return err
+		}
+		if err := tx.Delete(&review).Error; err != nil {
+			return err
+		}
+		if err := feed.Retract(tx, models.ActivityKindReview, review.ID.String()); err != nil {
+			return err
+		}
+		return aggregates.RefreshReviewStats(tx, review.RecipeID.String())
+	})
+}
+
+// validate は入力を検証し、コメントにNGワードが含まれていないかを確認する
+func (s *Service) validate(in *Input) error {
+	if err := in.Validate(); err != nil {
+		return err
+	}
+	if s.Filter.Contains(in.Comment) {
+		return ErrInappropriate
+	}
+	return nil
+}
+
+// update は編集期間を確認してからレビューを書き換え、平均評価を更新する
+func (s *Service) update(tx *gorm.DB, review *models.Review, in Input) error {
+	if until := s.EditableUntil(review); until != nil && s.Now().After(*until) {
+		return ErrEditWindowClosed
+	}
+
+	review.Rating = in.Rating
+	review.Comment = in.Comment
+	review.UpdatedAt = s.Now()
+	if err := tx.Model(review).Select("rating", "comment", "updated_at").Updates(review).Error; err != nil {
+		return err
+	}
+	return aggregates.RefreshReviewStats(tx, review.RecipeID.String())
+}
+
+// findOwn はレビューを取得し、投稿者が userID であることを確認する
+func (s *Service) findOwn(tx *gorm.DB, userID, reviewID string, review *models.Review) error {
+	err := tx.Where("id = ?", reviewID).Take(review).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrReviewNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(review.UserID.String(), userID) {
+		return ErrNotOwner
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package reviews
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"portfolio-amarimono/models"
+	"portfolio-amarimono/testharness/pgtest"
+
+	"gorm.io/gorm"
+)
+
+func TestInputValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          Input
+		wantErr     error
+		wantComment string
+	}{
+		{"minimum rating", Input{Rating: MinRating}, nil, ""},
+		{"maximum rating", Input{Rating: MaxRating, Comment: "おいしい"}, nil, "おいしい"},
+		{"rating too low", Input{Rating: MinRating - 1}, ErrInvalidRating, ""},
+		{"rating too high", Input{Rating: MaxRating + 1}, ErrInvalidRating, ""},
+		{"trims the comment", Input{Rating: 3, Comment: "  おいしい\n"}, nil, "おいしい"},
+		{"longest comment", Input{Rating: 3, Comment: strings.Repeat("あ", MaxCommentLength)}, nil, strings.Repeat("あ", MaxCommentLength)},
+		{"comment too long", Input{Rating: 3, Comment: strings.Repeat("あ", MaxCommentLength+1)}, ErrCommentTooLong, ""},
+		{"surrounding spaces do not count", Input{Rating: 3, Comment: " " + strings.Repeat("あ", MaxCommentLength) + " "}, nil, strings.Repeat("あ", MaxCommentLength)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := tt.in
+			err := in.Validate()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && in.Comment != tt.wantComment {
+				t.Errorf("comment = %q, want %q", in.Comment, tt.wantComment)
+			}
+		})
+	}
+}
+
+const (
+	submitRecipeID = "00000000-0000-4000-8000-0000000000e0"
+	author         = "00000000-0000-4000-8000-0000000000e1"
+	otherAuthor    = "00000000-0000-4000-8000-0000000000e2"
+	missingID      = "00000000-0000-4000-8000-0000000000ef"
+)
+
+func TestSubmit(t *testing.T) {
+	db := pgtest.Open(t)
+	seedSubmit(t, db)
+	s := NewService(db)
+
+	first, created, err := s.Submit(author, submitRecipeID, Input{Rating: 2, Comment: "しょっぱい"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Error("first submit was not created")
+	}
+
+	// 同じレシピへの2回目の投稿は既存のレビューの更新になる
+	second, created, err := s.Submit(author, submitRecipeID, Input{Rating: 4, Comment: " 作り直したらおいしかった "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || second.ID != first.ID || second.Rating != 4 || second.Comment != "作り直したらおいしかった" {
+		t.Errorf("second submit = %+v (created %v), want an update of %s", second, created, first.ID)
+	}
+	var stats struct {
+		Count       int64
+		ReviewCount int
+		RatingAvg   float64
+	}
+	if err := db.Raw(`SELECT (SELECT COUNT(*) FROM reviews WHERE recipe_id = ?) AS count, review_count, rating_avg FROM recipes WHERE id = ?`,
+		submitRecipeID, submitRecipeID).Scan(&stats).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stats.Count != 1 || stats.ReviewCount != 1 || stats.RatingAvg != 4 {
+		t.Errorf("stats = %+v, want one review rated 4", stats)
+	}
+
+	// 入力の誤りは 400 として返すエラーになり、何も保存しない
+	tests := []struct {
+		name     string
+		recipeID string
+		in       Input
+		wantErr  error
+	}{
+		{"invalid rating", submitRecipeID, Input{Rating: 0}, ErrInvalidRating},
+		{"comment too long", submitRecipeID, Input{Rating: 3, Comment: strings.Repeat("あ", MaxCommentLength+1)}, ErrCommentTooLong},
+		{"inappropriate comment", submitRecipeID, Input{Rating: 3, Comment: "バカみたいな味"}, ErrInappropriate},
+		{"unknown recipe", missingID, Input{Rating: 3}, ErrRecipeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := s.Submit(otherAuthor, tt.recipeID, tt.in); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			var count int64
+			if err := db.Model(&models.Review{}).Where("user_id = ?", otherAuthor).Count(&count).Error; err != nil {
+				t.Fatal(err)
+			}
+			if count != 0 {
+				t.Errorf("saved %d reviews", count)
+			}
+		})
+	}
+}
+
+func TestEditWindow(t *testing.T) {
+	db := pgtest.Open(t)
+	seedSubmit(t, db)
+	s := NewService(db)
+	review, _, err := s.Submit(author, submitRecipeID, Input{Rating: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := review.ID.String()
+
+	steps := []struct {
+		name    string
+		after   time.Duration
+		run     func() error
+		wantErr error
+	}{
+		{"update within the window", DefaultEditWindow - time.Minute, func() error {
+			_, err := s.Update(author, id, Input{Rating: 4})
+			return err
+		}, nil},
+		{"editable within the window", DefaultEditWindow - time.Minute, func() error {
+			_, err := s.Editable(author, id)
+			return err
+		}, nil},
+		{"update after the window", DefaultEditWindow + time.Minute, func() error {
+			_, err := s.Update(author, id, Input{Rating: 5})
+			return err
+		}, ErrEditWindowClosed},
+		{"submit again after the window", DefaultEditWindow + time.Minute, func() error {
+			_, _, err := s.Submit(author, submitRecipeID, Input{Rating: 5})
+			return err
+		}, ErrEditWindowClosed},
+		{"not editable after the window", DefaultEditWindow + time.Minute, func() error {
+			_, err := s.Editable(author, id)
+			return err
+		}, ErrEditWindowClosed},
+		{"delete after the window", DefaultEditWindow + time.Minute, func() error {
+			return s.Delete(author, id)
+		}, nil},
+	}
+	for _, step := range steps {
+		now := review.CreatedAt.Add(step.after)
+		s.Now = func() time.Time { return now }
+		if err := step.run(); !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: err = %v, want %v", step.name, err, step.wantErr)
+		}
+	}
+
+	var count int64
+	if err := db.Model(&models.Review{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Error("review was not deleted")
+	}
+
+	// 期間を0以下にすると無期限に編集できる
+	s.EditWindow = 0
+	if review, _, err = s.Submit(author, submitRecipeID, Input{Rating: 3}); err != nil {
+		t.Fatal(err)
+	}
+	s.Now = func() time.Time { return review.CreatedAt.Add(10 * DefaultEditWindow) }
+	if _, err := s.Update(author, review.ID.String(), Input{Rating: 1}); err != nil {
+		t.Errorf("update without a window: %v", err)
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	db := pgtest.Open(t)
+	seedSubmit(t, db)
+	s := NewService(db)
+	review, _, err := s.Submit(author, submitRecipeID, Input{Rating: 3, Comment: "ふつう"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := review.ID.String()
+
+	tests := []struct {
+		name     string
+		userID   string
+		reviewID string
+		wantErr  error
+	}{
+		{"another user", otherAuthor, id, ErrNotOwner},
+		{"unknown review", author, missingID, ErrReviewNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Update(tt.userID, tt.reviewID, Input{Rating: 1}); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Update: err = %v, want %v", err, tt.wantErr)
+			}
+			if _, err := s.Editable(tt.userID, tt.reviewID); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Editable: err = %v, want %v", err, tt.wantErr)
+			}
+			if err := s.Delete(tt.userID, tt.reviewID); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Delete: err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// 他のユーザーの操作ではレビューは変わらない
+	var got models.Review
+	if err := db.Where("id = ?", id).Take(&got).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Rating != 3 || got.Comment != "ふつう" {
+		t.Errorf("review = %+v, want unchanged", got)
+	}
+
+	// 本人の操作は大文字のIDでも受け付ける
+	if _, err := s.Update(strings.ToUpper(author), id, Input{Rating: 5}); err != nil {
+		t.Errorf("update by the author: %v", err)
+	}
+}
+
+// seedSubmit はレビューを投稿するレシピと2人のユーザーを作成する
+func seedSubmit(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	for _, id := range []string{author, otherAuthor} {
+		if err := db.Exec("INSERT INTO users (id, email) VALUES (?, ?)", id, id+"@example.com").Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Exec("INSERT INTO recipes (id, name) VALUES (?, ?)", submitRecipeID, "recipe").Error; err != nil {
+		t.Fatal(err)
+	}
+}
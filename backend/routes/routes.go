@@ -7,41 +7,65 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(router *gin.Engine, recipeHandler *handlers.RecipeHandler, likeHandler *handlers.LikeHandler, userHandler *handlers.UserHandler, genreHandler *handlers.GenreHandler, adminHandler *handlers.AdminHandler, reviewHandler *handlers.ReviewHandler, recommendationHandler *handlers.RecommendationHandler, userIngredientDefaultHandler *handlers.UserIngredientDefaultHandler, aiUsageHandler *handlers.AIUsageHandler, db *gorm.DB) {
+func SetupRoutes(router *gin.Engine, recipeHandler *handlers.RecipeHandler, likeHandler *handlers.LikeHandler, userHandler *handlers.UserHandler, genreHandler *handlers.GenreHandler, adminHandler *handlers.AdminHandler, reviewHandler *handlers.ReviewHandler, recommendationHandler *handlers.RecommendationHandler, userIngredientDefaultHandler *handlers.UserIngredientDefaultHandler, aiUsageHandler *handlers.AIUsageHandler, rankingHandler *handlers.RankingHandler, collectionHandler *handlers.CollectionHandler, cookLogHandler *handlers.CookLogHandler, profileHandler *handlers.ProfileHandler, feedHandler *handlers.FeedHandler, notificationHandler *handlers.NotificationHandler, db *gorm.DB) {
 	// いいね機能のエンドポイント
-	router.POST("/api/likes/:user_id/:recipe_id", likeHandler.ToggleUserLike) // レシピにいいねを追加
-	router.GET("/api/likes/:user_id", likeHandler.GetUserLikes)               // ユーザーのお気に入りレシピを取得
+	router.POST("/api/likes/:user_id/:recipe_id", likeHandler.ToggleUserLike)   // レシピのいいねを切り替え（旧API）
+	router.GET("/api/likes/:user_id", likeHandler.GetUserLikes)                 // ユーザーのお気に入りレシピを取得
+	router.PUT("/api/likes/:user_id/:recipe_id", likeHandler.PutUserLike)       // レシピにいいねする（冪等）
+	router.DELETE("/api/likes/:user_id/:recipe_id", likeHandler.DeleteUserLike) // レシピのいいねを取り消す（冪等）
+	router.GET("/api/likes/:user_id/status", likeHandler.GetUserLikeStatuses)   // 複数レシピのいいね状態を一括取得
 
 	// `/api/recipes` エンドポイントの登録
-	router.POST("/api/recipes", recipeHandler.SerchRecipes)              // レシピ検索
-	router.GET("/api/recipes/:id", recipeHandler.GetRecipeByID)          // レシピ詳細を取得
-	router.GET("/api/recipes/search", recipeHandler.SearchRecipesByName) // レシピ名付検索
+	router.POST("/api/recipes", recipeHandler.SerchRecipes)                 // レシピ検索
+	router.GET("/api/recipes/:id", recipeHandler.GetRecipeByID)             // レシピ詳細を取得
+	router.GET("/api/recipes/:id/similar", recipeHandler.GetSimilarRecipes) // 似ているレシピを取得
+	router.GET("/api/recipes/search", recipeHandler.SearchRecipesByName)    // レシピ名付検索
+
+	// ランキング（trending / top_rated / most_cooked）
+	router.GET("/api/rankings/:kind", rankingHandler.GetRanking)
 
 	// `/api/recommendations` エンドポイントの登録
 	router.GET("/api/recommendations/:user_id", recommendationHandler.GetRecommendedRecipes)
 
 	// ユーザー関連エンドポイント
-	router.POST("/api/users", userHandler.CreateUser)                           // 新規作成（純粋な作成のみ）
-	router.POST("/api/users/sync", userHandler.SyncUser)                        // 同期処理（作成・更新）
-	router.GET("/api/users/:id", userHandler.GetUser)                           // 純粋な取得
-	router.GET("/api/users/:id/profile", userHandler.GetUserProfile)            // プロフィール取得（ロール情報付き）
-	router.PUT("/api/users/:id", userHandler.UpdateUserProfile)                 // プロフィール更新
-	router.POST("/api/users/:id/profile-image", userHandler.UploadProfileImage) // プロフィール画像アップロード用エンドポイント
-	router.GET("/api/users/:id/likes", userHandler.GetUserLikeCount)            // ユーザーの投稿レシピの合計いいね数を取得
+	router.POST("/api/users", userHandler.CreateUser)                                        // 新規作成（純粋な作成のみ）
+	router.POST("/api/users/sync", userHandler.SyncUser)                                     // 同期処理（作成・更新）
+	router.GET("/api/users/:id", userHandler.GetUser)                                        // 純粋な取得
+	router.GET("/api/users/:id/profile", userHandler.GetUserProfile)                         // プロフィール取得（ロール情報付き）
+	router.PUT("/api/users/:id", userHandler.UpdateUserProfile)                              // プロフィール更新
+	router.DELETE("/api/users/:id", userHandler.DeleteUser)                                  // 退会（猶予期間後に完全削除）
+	router.POST("/api/users/:id/restore", userHandler.RestoreUser)                           // 猶予期間内の退会の取り消し
+	router.POST("/api/users/:id/export", userHandler.RequestDataExport)                      // 個人データのエクスポートを開始
+	router.GET("/api/users/:id/export", userHandler.GetDataExport)                           // エクスポートの進捗
+	router.GET("/api/users/:id/export/download", userHandler.DownloadDataExport)             // エクスポートしたZIPのダウンロード
+	router.GET("/api/users/:id/dietary-restrictions", userHandler.GetDietaryRestrictions)    // 除外するアレルゲン・食事制限
+	router.PUT("/api/users/:id/dietary-restrictions", userHandler.UpdateDietaryRestrictions) // 除外するアレルゲン・食事制限を更新
+	router.POST("/api/users/:id/profile-image", userHandler.UploadProfileImage)              // プロフィール画像アップロード用エンドポイント
+	router.GET("/api/users/:id/likes", userHandler.GetUserLikeCount)                         // ユーザーの投稿レシピの合計いいね数を取得
 	router.GET("/api/users/:id/reviews", userHandler.GetUserRecipeAverageRating)
-	router.POST("/api/users/role", userHandler.SetUserRole)       // 管理者権限設定用のエンドポイント
-	router.GET("/api/user/recipes", recipeHandler.GetUserRecipes) // 特定のレシピ取得
+	router.GET("/api/users/:id/public", profileHandler.GetPublicProfile) // 公開プロフィール（公開レシピ・集計値付き）
+	router.PUT("/api/users/:id/follow", profileHandler.FollowUser)       // フォロー（冪等）
+	router.DELETE("/api/users/:id/follow", profileHandler.UnfollowUser)  // フォロー解除（冪等）
+	router.GET("/api/users/:id/followers", profileHandler.ListFollowers) // フォロワー一覧
+	router.GET("/api/users/:id/following", profileHandler.ListFollowing) // フォロー中のユーザー一覧
+	router.POST("/api/users/role", userHandler.SetUserRole)              // 管理者権限設定用のエンドポイント
+	router.GET("/api/user/recipes", recipeHandler.GetUserRecipes)        // 特定のレシピ取得
 
 	// ジャンル取得エンドポイント
 	router.GET("/api/recipe_genres", genreHandler.ListRecipeGenres)
 	router.GET("/api/ingredient_genres", genreHandler.ListIngredientGenres)
 
 	// レビュー関連のルーティング
-	router.POST("/api/reviews", reviewHandler.AddReview)                       // レビュー追加
-	router.GET("/api/reviews/:recipe_id", reviewHandler.GetReviewsByRecipeID)  // レシピのレビュー取得
-	router.GET("/api/reviews/user/:user_id", reviewHandler.GetReviewsByUserID) // ユーザーのレビュー取得
-	router.PUT("/api/reviews/:id", reviewHandler.UpdateReview)                 // レビュー更新
-	router.DELETE("/api/reviews/:id", reviewHandler.DeleteReview)              // レビュー削除
+	router.POST("/api/reviews", reviewHandler.AddReview)                                // レビュー追加
+	router.GET("/api/reviews/:recipe_id", reviewHandler.GetReviewsByRecipeID)           // レシピのレビュー取得
+	router.GET("/api/reviews/user/:user_id", reviewHandler.GetReviewsByUserID)          // ユーザーのレビュー取得
+	router.PUT("/api/reviews/:id", reviewHandler.UpdateReview)                          // レビュー更新
+	router.DELETE("/api/reviews/:id", reviewHandler.DeleteReview)                       // レビュー削除
+	router.POST("/api/reviews/:id/report", reviewHandler.ReportReview)                  // レビューを通報
+	router.PUT("/api/reviews/:id/helpful", reviewHandler.MarkReviewHelpful)             // 「参考になった」を付ける
+	router.DELETE("/api/reviews/:id/helpful", reviewHandler.UnmarkReviewHelpful)        // 「参考になった」を取り消す
+	router.POST("/api/reviews/:id/photos", reviewHandler.UploadReviewPhotos)            // レビューに写真を追加
+	router.DELETE("/api/reviews/:id/photos/:photo_id", reviewHandler.DeleteReviewPhoto) // レビューの写真を削除
 
 	// 具材関連のルーティング（認証不要）
 	router.GET("/api/ingredients/by-category", userIngredientDefaultHandler.GetIngredientsByCategory) // カテゴリ別の具材を取得
@@ -52,6 +76,9 @@ func SetupRoutes(router *gin.Engine, recipeHandler *handlers.RecipeHandler, like
 	router.GET("/api/user/ingredient-defaults", userIngredientDefaultHandler.GetUserIngredientDefaults)   // ユーザーの初期設定具材を取得
 	router.PUT("/api/user/ingredient-defaults", userIngredientDefaultHandler.UpdateUserIngredientDefault) // ユーザーの初期設定具材を更新
 
+	// 共有URLから公開コレクションを取得（認証不要）
+	router.GET("/api/collections/shared/:slug", collectionHandler.GetSharedCollection)
+
 	// 認証済みルートグループ
 	auth := router.Group("/api")
 	{
@@ -59,23 +86,69 @@ func SetupRoutes(router *gin.Engine, recipeHandler *handlers.RecipeHandler, like
 		auth.GET("/recipe/ai-usage", aiUsageHandler.GetAIUsage)
 		auth.POST("/recipe/ai-usage", aiUsageHandler.IncrementAIUsage)
 		auth.POST("/recipe/generate-description", aiUsageHandler.GenerateDescription)
+		auth.POST("/recipe/generate-faq", aiUsageHandler.GenerateFAQ)     // FAQの候補を生成
+		auth.POST("/recipe/generate-steps", aiUsageHandler.GenerateSteps) // 手順の説明文の候補を生成
+		auth.POST("/recipe/generate-tips", aiUsageHandler.GenerateTips)   // 材料の代用のコツの候補を生成
+
+		// 「作った」記録
+		auth.POST("/recipes/:id/cook-logs", cookLogHandler.CreateCookLog) // レシピを作った記録を追加
+		auth.GET("/cook-logs", cookLogHandler.ListCookLogs)               // 自分の「作った」履歴
+		auth.PUT("/cook-logs/:id", cookLogHandler.UpdateCookLog)          // 記録を更新
+		auth.DELETE("/cook-logs/:id", cookLogHandler.DeleteCookLog)       // 記録を削除
+
+		// コレクション（レシピのフォルダ）
+		auth.GET("/collections", collectionHandler.ListCollections)                                  // 自分のコレクション一覧
+		auth.POST("/collections", collectionHandler.CreateCollection)                                // コレクション作成
+		auth.PUT("/collections/order", collectionHandler.ReorderCollections)                         // コレクションの並び替え
+		auth.GET("/collections/:id", collectionHandler.GetCollection)                                // コレクション詳細（レシピ付き）
+		auth.PUT("/collections/:id", collectionHandler.UpdateCollection)                             // 名前・説明・公開設定の更新
+		auth.DELETE("/collections/:id", collectionHandler.DeleteCollection)                          // コレクション削除
+		auth.POST("/collections/:id/recipes", collectionHandler.AddCollectionRecipe)                 // レシピを追加
+		auth.PUT("/collections/:id/recipes/order", collectionHandler.ReorderCollectionRecipes)       // レシピの並び替え
+		auth.DELETE("/collections/:id/recipes/:recipe_id", collectionHandler.RemoveCollectionRecipe) // レシピを外す
+
+		// フォローしているユーザーのフィード
+		auth.GET("/feed", feedHandler.GetFeed) // レシピ・レビュー・コレクションの新着（カーソルページング）
+
+		// 通知（受信箱と配信先の設定）
+		auth.GET("/notifications", notificationHandler.ListNotifications)                         // 受信箱（?unread=true で未読のみ）
+		auth.GET("/notifications/unread-count", notificationHandler.GetUnreadCount)               // 未読件数
+		auth.PUT("/notifications/read-all", notificationHandler.MarkAllNotificationsRead)         // すべて既読にする
+		auth.PUT("/notifications/:id/read", notificationHandler.MarkNotificationRead)             // 既読にする
+		auth.GET("/notifications/preferences", notificationHandler.GetNotificationPreferences)    // 配信先の設定
+		auth.PUT("/notifications/preferences", notificationHandler.UpdateNotificationPreferences) // 配信先の設定を更新
 	}
 
 	// 管理画面用エンドポイント
 	admin := router.Group("/admin")
 	{
-		admin.GET("/ingredients", adminHandler.ListIngredients)                    // 具材一覧
-		admin.POST("/ingredients", adminHandler.AddIngredient)                     // 具材追加
-		admin.PATCH("/ingredients/:id", adminHandler.UpdateIngredient)             // 具材更新
-		admin.DELETE("/ingredients/:id", adminHandler.DeleteIngredient)            // 具材削除
-		admin.GET("/recipes", adminHandler.ListRecipes)                            // レシピ一覧
-		admin.GET("/recipes/:id", adminHandler.GetRecipe)                          // レシピ取得
-		admin.POST("/recipes", adminHandler.AddRecipe)                             // レシピ追加
-		admin.PUT("/recipes/:id", adminHandler.UpdateRecipe)                       // レシピ更新
-		admin.DELETE("/recipes/:id", adminHandler.DeleteRecipe)                    // レシピ削除
-		admin.PUT("/recipes/:id/toggle-publish", adminHandler.ToggleRecipePublish) // レシピの公開/非公開を切り替え
-		admin.GET("/units", adminHandler.ListUnits)                                // 単位一覧
-		admin.POST("/draft-recipes", adminHandler.SaveDraftRecipe)                 // 下書きレシピの保存
+		admin.GET("/ingredients", adminHandler.ListIngredients)                                     // 具材一覧
+		admin.POST("/ingredients", adminHandler.AddIngredient)                                      // 具材追加
+		admin.PATCH("/ingredients/:id", adminHandler.UpdateIngredient)                              // 具材更新
+		admin.DELETE("/ingredients/:id", adminHandler.DeleteIngredient)                             // 具材削除
+		admin.GET("/ingredients/:id/dietary-tags", adminHandler.GetIngredientDietaryTags)           // 具材のアレルゲン・食事制限のタグ
+		admin.PUT("/ingredients/:id/dietary-tags", adminHandler.UpdateIngredientDietaryTags)        // タグを更新
+		admin.GET("/recipes", adminHandler.ListRecipes)                                             // レシピ一覧
+		admin.GET("/recipes/:id", adminHandler.GetRecipe)                                           // レシピ取得
+		admin.POST("/recipes", adminHandler.AddRecipe)                                              // レシピ追加
+		admin.PUT("/recipes/:id", adminHandler.UpdateRecipe)                                        // レシピ更新
+		admin.DELETE("/recipes/:id", adminHandler.DeleteRecipe)                                     // レシピ削除
+		admin.PUT("/recipes/:id/toggle-publish", adminHandler.ToggleRecipePublish)                  // レシピの公開/非公開を切り替え
+		admin.POST("/recipes/:id/ai-suggestions", adminHandler.AcceptRecipeSuggestions)             // AIが生成した候補の取り込み
+		admin.GET("/recipes/:id/image-prompts", adminHandler.ListImagePrompts)                      // 画像生成プロンプト一覧
+		admin.POST("/recipes/:id/image-prompts/defaults", adminHandler.GenerateDefaultImagePrompts) // 既定のプロンプトを生成
+		admin.PUT("/recipes/:id/image-prompts/:step", adminHandler.UpdateImagePrompt)               // プロンプト編集（新しいバージョンとして保存）
+		admin.GET("/recipes/:id/image-prompts/:step/history", adminHandler.GetImagePromptHistory)   // プロンプトのバージョン履歴
+		admin.GET("/recipes/:id/image-generations", adminHandler.ListImageGenerations)              // 画像の生成記録
+		admin.POST("/recipes/:id/image-generations", adminHandler.RecordImageGeneration)            // 画像の生成記録を追加
+		admin.GET("/reviews/reports", adminHandler.ListReviewReports)                               // 通報されたレビューの一覧（モデレーションキュー）
+		admin.POST("/reviews/:id/hide", adminHandler.HideReview)                                    // レビューを非表示
+		admin.POST("/reviews/:id/restore", adminHandler.RestoreReview)                              // 非表示のレビューを再表示
+		admin.POST("/reviews/:id/dismiss", adminHandler.DismissReviewReports)                       // 通報を却下
+		admin.DELETE("/reviews/:id", adminHandler.DeleteReview)                                     // レビューを削除
+		admin.GET("/reviews/:id/moderation-log", adminHandler.GetReviewModerationLog)               // モデレーション操作の履歴
+		admin.GET("/units", adminHandler.ListUnits)                                                 // 単位一覧
+		admin.POST("/draft-recipes", adminHandler.SaveDraftRecipe)                                  // 下書きレシピの保存
 		admin.GET("/draft-recipes/:userId", adminHandler.GetDraftRecipes)
 	}
 }
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound はキーに対応するオブジェクトが存在しない場合のエラー
+var ErrNotFound = errors.New("object not found")
+
+// ImageStore は画像の保存先を抽象化するインターフェース。
+// キーは "recipes/<id>/xxx.png" のようなスラッシュ区切りの相対パスで、DBにもこの形式で保存する。
+type ImageStore interface {
+	// Put はキーにデータを書き込む（既存のオブジェクトは上書き）
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Get はキーのオブジェクトを読み出す（存在しない場合は ErrNotFound）
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete はキーのオブジェクトを削除する（存在しない場合もエラーにしない）
+	Delete(ctx context.Context, key string) error
+	// URL はキーの公開URLを返す
+	URL(key string) string
+	// Exists はキーのオブジェクトが存在するかを返す
+	Exists(ctx context.Context, key string) (bool, error)
+	// List はプレフィックス配下の全オブジェクトを返す
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+}
+
+// ObjectInfo は保存されているオブジェクトの情報
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Type はストレージの種類
+type Type string
+
+const (
+	TypeLocal    Type = "local"
+	TypeR2       Type = "r2"
+	TypeS3       Type = "s3"
+	TypeSupabase Type = "supabase"
+	TypeMemory   Type = "memory"
+)
+
+// NewFromEnv は STORAGE_TYPE 環境変数に応じてImageStoreを作成する。
+// 未設定の場合は従来どおりSupabaseを使用する。
+func NewFromEnv() (ImageStore, error) {
+	return New(Type(os.Getenv("STORAGE_TYPE")))
+}
+
+// New は指定した種類のImageStoreを、その種類の環境変数の設定で作成する
+func New(storageType Type) (ImageStore, error) {
+	switch storageType {
+	case TypeLocal:
+		dir := os.Getenv("LOCAL_STORAGE_DIR")
+		if dir == "" {
+			dir = "./uploads"
+		}
+		baseURL := os.Getenv("LOCAL_STORAGE_URL")
+		if baseURL == "" {
+			baseURL = "/uploads"
+		}
+		return NewLocalStore(dir, baseURL)
+	case TypeR2:
+		return NewS3Store(context.Background(), R2ConfigFromEnv())
+	case TypeS3:
+		return NewS3Store(context.Background(), S3ConfigFromEnv())
+	case TypeMemory:
+		return NewMemoryStore(""), nil
+	case TypeSupabase, "":
+		return NewSupabaseStore(SupabaseConfigFromEnv())
+	default:
+		return nil, fmt.Errorf("unknown storage type: %s", storageType)
+	}
+}
+
+// KeyFromStoreURL は store 自身の公開URLのプレフィックス（LocalStore の "/uploads/" など）を取り除いてキーを返す。
+// プレフィックスが一致しない場合は KeyFromURL で取り出す。
+func KeyFromStoreURL(store ImageStore, url string) string {
+	if store != nil {
+		if prefix := store.URL(""); prefix != "" && strings.HasPrefix(url, prefix) {
+			return strings.TrimPrefix(url, prefix)
+		}
+	}
+	return KeyFromURL(url)
+}
+
+// KeyFromURL は公開URL（Supabase・R2・カスタムドメイン）またはキーそのものからキーを取り出す
+func KeyFromURL(url string) string {
+	// SupabaseのURLからパスを抽出
+	if parts := strings.SplitN(url, "/storage/v1/object/public/images/", 2); len(parts) == 2 {
+		return parts[1]
+	}
+	// R2のURLからパスを抽出
+	if parts := strings.SplitN(url, ".r2.dev/", 2); len(parts) == 2 {
+		return parts[1]
+	}
+	// カスタムドメインのURLからパスを抽出
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		rest := url[strings.Index(url, "://")+3:]
+		if i := strings.Index(rest, "/"); i >= 0 {
+			return rest[i+1:]
+		}
+		return ""
+	}
+	// パスがそのまま渡された場合
+	return strings.TrimPrefix(url, "/")
+}
+
+// cleanKey はWindowsのパス区切りや先頭のスラッシュを取り除いたキーを返す
+func cleanKey(key string) (string, error) {
+	key = strings.ReplaceAll(key, "\\", "/")
+	key = strings.TrimPrefix(path.Clean("/"+key), "/")
+	if key == "" || key == "." {
+		return "", fmt.Errorf("invalid storage key")
+	}
+	return key, nil
+}
+
+// joinURL は公開URLのベースとキーを連結する
+func joinURL(base, key string) string {
+	if base == "" {
+		return key
+	}
+	return strings.TrimRight(base, "/") + "/" + key
+}
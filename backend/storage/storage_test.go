@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeyFromStoreURL(t *testing.T) {
+	local, err := NewLocalStore(t.TempDir(), "/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	supabase, err := NewSupabaseStore(SupabaseConfig{URL: "https://example.supabase.co", Key: "service-role-key", Bucket: "images"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		store ImageStore
+		url   string
+		want  string
+	}{
+		{"local url", local, "/uploads/recipes/x.webp", "recipes/x.webp"},
+		{"local key", local, "recipes/x.webp", "recipes/x.webp"},
+		{"local absolute base", mustLocal(t, "http://localhost:8080/uploads"), "http://localhost:8080/uploads/users/a/b.webp", "users/a/b.webp"},
+		{"memory url", NewMemoryStore("/images"), "/images/reviews/r/1.webp", "reviews/r/1.webp"},
+		{"memory without base", NewMemoryStore(""), "reviews/r/1.webp", "reviews/r/1.webp"},
+		{"r2 custom domain", NewS3StoreWithClient(nil, "images", "https://cdn.example.com"), "https://cdn.example.com/recipes/x.webp", "recipes/x.webp"},
+		{"r2 dev domain", NewS3StoreWithClient(nil, "images", "https://pub-123.r2.dev"), "https://pub-123.r2.dev/recipes/x.webp", "recipes/x.webp"},
+		{"s3 key", NewS3StoreWithClient(nil, "images", "https://cdn.example.com"), "recipes/x.webp", "recipes/x.webp"},
+		{"supabase url", supabase, "https://example.supabase.co/storage/v1/object/public/images/recipes/x.webp", "recipes/x.webp"},
+		{"supabase key", supabase, "recipes/x.webp", "recipes/x.webp"},
+		{"other store's url", local, "https://example.supabase.co/storage/v1/object/public/images/recipes/x.webp", "recipes/x.webp"},
+		{"nil store", nil, "/recipes/x.webp", "recipes/x.webp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KeyFromStoreURL(tt.store, tt.url); got != tt.want {
+				t.Errorf("KeyFromStoreURL(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyFromStoreURLRoundTrip(t *testing.T) {
+	stores := map[string]ImageStore{
+		"local":  mustLocal(t, "/uploads"),
+		"memory": NewMemoryStore("https://images.example.com/"),
+		"s3":     NewS3StoreWithClient(nil, "images", "https://cdn.example.com"),
+	}
+	for name, store := range stores {
+		for _, key := range []string{"recipes/1/main.webp", "reviews/2/a_b.webp", "exports/u/e.zip"} {
+			if got := KeyFromStoreURL(store, store.URL(key)); got != key {
+				t.Errorf("%s: KeyFromStoreURL(URL(%q)) = %q", name, key, got)
+			}
+		}
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	stores := map[string]ImageStore{
+		"memory": NewMemoryStore("https://images.example.com"),
+		"local":  mustLocal(t, "/uploads"),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			put := func(key, body string) {
+				t.Helper()
+				if err := store.Put(ctx, key, strings.NewReader(body), "image/webp"); err != nil {
+					t.Fatalf("Put(%q): %v", key, err)
+				}
+			}
+			get := func(key string) (string, error) {
+				t.Helper()
+				rc, err := store.Get(ctx, key)
+				if err != nil {
+					return "", err
+				}
+				defer rc.Close()
+				data, err := io.ReadAll(rc)
+				return string(data), err
+			}
+			exists := func(key string) bool {
+				t.Helper()
+				ok, err := store.Exists(ctx, key)
+				if err != nil {
+					t.Fatalf("Exists(%q): %v", key, err)
+				}
+				return ok
+			}
+
+			put("recipes/1/main.webp", "old")
+			put("recipes/1/main.webp", "main") // 上書き
+			put("/recipes/1/main_thumb.webp", "thumb")
+			put("users/2/icon.webp", "icon")
+
+			// 先頭のスラッシュやWindowsの区切りは同じキーとして扱う
+			for key, want := range map[string]string{
+				"recipes/1/main.webp":       "main",
+				"recipes/1/main_thumb.webp": "thumb",
+				"\\users\\2\\icon.webp":     "icon",
+			} {
+				got, err := get(key)
+				if err != nil || got != want {
+					t.Errorf("Get(%q) = %q, %v, want %q", key, got, err, want)
+				}
+				if !exists(key) {
+					t.Errorf("Exists(%q) = false", key)
+				}
+			}
+
+			objects, err := store.List(ctx, "recipes/1/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys := []string{}
+			for _, o := range objects {
+				keys = append(keys, o.Key)
+				if o.Size == 0 || o.LastModified.IsZero() {
+					t.Errorf("object = %+v", o)
+				}
+			}
+			if want := []string{"recipes/1/main.webp", "recipes/1/main_thumb.webp"}; !reflect.DeepEqual(keys, want) {
+				t.Errorf("List = %v, want %v", keys, want)
+			}
+
+			if err := store.Delete(ctx, "recipes/1/main.webp"); err != nil {
+				t.Fatal(err)
+			}
+			if exists("recipes/1/main.webp") {
+				t.Error("deleted object still exists")
+			}
+			// 存在しないキーの Delete はエラーにせず、Get は ErrNotFound を返す
+			if err := store.Delete(ctx, "recipes/1/main.webp"); err != nil {
+				t.Errorf("Delete of a missing key: %v", err)
+			}
+			for _, key := range []string{"recipes/1/main.webp", "recipes/9/missing.webp"} {
+				if _, err := get(key); !errors.Is(err, ErrNotFound) {
+					t.Errorf("Get(%q) err = %v, want ErrNotFound", key, err)
+				}
+			}
+			if objects, err := store.List(ctx, "recipes/"); err != nil || len(objects) != 1 {
+				t.Errorf("List after delete = %+v, %v", objects, err)
+			}
+			if _, err := store.Get(ctx, "/"); err == nil {
+				t.Error("Get of an empty key succeeded")
+			}
+		})
+	}
+}
+
+func mustLocal(t *testing.T, baseURL string) *LocalStore {
+	t.Helper()
+	store, err := NewLocalStore(t.TempDir(), baseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
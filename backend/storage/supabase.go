@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	storage_go "github.com/supabase-community/storage-go"
+)
+
+// SupabaseConfig はSupabase Storageの接続設定
+type SupabaseConfig struct {
+	URL    string
+	Key    string
+	Bucket string
+}
+
+// SupabaseConfigFromEnv は環境変数から設定を作成する
+func SupabaseConfigFromEnv() SupabaseConfig {
+	url := os.Getenv("SUPABASE_URL")
+	// ローカル開発ではDockerコンテナからホストのSupabaseに接続する
+	if os.Getenv("ENVIRONMENT") == "development" {
+		url = "http://host.docker.internal:54321"
+	}
+	return SupabaseConfig{
+		URL:    url,
+		Key:    os.Getenv("SUPABASE_SERVICE_ROLE_KEY"),
+		Bucket: "images",
+	}
+}
+
+// SupabaseStore はSupabase Storageに保存するImageStore
+type SupabaseStore struct {
+	client *storage_go.Client
+	bucket string
+}
+
+// NewSupabaseStore はSupabase Storageのクライアントを初期化する
+func NewSupabaseStore(cfg SupabaseConfig) (*SupabaseStore, error) {
+	if cfg.URL == "" || cfg.Key == "" {
+		return nil, fmt.Errorf("Supabase storage is not properly configured")
+	}
+	client := storage_go.NewClient(strings.TrimRight(cfg.URL, "/")+"/storage/v1", cfg.Key, nil)
+	return &SupabaseStore{client: client, bucket: cfg.Bucket}, nil
+}
+
+func (s *SupabaseStore) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	upsert := true
+	_, err = s.client.UploadFile(s.bucket, key, body, storage_go.FileOptions{
+		ContentType: &contentType,
+		Upsert:      &upsert,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload to Supabase Storage: %v", err)
+	}
+	return nil
+}
+
+func (s *SupabaseStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.client.DownloadFile(s.bucket, key)
+	if err != nil {
+		// storage-goはステータスコードを返さないため、存在確認で404かどうかを判定する
+		if exists, existsErr := s.Exists(ctx, key); existsErr == nil && !exists {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to download from Supabase Storage: %v", err)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *SupabaseStore) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	if _, err := s.client.RemoveFile(s.bucket, []string{key}); err != nil {
+		return fmt.Errorf("failed to delete from Supabase Storage: %v", err)
+	}
+	return nil
+}
+
+func (s *SupabaseStore) URL(key string) string {
+	return s.client.GetPublicUrl(s.bucket, key).SignedURL
+}
+
+func (s *SupabaseStore) Exists(ctx context.Context, key string) (bool, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return false, err
+	}
+	dir, name := path.Split(key)
+	files, err := s.client.ListFiles(s.bucket, strings.TrimSuffix(dir, "/"), storage_go.FileSearchOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to list Supabase Storage: %v", err)
+	}
+	for _, f := range files {
+		if f.Name == name && f.Id != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// List はフォルダを再帰的にたどって全キーを返す（Supabaseの一覧APIは1階層ずつしか返さないため）
+func (s *SupabaseStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	dir := prefix
+	if !strings.HasSuffix(prefix, "/") {
+		dir, _ = path.Split(prefix)
+	}
+	objects := make([]ObjectInfo, 0)
+	if err := s.list(ctx, strings.TrimSuffix(dir, "/"), prefix, &objects); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+const supabaseListLimit = 1000
+
+func (s *SupabaseStore) list(ctx context.Context, dir, prefix string, objects *[]ObjectInfo) error {
+	for offset := 0; ; offset += supabaseListLimit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		files, err := s.client.ListFiles(s.bucket, dir, storage_go.FileSearchOptions{Limit: supabaseListLimit, Offset: offset})
+		if err != nil {
+			return fmt.Errorf("failed to list Supabase Storage: %v", err)
+		}
+		for _, f := range files {
+			key := f.Name
+			if dir != "" {
+				key = dir + "/" + f.Name
+			}
+			// IDの無いエントリはフォルダ
+			if f.Id == "" {
+				if strings.HasPrefix(key+"/", prefix) || strings.HasPrefix(prefix, key+"/") {
+					if err := s.list(ctx, key, prefix, objects); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if strings.HasPrefix(key, prefix) {
+				*objects = append(*objects, supabaseObjectInfo(key, f))
+			}
+		}
+		if len(files) < supabaseListLimit {
+			return nil
+		}
+	}
+}
+
+// supabaseObjectInfo は一覧APIのメタデータからサイズと更新日時を取り出す
+func supabaseObjectInfo(key string, f storage_go.FileObject) ObjectInfo {
+	info := ObjectInfo{Key: key}
+	if t, err := time.Parse(time.RFC3339, f.UpdatedAt); err == nil {
+		info.LastModified = t
+	}
+	if metadata, ok := f.Metadata.(map[string]interface{}); ok {
+		if size, ok := metadata["size"].(float64); ok {
+			info.Size = int64(size)
+		}
+	}
+	return info
+}
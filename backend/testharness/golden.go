@@ -0,0 +1,229 @@
+package testharness
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 実行ごとに変わる値（時刻）はゴールデンファイルでプレースホルダーに置き換える
+var volatileKeys = map[string]bool{
+	"created_at":      true,
+	"updated_at":      true,
+	"createdAt":       true,
+	"updatedAt":       true,
+	"deleted_at":      true,
+	"last_reset_date": true,
+	"last_reset_at":   true,
+	"lastResetAt":     true,
+	"reset_at":        true,
+	"used_at":         true,
+	"generated_at":    true,
+	"added_at":        true,
+	"computed_at":     true,
+	"requested_at":    true,
+	"purge_after":     true,
+	"completed_at":    true,
+	"expires_at":      true,
+	"read_at":         true,
+}
+
+// 実行ごとにランダムに発行される値（共有用のスラッグ・ページングのカーソルなど）はキー名のプレースホルダーに置き換える
+var randomKeys = map[string]bool{
+	"slug":        true,
+	"nextCursor":  true,
+	"next_cursor": true,
+}
+
+// 中身の時刻によって変わる数値（エクスポートしたZIPのサイズなど）はキー名のプレースホルダーに置き換える
+var volatileNumberKeys = map[string]bool{
+	"size_bytes": true,
+}
+
+var uuidPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// Golden はゴールデンファイルに保存するレスポンス
+type Golden struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// Result は1シナリオの実行結果
+type Result struct {
+	Name    string
+	Updated bool
+	// Diff は期待値と異なった場合の説明（一致した場合は空）
+	Diff string
+}
+
+// Run はシナリオを順に実行し、goldenDir のゴールデンファイルと比較する。
+// update がtrueの場合は比較せずにゴールデンファイルを書き直す。
+func Run(h *Harness, scenarios []Scenario, goldenDir string, update bool) ([]Result, error) {
+	if update {
+		if err := os.MkdirAll(goldenDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create golden directory: %v", err)
+		}
+	}
+
+	vars := NewVars(h.Seed)
+	results := make([]Result, 0, len(scenarios))
+
+	for _, s := range scenarios {
+		path, body, headers, err := buildRequest(s, vars)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %s: failed to build request: %v", s.Name, err)
+		}
+		w := h.Do(s.Method, path, body, headers)
+
+		var decoded interface{}
+		if len(w.Body.Bytes()) > 0 {
+			if err := json.Unmarshal(w.Body.Bytes(), &decoded); err != nil {
+				decoded = w.Body.String()
+			}
+		}
+
+		for name, field := range s.Capture {
+			if value, ok := lookup(decoded, field); ok {
+				vars[name] = value
+			}
+		}
+
+		actual, err := encodeGolden(w.Code, normalize(decoded, vars))
+		if err != nil {
+			return nil, fmt.Errorf("scenario %s: %v", s.Name, err)
+		}
+
+		file := filepath.Join(goldenDir, s.Name+".json")
+		if update {
+			if err := os.WriteFile(file, actual, 0644); err != nil {
+				return nil, fmt.Errorf("scenario %s: failed to write golden file: %v", s.Name, err)
+			}
+			results = append(results, Result{Name: s.Name, Updated: true})
+			continue
+		}
+
+		expected, err := os.ReadFile(file)
+		if err != nil {
+			results = append(results, Result{Name: s.Name, Diff: fmt.Sprintf("golden file not found: %s (run go test ./testharness -update against Postgres, review the files and commit them)", file)})
+			continue
+		}
+		result := Result{Name: s.Name}
+		if !bytes.Equal(bytes.TrimSpace(expected), bytes.TrimSpace(actual)) {
+			result.Diff = fmt.Sprintf("expected:\n%s\nactual:\n%s", expected, actual)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func encodeGolden(status int, body interface{}) ([]byte, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response body: %v", err)
+	}
+	out, err := json.MarshalIndent(Golden{Status: status, Body: raw}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode golden file: %v", err)
+	}
+	return append(out, '\n'), nil
+}
+
+// normalize は時刻をプレースホルダーに、既知のIDを変数名に、未知のUUIDを出現順の番号に置き換える
+func normalize(value interface{}, vars Vars) interface{} {
+	known := make(map[string]string, len(vars))
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if uuidPattern.MatchString(vars[name]) {
+			if _, exists := known[strings.ToLower(vars[name])]; !exists {
+				known[strings.ToLower(vars[name])] = "{" + name + "}"
+			}
+		}
+	}
+	unknown := map[string]string{}
+
+	var walk func(key string, v interface{}) interface{}
+	walk = func(key string, v interface{}) interface{} {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			out := make(map[string]interface{}, len(t))
+			for k, val := range t {
+				out[k] = walk(k, val)
+			}
+			return out
+		case []interface{}:
+			out := make([]interface{}, len(t))
+			for i, val := range t {
+				out[i] = walk(key, val)
+			}
+			return out
+		case string:
+			if volatileKeys[key] && t != "" {
+				return "{time}"
+			}
+			if randomKeys[key] && t != "" {
+				return "{" + key + "}"
+			}
+			return uuidPattern.ReplaceAllStringFunc(t, func(id string) string {
+				lower := strings.ToLower(id)
+				if name, ok := known[lower]; ok {
+					return name
+				}
+				if name, ok := unknown[lower]; ok {
+					return name
+				}
+				unknown[lower] = fmt.Sprintf("{uuid%d}", len(unknown)+1)
+				return unknown[lower]
+			})
+		case float64:
+			if volatileNumberKeys[key] {
+				return "{" + key + "}"
+			}
+			return v
+		default:
+			return v
+		}
+	}
+	return walk("", value)
+}
+
+// lookup はドット区切りのパスでJSONの値を取り出す（配列は "items.0.id" のように添字で指定する）
+func lookup(value interface{}, path string) (string, bool) {
+	current := value
+	for _, key := range strings.Split(path, ".") {
+		switch t := current.(type) {
+		case map[string]interface{}:
+			next, ok := t[key]
+			if !ok {
+				return "", false
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(t) {
+				return "", false
+			}
+			current = t[i]
+		default:
+			return "", false
+		}
+	}
+	switch t := current.(type) {
+	case string:
+		return t, true
+	case float64:
+		return fmt.Sprintf("%v", t), true
+	default:
+		return "", false
+	}
+}
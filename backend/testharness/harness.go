@@ -0,0 +1,182 @@
+package testharness
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"portfolio-amarimono/ai"
+	"portfolio-amarimono/handlers"
+	"portfolio-amarimono/notifications"
+	"portfolio-amarimono/routes"
+	"portfolio-amarimono/storage"
+	"portfolio-amarimono/testharness/pgtest"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Config はハーネスの起動設定
+type Config struct {
+	// DSN はPostgresの接続文字列。空の場合は HARNESS_DATABASE_URL、それも無ければ埋め込みのPostgresを起動して使用
+	DSN string
+	// DataRoot はリポジトリのルート（original-data・recipe-data・supabase/migrations を含むディレクトリ）
+	DataRoot string
+	// RecipeSample は recipe-data/recipes から取り込むレシピ数（0以下で全件）
+	RecipeSample int
+	// KeepSchema がtrueの場合、Close時に使い捨てスキーマを削除しない（デバッグ用）
+	KeepSchema bool
+}
+
+// Harness は使い捨てスキーマ上で本番と同じルーターを起動したテスト環境
+type Harness struct {
+	DB     *gorm.DB
+	Router *gin.Engine
+	Seed   *SeedResult
+	// Images は画像の保存先（外部ストレージに依存しないようメモリ上に保存）
+	Images *storage.MemoryStore
+
+	schema   *pgtest.Schema
+	postgres *pgtest.Server
+	keep     bool
+}
+
+// New は使い捨てスキーマを作成し、スキーマ定義とシードデータを投入したうえでルーターを起動する。
+// 接続先が指定されていない場合は埋め込みのPostgresを起動し、Close で停止する。
+func New(cfg Config) (*Harness, error) {
+	dsn := cfg.DSN
+	if dsn == "" {
+		dsn = os.Getenv("HARNESS_DATABASE_URL")
+	}
+	if cfg.DataRoot == "" {
+		cfg.DataRoot = ".."
+	}
+
+	// ハンドラーは ENVIRONMENT を見てモックを使うかどうか決めるため、開発環境として起動する
+	os.Setenv("ENVIRONMENT", "development")
+	gin.SetMode(gin.TestMode)
+
+	h := &Harness{keep: cfg.KeepSchema}
+	if dsn == "" {
+		server, err := pgtest.Start()
+		if err != nil {
+			return nil, err
+		}
+		h.postgres = server
+		dsn = server.DSN
+	}
+
+	schema, err := pgtest.NewSchema(dsn, filepath.Join(cfg.DataRoot, "supabase", "migrations"))
+	if err != nil {
+		h.Close()
+		return nil, err
+	}
+	h.schema = schema
+	h.DB = schema.DB
+
+	seed, err := SeedAll(h.DB, cfg.DataRoot, cfg.RecipeSample)
+	if err != nil {
+		h.Close()
+		return nil, fmt.Errorf("failed to seed data: %v", err)
+	}
+	h.Seed = seed
+
+	h.Images = storage.NewMemoryStore("")
+	h.Router = newRouter(h.DB, h.Images)
+	return h, nil
+}
+
+// Close は使い捨てスキーマを削除して接続を閉じる（埋め込みのPostgresを起動した場合は停止する）
+func (h *Harness) Close() error {
+	var err error
+	if h.schema != nil {
+		// 埋め込みのPostgresはデータディレクトリごと消えるため、スキーマを残しても意味がない
+		if h.keep && h.postgres == nil {
+			err = h.schema.Close()
+		} else {
+			err = h.schema.Drop()
+		}
+	}
+	if h.postgres != nil {
+		if stopErr := h.postgres.Stop(); stopErr != nil {
+			return stopErr
+		}
+	}
+	return err
+}
+
+// Schema は使い捨てスキーマ名を返す
+func (h *Harness) Schema() string {
+	if h.schema == nil {
+		return ""
+	}
+	return h.schema.Name
+}
+
+// Do はルーターにリクエストを送り、レスポンスを返す
+func (h *Harness) Do(method, path string, body []byte, headers map[string]string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.Router.ServeHTTP(w, req)
+	return w
+}
+
+// newRouter はmain.goと同じ構成でハンドラーを組み立ててルーティングを設定する
+func newRouter(db *gorm.DB, images storage.ImageStore) *gin.Engine {
+	r := gin.New()
+
+	// 通知はメール・Webhookに送らずメモリに記録し、レスポンスを決定的にするため同期的に配信する
+	notifier := notifications.NewService(db)
+	notifier.Channels = []notifications.Channel{notifications.NewFake(notifications.ChannelEmail), notifications.NewFake(notifications.ChannelWebhook)}
+	notifier.Dispatch = func(task func()) { task() }
+
+	recipeHandler := handlers.NewRecipeHandler(db)
+	likeHandler := handlers.NewLikeHandler(db)
+	likeHandler.Notifications = notifier
+	userHandler := handlers.NewUserHandler(db, images, storage.NewMemoryStore(""))
+	// エクスポートはレスポンスを決定的にするため同期的に作成する
+	userHandler.Exports.Dispatch = func(task func()) { task() }
+	adminHandler := &handlers.AdminHandler{
+		DB:            db,
+		Images:        images,
+		Notifications: notifier,
+	}
+	genreHandler := &handlers.GenreHandler{
+		DB: db,
+	}
+	reviewHandler := handlers.NewReviewHandler(db, images)
+	reviewHandler.Notifications = notifier
+	recommendationHandler := &handlers.RecommendationHandler{
+		DB: db,
+	}
+	userIngredientDefaultHandler := handlers.NewUserIngredientDefaultHandler(db)
+	aiUsageHandler := handlers.NewAIUsageHandler(db, ai.NewStub())
+	authHandler := handlers.NewAuthHandler(nil, db)
+	// Redisを使わずに毎回DBから計算する
+	rankingHandler := handlers.NewRankingHandler(db, nil)
+	collectionHandler := handlers.NewCollectionHandler(db)
+	cookLogHandler := handlers.NewCookLogHandler(db)
+	profileHandler := handlers.NewProfileHandler(db)
+	feedHandler := handlers.NewFeedHandler(db)
+	notificationHandler := handlers.NewNotificationHandler(notifier)
+
+	routes.SetupRoutes(r, recipeHandler, likeHandler, userHandler, genreHandler, adminHandler, reviewHandler, recommendationHandler, userIngredientDefaultHandler, aiUsageHandler, rankingHandler, collectionHandler, cookLogHandler, profileHandler, feedHandler, notificationHandler, db)
+	routes.SetupAuthRoutes(r, authHandler)
+
+	return r
+}
+
+// bearerToken は署名なしのJWT形式トークンを作る（ハンドラーはsubだけを読む）
+func bearerToken(userID string) string {
+	return "Bearer " + unsignedJWT(userID)
+}
@@ -0,0 +1,49 @@
+package testharness
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"portfolio-amarimono/testharness/pgtest"
+)
+
+var update = flag.Bool("update", false, "比較せずにゴールデンファイルを書き直す")
+
+// TestScenarios は埋め込みのPostgres（HARNESS_DATABASE_URL があればそちら）で全シナリオを実行し、testdata/golden と比較する。
+//
+//	go test ./testharness            # 比較
+//	go test ./testharness -update    # ゴールデンファイルを更新
+func TestScenarios(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping API scenarios in short mode")
+	}
+
+	h, err := New(Config{DataRoot: filepath.Join("..", ".."), RecipeSample: 5})
+	if errors.Is(err, pgtest.ErrPostgresUnavailable) && os.Getenv("HARNESS_REQUIRE_POSTGRES") == "" {
+		t.Skipf("%v (set HARNESS_DATABASE_URL to use another Postgres, or HARNESS_REQUIRE_POSTGRES=1 to fail instead)", err)
+	}
+	if err != nil {
+		t.Fatalf("failed to start harness: %v", err)
+	}
+	defer func() {
+		if err := h.Close(); err != nil {
+			t.Errorf("failed to clean up harness: %v", err)
+		}
+	}()
+
+	results, err := Run(h, DefaultScenarios(h.Seed), filepath.Join("testdata", "golden"), *update)
+	if err != nil {
+		t.Fatalf("failed to run scenarios: %v", err)
+	}
+	for _, r := range results {
+		r := r
+		t.Run(r.Name, func(t *testing.T) {
+			if r.Diff != "" {
+				t.Error(r.Diff)
+			}
+		})
+	}
+}
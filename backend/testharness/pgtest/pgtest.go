@@ -0,0 +1,73 @@
+package pgtest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+var (
+	shared     *Server
+	sharedErr  error
+	sharedOnce sync.Once
+)
+
+// Main はテストを実行し、Open が起動した埋め込みのPostgresを停止する。
+// Open を使うパッケージは TestMain から呼ぶ。
+//
+//	func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+func Main(m *testing.M) int {
+	code := m.Run()
+	if shared != nil {
+		if err := shared.Stop(); err != nil && code == 0 {
+			code = 1
+		}
+	}
+	return code
+}
+
+// Open はテストごとの使い捨てスキーマに接続したDBを返し、テストの終了時にスキーマを削除する。
+// 接続先は HARNESS_DATABASE_URL、無ければパッケージ内で共有する埋め込みのPostgres。
+// Postgresを用意できない環境ではテストをスキップする（HARNESS_REQUIRE_POSTGRES=1 で失敗にする）。
+func Open(t *testing.T) *gorm.DB {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping database test in short mode")
+	}
+
+	dsn := os.Getenv("HARNESS_DATABASE_URL")
+	if dsn == "" {
+		sharedOnce.Do(func() {
+			shared, sharedErr = Start()
+		})
+		if sharedErr != nil {
+			if errors.Is(sharedErr, ErrPostgresUnavailable) && os.Getenv("HARNESS_REQUIRE_POSTGRES") == "" {
+				t.Skipf("%v (set HARNESS_DATABASE_URL to use another Postgres, or HARNESS_REQUIRE_POSTGRES=1 to fail instead)", sharedErr)
+			}
+			t.Fatal(sharedErr)
+		}
+		dsn = shared.DSN
+	}
+
+	schema, err := NewSchema(dsn, MigrationsDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := schema.Drop(); err != nil {
+			t.Error(err)
+		}
+	})
+	return schema.DB
+}
+
+// MigrationsDir はリポジトリの supabase/migrations の場所を返す
+func MigrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "supabase", "migrations")
+}
@@ -0,0 +1,170 @@
+// Package pgtest はテスト用のPostgres（埋め込みのPostgresか HARNESS_DATABASE_URL）に、
+// マイグレーションを適用した使い捨てスキーマを用意する。
+package pgtest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ErrPostgresUnavailable は埋め込みのPostgresを起動できなかった（バイナリを取得できない環境など）ことを表す
+var ErrPostgresUnavailable = errors.New("embedded postgres is unavailable")
+
+// Server は一時ディレクトリで起動したテスト専用のPostgres
+type Server struct {
+	DSN string
+
+	server *embeddedpostgres.EmbeddedPostgres
+	dir    string
+}
+
+// Start は空いているポートでPostgresを起動する。
+// バイナリは初回のみダウンロードされ、~/.embedded-postgres-go にキャッシュされる。
+func Start() (*Server, error) {
+	port, err := freePort()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPostgresUnavailable, err)
+	}
+	dir, err := os.MkdirTemp("", "pgtest-")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPostgresUnavailable, err)
+	}
+
+	server := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Port(port).
+		RuntimePath(filepath.Join(dir, "runtime")).
+		DataPath(filepath.Join(dir, "data")).
+		StartTimeout(60 * time.Second).
+		Logger(io.Discard))
+	if err := server.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("%w: %v", ErrPostgresUnavailable, err)
+	}
+
+	return &Server{
+		DSN:    fmt.Sprintf("host=localhost port=%d user=postgres password=postgres dbname=postgres sslmode=disable", port),
+		server: server,
+		dir:    dir,
+	}, nil
+}
+
+// Stop はPostgresを停止し、データディレクトリを削除する
+func (s *Server) Stop() error {
+	defer os.RemoveAll(s.dir)
+	if err := s.server.Stop(); err != nil {
+		return fmt.Errorf("failed to stop embedded postgres: %v", err)
+	}
+	return nil
+}
+
+// Schema はマイグレーションを適用した使い捨てスキーマと、そこへ search_path を向けた接続
+type Schema struct {
+	DB   *gorm.DB
+	Name string
+
+	admin *sql.DB
+}
+
+// NewSchema は dsn のデータベースに使い捨てスキーマを作成し、migrationsDir のマイグレーションを適用する
+func NewSchema(dsn, migrationsDir string) (*Schema, error) {
+	adminDB, err := OpenGorm(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+	admin, err := adminDB.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %v", err)
+	}
+	s := &Schema{admin: admin}
+
+	// マイグレーションが使う uuid_generate_v4()（001_create_uuid_extension.sql と同じ）
+	if _, err := admin.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to create uuid-ossp extension: %v", err)
+	}
+
+	name := fmt.Sprintf("harness_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(fmt.Sprintf(`CREATE SCHEMA "%s"`, name)); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to create schema %s: %v", name, err)
+	}
+	s.Name = name
+
+	// search_pathを使い捨てスキーマに向けた接続でアプリを動かす
+	// （Supabaseでは拡張機能が extensions スキーマにあるため、それも含める）
+	db, err := OpenGorm(fmt.Sprintf("%s search_path=%s,public,extensions", dsn, name))
+	if err != nil {
+		s.Drop()
+		return nil, fmt.Errorf("failed to connect to schema %s: %v", name, err)
+	}
+	s.DB = db
+
+	if err := createSchema(db, migrationsDir); err != nil {
+		s.Drop()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close はスキーマを残したまま接続を閉じる（デバッグ用）
+func (s *Schema) Close() error {
+	if s.DB != nil {
+		if sqlDB, err := s.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+	return s.admin.Close()
+}
+
+// Drop はスキーマを削除して接続を閉じる
+func (s *Schema) Drop() error {
+	if s.DB != nil {
+		if sqlDB, err := s.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+	defer s.admin.Close()
+	if s.Name == "" {
+		return nil
+	}
+	if _, err := s.admin.Exec(fmt.Sprintf(`DROP SCHEMA IF EXISTS "%s" CASCADE`, s.Name)); err != nil {
+		return fmt.Errorf("failed to drop schema %s: %v", s.Name, err)
+	}
+	return nil
+}
+
+// OpenGorm はアプリ本体と同じくprepared statementを無効化した設定でGORMを開く
+func OpenGorm(dsn string) (*gorm.DB, error) {
+	return gorm.Open(postgres.New(postgres.Config{
+		DSN:                  dsn,
+		PreferSimpleProtocol: true,
+	}), &gorm.Config{
+		Logger:                 logger.Default.LogMode(logger.Silent),
+		SkipDefaultTransaction: true,
+		PrepareStmt:            false,
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+	})
+}
+
+// freePort はOSに空いているポートを割り当てさせて返す
+func freePort() (uint32, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return uint32(l.Addr().(*net.TCPAddr).Port), nil
+}
@@ -0,0 +1,203 @@
+package pgtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// baseMigration は baseSchemaDDL が表すマイグレーションの番号。
+// supabase/migrations の 001〜025 は Git LFS で管理されておりチェックアウトに実体が含まれないことがあるため、
+// 025 時点のスキーマをハンドラーが読み書きするカラムに絞ってここに定義し、026 以降は supabase/migrations のSQLをそのまま適用する。
+const baseMigration = 25
+
+// baseSchemaDDL は 025 までのマイグレーションを適用した時点のテーブルを使い捨てスキーマに作成する
+const baseSchemaDDL = `
+CREATE TABLE units (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(50) NOT NULL UNIQUE,
+	description VARCHAR(255) NOT NULL DEFAULT '',
+	step NUMERIC NOT NULL DEFAULT 1,
+	type VARCHAR(20) NOT NULL DEFAULT 'quantity'
+);
+
+CREATE TABLE ingredient_genres (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(50) NOT NULL UNIQUE
+);
+
+CREATE TABLE ingredients (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL UNIQUE,
+	genre_id INT NOT NULL REFERENCES ingredient_genres(id),
+	image_url TEXT NOT NULL DEFAULT '',
+	unit_id INT NOT NULL REFERENCES units(id),
+	nutrition JSONB,
+	gram_equivalent NUMERIC NOT NULL DEFAULT 100,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	deleted_at TIMESTAMPTZ
+);
+
+CREATE TABLE recipe_genres (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(50) NOT NULL UNIQUE
+);
+
+CREATE TABLE users (
+	id UUID PRIMARY KEY,
+	email VARCHAR(255) NOT NULL UNIQUE,
+	username VARCHAR(255),
+	age INT,
+	gender VARCHAR(20),
+	profile_image TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	deleted_at TIMESTAMPTZ
+);
+
+CREATE TABLE user_roles (
+	user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	role VARCHAR(20) NOT NULL DEFAULT 'user'
+);
+
+CREATE TABLE recipes (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	name VARCHAR(255) NOT NULL,
+	instructions JSONB NOT NULL DEFAULT '[]',
+	image_url TEXT,
+	genre_id INT REFERENCES recipe_genres(id),
+	cooking_time INT,
+	cost_estimate INT,
+	summary TEXT,
+	nutrition JSONB,
+	catchphrase TEXT,
+	faq JSONB DEFAULT '[]',
+	user_id UUID,
+	is_public BOOLEAN DEFAULT true,
+	is_draft BOOLEAN DEFAULT false,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE recipe_ingredients (
+	recipe_id UUID NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	ingredient_id INT NOT NULL REFERENCES ingredients(id),
+	quantity_required NUMERIC NOT NULL DEFAULT 0,
+	unit_id INT REFERENCES units(id),
+	PRIMARY KEY (recipe_id, ingredient_id)
+);
+
+CREATE TABLE reviews (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	recipe_id UUID NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	rating INT CHECK (rating >= 1 AND rating <= 5),
+	comment TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE likes (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	user_id UUID NOT NULL,
+	recipe_id UUID NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_ingredient_defaults (
+	id SERIAL PRIMARY KEY,
+	user_id UUID NOT NULL,
+	ingredient_id INT NOT NULL REFERENCES ingredients(id),
+	default_quantity INT NOT NULL DEFAULT 0,
+	quantity NUMERIC,
+	unit_id INT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE ai_usage (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	user_id UUID NOT NULL UNIQUE,
+	usage_count INT NOT NULL DEFAULT 0,
+	last_reset_date TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE nutrition_standards (
+	age_group VARCHAR(50) NOT NULL,
+	gender VARCHAR(10) NOT NULL,
+	calories NUMERIC NOT NULL,
+	protein NUMERIC NOT NULL,
+	fat NUMERIC NOT NULL,
+	carbohydrates NUMERIC NOT NULL,
+	salt NUMERIC NOT NULL
+);
+`
+
+// createSchema は baseSchemaDDL を作成し、migrationsDir にある baseMigration より後のマイグレーションを番号順に適用する
+func createSchema(db *gorm.DB, migrationsDir string) error {
+	if err := db.Exec(baseSchemaDDL).Error; err != nil {
+		return fmt.Errorf("failed to create base tables: %v", err)
+	}
+
+	files, err := pendingMigrations(migrationsDir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %v", file, err)
+		}
+		if strings.HasPrefix(string(sql), "version https://git-lfs") {
+			return fmt.Errorf("migration %s is a Git LFS pointer; run git lfs pull", filepath.Base(file))
+		}
+		if err := db.Exec(string(sql)).Error; err != nil {
+			return fmt.Errorf("failed to apply migration %s: %v", filepath.Base(file), err)
+		}
+	}
+	return nil
+}
+
+// pendingMigrations は migrationsDir の NNN_*.sql のうち baseMigration より後のものを番号順に返す
+func pendingMigrations(migrationsDir string) ([]string, error) {
+	entries, err := os.ReadDir(migrationsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %v", err)
+	}
+
+	type migration struct {
+		number int
+		path   string
+	}
+	var migrations []migration
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || filepath.Ext(name) != ".sql" {
+			continue
+		}
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			continue
+		}
+		number, err := strconv.Atoi(prefix)
+		if err != nil || number <= baseMigration {
+			continue
+		}
+		migrations = append(migrations, migration{number: number, path: filepath.Join(migrationsDir, name)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].number < migrations[j].number })
+
+	files := make([]string, len(migrations))
+	for i, m := range migrations {
+		files[i] = m.path
+	}
+	return files, nil
+}
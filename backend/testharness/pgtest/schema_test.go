@@ -0,0 +1,51 @@
+package pgtest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPendingMigrations(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"001_init.sql", "025_base.sql", "026_a.sql", "100_c.sql", "027_b.sql", "013_old.sql.bak", "README.md", "notes.sql"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := pendingMigrations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, filepath.Base(f))
+	}
+	if want := []string{"026_a.sql", "027_b.sql", "100_c.sql"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("pendingMigrations = %v, want %v", names, want)
+	}
+}
+
+// TestRepositoryMigrationsAreCheckedOut は baseMigration より後のマイグレーションが
+// Git LFS のポインタではなくSQLとしてチェックアウトされていることを確認する
+func TestRepositoryMigrationsAreCheckedOut(t *testing.T) {
+	files, err := pendingMigrations(MigrationsDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no migrations after the base schema")
+	}
+	for _, f := range files {
+		sql, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(string(sql), "version https://git-lfs") {
+			t.Errorf("%s is a Git LFS pointer", filepath.Base(f))
+		}
+	}
+}
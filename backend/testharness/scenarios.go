@@ -0,0 +1,483 @@
+package testharness
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// Scenario は1リクエスト分のテストケース
+type Scenario struct {
+	// Name はゴールデンファイル名にも使う一意な名前
+	Name   string
+	Method string
+	// Path は {user} や {recipe0} のようなプレースホルダーを含められる
+	Path string
+	// JSON が設定されている場合はJSONボディとして送る
+	JSON interface{}
+	// Form が設定されている場合はmultipart/form-dataとして送る
+	Form map[string]string
+	// AuthAs が設定されている場合はそのユーザーIDのBearerトークンを付ける
+	AuthAs string
+	// Capture はレスポンスJSONから値を取り出して以降のシナリオで使う（変数名 -> ドット区切りのパス）
+	Capture map[string]string
+}
+
+// Vars はシナリオのプレースホルダーに展開する値
+type Vars map[string]string
+
+// NewVars はシード結果からプレースホルダーの初期値を作る
+func NewVars(seed *SeedResult) Vars {
+	vars := Vars{
+		"user":  seed.UserID,
+		"admin": seed.AdminID,
+	}
+	for i, id := range seed.RecipeIDs {
+		vars[fmt.Sprintf("recipe%d", i)] = id
+		vars[fmt.Sprintf("recipe%d_name", i)] = seed.RecipeNames[i]
+	}
+	if len(seed.RecipeIDs) > 0 {
+		if ids := seed.RecipeIngredients[seed.RecipeIDs[0]]; len(ids) > 0 {
+			vars["recipe0_ingredient"] = fmt.Sprintf("%d", ids[0])
+		}
+	}
+	return vars
+}
+
+// expand はプレースホルダーを値に置き換える
+func (v Vars) expand(s string) string {
+	for k, val := range v {
+		s = strings.ReplaceAll(s, "{"+k+"}", val)
+	}
+	return s
+}
+
+// expandValue はJSONボディ内の文字列も再帰的に展開する
+func (v Vars) expandValue(value interface{}) interface{} {
+	switch t := value.(type) {
+	case string:
+		return v.expand(t)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[k] = v.expandValue(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = v.expandValue(val)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// DefaultScenarios は routes.SetupRoutes と routes.SetupAuthRoutes に登録された全ルートを網羅するシナリオ。
+// 順序に意味がある（いいね→おすすめ→いいね解除、レビュー作成→更新→削除 など）。
+func DefaultScenarios(seed *SeedResult) []Scenario {
+	ingredients := make([]interface{}, 0)
+	if len(seed.RecipeIDs) > 0 {
+		for _, id := range seed.RecipeIngredients[seed.RecipeIDs[0]] {
+			ingredients = append(ingredients, map[string]interface{}{
+				"ingredientId":     id,
+				"quantityRequired": 1000,
+				"unitName":         "",
+			})
+		}
+	}
+	search := func(mode string) map[string]interface{} {
+		return map[string]interface{}{"ingredients": ingredients, "searchMode": mode}
+	}
+
+	return []Scenario{
+		// ジャンル
+		{Name: "genres_recipe", Method: http.MethodGet, Path: "/api/recipe_genres"},
+		{Name: "genres_ingredient", Method: http.MethodGet, Path: "/api/ingredient_genres"},
+
+		// レシピ検索（4モード）と詳細
+		{Name: "recipes_search_exact_with_quantity", Method: http.MethodPost, Path: "/api/recipes", JSON: search("exact_with_quantity")},
+		{Name: "recipes_search_exact_without_quantity", Method: http.MethodPost, Path: "/api/recipes", JSON: search("exact_without_quantity")},
+		{Name: "recipes_search_partial_with_quantity", Method: http.MethodPost, Path: "/api/recipes", JSON: search("partial_with_quantity")},
+		{Name: "recipes_search_partial_without_quantity", Method: http.MethodPost, Path: "/api/recipes", JSON: search("partial_without_quantity")},
+		{Name: "recipes_search_invalid_json", Method: http.MethodPost, Path: "/api/recipes", JSON: "not-an-object"},
+		{Name: "recipes_get", Method: http.MethodGet, Path: "/api/recipes/{recipe0}"},
+		{Name: "recipes_similar", Method: http.MethodGet, Path: "/api/recipes/{recipe0}/similar"},
+		{Name: "recipes_similar_not_found", Method: http.MethodGet, Path: "/api/recipes/44444444-4444-4444-8444-444444444444/similar"},
+		{Name: "recipes_search_by_name", Method: http.MethodGet, Path: "/api/recipes/search?q={recipe0_name}"},
+		{Name: "recipes_search_by_name_missing_query", Method: http.MethodGet, Path: "/api/recipes/search"},
+		{Name: "user_recipes", Method: http.MethodGet, Path: "/api/user/recipes?userId={admin}"},
+
+		// ユーザー同期・取得・更新
+		{Name: "users_create", Method: http.MethodPost, Path: "/api/users", JSON: map[string]interface{}{
+			"id": "33333333-3333-4333-8333-333333333333", "email": "new@example.com",
+		}},
+		{Name: "users_sync_existing", Method: http.MethodPost, Path: "/api/users/sync", JSON: map[string]interface{}{
+			"id": "{user}", "email": "user@example.com", "username": "同期ユーザー",
+		}},
+		{Name: "users_sync_missing_email", Method: http.MethodPost, Path: "/api/users/sync", JSON: map[string]interface{}{"id": "{user}"}},
+		{Name: "users_get", Method: http.MethodGet, Path: "/api/users/{user}"},
+		{Name: "users_get_not_found", Method: http.MethodGet, Path: "/api/users/44444444-4444-4444-8444-444444444444"},
+		{Name: "users_profile", Method: http.MethodGet, Path: "/api/users/{admin}/profile"},
+		{Name: "users_update", Method: http.MethodPut, Path: "/api/users/{user}", Form: map[string]string{"username": "更新ユーザー", "age": "30", "bio": "冷蔵庫の余り物で作る料理が好きです"}},
+		{Name: "users_delete_unauthenticated", Method: http.MethodDelete, Path: "/api/users/{user}"},
+		{Name: "users_delete_other_user", Method: http.MethodDelete, Path: "/api/users/{user}", AuthAs: "{admin}"},
+		{Name: "users_restore_not_pending", Method: http.MethodPost, Path: "/api/users/{user}/restore", AuthAs: "{user}"},
+		{Name: "users_delete", Method: http.MethodDelete, Path: "/api/users/{user}", AuthAs: "{user}"},
+		{Name: "users_get_after_delete", Method: http.MethodGet, Path: "/api/users/{user}"},
+		{Name: "users_delete_again", Method: http.MethodDelete, Path: "/api/users/{user}", AuthAs: "{user}"},
+		{Name: "users_restore", Method: http.MethodPost, Path: "/api/users/{user}/restore", AuthAs: "{user}"},
+		{Name: "users_get_after_restore", Method: http.MethodGet, Path: "/api/users/{user}"},
+		{Name: "users_export_status_none", Method: http.MethodGet, Path: "/api/users/{user}/export", AuthAs: "{user}"},
+		{Name: "users_export_other_user", Method: http.MethodPost, Path: "/api/users/{user}/export", AuthAs: "{admin}"},
+		{Name: "users_export_request", Method: http.MethodPost, Path: "/api/users/{user}/export", AuthAs: "{user}"},
+		{Name: "users_export_status", Method: http.MethodGet, Path: "/api/users/{user}/export", AuthAs: "{user}"},
+		{Name: "users_profile_image_missing_file", Method: http.MethodPost, Path: "/api/users/{user}/profile-image", Form: map[string]string{}},
+		{Name: "users_set_role_unauthenticated", Method: http.MethodPost, Path: "/api/users/role", JSON: map[string]interface{}{"user_id": "{user}", "role": "admin"}},
+
+		// アレルゲン・食事制限（recipe0 の具材にタグを付け、ユーザーの設定に応じて検索・おすすめから除外する）
+		{Name: "admin_ingredients_dietary_tags_get", Method: http.MethodGet, Path: "/admin/ingredients/{recipe0_ingredient}/dietary-tags"},
+		{Name: "admin_ingredients_dietary_tags_update", Method: http.MethodPut, Path: "/admin/ingredients/{recipe0_ingredient}/dietary-tags", JSON: map[string]interface{}{
+			"tags": []string{"vegetarian", "egg", "egg"},
+		}},
+		{Name: "admin_ingredients_dietary_tags_unknown", Method: http.MethodPut, Path: "/admin/ingredients/{recipe0_ingredient}/dietary-tags", JSON: map[string]interface{}{
+			"tags": []string{"unknown"},
+		}},
+		{Name: "admin_ingredients_dietary_tags_not_found", Method: http.MethodGet, Path: "/admin/ingredients/999999/dietary-tags"},
+		{Name: "users_dietary_restrictions_unauthenticated", Method: http.MethodGet, Path: "/api/users/{user}/dietary-restrictions"},
+		{Name: "users_dietary_restrictions_other_user", Method: http.MethodGet, Path: "/api/users/{user}/dietary-restrictions", AuthAs: "{admin}"},
+		{Name: "users_dietary_restrictions_get", Method: http.MethodGet, Path: "/api/users/{user}/dietary-restrictions", AuthAs: "{user}"},
+		{Name: "users_dietary_restrictions_update", Method: http.MethodPut, Path: "/api/users/{user}/dietary-restrictions", AuthAs: "{user}", JSON: map[string]interface{}{
+			"restrictions": []string{"low_salt", "egg", "egg"},
+		}},
+		{Name: "users_dietary_restrictions_unknown", Method: http.MethodPut, Path: "/api/users/{user}/dietary-restrictions", AuthAs: "{user}", JSON: map[string]interface{}{
+			"restrictions": []string{"unknown"},
+		}},
+		{Name: "users_dietary_restrictions_missing", Method: http.MethodPut, Path: "/api/users/{user}/dietary-restrictions", AuthAs: "{user}", JSON: map[string]interface{}{}},
+		{Name: "recipes_search_dietary_filtered", Method: http.MethodPost, Path: "/api/recipes", AuthAs: "{user}", JSON: search("partial_without_quantity")},
+		{Name: "recipes_search_dietary_excluded", Method: http.MethodPost, Path: "/api/recipes?include_excluded=true", AuthAs: "{user}", JSON: search("partial_without_quantity")},
+		{Name: "recipes_search_by_name_dietary_filtered", Method: http.MethodGet, Path: "/api/recipes/search?q={recipe0_name}", AuthAs: "{user}"},
+		{Name: "recipes_search_by_name_dietary_excluded", Method: http.MethodGet, Path: "/api/recipes/search?q={recipe0_name}&include_excluded=true", AuthAs: "{user}"},
+		{Name: "recipes_similar_dietary_excluded", Method: http.MethodGet, Path: "/api/recipes/{recipe1}/similar", AuthAs: "{user}"},
+		{Name: "recommendations_dietary_filtered", Method: http.MethodGet, Path: "/api/recommendations/{user}"},
+		{Name: "recommendations_dietary_excluded", Method: http.MethodGet, Path: "/api/recommendations/{user}?include_excluded=true"},
+		{Name: "users_dietary_restrictions_clear", Method: http.MethodPut, Path: "/api/users/{user}/dietary-restrictions", AuthAs: "{user}", JSON: map[string]interface{}{
+			"restrictions": []string{},
+		}},
+
+		// いいね（トグル）とおすすめ
+		{Name: "likes_toggle_unauthenticated", Method: http.MethodPost, Path: "/api/likes/{user}/{recipe0}"},
+		{Name: "likes_toggle_other_user", Method: http.MethodPost, Path: "/api/likes/{user}/{recipe0}", AuthAs: "{admin}"},
+		{Name: "likes_toggle_add", Method: http.MethodPost, Path: "/api/likes/{user}/{recipe0}", AuthAs: "{user}"},
+		{Name: "likes_list_after_add", Method: http.MethodGet, Path: "/api/likes/{user}"},
+		{Name: "recommendations", Method: http.MethodGet, Path: "/api/recommendations/{user}"},
+		{Name: "rankings_trending", Method: http.MethodGet, Path: "/api/rankings/trending"},
+		{Name: "rankings_top_rated_by_genre", Method: http.MethodGet, Path: "/api/rankings/top_rated?genre_id=1&limit=5"},
+		{Name: "rankings_most_cooked", Method: http.MethodGet, Path: "/api/rankings/most_cooked"},
+		{Name: "rankings_invalid_kind", Method: http.MethodGet, Path: "/api/rankings/unknown"},
+		{Name: "recommendations_cold_start", Method: http.MethodGet, Path: "/api/recommendations/{admin}?limit=3"},
+		{Name: "users_public_profile", Method: http.MethodGet, Path: "/api/users/{admin}/public"},
+		{Name: "users_public_profile_not_found", Method: http.MethodGet, Path: "/api/users/44444444-4444-4444-8444-444444444444/public"},
+		{Name: "users_follow_unauthenticated", Method: http.MethodPut, Path: "/api/users/{admin}/follow"},
+		{Name: "users_follow_self", Method: http.MethodPut, Path: "/api/users/{user}/follow", AuthAs: "{user}"},
+		{Name: "users_follow", Method: http.MethodPut, Path: "/api/users/{admin}/follow", AuthAs: "{user}"},
+		{Name: "users_follow_again", Method: http.MethodPut, Path: "/api/users/{admin}/follow", AuthAs: "{user}"},
+		{Name: "users_public_profile_following", Method: http.MethodGet, Path: "/api/users/{admin}/public", AuthAs: "{user}"},
+		{Name: "users_followers", Method: http.MethodGet, Path: "/api/users/{admin}/followers"},
+		{Name: "users_following", Method: http.MethodGet, Path: "/api/users/{user}/following"},
+		{Name: "feed_unauthenticated", Method: http.MethodGet, Path: "/api/feed"},
+		{Name: "feed_before_publish", Method: http.MethodGet, Path: "/api/feed", AuthAs: "{user}"},
+		{Name: "feed_publish_collection", Method: http.MethodPost, Path: "/api/collections", AuthAs: "{admin}", JSON: map[string]interface{}{"name": "管理者のおすすめ", "is_public": true}, Capture: map[string]string{"admin_collection": "collection.id"}},
+		{Name: "feed_after_publish", Method: http.MethodGet, Path: "/api/feed", AuthAs: "{user}"},
+		{Name: "feed_page_size", Method: http.MethodGet, Path: "/api/feed?limit=1", AuthAs: "{user}"},
+		{Name: "feed_invalid_cursor", Method: http.MethodGet, Path: "/api/feed?cursor=invalid", AuthAs: "{user}"},
+		{Name: "feed_invalid_limit", Method: http.MethodGet, Path: "/api/feed?limit=0", AuthAs: "{user}"},
+		{Name: "feed_unpublish_collection", Method: http.MethodPut, Path: "/api/collections/{admin_collection}", AuthAs: "{admin}", JSON: map[string]interface{}{"is_public": false}},
+		{Name: "feed_after_unpublish", Method: http.MethodGet, Path: "/api/feed", AuthAs: "{user}"},
+		{Name: "users_unfollow", Method: http.MethodDelete, Path: "/api/users/{admin}/follow", AuthAs: "{user}"},
+		{Name: "user_recipes_public_only", Method: http.MethodGet, Path: "/api/user/recipes?userId={admin}"},
+		{Name: "users_like_count", Method: http.MethodGet, Path: "/api/users/{admin}/likes"},
+		{Name: "likes_toggle_remove", Method: http.MethodPost, Path: "/api/likes/{user}/{recipe0}", AuthAs: "{user}"},
+		{Name: "likes_list_after_remove", Method: http.MethodGet, Path: "/api/likes/{user}"},
+		{Name: "likes_invalid_user", Method: http.MethodPost, Path: "/api/likes/not-a-uuid/{recipe0}", AuthAs: "{user}"},
+		{Name: "likes_put_unauthenticated", Method: http.MethodPut, Path: "/api/likes/{user}/{recipe0}"},
+		{Name: "likes_put_other_user", Method: http.MethodPut, Path: "/api/likes/{user}/{recipe0}", AuthAs: "{admin}"},
+		{Name: "likes_put", Method: http.MethodPut, Path: "/api/likes/{user}/{recipe0}", AuthAs: "{user}"},
+		{Name: "likes_put_again", Method: http.MethodPut, Path: "/api/likes/{user}/{recipe0}", AuthAs: "{user}"},
+		{Name: "likes_status_batch", Method: http.MethodGet, Path: "/api/likes/{user}/status?recipe_ids={recipe0},{recipe1}", AuthAs: "{user}"},
+		{Name: "likes_status_other_user", Method: http.MethodGet, Path: "/api/likes/{user}/status?recipe_ids={recipe0}", AuthAs: "{admin}"},
+		{Name: "likes_delete_other_user", Method: http.MethodDelete, Path: "/api/likes/{user}/{recipe0}", AuthAs: "{admin}"},
+		{Name: "likes_delete", Method: http.MethodDelete, Path: "/api/likes/{user}/{recipe0}", AuthAs: "{user}"},
+		{Name: "likes_delete_again", Method: http.MethodDelete, Path: "/api/likes/{user}/{recipe0}", AuthAs: "{user}"},
+		{Name: "likes_status_missing_ids", Method: http.MethodGet, Path: "/api/likes/{user}/status", AuthAs: "{user}"},
+
+		// レビューCRUD
+		{Name: "reviews_add_unauthenticated", Method: http.MethodPost, Path: "/api/reviews", JSON: map[string]interface{}{
+			"recipeId": "{recipe0}", "rating": 4, "comment": "おいしかったです",
+		}},
+		{Name: "reviews_add", Method: http.MethodPost, Path: "/api/reviews", AuthAs: "{user}", JSON: map[string]interface{}{
+			"recipeId": "{recipe0}", "userId": "{admin}", "rating": 4, "comment": "おいしかったです",
+		}, Capture: map[string]string{"review": "id"}},
+		{Name: "reviews_add_again_updates", Method: http.MethodPost, Path: "/api/reviews", AuthAs: "{user}", JSON: map[string]interface{}{
+			"recipeId": "{recipe0}", "rating": 3, "comment": "  2回目の感想  ",
+		}},
+		{Name: "reviews_add_invalid_rating", Method: http.MethodPost, Path: "/api/reviews", AuthAs: "{user}", JSON: map[string]interface{}{
+			"recipeId": "{recipe0}", "rating": 6,
+		}},
+		{Name: "reviews_add_unknown_recipe", Method: http.MethodPost, Path: "/api/reviews", AuthAs: "{user}", JSON: map[string]interface{}{
+			"recipeId": "44444444-4444-4444-8444-444444444444", "rating": 4,
+		}},
+		{Name: "reviews_by_recipe", Method: http.MethodGet, Path: "/api/reviews/{recipe0}"},
+		{Name: "reviews_by_recipe_sorted", Method: http.MethodGet, Path: "/api/reviews/{recipe0}?sort=highest&limit=1"},
+		{Name: "reviews_by_recipe_invalid_sort", Method: http.MethodGet, Path: "/api/reviews/{recipe0}?sort=random"},
+		{Name: "reviews_by_recipe_invalid_cursor", Method: http.MethodGet, Path: "/api/reviews/{recipe0}?cursor=not-a-cursor"},
+		{Name: "reviews_helpful_own", Method: http.MethodPut, Path: "/api/reviews/{review}/helpful", AuthAs: "{user}"},
+		{Name: "reviews_helpful", Method: http.MethodPut, Path: "/api/reviews/{review}/helpful", AuthAs: "{admin}"},
+		{Name: "reviews_helpful_again", Method: http.MethodPut, Path: "/api/reviews/{review}/helpful", AuthAs: "{admin}"},
+		{Name: "reviews_by_recipe_helpful", Method: http.MethodGet, Path: "/api/reviews/{recipe0}?sort=helpful", AuthAs: "{admin}"},
+		{Name: "reviews_helpful_remove", Method: http.MethodDelete, Path: "/api/reviews/{review}/helpful", AuthAs: "{admin}"},
+		{Name: "reviews_helpful_unauthenticated", Method: http.MethodPut, Path: "/api/reviews/{review}/helpful"},
+		{Name: "reviews_by_user", Method: http.MethodGet, Path: "/api/reviews/user/{user}"},
+		// 削除を申請中（猶予期間中）のユーザーのレビューは一覧に出さず、取り消すと元に戻る
+		{Name: "users_delete_with_review", Method: http.MethodDelete, Path: "/api/users/{user}", AuthAs: "{user}"},
+		{Name: "reviews_by_recipe_pending_deletion", Method: http.MethodGet, Path: "/api/reviews/{recipe0}"},
+		{Name: "reviews_by_user_pending_deletion", Method: http.MethodGet, Path: "/api/reviews/user/{user}"},
+		{Name: "users_restore_with_review", Method: http.MethodPost, Path: "/api/users/{user}/restore", AuthAs: "{user}"},
+		{Name: "reviews_by_recipe_after_restore", Method: http.MethodGet, Path: "/api/reviews/{recipe0}"},
+		{Name: "users_average_rating", Method: http.MethodGet, Path: "/api/users/{admin}/reviews"},
+		{Name: "reviews_update", Method: http.MethodPut, Path: "/api/reviews/{review}", AuthAs: "{user}", JSON: map[string]interface{}{
+			"rating": 5, "comment": "また作ります",
+		}},
+		{Name: "reviews_update_other_user", Method: http.MethodPut, Path: "/api/reviews/{review}", AuthAs: "{admin}", JSON: map[string]interface{}{
+			"rating": 1, "comment": "他人のレビュー",
+		}},
+		{Name: "reviews_add_ng_word", Method: http.MethodPost, Path: "/api/reviews", AuthAs: "{admin}", JSON: map[string]interface{}{
+			"recipeId": "{recipe0}", "rating": 1, "comment": "作った人はﾊﾞ カ",
+		}},
+		{Name: "reviews_report_own", Method: http.MethodPost, Path: "/api/reviews/{review}/report", AuthAs: "{user}", JSON: map[string]interface{}{"reason": "spam"}},
+		{Name: "reviews_report_invalid_reason", Method: http.MethodPost, Path: "/api/reviews/{review}/report", AuthAs: "{admin}", JSON: map[string]interface{}{"reason": "dislike"}},
+		{Name: "reviews_report", Method: http.MethodPost, Path: "/api/reviews/{review}/report", AuthAs: "{admin}", JSON: map[string]interface{}{"reason": "offensive", "detail": "攻撃的な表現"}},
+		{Name: "reviews_report_again", Method: http.MethodPost, Path: "/api/reviews/{review}/report", AuthAs: "{admin}", JSON: map[string]interface{}{"reason": "spam"}},
+		{Name: "admin_review_reports_unauthenticated", Method: http.MethodGet, Path: "/admin/reviews/reports"},
+		{Name: "admin_review_reports_forbidden", Method: http.MethodGet, Path: "/admin/reviews/reports", AuthAs: "{user}"},
+		{Name: "admin_review_reports", Method: http.MethodGet, Path: "/admin/reviews/reports", AuthAs: "{admin}"},
+		{Name: "admin_review_hide_unauthenticated", Method: http.MethodPost, Path: "/admin/reviews/{review}/hide"},
+		{Name: "admin_review_hide_forbidden", Method: http.MethodPost, Path: "/admin/reviews/{review}/hide", AuthAs: "{user}"},
+		{Name: "admin_review_hide", Method: http.MethodPost, Path: "/admin/reviews/{review}/hide", AuthAs: "{admin}", JSON: map[string]interface{}{"note": "通報内容を確認"}},
+		{Name: "reviews_by_recipe_after_hide", Method: http.MethodGet, Path: "/api/reviews/{recipe0}"},
+		{Name: "admin_review_restore", Method: http.MethodPost, Path: "/admin/reviews/{review}/restore", AuthAs: "{admin}"},
+		{Name: "admin_review_dismiss", Method: http.MethodPost, Path: "/admin/reviews/{review}/dismiss", AuthAs: "{admin}"},
+		{Name: "admin_review_moderation_log_forbidden", Method: http.MethodGet, Path: "/admin/reviews/{review}/moderation-log", AuthAs: "{user}"},
+		{Name: "admin_review_moderation_log", Method: http.MethodGet, Path: "/admin/reviews/{review}/moderation-log", AuthAs: "{admin}"},
+		{Name: "admin_review_hide_not_found", Method: http.MethodPost, Path: "/admin/reviews/44444444-4444-4444-8444-444444444444/hide", AuthAs: "{admin}"},
+		{Name: "admin_review_delete_forbidden", Method: http.MethodDelete, Path: "/admin/reviews/{review}", AuthAs: "{user}"},
+		{Name: "reviews_photos_missing_file", Method: http.MethodPost, Path: "/api/reviews/{review}/photos", AuthAs: "{user}", Form: map[string]string{}},
+		{Name: "reviews_photos_other_user", Method: http.MethodPost, Path: "/api/reviews/{review}/photos", AuthAs: "{admin}", Form: map[string]string{}},
+		{Name: "reviews_photo_delete_not_found", Method: http.MethodDelete, Path: "/api/reviews/{review}/photos/44444444-4444-4444-8444-444444444444", AuthAs: "{user}"},
+		{Name: "reviews_delete_other_user", Method: http.MethodDelete, Path: "/api/reviews/{review}", AuthAs: "{admin}"},
+		{Name: "reviews_delete", Method: http.MethodDelete, Path: "/api/reviews/{review}", AuthAs: "{user}"},
+		{Name: "reviews_delete_invalid_id", Method: http.MethodDelete, Path: "/api/reviews/not-a-uuid", AuthAs: "{user}"},
+
+		// 具材と初期設定
+		{Name: "ingredients_by_category", Method: http.MethodGet, Path: "/api/ingredients/by-category?category_id=8"},
+		{Name: "ingredients_by_category_missing", Method: http.MethodGet, Path: "/api/ingredients/by-category"},
+		{Name: "ingredient_defaults_get", Method: http.MethodGet, Path: "/api/ingredient-defaults"},
+		{Name: "ingredient_defaults_put", Method: http.MethodPut, Path: "/api/ingredient-defaults", JSON: []interface{}{
+			map[string]interface{}{"ingredient_id": 1, "default_quantity": 2},
+		}},
+		{Name: "user_ingredient_defaults_put", Method: http.MethodPut, Path: "/api/user/ingredient-defaults?user_id={user}", JSON: []interface{}{
+			map[string]interface{}{"ingredient_id": 1, "default_quantity": 2},
+		}},
+		{Name: "user_ingredient_defaults_get", Method: http.MethodGet, Path: "/api/user/ingredient-defaults?user_id={user}"},
+
+		// AI使用回数
+		{Name: "ai_usage_unauthenticated", Method: http.MethodGet, Path: "/api/recipe/ai-usage"},
+		{Name: "ai_usage_get_initial", Method: http.MethodGet, Path: "/api/recipe/ai-usage", AuthAs: "{user}"},
+		{Name: "ai_generate_description", Method: http.MethodPost, Path: "/api/recipe/generate-description", AuthAs: "{user}", JSON: map[string]interface{}{"recipe_name": "カレー"}},
+		{Name: "ai_usage_increment", Method: http.MethodPost, Path: "/api/recipe/ai-usage", AuthAs: "{user}"},
+		{Name: "ai_usage_get_unknown_feature", Method: http.MethodGet, Path: "/api/recipe/ai-usage?feature=unknown", AuthAs: "{user}"},
+		{Name: "ai_usage_increment_unknown_feature", Method: http.MethodPost, Path: "/api/recipe/ai-usage?feature=unknown", AuthAs: "{user}"},
+		{Name: "ai_usage_get_after", Method: http.MethodGet, Path: "/api/recipe/ai-usage", AuthAs: "{user}"},
+		{Name: "ai_generate_faq", Method: http.MethodPost, Path: "/api/recipe/generate-faq", AuthAs: "{user}", JSON: map[string]interface{}{"recipe_id": "{recipe0}"}},
+		{Name: "ai_generate_steps", Method: http.MethodPost, Path: "/api/recipe/generate-steps", AuthAs: "{user}", JSON: map[string]interface{}{"recipe_id": "{recipe0}"}},
+		{Name: "ai_generate_tips", Method: http.MethodPost, Path: "/api/recipe/generate-tips", AuthAs: "{user}", JSON: map[string]interface{}{"recipe_id": "{recipe0}"}},
+		{Name: "ai_generate_faq_missing_input", Method: http.MethodPost, Path: "/api/recipe/generate-faq", AuthAs: "{user}", JSON: map[string]interface{}{"name": "カレー"}},
+
+		// コレクション
+		{Name: "collections_unauthenticated", Method: http.MethodGet, Path: "/api/collections"},
+		{Name: "collections_create", Method: http.MethodPost, Path: "/api/collections", AuthAs: "{user}", JSON: map[string]interface{}{
+			"name": "平日の晩ごはん", "description": "30分以内で作れるもの",
+		}, Capture: map[string]string{"collection": "collection.id"}},
+		{Name: "collections_create_missing_name", Method: http.MethodPost, Path: "/api/collections", AuthAs: "{user}", JSON: map[string]interface{}{"name": " "}},
+		{Name: "collections_add_recipe0", Method: http.MethodPost, Path: "/api/collections/{collection}/recipes", AuthAs: "{user}", JSON: map[string]interface{}{"recipe_id": "{recipe0}"}},
+		{Name: "collections_add_recipe1", Method: http.MethodPost, Path: "/api/collections/{collection}/recipes", AuthAs: "{user}", JSON: map[string]interface{}{"recipe_id": "{recipe1}"}},
+		{Name: "collections_add_recipe_again", Method: http.MethodPost, Path: "/api/collections/{collection}/recipes", AuthAs: "{user}", JSON: map[string]interface{}{"recipe_id": "{recipe0}"}},
+		{Name: "collections_reorder_recipes", Method: http.MethodPut, Path: "/api/collections/{collection}/recipes/order", AuthAs: "{user}", JSON: map[string]interface{}{
+			"recipe_ids": []string{"{recipe1}", "{recipe0}"},
+		}},
+		{Name: "collections_list", Method: http.MethodGet, Path: "/api/collections", AuthAs: "{user}"},
+		{Name: "collections_get_private_other_user", Method: http.MethodGet, Path: "/api/collections/{collection}", AuthAs: "{admin}"},
+		{Name: "collections_publish", Method: http.MethodPut, Path: "/api/collections/{collection}", AuthAs: "{user}", JSON: map[string]interface{}{"is_public": true}, Capture: map[string]string{"collection_slug": "collection.slug"}},
+		{Name: "collections_get_shared", Method: http.MethodGet, Path: "/api/collections/shared/{collection_slug}"},
+		{Name: "collections_remove_recipe", Method: http.MethodDelete, Path: "/api/collections/{collection}/recipes/{recipe0}", AuthAs: "{user}"},
+		{Name: "collections_get", Method: http.MethodGet, Path: "/api/collections/{collection}", AuthAs: "{user}"},
+		{Name: "collections_reorder", Method: http.MethodPut, Path: "/api/collections/order", AuthAs: "{user}", JSON: map[string]interface{}{"collection_ids": []string{"{collection}"}}},
+		{Name: "collections_delete_other_user", Method: http.MethodDelete, Path: "/api/collections/{collection}", AuthAs: "{admin}"},
+		{Name: "collections_delete", Method: http.MethodDelete, Path: "/api/collections/{collection}", AuthAs: "{user}"},
+
+		// 「作った」記録
+		{Name: "cook_logs_unauthenticated", Method: http.MethodGet, Path: "/api/cook-logs"},
+		{Name: "cook_logs_create", Method: http.MethodPost, Path: "/api/recipes/{recipe0}/cook-logs", AuthAs: "{user}", JSON: map[string]interface{}{
+			"cooked_on": "2024-05-01", "note": "少し甘めにした", "servings": 2,
+			"substitutions": []map[string]string{{"original": "みりん", "substitute": "砂糖"}},
+		}, Capture: map[string]string{"cook_log": "cook_log.id"}},
+		{Name: "cook_logs_create_future_date", Method: http.MethodPost, Path: "/api/recipes/{recipe0}/cook-logs", AuthAs: "{user}", JSON: map[string]interface{}{"cooked_on": "2999-01-01"}},
+		{Name: "cook_logs_create_invalid_servings", Method: http.MethodPost, Path: "/api/recipes/{recipe0}/cook-logs", AuthAs: "{user}", JSON: map[string]interface{}{"cooked_on": "2024-05-01", "servings": 0}},
+		{Name: "cook_logs_create_recipe_not_found", Method: http.MethodPost, Path: "/api/recipes/44444444-4444-4444-8444-444444444444/cook-logs", AuthAs: "{user}", JSON: map[string]interface{}{"cooked_on": "2024-05-01"}},
+		{Name: "cook_logs_list", Method: http.MethodGet, Path: "/api/cook-logs", AuthAs: "{user}"},
+		{Name: "cook_logs_update", Method: http.MethodPut, Path: "/api/cook-logs/{cook_log}", AuthAs: "{user}", JSON: map[string]interface{}{"note": "次は辛めに", "servings": 4}},
+		{Name: "cook_logs_update_cooked_on_only", Method: http.MethodPut, Path: "/api/cook-logs/{cook_log}", AuthAs: "{user}", JSON: map[string]interface{}{"cooked_on": "2024-05-02"}},
+		{Name: "cook_logs_update_other_user", Method: http.MethodPut, Path: "/api/cook-logs/{cook_log}", AuthAs: "{admin}", JSON: map[string]interface{}{"note": "他人の記録"}},
+		{Name: "recipe_after_cook_log", Method: http.MethodGet, Path: "/api/recipes/{recipe0}"},
+		{Name: "cook_logs_delete", Method: http.MethodDelete, Path: "/api/cook-logs/{cook_log}", AuthAs: "{user}"},
+		{Name: "cook_logs_list_after_delete", Method: http.MethodGet, Path: "/api/cook-logs", AuthAs: "{user}"},
+
+		// 認証
+		{Name: "auth_role_admin", Method: http.MethodGet, Path: "/api/auth/role", AuthAs: "{admin}"},
+		{Name: "auth_role_user", Method: http.MethodGet, Path: "/api/auth/role", AuthAs: "{user}"},
+
+		// 管理画面：画像生成プロンプト
+		{Name: "admin_image_prompts_list", Method: http.MethodGet, Path: "/admin/recipes/{recipe0}/image-prompts"},
+		{Name: "admin_image_prompts_update_main", Method: http.MethodPut, Path: "/admin/recipes/{recipe0}/image-prompts/0", AuthAs: "{admin}", JSON: map[string]interface{}{"prompt": "日本の家庭的な料理写真、{recipe0_name}、白い器に盛り付け"}},
+		{Name: "admin_image_prompts_update_invalid_step", Method: http.MethodPut, Path: "/admin/recipes/{recipe0}/image-prompts/99", JSON: map[string]interface{}{"prompt": "x"}},
+		{Name: "admin_image_prompts_history_main", Method: http.MethodGet, Path: "/admin/recipes/{recipe0}/image-prompts/0/history"},
+		{Name: "admin_image_generations_record", Method: http.MethodPost, Path: "/admin/recipes/{recipe0}/image-generations", JSON: map[string]interface{}{"step_number": 0, "image_url": "recipes/{recipe0}/main.webp", "model": "test", "prompt_version": 1}},
+		{Name: "admin_image_generations_list", Method: http.MethodGet, Path: "/admin/recipes/{recipe0}/image-generations"},
+		{Name: "admin_image_prompts_defaults", Method: http.MethodPost, Path: "/admin/recipes/{recipe0}/image-prompts/defaults"},
+
+		// 管理画面：具材
+		{Name: "admin_ingredients_list", Method: http.MethodGet, Path: "/admin/ingredients"},
+		{Name: "admin_ingredients_add_without_image", Method: http.MethodPost, Path: "/admin/ingredients", Form: map[string]string{
+			"name": "テスト具材", "genre_id": "1", "unit_id": "1",
+		}},
+		{Name: "admin_ingredients_update", Method: http.MethodPatch, Path: "/admin/ingredients/401", Form: map[string]string{
+			"name": "更新された具材", "genre": `{"id":10}`, "unit": `{"id":1}`,
+		}},
+		{Name: "admin_units", Method: http.MethodGet, Path: "/admin/units"},
+
+		// 管理画面：レシピCRUD
+		{Name: "admin_recipes_list", Method: http.MethodGet, Path: "/admin/recipes"},
+		{Name: "admin_recipes_get", Method: http.MethodGet, Path: "/admin/recipes/{recipe0}"},
+		{Name: "admin_recipes_get_invalid_id", Method: http.MethodGet, Path: "/admin/recipes/not-a-uuid"},
+		{Name: "admin_recipes_add", Method: http.MethodPost, Path: "/admin/recipes", Form: map[string]string{
+			"user_id":       "{admin}",
+			"name":          "ハーネスのレシピ",
+			"genre_id":      "1",
+			"is_public":     "true",
+			"is_draft":      "false",
+			"cooking_time":  "15",
+			"cost_estimate": "300",
+			"summary":       "テスト用のレシピです",
+			"catchphrase":   "テスト",
+			"instructions":  `[{"stepNumber":1,"description":"材料を切ります。"}]`,
+			"ingredients":   `[{"ingredient_id":1,"quantity_required":1,"unit_id":3}]`,
+			"nutrition":     `{"calories":100,"carbohydrates":10,"fat":1,"protein":5,"salt":0.5}`,
+			"faq":           `[]`,
+		}, Capture: map[string]string{"created_recipe": "recipe.id"}},
+		{Name: "admin_recipes_update", Method: http.MethodPut, Path: "/admin/recipes/{created_recipe}", Form: map[string]string{
+			"name": "ハーネスのレシピ（改）", "summary": "更新しました", "catchphrase": "更新", "genre": "2", "cookingTime": "20",
+		}},
+		{Name: "admin_recipes_toggle_publish", Method: http.MethodPut, Path: "/admin/recipes/{created_recipe}/toggle-publish"},
+
+		// 通知（管理者が投稿したレシピへの公開状態の変更・いいね）
+		{Name: "admin_recipes_toggle_publish_again", Method: http.MethodPut, Path: "/admin/recipes/{created_recipe}/toggle-publish"},
+		{Name: "notifications_unauthenticated", Method: http.MethodGet, Path: "/api/notifications"},
+		{Name: "notifications_like_received", Method: http.MethodPut, Path: "/api/likes/{user}/{created_recipe}", AuthAs: "{user}"},
+		{Name: "notifications_like_removed", Method: http.MethodDelete, Path: "/api/likes/{user}/{created_recipe}", AuthAs: "{user}"},
+		{Name: "notifications_like_received_again", Method: http.MethodPut, Path: "/api/likes/{user}/{created_recipe}", AuthAs: "{user}"},
+		{Name: "notifications_list", Method: http.MethodGet, Path: "/api/notifications", AuthAs: "{admin}",
+			Capture: map[string]string{"notification": "notifications.0.id"}},
+		{Name: "notifications_list_page_size", Method: http.MethodGet, Path: "/api/notifications?limit=1", AuthAs: "{admin}"},
+		{Name: "notifications_list_invalid_limit", Method: http.MethodGet, Path: "/api/notifications?limit=0", AuthAs: "{admin}"},
+		{Name: "notifications_list_invalid_cursor", Method: http.MethodGet, Path: "/api/notifications?cursor=invalid", AuthAs: "{admin}"},
+		{Name: "notifications_unread_count", Method: http.MethodGet, Path: "/api/notifications/unread-count", AuthAs: "{admin}"},
+		{Name: "notifications_mark_read_other_user", Method: http.MethodPut, Path: "/api/notifications/{notification}/read", AuthAs: "{user}"},
+		{Name: "notifications_mark_read_invalid_id", Method: http.MethodPut, Path: "/api/notifications/not-a-uuid/read", AuthAs: "{admin}"},
+		{Name: "notifications_mark_read", Method: http.MethodPut, Path: "/api/notifications/{notification}/read", AuthAs: "{admin}"},
+		{Name: "notifications_list_unread", Method: http.MethodGet, Path: "/api/notifications?unread=true", AuthAs: "{admin}"},
+		{Name: "notifications_read_all", Method: http.MethodPut, Path: "/api/notifications/read-all", AuthAs: "{admin}"},
+		{Name: "notifications_unread_count_after_read_all", Method: http.MethodGet, Path: "/api/notifications/unread-count", AuthAs: "{admin}"},
+		{Name: "notifications_preferences_get", Method: http.MethodGet, Path: "/api/notifications/preferences", AuthAs: "{admin}"},
+		{Name: "notifications_preferences_update", Method: http.MethodPut, Path: "/api/notifications/preferences", AuthAs: "{admin}", JSON: map[string]interface{}{
+			"preferences": []map[string]interface{}{
+				{"type": "like_received", "in_app": false, "email": false, "webhook": false},
+				{"type": "recipe_published", "in_app": true, "email": false, "webhook": true},
+			},
+		}},
+		{Name: "notifications_preferences_after_update", Method: http.MethodGet, Path: "/api/notifications/preferences", AuthAs: "{admin}"},
+		{Name: "notifications_preferences_unknown_type", Method: http.MethodPut, Path: "/api/notifications/preferences", AuthAs: "{admin}", JSON: map[string]interface{}{
+			"preferences": []map[string]interface{}{{"type": "unknown", "in_app": true}},
+		}},
+		{Name: "notifications_preferences_empty", Method: http.MethodPut, Path: "/api/notifications/preferences", AuthAs: "{admin}", JSON: map[string]interface{}{
+			"preferences": []map[string]interface{}{},
+		}},
+
+		{Name: "admin_recipes_delete", Method: http.MethodDelete, Path: "/admin/recipes/{created_recipe}"},
+		{Name: "admin_recipes_delete_not_found", Method: http.MethodDelete, Path: "/admin/recipes/{created_recipe}"},
+
+		// 管理画面：下書き（ハーネスではRedisを接続しない）
+		{Name: "admin_draft_save_without_redis", Method: http.MethodPost, Path: "/admin/draft-recipes", JSON: map[string]interface{}{}},
+		{Name: "admin_draft_get_without_redis", Method: http.MethodGet, Path: "/admin/draft-recipes/{admin}"},
+
+		// 具材削除は他のシナリオに影響するため最後に行う
+		{Name: "admin_ingredients_delete", Method: http.MethodDelete, Path: "/admin/ingredients/401"},
+		{Name: "admin_ingredients_delete_not_found", Method: http.MethodDelete, Path: "/admin/ingredients/401"},
+	}
+}
+
+// buildRequest はシナリオからリクエストボディとヘッダーを組み立てる
+func buildRequest(s Scenario, vars Vars) (string, []byte, map[string]string, error) {
+	path := vars.expand(s.Path)
+	headers := map[string]string{}
+	if s.AuthAs != "" {
+		headers["Authorization"] = bearerToken(vars.expand(s.AuthAs))
+	}
+
+	switch {
+	case s.Form != nil:
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		for k, v := range s.Form {
+			if err := mw.WriteField(k, vars.expand(v)); err != nil {
+				return "", nil, nil, err
+			}
+		}
+		if err := mw.Close(); err != nil {
+			return "", nil, nil, err
+		}
+		headers["Content-Type"] = mw.FormDataContentType()
+		return path, buf.Bytes(), headers, nil
+	case s.JSON != nil:
+		body, err := json.Marshal(vars.expandValue(s.JSON))
+		if err != nil {
+			return "", nil, nil, err
+		}
+		headers["Content-Type"] = "application/json"
+		return path, body, headers, nil
+	default:
+		return path, nil, headers, nil
+	}
+}
+
+// unsignedJWT はsubだけを持つ署名なしのJWTを作る
+func unsignedJWT(sub string) string {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	payload, _ := json.Marshal(map[string]string{"sub": sub})
+	return header + "." + enc.EncodeToString(payload) + ".harness"
+}
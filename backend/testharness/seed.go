@@ -0,0 +1,263 @@
+package testharness
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"portfolio-amarimono/jobs"
+	"portfolio-amarimono/recommend"
+
+	"gorm.io/gorm"
+)
+
+// 固定のテストユーザー（ゴールデンファイルが安定するようにIDを固定）
+const (
+	UserID  = "11111111-1111-4111-8111-111111111111"
+	AdminID = "22222222-2222-4222-8222-222222222222"
+)
+
+// ingredient_data.csv のgenre_idに対応する具材ジャンル（original-dataにはジャンル定義が無いため）
+var ingredientGenres = []struct {
+	ID   int
+	Name string
+}{
+	{1, "野菜"},
+	{2, "肉"},
+	{3, "魚介"},
+	{4, "穀物"},
+	{5, "調味料"},
+	{6, "スパイス"},
+	{7, "卵・乳製品・豆類"},
+	{8, "海藻"},
+	{9, "果物・お菓子"},
+	{10, "その他"},
+}
+
+// SeedResult はシード後にシナリオから参照する値
+type SeedResult struct {
+	UserID  string
+	AdminID string
+	// RecipeIDs は取り込んだレシピのID（スラッグ順）
+	RecipeIDs []string
+	// RecipeNames はRecipeIDsと同じ順のレシピ名
+	RecipeNames []string
+	// RecipeIngredients はレシピIDごとの具材ID
+	RecipeIngredients map[string][]int
+}
+
+// SeedAll はoriginal-dataのマスタデータとrecipe-dataのレシピを投入する
+func SeedAll(db *gorm.DB, dataRoot string, recipeSample int) (*SeedResult, error) {
+	originalDir := filepath.Join(dataRoot, "original-data")
+
+	if err := seedCSV(db, filepath.Join(originalDir, "units_data.csv"), "units", nil); err != nil {
+		return nil, err
+	}
+	if err := seedCSV(db, filepath.Join(originalDir, "recipe_genres.csv"), "recipe_genres", nil); err != nil {
+		return nil, err
+	}
+	for _, g := range ingredientGenres {
+		if err := db.Exec("INSERT INTO ingredient_genres (id, name) VALUES (?, ?)", g.ID, g.Name).Error; err != nil {
+			return nil, fmt.Errorf("failed to insert ingredient genre %d: %v", g.ID, err)
+		}
+	}
+	if err := seedCSV(db, filepath.Join(originalDir, "ingredient_data.csv"), "ingredients", map[string]bool{"nutrition": true}); err != nil {
+		return nil, err
+	}
+	if err := db.Exec(`INSERT INTO nutrition_standards (age_group, gender, calories, protein, fat, carbohydrates, salt)
+		VALUES ('18-29', 'male', 2500, 60, 70, 300, 8)`).Error; err != nil {
+		return nil, fmt.Errorf("failed to insert nutrition standard: %v", err)
+	}
+
+	// SERIALの採番をCSVで指定したIDの後ろに合わせる
+	for _, table := range []string{"units", "recipe_genres", "ingredient_genres", "ingredients"} {
+		if err := db.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), (SELECT MAX(id) FROM %s))", table, table)).Error; err != nil {
+			return nil, fmt.Errorf("failed to reset sequence for %s: %v", table, err)
+		}
+	}
+
+	result := &SeedResult{
+		UserID:            UserID,
+		AdminID:           AdminID,
+		RecipeIngredients: make(map[string][]int),
+	}
+
+	if err := seedUser(db, UserID, "user@example.com", "テストユーザー"); err != nil {
+		return nil, err
+	}
+	if err := seedUser(db, AdminID, "admin@example.com", "管理者"); err != nil {
+		return nil, err
+	}
+	if err := db.Exec("INSERT INTO user_roles (user_id, role) VALUES (?, 'admin')", AdminID).Error; err != nil {
+		return nil, fmt.Errorf("failed to insert admin role: %v", err)
+	}
+
+	recipesDir := filepath.Join(dataRoot, "recipe-data", "recipes")
+	if err := seedRecipes(db, recipesDir, recipeSample, result); err != nil {
+		return nil, err
+	}
+
+	// 取り込んだレシピの画像生成プロンプト（prompts.json）
+	if _, err := jobs.ImportImagePrompts(db, recipesDir); err != nil {
+		return nil, fmt.Errorf("failed to import image prompts: %v", err)
+	}
+
+	// おすすめ用の類似度（シード時点ではいいねが無いため具材の重なりのみ）
+	if _, err := recommend.RefreshSimilarities(db, recommend.DefaultNeighbors); err != nil {
+		return nil, fmt.Errorf("failed to compute recipe similarities: %v", err)
+	}
+
+	return result, nil
+}
+
+func seedUser(db *gorm.DB, id, email, username string) error {
+	if err := db.Exec("INSERT INTO users (id, email, username) VALUES (?, ?, ?) ON CONFLICT (id) DO NOTHING", id, email, username).Error; err != nil {
+		return fmt.Errorf("failed to insert user %s: %v", id, err)
+	}
+	return nil
+}
+
+// seedRecipes はrecipe-data/recipes/<slug>/recipe.csv と ingredients.csv を取り込む
+func seedRecipes(db *gorm.DB, recipesDir string, sample int, result *SeedResult) error {
+	entries, err := os.ReadDir(recipesDir)
+	if err != nil {
+		return fmt.Errorf("failed to read recipes directory: %v", err)
+	}
+
+	var slugs []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		// CSVがまだ揃っていないレシピ（画像プロンプトのみ等）は対象外
+		if _, err := os.Stat(filepath.Join(recipesDir, e.Name(), "recipe.csv")); err != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(recipesDir, e.Name(), "ingredients.csv")); err != nil {
+			continue
+		}
+		slugs = append(slugs, e.Name())
+	}
+	sort.Strings(slugs)
+	if sample > 0 && len(slugs) > sample {
+		slugs = slugs[:sample]
+	}
+
+	for _, slug := range slugs {
+		rows, err := readCSV(filepath.Join(recipesDir, slug, "recipe.csv"))
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		recipe := rows[0]
+		recipeID := strings.ToLower(recipe["id"])
+		recipe["id"] = recipeID
+
+		// レシピの作成者はusersに存在しないことがあるため、プレースホルダーを作る
+		if authorID := recipe["user_id"]; authorID != "" {
+			if err := seedUser(db, authorID, authorID+"@example.com", "author-"+authorID[:8]); err != nil {
+				return err
+			}
+		}
+
+		if err := insertRow(db, "recipes", recipe, map[string]bool{"instructions": true, "faq": true, "nutrition": true}); err != nil {
+			return fmt.Errorf("failed to insert recipe %s: %v", slug, err)
+		}
+
+		ingredients, err := readCSV(filepath.Join(recipesDir, slug, "ingredients.csv"))
+		if err != nil {
+			return err
+		}
+		for _, ing := range ingredients {
+			ing["recipe_id"] = strings.ToLower(ing["recipe_id"])
+			if err := insertRow(db, "recipe_ingredients", ing, nil); err != nil {
+				return fmt.Errorf("failed to insert ingredients for %s: %v", slug, err)
+			}
+			id, _ := strconv.Atoi(ing["ingredient_id"])
+			result.RecipeIngredients[recipeID] = append(result.RecipeIngredients[recipeID], id)
+		}
+
+		result.RecipeIDs = append(result.RecipeIDs, recipeID)
+		result.RecipeNames = append(result.RecipeNames, recipe["name"])
+	}
+
+	return nil
+}
+
+// seedCSV はヘッダー付きCSVをそのままテーブルに投入する
+func seedCSV(db *gorm.DB, path, table string, jsonColumns map[string]bool) error {
+	rows, err := readCSV(path)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := insertRow(db, table, row, jsonColumns); err != nil {
+			return fmt.Errorf("failed to insert into %s from %s: %v", table, filepath.Base(path), err)
+		}
+	}
+	return nil
+}
+
+// insertRow は列名と値のマップを1行INSERTする（空文字はNULLとして扱う）
+func insertRow(db *gorm.DB, table string, row map[string]string, jsonColumns map[string]bool) error {
+	columns := make([]string, 0, len(row))
+	for col := range row {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	placeholders := make([]string, len(columns))
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		placeholders[i] = "?"
+		if jsonColumns[col] {
+			placeholders[i] = "?::jsonb"
+		}
+		if row[col] == "" {
+			values[i] = nil
+		} else {
+			values[i] = row[col]
+		}
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	return db.Exec(query, values...).Error
+}
+
+// readCSV はヘッダー行をキーにしたマップの配列としてCSVを読み込む
+func readCSV(path string) ([]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if len(records) < 2 {
+		return nil, nil
+	}
+
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, col := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(col)] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
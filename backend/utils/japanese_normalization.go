@@ -3,6 +3,9 @@ package utils
 import (
 	"regexp"
 	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // 漢字→ひらがな変換辞書
@@ -514,6 +517,20 @@ func NormalizeTextForSearch(text string) string {
 	return result
 }
 
+// NormalizeForFilter はNGワードの判定用にテキストを正規化する。
+// 全角英数・半角カナをNFKCで揃え、小文字化・カタカナのひらがな化を行い、
+// 「ば か」「バ☆カ」のように空白や記号を挟んだ書き方も検出できるよう文字と数字以外を取り除く。
+func NormalizeForFilter(text string) string {
+	text = strings.ToLower(norm.NFKC.String(text))
+	text = katakanaToHiragana(text)
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || r == 'ー' {
+			return r
+		}
+		return -1
+	}, text)
+}
+
 // 検索クエリとターゲットテキストを比較する
 func MatchesSearchQuery(query, target string) bool {
 	if query == "" || target == "" {
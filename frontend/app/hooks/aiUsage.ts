@@ -4,6 +4,9 @@ import { supabase } from '@/app/lib/api/supabase/supabaseClient';
 interface AIUsageResponse {
   usage_count: number;
   usage_limit: number;
+  // 管理者など上限の無いプランの場合はtrue
+  unlimited?: boolean;
+  resets_at?: string;
 }
 
 // AI使用回数を取得
@@ -95,7 +98,7 @@ export const useAIUsage = () => {
     },
   });
 
-  const remainingUsage = data ? data.usage_limit - data.usage_count : null;
+  const remainingUsage = data && !data.unlimited ? Math.max(data.usage_limit - data.usage_count, 0) : null;
 
   return { 
     remainingUsage,
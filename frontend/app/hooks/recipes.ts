@@ -473,20 +473,16 @@ export const handleLikeService = async (
   }
 
   try {
-    const response = await fetch(`${backendUrl}/api/likes/${userId}/${recipeId}`, {
-      method: "POST",
-    });
-
-    if (response.ok) {
-      const data = await response.json();
-      setIsLiked((prev) => !prev);
-      toast.success(data.message || "お気に入りを更新しました");
-    } else {
-      console.error("Failed to toggle like");
-      toast.error("お気に入りの更新に失敗しました");
-    }
-  } catch (error) {
+    // api はログイン中のセッションのトークンを Authorization ヘッダーに付ける（本人以外のいいねは操作できない）
+    const response = await api.post(`/api/likes/${userId}/${recipeId}`);
+    setIsLiked((prev) => !prev);
+    toast.success(response.data?.message || "お気に入りを更新しました");
+  } catch (error: any) {
     console.error("Error toggling like", error);
+    if (error.response?.status === 401) {
+      setShowLoginModal(true);
+      return;
+    }
     toast.error("お気に入りの更新に失敗しました");
   }
 };
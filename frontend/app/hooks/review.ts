@@ -2,6 +2,19 @@
 
 import { backendUrl } from "@/app/utils/api";
 import { Review } from "@/app/types/index"; // レビューデータの型定義
+import { supabase } from "@/app/lib/api/supabase/supabaseClient";
+
+// レビューの投稿・更新・削除に付ける認証ヘッダー（ログイン中のセッションのトークン）
+const authHeaders = async (): Promise<Record<string, string>> => {
+  const { data: { session } } = await supabase.auth.getSession();
+  if (!session) {
+    throw new Error("認証が必要です");
+  }
+  return {
+    "Authorization": `Bearer ${session.access_token}`,
+    "Content-Type": "application/json",
+  };
+};
 
 // Goのレスポンスをキャメルケースに変換する関数
 const convertReview = (review: any): Review => ({
@@ -22,11 +35,8 @@ export const fetchReviewsByRecipeID = async (recipeId: string): Promise<Review[]
       throw new Error("Failed to fetch reviews");
     }
     const data = await response.json();
-    console.log("🌀API Response:", data);
-    console.log("🌀Response Headers:", Object.fromEntries(response.headers.entries()));
-    const convertedReviews = data.map(convertReview);
-    console.log("🌀Converted Reviews:", convertedReviews);
-    return convertedReviews;
+    // レスポンスは { reviews, summary, nextCursor, hasNextPage }（1ページ目のみ取得）
+    return (data.reviews ?? []).map(convertReview);
   } catch (error) {
     console.error("Error fetching reviews:", error);
     throw error;
@@ -53,9 +63,7 @@ export const addReview = async (review: Omit<Review, "id" | "createdAt" | "updat
   try {
     const response = await fetch(`${backendUrl}/api/reviews`, {
       method: "POST",
-      headers: {
-        "Content-Type": "application/json",
-      },
+      headers: await authHeaders(),
       body: JSON.stringify(review),
     });
     if (!response.ok) {
@@ -74,9 +82,7 @@ export const updateReview = async (id: string, updatedReview: Partial<Review>):
   try {
     const response = await fetch(`${backendUrl}/api/reviews/${id}`, {
       method: "PUT",
-      headers: {
-        "Content-Type": "application/json",
-      },
+      headers: await authHeaders(),
       body: JSON.stringify(updatedReview),
     });
     if (!response.ok) {
@@ -95,6 +101,7 @@ export const deleteReview = async (id: string): Promise<void> => {
   try {
     const response = await fetch(`${backendUrl}/api/reviews/${id}`, {
       method: "DELETE",
+      headers: await authHeaders(),
     });
     if (!response.ok) {
       throw new Error("Failed to delete review");
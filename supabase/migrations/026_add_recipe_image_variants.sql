@@ -0,0 +1,4 @@
+-- アップロード時に生成したWebP画像（thumbnail / card / full）のキーを保存する
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS image_variants JSONB;
+
+COMMENT ON COLUMN recipes.image_variants IS 'サイズ別のWebP画像のキー {"thumbnail": ..., "card": ..., "full": ...}';
@@ -0,0 +1,10 @@
+-- AIの使用回数を機能ごとに集計する（既存の行は説明文生成の回数として扱う）
+ALTER TABLE ai_usage ADD COLUMN IF NOT EXISTS feature VARCHAR(50) NOT NULL DEFAULT 'description';
+
+-- ユーザーごとに1行だった制約を、ユーザー×機能ごとに1行へ変更する
+ALTER TABLE ai_usage DROP CONSTRAINT IF EXISTS ai_usage_user_id_key;
+DROP INDEX IF EXISTS ai_usage_user_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS ai_usage_user_id_feature_key ON ai_usage (user_id, feature);
+
+COMMENT ON COLUMN ai_usage.feature IS 'AI機能の種類（description など）';
+COMMENT ON COLUMN ai_usage.last_reset_date IS '現在の集計期間の開始時刻（日本時間の日・月の始まり）';
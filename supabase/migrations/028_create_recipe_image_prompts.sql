@@ -0,0 +1,27 @@
+-- レシピのメイン画像・手順画像の生成プロンプト（step_number = 0 がメイン画像）
+-- 編集するたびにバージョンを追加し、最新のバージョンを現在のプロンプトとして扱う
+CREATE TABLE IF NOT EXISTS recipe_image_prompts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    recipe_id UUID NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+    step_number INT NOT NULL CHECK (step_number >= 0),
+    prompt TEXT NOT NULL,
+    version INT NOT NULL,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('imported', 'default', 'manual')),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (recipe_id, step_number, version)
+);
+
+-- どのバージョンのプロンプトからどの画像を生成したかの記録
+CREATE TABLE IF NOT EXISTS recipe_image_generations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    recipe_id UUID NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+    step_number INT NOT NULL CHECK (step_number >= 0),
+    prompt_id UUID NOT NULL REFERENCES recipe_image_prompts(id) ON DELETE CASCADE,
+    prompt_version INT NOT NULL,
+    image_url TEXT NOT NULL,
+    model VARCHAR(100),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_recipe_image_generations_recipe ON recipe_image_generations (recipe_id, step_number, created_at DESC);
@@ -0,0 +1,14 @@
+-- おすすめ用に事前計算したレシピ間の類似度（いいね・レビューによる協調フィルタリングと具材の重なり）
+-- 定期的に全件を再計算して置き換える
+CREATE TABLE IF NOT EXISTS recipe_similarities (
+    recipe_id UUID NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+    similar_recipe_id UUID NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+    score DOUBLE PRECISION NOT NULL,
+    cf_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ingredient_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    computed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (recipe_id, similar_recipe_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_likes_user_id ON likes (user_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_user_id ON reviews (user_id);
@@ -0,0 +1,16 @@
+-- いいね数・レビュー数・平均評価をレシピに持たせる（いいね・レビューの更新時に同じトランザクションで更新する）
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS like_count INT NOT NULL DEFAULT 0;
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS review_count INT NOT NULL DEFAULT 0;
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS rating_avg NUMERIC(3, 2) NOT NULL DEFAULT 0;
+
+-- 既存のデータから集計
+UPDATE recipes r SET
+    like_count = COALESCE((SELECT COUNT(*) FROM likes l WHERE l.recipe_id = r.id), 0),
+    review_count = COALESCE((SELECT COUNT(*) FROM reviews rv WHERE rv.recipe_id = r.id), 0),
+    rating_avg = COALESCE((SELECT ROUND(AVG(rating)::numeric, 2) FROM reviews rv WHERE rv.recipe_id = r.id), 0);
+
+-- 並び替え用
+CREATE INDEX IF NOT EXISTS idx_recipes_like_count ON recipes (like_count DESC);
+CREATE INDEX IF NOT EXISTS idx_recipes_rating ON recipes (rating_avg DESC, review_count DESC);
+CREATE INDEX IF NOT EXISTS idx_likes_recipe_id ON likes (recipe_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_recipe_id ON reviews (recipe_id);
@@ -0,0 +1,12 @@
+-- 同じユーザーが同じレシピに複数回いいねできないようにする
+-- 既存の重複は最も古い1件を残して削除する
+DELETE FROM likes l
+USING likes dup
+WHERE l.user_id = dup.user_id
+    AND l.recipe_id = dup.recipe_id
+    AND (l.created_at, l.id) > (dup.created_at, dup.id);
+
+ALTER TABLE likes ADD CONSTRAINT likes_user_id_recipe_id_key UNIQUE (user_id, recipe_id);
+
+-- 重複削除に合わせていいね数を集計し直す
+UPDATE recipes r SET like_count = COALESCE((SELECT COUNT(*) FROM likes l WHERE l.recipe_id = r.id), 0);
@@ -0,0 +1,14 @@
+-- レビューは1ユーザー1レシピにつき1件にする
+-- 既存の重複は最後に更新された1件を残して削除する
+DELETE FROM reviews r
+USING reviews newer
+WHERE r.user_id = newer.user_id
+    AND r.recipe_id = newer.recipe_id
+    AND (r.updated_at, r.id) < (newer.updated_at, newer.id);
+
+ALTER TABLE reviews ADD CONSTRAINT reviews_user_id_recipe_id_key UNIQUE (user_id, recipe_id);
+
+-- 重複削除に合わせてレビュー数・平均評価を集計し直す
+UPDATE recipes r SET
+    review_count = COALESCE((SELECT COUNT(*) FROM reviews rv WHERE rv.recipe_id = r.id), 0),
+    rating_avg = COALESCE((SELECT ROUND(AVG(rating)::numeric, 2) FROM reviews rv WHERE rv.recipe_id = r.id), 0);
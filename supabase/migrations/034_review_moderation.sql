@@ -0,0 +1,34 @@
+-- レビューの公開状態（モデレーターが非表示にしたレビューは一覧・集計から除外する）
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'visible';
+CREATE INDEX IF NOT EXISTS idx_reviews_recipe_status ON reviews (recipe_id, status);
+
+-- ユーザーによるレビューの通報
+CREATE TABLE IF NOT EXISTS review_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    review_id UUID NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+    reporter_id UUID NOT NULL,
+    reason VARCHAR(20) NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    resolved_by UUID,
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (review_id, reporter_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_reports_open ON review_reports (status, created_at DESC);
+
+-- モデレーション操作の監査ログ（レビューが削除されても残すため外部キーは張らない）
+CREATE TABLE IF NOT EXISTS review_moderation_actions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    review_id UUID NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    moderator_id UUID,
+    note TEXT NOT NULL DEFAULT '',
+    review_user_id UUID,
+    rating INT,
+    comment TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_moderation_actions_review_id ON review_moderation_actions (review_id, created_at);
@@ -0,0 +1,33 @@
+-- レビューに添付する写真（1件のレビューにつき最大4枚）
+CREATE TABLE IF NOT EXISTS review_photos (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    review_id UUID NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    image_url TEXT NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_photos_review_id ON review_photos (review_id, position);
+
+-- レシピを「作った」記録（レビューを書かなくても記録できる）
+CREATE TABLE IF NOT EXISTS cook_logs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    recipe_id UUID NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+    cooked_on DATE NOT NULL DEFAULT CURRENT_DATE,
+    note TEXT NOT NULL DEFAULT '',
+    servings INT,
+    substitutions JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_cook_logs_user_cooked_on ON cook_logs (user_id, cooked_on DESC);
+CREATE INDEX IF NOT EXISTS idx_cook_logs_recipe_id ON cook_logs (recipe_id);
+
+-- 「作った」回数をレシピに持たせる（記録の追加・削除時に同じトランザクションで更新する）
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS cook_count INT NOT NULL DEFAULT 0;
+
+UPDATE recipes r SET
+    cook_count = COALESCE((SELECT COUNT(*) FROM cook_logs cl WHERE cl.recipe_id = r.id), 0);
@@ -0,0 +1,16 @@
+-- レビューへの「参考になった」の投票（1ユーザー1レビューにつき1票）
+CREATE TABLE IF NOT EXISTS review_helpful_votes (
+    review_id UUID NOT NULL REFERENCES reviews(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (review_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_helpful_votes_user_id ON review_helpful_votes (user_id);
+
+-- 「参考になった」の数をレビューに持たせる（投票と同じトランザクションで更新する）
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS helpful_count INT NOT NULL DEFAULT 0;
+
+-- レビュー一覧のカーソルページング用
+CREATE INDEX IF NOT EXISTS idx_reviews_recipe_created ON reviews (recipe_id, status, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_reviews_recipe_helpful ON reviews (recipe_id, status, helpful_count DESC, created_at DESC);
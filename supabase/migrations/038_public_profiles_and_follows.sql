@@ -0,0 +1,16 @@
+-- 公開プロフィールに表示する自己紹介
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bio TEXT;
+
+-- ユーザー間のフォロー関係
+CREATE TABLE IF NOT EXISTS follows (
+    follower_id UUID NOT NULL,
+    followee_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, followee_id),
+    CHECK (follower_id <> followee_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_follows_followee_id ON follows (followee_id, created_at DESC);
+
+-- 公開プロフィールのレシピ一覧用
+CREATE INDEX IF NOT EXISTS idx_recipes_user_id ON recipes (user_id, created_at DESC);
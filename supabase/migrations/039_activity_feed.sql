@@ -0,0 +1,50 @@
+-- フォローしているユーザーの新着（公開したレシピ・投稿したレビュー・公開したコレクション）。対象ごとに1件。
+CREATE TABLE IF NOT EXISTS activities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    actor_id UUID NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    subject_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (kind, subject_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_activities_actor_id ON activities (actor_id, created_at DESC);
+
+-- フォロワーごとのフィード（新着の公開時にフォロワー全員分を書き込む）
+CREATE TABLE IF NOT EXISTS feed_items (
+    user_id UUID NOT NULL,
+    activity_id UUID NOT NULL REFERENCES activities(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, activity_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_feed_items_user_id ON feed_items (user_id, created_at DESC, activity_id DESC);
+CREATE INDEX IF NOT EXISTS idx_feed_items_activity_id ON feed_items (activity_id);
+
+-- 既存の公開中のデータを新着として記録（フォローした時点で過去の新着を取り込めるように）
+INSERT INTO activities (actor_id, kind, subject_id, created_at)
+SELECT user_id, 'recipe', id, created_at FROM recipes
+WHERE user_id IS NOT NULL AND is_public = true AND is_draft = false
+ON CONFLICT (kind, subject_id) DO NOTHING;
+
+INSERT INTO activities (actor_id, kind, subject_id, created_at)
+SELECT user_id, 'review', id, created_at FROM reviews
+WHERE status = 'visible'
+ON CONFLICT (kind, subject_id) DO NOTHING;
+
+INSERT INTO activities (actor_id, kind, subject_id, created_at)
+SELECT user_id, 'collection', id, updated_at FROM collections
+WHERE is_public = true
+ON CONFLICT (kind, subject_id) DO NOTHING;
+
+-- 既にフォローしている相手の最近の新着をフィードに取り込む
+INSERT INTO feed_items (user_id, activity_id, created_at)
+SELECT follows.follower_id, recent.id, recent.created_at
+FROM follows
+CROSS JOIN LATERAL (
+    SELECT id, created_at FROM activities
+    WHERE actor_id = follows.followee_id
+    ORDER BY created_at DESC
+    LIMIT 20 -- feed.BackfillLimit と同じ件数
+) AS recent
+ON CONFLICT (user_id, activity_id) DO NOTHING;
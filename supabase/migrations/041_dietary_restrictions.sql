@@ -0,0 +1,57 @@
+-- 具材のアレルゲン・食事制限のタグ（code の制限がある人はこの具材を避ける）
+CREATE TABLE IF NOT EXISTS ingredient_dietary_tags (
+    ingredient_id INT NOT NULL REFERENCES ingredients(id) ON DELETE CASCADE,
+    code TEXT NOT NULL,
+    PRIMARY KEY (ingredient_id, code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingredient_dietary_tags_code ON ingredient_dietary_tags (code);
+
+-- ユーザーが検索・おすすめから除外したいアレルゲン・食事制限
+CREATE TABLE IF NOT EXISTS user_dietary_restrictions (
+    user_id UUID NOT NULL,
+    code TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, code)
+);
+
+-- 既存の具材にタグを付ける（アレルゲン：特定原材料）
+INSERT INTO ingredient_dietary_tags (ingredient_id, code)
+SELECT ingredients.id, tags.code
+FROM (VALUES
+    ('エビ', 'shrimp'), ('甘エビ', 'shrimp'), ('伊勢エビ', 'shrimp'), ('さくらえび', 'shrimp'), ('シーフードミックス', 'shrimp'),
+    ('カニ', 'crab'),
+    ('ナッツ', 'walnut'),
+    ('うどん', 'wheat'), ('麺', 'wheat'), ('パスタ', 'wheat'), ('ショートパスタ', 'wheat'), ('食パン', 'wheat'),
+    ('パン', 'wheat'), ('フランスパン', 'wheat'), ('そうめん', 'wheat'), ('小麦粉', 'wheat'), ('パン粉', 'wheat'),
+    ('お好み焼き粉', 'wheat'), ('ホットケーキミックス', 'wheat'), ('てんぷら粉', 'wheat'), ('醤油', 'wheat'),
+    ('天かす', 'wheat'), ('ぎょうざの皮', 'wheat'), ('麩', 'wheat'), ('クラッカー', 'wheat'),
+    ('そば', 'buckwheat'), ('そば米', 'buckwheat'), ('そば粉', 'buckwheat'),
+    ('卵', 'egg'), ('うずらの卵', 'egg'), ('ピータン', 'egg'), ('マヨネーズ', 'egg'),
+    ('牛乳', 'milk'), ('ヨーグルト', 'milk'), ('クリーム', 'milk'), ('プロセスチーズ', 'milk'), ('スライスチーズ', 'milk'),
+    ('粉チーズ', 'milk'), ('クリームチーズ', 'milk'), ('モッツァレラチーズ', 'milk'), ('カマンベールチーズ', 'milk'),
+    ('チーズ', 'milk'), ('スキムミルク', 'milk'), ('サワークリーム', 'milk'), ('バター', 'milk'),
+    ('アイスクリーム', 'milk'), ('ホワイトソース', 'milk'),
+    ('ピーナッツバター', 'peanut'), ('ナッツ', 'peanut')
+) AS tags (name, code)
+JOIN ingredients ON ingredients.name = tags.name
+ON CONFLICT (ingredient_id, code) DO NOTHING;
+
+-- 食事制限：肉・魚介（ジャンル 2・3）と動物性のだし・調味料はベジタリアン向けでない
+INSERT INTO ingredient_dietary_tags (ingredient_id, code)
+SELECT id, 'vegetarian' FROM ingredients WHERE genre_id IN (2, 3)
+ON CONFLICT (ingredient_id, code) DO NOTHING;
+
+INSERT INTO ingredient_dietary_tags (ingredient_id, code)
+SELECT ingredients.id, tags.code
+FROM (VALUES
+    ('かつお節', 'vegetarian'), ('ゼラチン', 'vegetarian'), ('オイスターソース', 'vegetarian'), ('ナンプラー', 'vegetarian'),
+    ('豚ひき肉', 'no_pork'), ('豚ロース肉', 'no_pork'), ('豚もも肉', 'no_pork'), ('豚バラ肉', 'no_pork'), ('豚ヒレ肉', 'no_pork'),
+    ('豚レバー', 'no_pork'), ('豚もつ', 'no_pork'), ('ベーコン', 'no_pork'), ('ソーセージ', 'no_pork'), ('ハム', 'no_pork'),
+    ('チャーシュー', 'no_pork'), ('スパム', 'no_pork'), ('サラミ', 'no_pork'),
+    ('塩', 'low_salt'), ('醤油', 'low_salt'), ('みそ', 'low_salt'), ('塩昆布', 'low_salt'), ('塩こうじ', 'low_salt'),
+    ('めんつゆ（3倍濃縮）', 'low_salt'), ('ナンプラー', 'low_salt'), ('梅干し', 'low_salt'), ('たらこ', 'low_salt'),
+    ('ザーサイ', 'low_salt'), ('高菜', 'low_salt'), ('野沢菜', 'low_salt'), ('たくあん', 'low_salt')
+) AS tags (name, code)
+JOIN ingredients ON ingredients.name = tags.name
+ON CONFLICT (ingredient_id, code) DO NOTHING;